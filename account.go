@@ -75,6 +75,8 @@ type AccountSummary struct {
 		ID                          string    `json:"id"`
 		LastTransactionID           string    `json:"lastTransactionID"`
 		MarginAvailable             float64   `json:"marginAvailable,string"`
+		MarginCallMarginUsed        string    `json:"marginCallMarginUsed"`
+		MarginCallPercent           string    `json:"marginCallPercent"`
 		MarginCloseoutMarginUsed    string    `json:"marginCloseoutMarginUsed"`
 		MarginCloseoutNAV           string    `json:"marginCloseoutNAV"`
 		MarginCloseoutPercent       string    `json:"marginCloseoutPercent"`
@@ -94,7 +96,9 @@ type AccountSummary struct {
 	LastTransactionID string `json:"lastTransactionID"`
 }
 
-type Instruments []struct {
+type Instruments []InstrumentDetails
+
+type InstrumentDetails struct {
 	DisplayName                 string `json:"displayName"`
 	DisplayPrecision            int    `json:"displayPrecision"`
 	MarginRate                  string `json:"marginRate"`
@@ -254,6 +254,8 @@ func TestGetAccountSummary(t *testing.T) {
 				ID                          string    `json:"id"`
 				LastTransactionID           string    `json:"lastTransactionID"`
 				MarginAvailable             float64   `json:"marginAvailable,string"`
+				MarginCallMarginUsed        string    `json:"marginCallMarginUsed"`
+				MarginCallPercent           string    `json:"marginCallPercent"`
 				MarginCloseoutMarginUsed    string    `json:"marginCloseoutMarginUsed"`
 				MarginCloseoutNAV           string    `json:"marginCloseoutNAV"`
 				MarginCloseoutPercent       string    `json:"marginCloseoutPercent"`
@@ -0,0 +1,83 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultAccountChangesInterval is how often StreamAccountChanges polls when no AccountChangesInterval is set
+const DefaultAccountChangesInterval = 5 * time.Second
+
+// StreamAccountChanges delivers the account's changes until ctx is cancelled, returning ctx.Err(), or a
+// poll fails. OANDA has no account changes stream, so GET /accounts/{id}/changes is polled every
+// AccountChangesInterval with the sinceTransactionID of the previous poll, starting from the account's
+// last transaction. A response is delivered for each poll that found new transactions, Decode gives
+// its changes and state. With a Reconnect policy, failed polls are logged, passed to OnError and
+// retried at the next interval, until MaxRetries consecutive polls have failed.
+func (sc *StreamingConnection) StreamAccountChanges(ctx context.Context, callback func(AccountChangesStreamResponse)) error {
+	return sc.StreamAccountChangesSince(ctx, "", callback)
+}
+
+// StreamAccountChangesSince is StreamAccountChanges, first delivering the changes since lastTransactionID,
+// such as the last one applied to a LocalAccountState. An empty lastTransactionID starts from now.
+func (sc *StreamingConnection) StreamAccountChangesSince(ctx context.Context, lastTransactionID string, callback func(AccountChangesStreamResponse)) error {
+	return sc.streamAccountChanges(ctx, lastTransactionID, func(response AccountChangesStreamResponse) error {
+		callback(response)
+		return nil
+	})
+}
+
+func (sc *StreamingConnection) streamAccountChanges(ctx context.Context, last string, handler func(AccountChangesStreamResponse) error) error {
+	interval := sc.AccountChangesInterval
+	if interval <= 0 {
+		interval = DefaultAccountChangesInterval
+	}
+	endpoint := fmt.Sprintf("/accounts/%s/changes", sc.account())
+
+	return sc.poll(ctx, endpoint, interval, func() error {
+		if last == "" {
+			var summary struct {
+				LastTransactionID string `json:"lastTransactionID"`
+			}
+			if err := sc.getAndUnmarshal(fmt.Sprintf("/accounts/%s/summary", sc.account()), &summary); err != nil {
+				return &dropError{err}
+			}
+			last = summary.LastTransactionID
+			return nil
+		}
+
+		data, err := sc.Get(endpoint + "?sinceTransactionID=" + last)
+		if err != nil {
+			return &dropError{err}
+		}
+		response := AccountChangesStreamResponse{Type: "ACCOUNT_CHANGES", Time: time.Now().UTC().Format(time.RFC3339Nano)}
+		if err := json.Unmarshal(data, &response); err != nil {
+			return &dropError{fmt.Errorf("goanda: decoding account changes: %w", err)}
+		}
+		if response.LastTransactionID == "" || response.LastTransactionID == last {
+			return nil
+		}
+		last = response.LastTransactionID
+		return sc.deliver(endpoint, func([]byte) error {
+			return handler(response)
+		}, data)
+	})
+}
+
+// Decode decodes the polled changes and state, to apply with ApplyChanges
+func (r AccountChangesStreamResponse) Decode() (AccountChanges, error) {
+	changes := AccountChanges{LastTransactionID: r.LastTransactionID}
+	if len(r.Changes) > 0 {
+		if err := json.Unmarshal(r.Changes, &changes.Changes); err != nil {
+			return changes, fmt.Errorf("goanda: decoding account changes: %w", err)
+		}
+	}
+	if len(r.State) > 0 {
+		if err := json.Unmarshal(r.State, &changes.State); err != nil {
+			return changes, fmt.Errorf("goanda: decoding account state: %w", err)
+		}
+	}
+	return changes, nil
+}
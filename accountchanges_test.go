@@ -0,0 +1,139 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStreamAccountChanges(t *testing.T) {
+	defer logTestResult(t, "StreamAccountChanges")
+
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/summary":
+			w.Write([]byte(`{"account":{"id":"test-account"},"lastTransactionID":"10"}`))
+		case "/accounts/test-account/changes":
+			since := r.URL.Query().Get("sinceTransactionID")
+			// Nothing happens on the first poll, then an order fills
+			if since == "10" && atomic.AddInt32(&polls, 1) > 1 {
+				w.Write([]byte(`{"changes":{"ordersFilled":[{"id":"11","instrument":"EUR_USD","units":"100"}]},` +
+					`"state":{"NAV":"1000.5"},"lastTransactionID":"12"}`))
+				return
+			}
+			w.Write([]byte(`{"changes":{},"state":{"NAV":"1000.0"},"lastTransactionID":"` + since + `"}`))
+		default:
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.AccountChangesInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var responses []AccountChangesStreamResponse
+	err := sc.StreamAccountChanges(ctx, func(response AccountChangesStreamResponse) {
+		responses = append(responses, response)
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if len(responses) != 1 {
+		t.Fatalf("Expected only the poll with changes, got %d", len(responses))
+	}
+	response := responses[0]
+	if response.Type != "ACCOUNT_CHANGES" || response.LastTransactionID != "12" {
+		t.Errorf("Expected the changes up to 12, got %+v", response)
+	}
+	changes, err := response.Decode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes.Changes.OrdersFilled) != 1 || changes.Changes.OrdersFilled[0].ID != "11" || changes.State.NAV != "1000.5" {
+		t.Errorf("Expected the decoded fill and state, got %+v", changes)
+	}
+}
+
+func TestStreamAccountChangesSince(t *testing.T) {
+	defer logTestResult(t, "StreamAccountChangesSince")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/changes" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		if since := r.URL.Query().Get("sinceTransactionID"); since != "5" {
+			t.Errorf("Expected changes since 5, got %s", since)
+		}
+		w.Write([]byte(`{"changes":{},"lastTransactionID":"6"}`))
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+
+	var last string
+	err := sc.streamAccountChanges(context.Background(), "5", func(response AccountChangesStreamResponse) error {
+		last = response.LastTransactionID
+		return errStopStream
+	})
+	if err != nil || last != "6" {
+		t.Errorf("Expected the changes since 5 to be delivered at once, got %q (%v)", last, err)
+	}
+}
+
+func TestStreamAccountChangesStops(t *testing.T) {
+	defer logTestResult(t, "StreamAccountChangesStops")
+
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&polls, 1) == 1 {
+			http.Error(w, `{"errorMessage":"Insufficient authorization to perform request."}`, http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"account":{"id":"test-account"},"lastTransactionID":"10"}`))
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()})
+	sc.Reconnect = &ReconnectPolicy{}
+	sc.OnError = func(err error) { t.Errorf("Expected a refused poll not to be retried, got %v", err) }
+	var apiErr APIError
+	if err := sc.StreamAccountChanges(context.Background(), func(AccountChangesStreamResponse) {}); !errors.As(err, &apiErr) {
+		t.Errorf("Expected the 403 to end polling, got %v", err)
+	}
+
+	// Closing the connection stops polling, and polling can't start on a closed connection
+	result := make(chan error, 1)
+	go func() {
+		result <- sc.StreamAccountChanges(context.Background(), func(AccountChangesStreamResponse) {})
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&polls) > 1 })
+	sc.Close()
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrConnectionClosed) {
+			t.Errorf("Expected ErrConnectionClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected polling to end when the connection closed")
+	}
+	if err := sc.StreamAccountChanges(context.Background(), func(AccountChangesStreamResponse) {}); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Expected ErrConnectionClosed, got %v", err)
+	}
+}
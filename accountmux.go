@@ -0,0 +1,90 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultMaxStreams is the number of streams an AccountMux may hold open at once when no MaxStreams is set,
+// OANDA limits how many concurrent streams a token can have
+const DefaultMaxStreams = 20
+
+// AccountMux streams several accounts reachable with one token, such as a master account's
+// sub-accounts, and merges their events into one channel. Each Event carries its AccountID.
+type AccountMux struct {
+	// Options select the streams opened for every account, see Events
+	Options EventsOptions
+	// LastTransactionIDs, if set, are the transactions after which each account's transaction stream
+	// starts, in place of Options.LastTransactionID
+	LastTransactionIDs map[string]string
+	// MaxStreams is the most streams the mux opens, it defaults to DefaultMaxStreams
+	MaxStreams int
+
+	sc       *StreamingConnection
+	accounts []string
+}
+
+// NewAccountMux creates a mux for the accounts using the streaming connection's settings
+func NewAccountMux(sc *StreamingConnection, accountIDs ...string) *AccountMux {
+	return &AccountMux{sc: sc, accounts: accountIDs}
+}
+
+// Streams returns the number of streams Events opens
+func (m *AccountMux) Streams() int {
+	perAccount := 0
+	if len(m.Options.Instruments) > 0 {
+		perAccount++
+	}
+	if m.Options.Transactions {
+		perAccount++
+	}
+	return perAccount * len(m.accounts)
+}
+
+// Events opens the streams of every account and merges their events into one channel. It returns an
+// error, without opening any, if they would exceed MaxStreams. An account whose stream fails sends
+// an EventError for it and closes its other streams, the other accounts keep streaming.
+// The channel is closed once every account's streams have ended, or ctx is done.
+func (m *AccountMux) Events(ctx context.Context) (<-chan Event, error) {
+	limit := m.MaxStreams
+	if limit <= 0 {
+		limit = DefaultMaxStreams
+	}
+	if streams := m.Streams(); streams > limit {
+		return nil, fmt.Errorf("goanda: %d accounts need %d streams, more than the limit of %d", len(m.accounts), streams, limit)
+	}
+
+	buffer := m.Options.BufferSize
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+	merged := make(chan Event, buffer)
+
+	var wg sync.WaitGroup
+	for _, accountID := range m.accounts {
+		opts := m.Options
+		if last, ok := m.LastTransactionIDs[accountID]; ok {
+			opts.LastTransactionID = last
+		}
+		events := m.sc.ForAccount(accountID).Events(ctx, opts)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range events {
+				select {
+				case merged <- event:
+				case <-ctx.Done():
+					// Keep draining, so the account's streams can see ctx is done and end
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+	return merged, nil
+}
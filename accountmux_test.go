@@ -0,0 +1,61 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccountMux(t *testing.T) {
+	defer logTestResult(t, "AccountMux")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		account := strings.Split(r.URL.Path, "/")[2]
+		if account == "sub-3" {
+			http.Error(w, `{"errorMessage":"no access"}`, http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, `{"type":"ORDER_FILL","time":"2024-01-02T15:04:05Z","id":"%s"}`+"\n", account)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "master",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	mux := NewAccountMux(sc, "sub-1", "sub-2", "sub-3")
+	mux.Options = EventsOptions{Transactions: true}
+	mux.MaxStreams = 2
+	if _, err := mux.Events(context.Background()); err == nil {
+		t.Fatal("Expected an error for more streams than the limit")
+	}
+	mux.MaxStreams = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := mux.Events(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	seen := map[string]EventKind{}
+	for event := range events {
+		seen[event.AccountID] = event.Kind
+		if event.Kind == EventTransaction && event.Transaction.ID != event.AccountID {
+			t.Errorf("Event labelled with the wrong account: %+v", event)
+		}
+		if len(seen) == 3 {
+			cancel()
+		}
+	}
+	if seen["sub-1"] != EventTransaction || seen["sub-2"] != EventTransaction || seen["sub-3"] != EventError {
+		t.Errorf("Unexpected events: %v", seen)
+	}
+}
@@ -0,0 +1,266 @@
+package goanda
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// LocalAccountState is a locally maintained model of an account, built from a full snapshot and kept
+// current by applying the changes OANDA reports since its LastTransactionID
+type LocalAccountState struct {
+	LastTransactionID string
+	Balance           string
+	NAV               string
+	UnrealizedPL      string
+	MarginUsed        string
+	MarginAvailable   string
+	PositionValue     string
+	WithdrawalLimit   string
+
+	// Orders are the pending orders, Trades the open trades and Positions the open positions, keyed
+	// by order ID, trade ID and instrument
+	Orders    map[string]OrderInfo
+	Trades    map[string]Trade
+	Positions map[string]LocalPosition
+}
+
+// LocalPosition is an open position in a LocalAccountState
+type LocalPosition struct {
+	Instrument   string
+	Pl           string
+	ResettablePL string
+	UnrealizedPL string
+	Long         LocalPositionSide
+	Short        LocalPositionSide
+}
+
+// LocalPositionSide is the long or short side of a LocalPosition
+type LocalPositionSide struct {
+	Units        string
+	Pl           string
+	ResettablePL string
+	UnrealizedPL string
+}
+
+func (p LocalPosition) open() bool {
+	return !zeroUnits(p.Long.Units) || !zeroUnits(p.Short.Units)
+}
+
+func zeroUnits(units string) bool {
+	return units == "" || units == "0"
+}
+
+// NewLocalAccountState builds a local model from a full account snapshot
+func NewLocalAccountState(info AccountInfo) (*LocalAccountState, error) {
+	account := info.Account
+	state := &LocalAccountState{
+		LastTransactionID: info.LastTransactionID,
+		Balance:           account.Balance,
+		NAV:               account.NAV,
+		UnrealizedPL:      account.UnrealizedPL,
+		MarginUsed:        account.MarginUsed,
+		MarginAvailable:   account.MarginAvailable,
+		PositionValue:     account.PositionValue,
+		WithdrawalLimit:   account.WithdrawalLimit,
+		Orders:            map[string]OrderInfo{},
+		Trades:            map[string]Trade{},
+		Positions:         map[string]LocalPosition{},
+	}
+
+	if err := addOrders(state, account.Orders); err != nil {
+		return nil, err
+	}
+	if err := putTrades(state, account.Trades); err != nil {
+		return nil, err
+	}
+	for _, p := range account.Positions {
+		position := LocalPosition{
+			Instrument:   p.Instrument,
+			Pl:           p.Pl,
+			ResettablePL: p.ResettablePL,
+			UnrealizedPL: p.UnrealizedPL,
+			Long:         LocalPositionSide{p.Long.Units, p.Long.Pl, p.Long.ResettablePL, p.Long.UnrealizedPL},
+			Short:        LocalPositionSide{p.Short.Units, p.Short.Pl, p.Short.ResettablePL, p.Short.UnrealizedPL},
+		}
+		if position.open() {
+			state.Positions[p.Instrument] = position
+		}
+	}
+	return state, nil
+}
+
+// ResetState empties the model, as after the account is reset. It is to be rebuilt from a new snapshot,
+// the changes since its LastTransactionID no longer making sense.
+func (state *LocalAccountState) ResetState() error {
+	*state = LocalAccountState{
+		Orders:    map[string]OrderInfo{},
+		Trades:    map[string]Trade{},
+		Positions: map[string]LocalPosition{},
+	}
+	return nil
+}
+
+// ApplyChanges updates the local model with the changes since its LastTransactionID, as returned by
+// GetAccountChanges. Created orders are added and filled, cancelled or triggered orders removed; opened
+// trades are added, reduced trades replaced and closed trades removed; changed positions are replaced,
+// or removed once they hold no units; and the account's figures and unrealized P/L are taken from the
+// changes' state. Changes that are not newer than the model are ignored, so applying them twice is harmless.
+func ApplyChanges(state *LocalAccountState, changes AccountChanges) error {
+	if state.LastTransactionID != "" && compareTransactionIDs(changes.LastTransactionID, state.LastTransactionID) <= 0 {
+		return nil
+	}
+	if state.Orders == nil {
+		state.Orders = map[string]OrderInfo{}
+	}
+	if state.Trades == nil {
+		state.Trades = map[string]Trade{}
+	}
+	if state.Positions == nil {
+		state.Positions = map[string]LocalPosition{}
+	}
+
+	c := changes.Changes
+	if err := addOrders(state, c.OrdersCreated); err != nil {
+		return err
+	}
+	for _, removed := range [][]interface{}{c.OrdersCancelled, c.OrdersTriggered} {
+		ids, err := changedIDs(removed)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			delete(state.Orders, id)
+		}
+	}
+	for _, o := range c.OrdersFilled {
+		delete(state.Orders, o.ID)
+	}
+
+	for _, t := range c.TradesOpened {
+		var trade Trade
+		if err := convertChange(t, &trade); err != nil {
+			return err
+		}
+		state.Trades[trade.ID] = trade
+	}
+	if err := putTrades(state, c.TradesReduced); err != nil {
+		return err
+	}
+	closed, err := changedIDs(c.TradesClosed)
+	if err != nil {
+		return err
+	}
+	for _, id := range closed {
+		delete(state.Trades, id)
+	}
+
+	for _, p := range c.Positions {
+		position := LocalPosition{
+			Instrument:   p.Instrument,
+			Pl:           p.Pl,
+			ResettablePL: p.ResettablePL,
+			Long:         LocalPositionSide{Units: p.Long.Units, Pl: p.Long.Pl, ResettablePL: p.Long.ResettablePL},
+			Short:        LocalPositionSide{Units: p.Short.Units, Pl: p.Short.Pl, ResettablePL: p.Short.ResettablePL},
+		}
+		if !position.open() {
+			delete(state.Positions, p.Instrument)
+			continue
+		}
+		// Unrealized P/L is reported in the state rather than with the position
+		if previous, ok := state.Positions[p.Instrument]; ok {
+			position.UnrealizedPL = previous.UnrealizedPL
+			position.Long.UnrealizedPL = previous.Long.UnrealizedPL
+			position.Short.UnrealizedPL = previous.Short.UnrealizedPL
+		}
+		state.Positions[p.Instrument] = position
+	}
+
+	for _, t := range c.Transactions {
+		if t.AccountBalance != "" {
+			state.Balance = t.AccountBalance
+		}
+	}
+
+	s := changes.State
+	state.NAV = s.NAV
+	state.UnrealizedPL = s.UnrealizedPL
+	state.MarginUsed = s.MarginUsed
+	state.MarginAvailable = s.MarginAvailable
+	state.PositionValue = s.PositionValue
+	state.WithdrawalLimit = s.WithdrawalLimit
+	for _, t := range s.Trades {
+		if trade, ok := state.Trades[t.ID]; ok {
+			trade.UnrealizedPL = t.UnrealizedPL
+			state.Trades[t.ID] = trade
+		}
+	}
+	for _, p := range s.Positions {
+		if position, ok := state.Positions[p.Instrument]; ok {
+			position.UnrealizedPL = p.NetUnrealizedPL
+			position.Long.UnrealizedPL = p.LongUnrealizedPL
+			position.Short.UnrealizedPL = p.ShortUnrealizedPL
+			state.Positions[p.Instrument] = position
+		}
+	}
+
+	state.LastTransactionID = changes.LastTransactionID
+	return nil
+}
+
+// SyncAccountChanges fetches the account's changes since the model's LastTransactionID and applies them
+func (c *Connection) SyncAccountChanges(state *LocalAccountState) error {
+	changes, err := c.GetAccountChanges(c.accountID, state.LastTransactionID)
+	if err != nil {
+		return err
+	}
+	return ApplyChanges(state, changes)
+}
+
+func addOrders(state *LocalAccountState, orders []interface{}) error {
+	for _, o := range orders {
+		var order OrderInfo
+		if err := convertChange(o, &order); err != nil {
+			return err
+		}
+		state.Orders[order.ID] = order
+	}
+	return nil
+}
+
+func putTrades(state *LocalAccountState, trades []interface{}) error {
+	for _, t := range trades {
+		var trade Trade
+		if err := convertChange(t, &trade); err != nil {
+			return err
+		}
+		state.Trades[trade.ID] = trade
+	}
+	return nil
+}
+
+func changedIDs(items []interface{}) ([]string, error) {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		var v struct {
+			ID string `json:"id"`
+		}
+		if err := convertChange(item, &v); err != nil {
+			return nil, err
+		}
+		ids = append(ids, v.ID)
+	}
+	return ids, nil
+}
+
+// convertChange decodes an element of an account snapshot or change set, which are decoded generically,
+// into its typed form
+func convertChange(from interface{}, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, to); err != nil {
+		return fmt.Errorf("goanda: decoding account change: %w", err)
+	}
+	return nil
+}
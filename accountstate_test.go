@@ -0,0 +1,340 @@
+package goanda
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// accountSim is a simulated account that reports each step both as OANDA's account changes and as a full snapshot
+type accountSim struct {
+	rng     *rand.Rand
+	lastID  int
+	balance int
+	orders  map[string]map[string]any
+	trades  map[string]*simTrade
+	pl      map[string][2]int
+	touched []string
+}
+
+type simTrade struct {
+	id         string
+	instrument string
+	initial    int
+	current    int
+	realized   int
+	unrealized int
+}
+
+var simInstruments = []string{"EUR_USD", "GBP_USD", "USD_JPY"}
+
+func newAccountSim(seed uint64) *accountSim {
+	return &accountSim{
+		rng:     rand.New(rand.NewPCG(seed, seed)),
+		lastID:  100,
+		balance: 100000,
+		orders:  map[string]map[string]any{},
+		trades:  map[string]*simTrade{},
+		pl:      map[string][2]int{},
+	}
+}
+
+func (s *accountSim) id() string {
+	s.lastID++
+	return strconv.Itoa(s.lastID)
+}
+
+func (t *simTrade) json() map[string]any {
+	return map[string]any{
+		"id":           t.id,
+		"instrument":   t.instrument,
+		"price":        "1.10000",
+		"openTime":     "2024-01-02T15:04:05Z",
+		"state":        "OPEN",
+		"initialUnits": strconv.Itoa(t.initial),
+		"currentUnits": strconv.Itoa(t.current),
+		"realizedPL":   strconv.Itoa(t.realized),
+		"financing":    "0",
+		"unrealizedPL": strconv.Itoa(t.unrealized),
+	}
+}
+
+func side(units int) int {
+	if units < 0 {
+		return 1
+	}
+	return 0
+}
+
+func (s *accountSim) sortedKeys(m map[string]*simTrade) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (s *accountSim) pick(keys []string) string {
+	return keys[s.rng.IntN(len(keys))]
+}
+
+// step performs a few random operations, returning the account changes they produce
+func (s *accountSim) step() AccountChanges {
+	changes := map[string][]any{}
+	add := func(key string, v any) { changes[key] = append(changes[key], v) }
+	s.touched = nil
+
+	for n := 1 + s.rng.IntN(4); n > 0; n-- {
+		var orderIDs []string
+		for id := range s.orders {
+			orderIDs = append(orderIDs, id)
+		}
+		slices.Sort(orderIDs)
+		tradeIDs := s.sortedKeys(s.trades)
+
+		switch op := s.rng.IntN(6); {
+		case op == 0 || len(orderIDs) == 0 && op < 3:
+			units := (1 + s.rng.IntN(10)) * 1000
+			if s.rng.IntN(2) == 0 {
+				units = -units
+			}
+			order := map[string]any{
+				"id":          s.id(),
+				"instrument":  simInstruments[s.rng.IntN(len(simInstruments))],
+				"units":       strconv.Itoa(units),
+				"type":        "LIMIT",
+				"state":       "PENDING",
+				"timeInForce": "GTC",
+				"price":       "1.05000",
+			}
+			s.orders[order["id"].(string)] = order
+			add("ordersCreated", order)
+		case op == 1:
+			order := s.orders[s.pick(orderIDs)]
+			delete(s.orders, order["id"].(string))
+			cancelled := map[string]any{}
+			for k, v := range order {
+				cancelled[k] = v
+			}
+			cancelled["state"] = "CANCELLED"
+			add("ordersCancelled", cancelled)
+		case op == 2:
+			order := s.orders[s.pick(orderIDs)]
+			delete(s.orders, order["id"].(string))
+			units, _ := strconv.Atoi(order["units"].(string))
+			trade := &simTrade{id: s.id(), instrument: order["instrument"].(string), initial: units, current: units}
+			s.trades[trade.id] = trade
+			add("ordersFilled", map[string]any{"id": order["id"], "instrument": trade.instrument, "state": "FILLED", "tradeOpenedID": trade.id})
+			add("tradesOpened", trade.json())
+			s.touched = append(s.touched, trade.instrument)
+		case (op == 3 || op == 4) && len(tradeIDs) > 0:
+			trade := s.trades[s.pick(tradeIDs)]
+			realized := s.rng.IntN(200) - 100
+			trade.realized += realized
+			s.balance += realized
+			pl := s.pl[trade.instrument]
+			pl[side(trade.current)] += realized
+			s.pl[trade.instrument] = pl
+			s.touched = append(s.touched, trade.instrument)
+			if op == 3 && trade.current/2 != 0 {
+				trade.current -= trade.current / 2
+				add("tradesReduced", trade.json())
+			} else {
+				delete(s.trades, trade.id)
+				closed := trade.json()
+				closed["state"] = "CLOSED"
+				closed["currentUnits"] = "0"
+				add("tradesClosed", closed)
+			}
+		default:
+			// Prices moved, changing only the state
+		}
+	}
+
+	for _, trade := range s.trades {
+		trade.unrealized = s.rng.IntN(1000) - 500
+	}
+
+	var positions []any
+	touched := slices.Compact(slices.Sorted(slices.Values(s.touched)))
+	for _, instrument := range touched {
+		positions = append(positions, s.position(instrument))
+	}
+
+	lastID := s.id()
+	body := map[string]any{
+		"changes": map[string]any{
+			"ordersCreated":   changes["ordersCreated"],
+			"ordersCancelled": changes["ordersCancelled"],
+			"ordersFilled":    changes["ordersFilled"],
+			"tradesOpened":    changes["tradesOpened"],
+			"tradesReduced":   changes["tradesReduced"],
+			"tradesClosed":    changes["tradesClosed"],
+			"positions":       positions,
+			"transactions":    []any{map[string]any{"id": lastID, "type": "ORDER_FILL", "accountBalance": strconv.Itoa(s.balance)}},
+		},
+		"state":             s.state(),
+		"lastTransactionID": lastID,
+	}
+
+	var ac AccountChanges
+	data, _ := json.Marshal(body)
+	if err := json.Unmarshal(data, &ac); err != nil {
+		panic(err)
+	}
+	return ac
+}
+
+func (s *accountSim) position(instrument string) map[string]any {
+	var units, unrealized [2]int
+	for _, trade := range s.trades {
+		if trade.instrument == instrument {
+			units[side(trade.current)] += trade.current
+			unrealized[side(trade.current)] += trade.unrealized
+		}
+	}
+	pl := s.pl[instrument]
+	return map[string]any{
+		"instrument":   instrument,
+		"pl":           strconv.Itoa(pl[0] + pl[1]),
+		"resettablePL": strconv.Itoa(pl[0] + pl[1]),
+		"unrealizedPL": strconv.Itoa(unrealized[0] + unrealized[1]),
+		"long":         map[string]any{"units": strconv.Itoa(units[0]), "pl": strconv.Itoa(pl[0]), "resettablePL": strconv.Itoa(pl[0]), "unrealizedPL": strconv.Itoa(unrealized[0])},
+		"short":        map[string]any{"units": strconv.Itoa(units[1]), "pl": strconv.Itoa(pl[1]), "resettablePL": strconv.Itoa(pl[1]), "unrealizedPL": strconv.Itoa(unrealized[1])},
+	}
+}
+
+func (s *accountSim) unrealized() int {
+	total := 0
+	for _, trade := range s.trades {
+		total += trade.unrealized
+	}
+	return total
+}
+
+func (s *accountSim) state() map[string]any {
+	var trades, positions []any
+	for _, id := range s.sortedKeys(s.trades) {
+		trades = append(trades, map[string]any{"id": id, "unrealizedPL": strconv.Itoa(s.trades[id].unrealized)})
+	}
+	for _, instrument := range simInstruments {
+		p := s.position(instrument)
+		positions = append(positions, map[string]any{
+			"instrument":        instrument,
+			"netUnrealizedPL":   p["unrealizedPL"],
+			"longUnrealizedPL":  p["long"].(map[string]any)["unrealizedPL"],
+			"shortUnrealizedPL": p["short"].(map[string]any)["unrealizedPL"],
+		})
+	}
+	return map[string]any{
+		"NAV":             strconv.Itoa(s.balance + s.unrealized()),
+		"unrealizedPL":    strconv.Itoa(s.unrealized()),
+		"marginUsed":      strconv.Itoa(len(s.trades) * 100),
+		"marginAvailable": strconv.Itoa(s.balance - len(s.trades)*100),
+		"positionValue":   strconv.Itoa(len(s.trades) * 1000),
+		"withdrawalLimit": strconv.Itoa(s.balance),
+		"trades":          trades,
+		"positions":       positions,
+	}
+}
+
+// snapshot returns the account as GetAccount would
+func (s *accountSim) snapshot() AccountInfo {
+	state := s.state()
+	account := map[string]any{}
+	for _, k := range []string{"NAV", "unrealizedPL", "marginUsed", "marginAvailable", "positionValue", "withdrawalLimit"} {
+		account[k] = state[k]
+	}
+	account["balance"] = strconv.Itoa(s.balance)
+
+	var orders, trades, positions []any
+	var orderIDs []string
+	for id := range s.orders {
+		orderIDs = append(orderIDs, id)
+	}
+	slices.Sort(orderIDs)
+	for _, id := range orderIDs {
+		orders = append(orders, s.orders[id])
+	}
+	for _, id := range s.sortedKeys(s.trades) {
+		trades = append(trades, s.trades[id].json())
+	}
+	// Snapshots include positions that have been closed
+	for _, instrument := range simInstruments {
+		positions = append(positions, s.position(instrument))
+	}
+	account["orders"] = orders
+	account["trades"] = trades
+	account["positions"] = positions
+
+	var info AccountInfo
+	data, _ := json.Marshal(map[string]any{"account": account, "lastTransactionID": strconv.Itoa(s.lastID)})
+	if err := json.Unmarshal(data, &info); err != nil {
+		panic(err)
+	}
+	return info
+}
+
+func TestApplyChangesMatchesSnapshots(t *testing.T) {
+	defer logTestResult(t, "ApplyChangesMatchesSnapshots")
+
+	for seed := uint64(1); seed <= 200; seed++ {
+		sim := newAccountSim(seed)
+		local, err := NewLocalAccountState(sim.snapshot())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for step := 0; step < 25; step++ {
+			changes := sim.step()
+			if err := ApplyChanges(local, changes); err != nil {
+				t.Fatalf("seed %d step %d: unexpected error: %v", seed, step, err)
+			}
+			expected, err := NewLocalAccountState(sim.snapshot())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(local, expected) {
+				t.Fatalf("seed %d step %d: local state diverged from the snapshot\nlocal:    %+v\nsnapshot: %+v", seed, step, local, expected)
+			}
+
+			// Applying the same changes again is a no-op
+			if err := ApplyChanges(local, changes); err != nil || !reflect.DeepEqual(local, expected) {
+				t.Fatalf("seed %d step %d: reapplying changes altered the state (%v)", seed, step, err)
+			}
+		}
+	}
+}
+
+func TestSyncAccountChanges(t *testing.T) {
+	defer logTestResult(t, "SyncAccountChanges")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/changes" || r.URL.Query().Get("sinceTransactionID") != "10" {
+			t.Errorf("Unexpected request: %s", r.URL)
+		}
+		fmt.Fprint(w, `{"changes":{"ordersCreated":[{"id":"11","instrument":"EUR_USD","units":"100","type":"LIMIT","state":"PENDING"}]},"state":{"NAV":"1000"},"lastTransactionID":"11"}`)
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	state := &LocalAccountState{LastTransactionID: "10"}
+	if err := c.SyncAccountChanges(state); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.LastTransactionID != "11" || state.NAV != "1000" || state.Orders["11"].Units != "100" {
+		t.Errorf("Unexpected state: %+v", state)
+	}
+}
@@ -0,0 +1,164 @@
+package goanda
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Session is the trading session a trade was opened in, based on the UTC hour
+type Session string
+
+// Sessions used by AnalyzeTrades. The London and New York overlap is kept separate
+// as it usually has the most liquidity of the day.
+const (
+	SessionAsia          Session = "Asia"           // 22:00 - 07:00 UTC
+	SessionLondon        Session = "London"         // 07:00 - 12:00 UTC
+	SessionLondonNewYork Session = "London/NewYork" // 12:00 - 16:00 UTC
+	SessionNewYork       Session = "NewYork"        // 16:00 - 22:00 UTC
+)
+
+// SessionFor returns the session a time falls in
+func SessionFor(t time.Time) Session {
+	switch hour := t.UTC().Hour(); {
+	case hour >= 7 && hour < 12:
+		return SessionLondon
+	case hour >= 12 && hour < 16:
+		return SessionLondonNewYork
+	case hour >= 16 && hour < 22:
+		return SessionNewYork
+	}
+	return SessionAsia
+}
+
+// Performance summarises the closed trades in a bucket.
+// Profit and loss include financing, in the account's home currency.
+type Performance struct {
+	Trades      int     `json:"trades"`
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	NetPL       float64 `json:"netPL"`
+	GrossProfit float64 `json:"grossProfit"`
+	GrossLoss   float64 `json:"grossLoss"`
+}
+
+// WinRate is the fraction of trades that made money
+func (p Performance) WinRate() float64 {
+	if p.Trades == 0 {
+		return 0
+	}
+	return float64(p.Wins) / float64(p.Trades)
+}
+
+// ProfitFactor is the gross profit divided by the gross loss, or zero when there were no losses
+func (p Performance) ProfitFactor() float64 {
+	if p.GrossLoss == 0 {
+		return 0
+	}
+	return p.GrossProfit / -p.GrossLoss
+}
+
+func (p *Performance) add(pl float64) {
+	p.Trades++
+	p.NetPL += pl
+	switch {
+	case pl > 0:
+		p.Wins++
+		p.GrossProfit += pl
+	case pl < 0:
+		p.Losses++
+		p.GrossLoss += pl
+	}
+}
+
+// TradeAnalytics breaks down the performance of closed trades by when they were opened
+type TradeAnalytics struct {
+	Overall   Performance                   `json:"overall"`
+	BySession map[Session]*Performance      `json:"bySession"`
+	ByWeekday map[time.Weekday]*Performance `json:"byWeekday"`
+	ByHour    [24]Performance               `json:"byHour"`
+}
+
+// AnalyzeTrades buckets closed trades by session, weekday and UTC hour of day of their open time,
+// revealing when a strategy actually makes money. Trades that are still open are ignored.
+func AnalyzeTrades(trades []Trade) TradeAnalytics {
+	ta := TradeAnalytics{
+		BySession: map[Session]*Performance{},
+		ByWeekday: map[time.Weekday]*Performance{},
+	}
+
+	for _, trade := range trades {
+		if trade.State != "CLOSED" {
+			continue
+		}
+
+		realized, _ := strconv.ParseFloat(trade.RealizedPL, 64)
+		financing, _ := strconv.ParseFloat(trade.Financing, 64)
+		pl := realized + financing
+		opened := trade.OpenTime.UTC()
+
+		ta.Overall.add(pl)
+		ta.ByHour[opened.Hour()].add(pl)
+
+		session := SessionFor(opened)
+		if ta.BySession[session] == nil {
+			ta.BySession[session] = &Performance{}
+		}
+		ta.BySession[session].add(pl)
+
+		if ta.ByWeekday[opened.Weekday()] == nil {
+			ta.ByWeekday[opened.Weekday()] = &Performance{}
+		}
+		ta.ByWeekday[opened.Weekday()].add(pl)
+	}
+
+	return ta
+}
+
+// WriteCSV writes the breakdown with one row per bucket, under the columns
+// dimension, bucket, trades, wins, losses, win_rate, net_pl, gross_profit, gross_loss and profit_factor.
+// Empty buckets are omitted.
+func (ta TradeAnalytics) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"dimension", "bucket", "trades", "wins", "losses",
+		"win_rate", "net_pl", "gross_profit", "gross_loss", "profit_factor",
+	})
+
+	row := func(dimension string, bucket string, p Performance) {
+		if p.Trades == 0 {
+			return
+		}
+		cw.Write([]string{
+			dimension,
+			bucket,
+			strconv.Itoa(p.Trades),
+			strconv.Itoa(p.Wins),
+			strconv.Itoa(p.Losses),
+			strconv.FormatFloat(p.WinRate(), 'f', 4, 64),
+			strconv.FormatFloat(p.NetPL, 'f', -1, 64),
+			strconv.FormatFloat(p.GrossProfit, 'f', -1, 64),
+			strconv.FormatFloat(p.GrossLoss, 'f', -1, 64),
+			strconv.FormatFloat(p.ProfitFactor(), 'f', 4, 64),
+		})
+	}
+
+	row("overall", "all", ta.Overall)
+	for _, session := range []Session{SessionAsia, SessionLondon, SessionLondonNewYork, SessionNewYork} {
+		if p := ta.BySession[session]; p != nil {
+			row("session", string(session), *p)
+		}
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if p := ta.ByWeekday[day]; p != nil {
+			row("weekday", day.String(), *p)
+		}
+	}
+	for hour, p := range ta.ByHour {
+		row("hour", strconv.Itoa(hour), p)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,87 @@
+package goanda
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func closedTrade(opened time.Time, pl string) Trade {
+	return Trade{State: "CLOSED", OpenTime: opened, RealizedPL: pl, Financing: "0"}
+}
+
+func TestSessionFor(t *testing.T) {
+	defer logTestResult(t, "SessionFor")
+
+	tests := []struct {
+		hour     int
+		expected Session
+	}{
+		{0, SessionAsia},
+		{6, SessionAsia},
+		{7, SessionLondon},
+		{12, SessionLondonNewYork},
+		{16, SessionNewYork},
+		{22, SessionAsia},
+	}
+
+	for _, tt := range tests {
+		if got := SessionFor(time.Date(2024, 1, 2, tt.hour, 30, 0, 0, time.UTC)); got != tt.expected {
+			t.Errorf("For hour %d expected %s, got %s", tt.hour, tt.expected, got)
+		}
+	}
+}
+
+func TestAnalyzeTrades(t *testing.T) {
+	defer logTestResult(t, "AnalyzeTrades")
+
+	tuesday := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	trades := []Trade{
+		closedTrade(tuesday.Add(8*time.Hour), "10"),
+		closedTrade(tuesday.Add(9*time.Hour), "-4"),
+		closedTrade(tuesday.Add(24*time.Hour+13*time.Hour), "6"),
+		{State: "OPEN", OpenTime: tuesday.Add(8 * time.Hour), UnrealizedPL: "100"},
+	}
+	trades[0].Financing = "-0.5"
+
+	ta := AnalyzeTrades(trades)
+
+	if ta.Overall.Trades != 3 || ta.Overall.NetPL != 11.5 {
+		t.Errorf("Unexpected overall performance: %+v", ta.Overall)
+	}
+	london := ta.BySession[SessionLondon]
+	if london == nil || london.Trades != 2 || london.Wins != 1 || london.Losses != 1 {
+		t.Fatalf("Unexpected London performance: %+v", london)
+	}
+	if london.ProfitFactor() != 9.5/4 {
+		t.Errorf("Expected a profit factor of %v, got %v", 9.5/4, london.ProfitFactor())
+	}
+	if ta.ByWeekday[time.Wednesday] == nil || ta.ByWeekday[time.Wednesday].NetPL != 6 {
+		t.Errorf("Unexpected Wednesday performance: %+v", ta.ByWeekday[time.Wednesday])
+	}
+	if ta.ByHour[8].Trades != 1 || ta.ByHour[13].WinRate() != 1 {
+		t.Errorf("Unexpected hourly performance: %+v %+v", ta.ByHour[8], ta.ByHour[13])
+	}
+
+	if _, err := json.Marshal(ta); err != nil {
+		t.Errorf("Expected the analytics to marshal to JSON, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ta.WriteCSV(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Unexpected error reading the CSV: %v", err)
+	}
+	// Header, overall, two sessions, two weekdays and three hours
+	if len(rows) != 9 {
+		t.Errorf("Expected 9 rows, got %d: %v", len(rows), rows)
+	}
+	if rows[1][0] != "overall" || rows[1][2] != "3" {
+		t.Errorf("Unexpected overall row: %v", rows[1])
+	}
+}
@@ -0,0 +1,199 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// DefaultBackpressureBuffer is the number of messages buffered when a Backpressure policy sets no BufferSize
+const DefaultBackpressureBuffer = 1024
+
+// BackpressureMode decides what a stream does when its callback falls behind and the buffer fills
+type BackpressureMode int
+
+const (
+	// BackpressureBlock stops reading the stream until the callback makes room in the buffer
+	BackpressureBlock BackpressureMode = iota
+	// BackpressureDropOldest discards the oldest buffered message to make room for the newest
+	BackpressureDropOldest
+	// BackpressureLatest keeps only the latest buffered price for each instrument, replacing older ones
+	// in place. Other messages are buffered as with BackpressureBlock.
+	BackpressureLatest
+)
+
+// Backpressure decouples reading a stream from its callback. Messages are read into a bounded buffer
+// as they arrive and delivered to the callback from a separate goroutine, so a slow callback no longer
+// stalls the connection until OANDA drops it. Discarded messages are reported as StreamEventDropped.
+type Backpressure struct {
+	Mode       BackpressureMode
+	BufferSize int
+}
+
+// errDeliveryFailed tears down a stream whose callback returned an error
+var errDeliveryFailed = errors.New("goanda: stream callback failed")
+
+type queuedMessage struct {
+	// key is the instrument of a price conflated under BackpressureLatest
+	key     string
+	message []byte
+}
+
+// deliveryQueue buffers a stream's messages between the goroutine reading it and the one running its callback
+type deliveryQueue struct {
+	mode    BackpressureMode
+	size    int
+	dropped func(message []byte)
+
+	mu      sync.Mutex
+	items   []queuedMessage
+	latest  map[string][]byte
+	closed  bool
+	aborted bool
+	err     error
+
+	ready chan struct{}
+	space chan struct{}
+	done  chan struct{}
+}
+
+func newDeliveryQueue(policy Backpressure, dropped func(message []byte)) *deliveryQueue {
+	size := policy.BufferSize
+	if size <= 0 {
+		size = DefaultBackpressureBuffer
+	}
+	return &deliveryQueue{
+		mode:    policy.Mode,
+		size:    size,
+		dropped: dropped,
+		latest:  map[string][]byte{},
+		ready:   make(chan struct{}, 1),
+		space:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// push buffers a message, applying the policy if the buffer is full. It returns the callback's error
+// once delivery has failed, and gives up without error if ctx ends while blocked.
+func (q *deliveryQueue) push(ctx context.Context, message []byte) error {
+	key := ""
+	if q.mode == BackpressureLatest {
+		key = conflationKey(message)
+	}
+
+	for {
+		q.mu.Lock()
+		if q.err != nil {
+			err := q.err
+			q.mu.Unlock()
+			return err
+		}
+
+		if previous, ok := q.latest[key]; ok && key != "" {
+			q.latest[key] = message
+			q.mu.Unlock()
+			q.dropped(previous)
+			return nil
+		}
+
+		if len(q.items) < q.size || q.mode == BackpressureDropOldest {
+			var dropped []byte
+			if len(q.items) >= q.size {
+				dropped = q.shift()
+			}
+			q.items = append(q.items, queuedMessage{key, message})
+			if key != "" {
+				q.latest[key] = message
+			}
+			q.mu.Unlock()
+			signal(q.ready)
+			if dropped != nil {
+				q.dropped(dropped)
+			}
+			return nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.space:
+		case <-q.done:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// shift removes and returns the oldest message, q.mu must be held
+func (q *deliveryQueue) shift() []byte {
+	item := q.items[0]
+	q.items = q.items[1:]
+	if item.key == "" {
+		return item.message
+	}
+	message := q.latest[item.key]
+	delete(q.latest, item.key)
+	return message
+}
+
+// run delivers buffered messages until the queue is finished or aborted, or deliver fails
+func (q *deliveryQueue) run(deliver func([]byte) error, failed func()) {
+	defer close(q.done)
+
+	for {
+		q.mu.Lock()
+		for len(q.items) == 0 && !q.closed && !q.aborted {
+			q.mu.Unlock()
+			<-q.ready
+			q.mu.Lock()
+		}
+		if q.aborted || len(q.items) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		message := q.shift()
+		q.mu.Unlock()
+		signal(q.space)
+
+		if err := deliver(message); err != nil {
+			q.mu.Lock()
+			q.err = err
+			q.mu.Unlock()
+			failed()
+			return
+		}
+	}
+}
+
+// finish waits for the buffered messages to be delivered, returning the callback's error if any
+func (q *deliveryQueue) finish() error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	signal(q.ready)
+
+	<-q.done
+	return q.failure()
+}
+
+// abort discards the buffered messages and waits for the callback to return
+func (q *deliveryQueue) abort() {
+	q.mu.Lock()
+	q.aborted = true
+	q.mu.Unlock()
+	signal(q.ready)
+
+	<-q.done
+}
+
+func (q *deliveryQueue) failure() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.err
+}
@@ -0,0 +1,144 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// backpressureStream serves the prices in one response and returns a StreamingConnection reading from it.
+// If started is not nil the rest of the prices are held back until it is closed.
+func backpressureStream(t *testing.T, policy Backpressure, started chan struct{}, instruments ...string) (*StreamingConnection, string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i, instrument := range instruments {
+			fmt.Fprintf(w, `{"type":"PRICE","instrument":"%s","time":"%d"}`+"\n", instrument, i)
+			if i == 0 && started != nil {
+				w.(http.Flusher).Flush()
+				<-started
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sc := NewStreamingConnection(&Connection{
+		hostname: server.URL,
+		client:   *server.Client(),
+	})
+	sc.Backpressure = &policy
+	return sc, server.URL
+}
+
+// blockFirst returns a handler that records messages, closing started on the first and holding it
+// until release is closed
+func blockFirst(started chan struct{}, release chan struct{}, received *[]string) func([]byte) error {
+	return func(data []byte) error {
+		var price PricingStreamResponse
+		if err := decodeMessage(data, &price); err != nil {
+			return err
+		}
+		if len(*received) == 0 {
+			close(started)
+			<-release
+		}
+		*received = append(*received, price.Instrument+"@"+price.Time)
+		return nil
+	}
+}
+
+func TestBackpressureLatest(t *testing.T) {
+	defer logTestResult(t, "BackpressureLatest")
+
+	started := make(chan struct{})
+	sc, url := backpressureStream(t, Backpressure{Mode: BackpressureLatest}, started,
+		"EUR_USD", "GBP_USD", "EUR_USD", "GBP_USD", "EUR_USD", "GBP_USD")
+
+	release := make(chan struct{})
+	dropped := 0
+	sc.OnEvent = func(event StreamEvent) {
+		if event.Kind == StreamEventDropped {
+			if dropped++; dropped == 3 {
+				close(release)
+			}
+		}
+	}
+
+	var received []string
+	if err := sc.stream(context.Background(), url, blockFirst(started, release, &received)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"EUR_USD@0", "GBP_USD@5", "EUR_USD@4"}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("Expected %v, got %v", expected, received)
+	}
+	if m := sc.Metrics(); m.StreamDropped != 3 {
+		t.Errorf("Expected 3 dropped messages, got %d", m.StreamDropped)
+	}
+}
+
+func TestBackpressureDropOldest(t *testing.T) {
+	defer logTestResult(t, "BackpressureDropOldest")
+
+	started := make(chan struct{})
+	sc, url := backpressureStream(t, Backpressure{Mode: BackpressureDropOldest, BufferSize: 2}, started,
+		"A", "B", "C", "D", "E", "F")
+
+	release := make(chan struct{})
+	dropped := 0
+	sc.OnEvent = func(event StreamEvent) {
+		if event.Kind == StreamEventDropped {
+			if dropped++; dropped == 3 {
+				close(release)
+			}
+		}
+	}
+
+	var received []string
+	if err := sc.stream(context.Background(), url, blockFirst(started, release, &received)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"A@0", "E@4", "F@5"}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("Expected %v, got %v", expected, received)
+	}
+}
+
+func TestBackpressureBlock(t *testing.T) {
+	defer logTestResult(t, "BackpressureBlock")
+
+	instruments := make([]string, 50)
+	for i := range instruments {
+		instruments[i] = "EUR_USD"
+	}
+	sc, url := backpressureStream(t, Backpressure{Mode: BackpressureBlock, BufferSize: 4}, nil, instruments...)
+
+	var received []string
+	err := sc.stream(context.Background(), url, func(data []byte) error {
+		var price PricingStreamResponse
+		if err := decodeMessage(data, &price); err != nil {
+			return err
+		}
+		received = append(received, price.Time)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(received) != 50 || received[0] != "0" || received[49] != "49" {
+		t.Errorf("Expected every message in order, got %v", received)
+	}
+
+	// An error from the callback ends the stream
+	failure := errors.New("callback failed")
+	err = sc.stream(context.Background(), url, func([]byte) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the callback's error, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package goanda
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultBookPollInterval is how often StreamOrderBook and StreamPositionBook poll when no interval
+// is set. OANDA publishes a new book every 20 minutes, a snapshot is only delivered when it changes.
+const DefaultBookPollInterval = time.Minute
+
+// BookUpdate is a new snapshot of an instrument's order or position book
+type BookUpdate struct {
+	Book BrokerBook
+	// Changed are the buckets that are new or whose percentages changed since the previous snapshot,
+	// and Removed the prices of the buckets no longer in it. Both are empty for the first snapshot.
+	Changed []Bucket
+	Removed []string
+	// Initial is set on the first snapshot delivered
+	Initial bool
+}
+
+// StreamOrderBook polls an instrument's order book every interval, or DefaultBookPollInterval, so it can
+// be consumed like the streams. Each new snapshot is delivered with its differences from the previous one.
+// It runs until ctx is cancelled, returning ctx.Err(), or a poll fails. With a Reconnect policy, failures
+// are logged and passed to OnError, and polling continues until MaxRetries consecutive polls have failed.
+func (sc *StreamingConnection) StreamOrderBook(ctx context.Context, instrument string, interval time.Duration, callback func(BookUpdate)) error {
+	return sc.pollBook(ctx, "/instruments/"+instrument+"/orderBook", interval, func() (BrokerBook, error) {
+		return sc.OrderBook(instrument)
+	}, callback)
+}
+
+// StreamPositionBook polls an instrument's position book, see StreamOrderBook
+func (sc *StreamingConnection) StreamPositionBook(ctx context.Context, instrument string, interval time.Duration, callback func(BookUpdate)) error {
+	return sc.pollBook(ctx, "/instruments/"+instrument+"/positionBook", interval, func() (BrokerBook, error) {
+		return sc.PositionBook(instrument)
+	}, callback)
+}
+
+func (sc *StreamingConnection) pollBook(ctx context.Context, endpoint string, interval time.Duration, fetch func() (BrokerBook, error), callback func(BookUpdate)) error {
+	if interval <= 0 {
+		interval = DefaultBookPollInterval
+	}
+
+	var previous *BrokerBook
+	return sc.poll(ctx, endpoint, interval, func() error {
+		book, err := fetch()
+		if err != nil {
+			return &dropError{err}
+		}
+		if previous != nil && book.Time.Equal(previous.Time) {
+			return nil
+		}
+		update := diffBooks(previous, book)
+		previous = &book
+		return sc.deliver(endpoint, func([]byte) error {
+			callback(update)
+			return nil
+		}, nil)
+	})
+}
+
+// diffBooks compares a snapshot with the previous one, which is nil for the first
+func diffBooks(previous *BrokerBook, book BrokerBook) BookUpdate {
+	update := BookUpdate{Book: book, Initial: previous == nil}
+	if previous == nil {
+		return update
+	}
+
+	before := make(map[string]Bucket, len(previous.Buckets))
+	for _, bucket := range previous.Buckets {
+		before[bucket.Price] = bucket
+	}
+	for _, bucket := range book.Buckets {
+		if old, ok := before[bucket.Price]; !ok || old != bucket {
+			update.Changed = append(update.Changed, bucket)
+		}
+		delete(before, bucket.Price)
+	}
+	for _, bucket := range previous.Buckets {
+		if _, ok := before[bucket.Price]; ok {
+			update.Removed = append(update.Removed, bucket.Price)
+		}
+	}
+	return update
+}
@@ -0,0 +1,105 @@
+package goanda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStreamOrderBook(t *testing.T) {
+	defer logTestResult(t, "StreamOrderBook")
+
+	books := []BrokerBook{
+		{Instrument: "EUR_USD", Time: time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC), Buckets: []Bucket{
+			{Price: "1.0999", LongCountPercent: "40", ShortCountPercent: "60"},
+			{Price: "1.1000", LongCountPercent: "50", ShortCountPercent: "50"},
+		}},
+		// Unchanged until OANDA publishes the next book
+		{Instrument: "EUR_USD", Time: time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)},
+		{Instrument: "EUR_USD", Time: time.Date(2024, 1, 2, 15, 20, 0, 0, time.UTC), Buckets: []Bucket{
+			{Price: "1.1000", LongCountPercent: "55", ShortCountPercent: "45"},
+			{Price: "1.1001", LongCountPercent: "60", ShortCountPercent: "40"},
+		}},
+	}
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/instruments/EUR_USD/orderBook" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		n := int(atomic.AddInt32(&polls, 1)) - 1
+		if n >= len(books) {
+			n = len(books) - 1
+		}
+		json.NewEncoder(w).Encode(books[n])
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var updates []BookUpdate
+	err := sc.StreamOrderBook(ctx, "EUR_USD", time.Millisecond, func(update BookUpdate) {
+		updates = append(updates, update)
+		if len(updates) == 2 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if len(updates) != 2 {
+		t.Fatalf("Expected the book's 2 snapshots, got %d", len(updates))
+	}
+	if !updates[0].Initial || len(updates[0].Book.Buckets) != 2 || updates[0].Changed != nil {
+		t.Errorf("Expected the first snapshot without changes, got %+v", updates[0])
+	}
+	second := updates[1]
+	if second.Initial || len(second.Changed) != 2 || second.Changed[0].LongCountPercent != "55" || second.Changed[1].Price != "1.1001" {
+		t.Errorf("Expected the changed and new buckets, got %+v", second.Changed)
+	}
+	if len(second.Removed) != 1 || second.Removed[0] != "1.0999" {
+		t.Errorf("Expected the removed bucket, got %v", second.Removed)
+	}
+}
+
+func TestStreamOrderBookFailures(t *testing.T) {
+	defer logTestResult(t, "StreamOrderBookFailures")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errorMessage":"unavailable"}`, http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+		logger:    log.New(&bytes.Buffer{}, "", 0),
+	})
+	if err := sc.StreamPositionBook(context.Background(), "EUR_USD", time.Millisecond, func(BookUpdate) {}); err == nil {
+		t.Error("Expected the failed poll to end the stream without a Reconnect policy")
+	}
+
+	var recovered int
+	sc.Reconnect = &ReconnectPolicy{InitialBackoff: time.Millisecond, MaxRetries: 2}
+	sc.OnError = func(err error) { recovered++ }
+	if err := sc.StreamPositionBook(context.Background(), "EUR_USD", time.Millisecond, func(BookUpdate) {}); err == nil {
+		t.Error("Expected the stream to end after MaxRetries failures")
+	}
+	if recovered != 2 {
+		t.Errorf("Expected 2 failures passed to OnError, got %d", recovered)
+	}
+}
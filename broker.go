@@ -0,0 +1,133 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultSubscriberBuffer is the capacity of a broker subscriber's channel when BufferSize is not set
+const DefaultSubscriberBuffer = 256
+
+// StreamBroker maintains a single upstream stream and fans its messages out to any number of subscribers,
+// each with its own buffered channel, so a process needs only one connection however many goroutines
+// consume it. A subscriber whose channel is full misses messages rather than holding up the others,
+// see Subscriber.Dropped.
+type StreamBroker[T any] struct {
+	// BufferSize is the capacity of each subscriber's channel, it defaults to DefaultSubscriberBuffer
+	BufferSize int
+
+	stream func(ctx context.Context, callback func(T)) error
+
+	mu          sync.Mutex
+	subscribers map[*Subscriber[T]]struct{}
+	running     bool
+}
+
+// Subscriber receives a broker's messages on C until it unsubscribes or the broker stops running,
+// at which point C is closed
+type Subscriber[T any] struct {
+	C <-chan T
+
+	ch      chan T
+	broker  *StreamBroker[T]
+	dropped atomic.Uint64
+}
+
+// NewPriceBroker creates a broker for a price stream of the instruments
+func NewPriceBroker(sc *StreamingConnection, instruments ...string) *StreamBroker[PricingStreamResponse] {
+	return &StreamBroker[PricingStreamResponse]{
+		stream: func(ctx context.Context, callback func(PricingStreamResponse)) error {
+			return sc.StreamPrices(ctx, instruments, callback)
+		},
+	}
+}
+
+// NewTransactionBroker creates a broker for the account's transaction stream
+func NewTransactionBroker(sc *StreamingConnection) *StreamBroker[TransactionStreamResponse] {
+	return &StreamBroker[TransactionStreamResponse]{
+		stream: sc.StreamTransactions,
+	}
+}
+
+// Subscribe adds a subscriber, it receives the messages that arrive from now on
+func (b *StreamBroker[T]) Subscribe() *Subscriber[T] {
+	size := b.BufferSize
+	if size <= 0 {
+		size = DefaultSubscriberBuffer
+	}
+	ch := make(chan T, size)
+	s := &Subscriber[T]{C: ch, ch: ch, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers == nil {
+		b.subscribers = map[*Subscriber[T]]struct{}{}
+	}
+	b.subscribers[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe removes the subscriber and closes its channel, it is safe to call more than once
+func (s *Subscriber[T]) Unsubscribe() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.ch)
+	}
+}
+
+// Dropped is the number of messages the subscriber missed because its channel was full
+func (s *Subscriber[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Subscribers returns the number of current subscribers
+func (b *StreamBroker[T]) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
+
+// Run streams from upstream to the subscribers until ctx is done, returning ctx.Err(), or the stream
+// ends, returning its error. Every subscriber's channel is closed when it returns.
+func (b *StreamBroker[T]) Run(ctx context.Context) error {
+	b.mu.Lock()
+	if b.running {
+		b.mu.Unlock()
+		return errors.New("goanda: stream broker is already running")
+	}
+	b.running = true
+	b.mu.Unlock()
+
+	err := b.stream(ctx, b.publish)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscribers {
+		close(s.ch)
+	}
+	b.subscribers = nil
+	b.running = false
+	return err
+}
+
+func (b *StreamBroker[T]) publish(message T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscribers {
+		select {
+		case s.ch <- message:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPriceBroker(t *testing.T) {
+	defer logTestResult(t, "PriceBroker")
+
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections.Add(1)
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, `{"type":"PRICE","instrument":"EUR_USD","time":"%d"}`+"\n", i)
+		}
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	broker := NewPriceBroker(sc, "EUR_USD")
+	broker.BufferSize = 16
+	first := broker.Subscribe()
+	second := broker.Subscribe()
+	gone := broker.Subscribe()
+	gone.Unsubscribe()
+	gone.Unsubscribe()
+	if _, open := <-gone.C; open {
+		t.Error("Expected an unsubscribed channel to be closed")
+	}
+	if n := broker.Subscribers(); n != 2 {
+		t.Errorf("Expected 2 subscribers, got %d", n)
+	}
+
+	if err := broker.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := connections.Load(); n != 1 {
+		t.Errorf("Expected one upstream connection, got %d", n)
+	}
+
+	for _, s := range []*Subscriber[PricingStreamResponse]{first, second} {
+		var times []string
+		for price := range s.C {
+			times = append(times, price.Time)
+		}
+		if len(times) != 10 || times[0] != "0" || times[9] != "9" {
+			t.Errorf("Expected every price in order, got %v", times)
+		}
+	}
+}
+
+func TestBrokerSlowSubscriber(t *testing.T) {
+	defer logTestResult(t, "BrokerSlowSubscriber")
+
+	broker := &StreamBroker[int]{
+		BufferSize: 2,
+		stream: func(ctx context.Context, callback func(int)) error {
+			for i := 0; i < 5; i++ {
+				callback(i)
+			}
+			return nil
+		},
+	}
+	slow := broker.Subscribe()
+	if err := broker.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var received []int
+	for i := range slow.C {
+		received = append(received, i)
+	}
+	if fmt.Sprint(received) != "[0 1]" || slow.Dropped() != 3 {
+		t.Errorf("Expected the first 2 messages and 3 drops, got %v and %d", received, slow.Dropped())
+	}
+}
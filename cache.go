@@ -0,0 +1,94 @@
+package goanda
+
+import (
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Cache stores api responses, it must be safe for concurrent use
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+var (
+	accountsEndpoint           = regexp.MustCompile(`^/accounts$`)
+	accountInstrumentsEndpoint = regexp.MustCompile(`^/accounts/[^/]+/instruments($|\?)`)
+)
+
+// DefaultCacheTTL caches the endpoints whose data rarely changes:
+// the accounts authorized for the token and the instruments tradeable by an account, for an hour each.
+// Everything else, including account details, is fetched every time.
+func DefaultCacheTTL(endpoint string) time.Duration {
+	if accountsEndpoint.MatchString(endpoint) || accountInstrumentsEndpoint.MatchString(endpoint) {
+		return time.Hour
+	}
+	return 0
+}
+
+// MemoryCache is an in memory Cache
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache creates an empty in memory cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]cacheEntry{}}
+}
+
+// Get returns the value stored under key if it has not expired
+func (mc *MemoryCache) Get(key string) ([]byte, bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	entry, ok := mc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(mc.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Set stores a value under key until the ttl passes
+func (mc *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.entries[key] = cacheEntry{value: value, expires: time.Now().Add(ttl)}
+}
+
+// Clear removes every entry
+func (mc *MemoryCache) Clear() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.entries = map[string]cacheEntry{}
+}
+
+// cached returns a cached response for the endpoint, if caching applies to it
+func (c *Connection) cached(endpoint string) ([]byte, time.Duration, bool) {
+	if c.cache == nil {
+		return nil, 0, false
+	}
+
+	ttl := DefaultCacheTTL(endpoint)
+	if c.cacheTTL != nil {
+		ttl = c.cacheTTL(endpoint)
+	}
+	if ttl <= 0 {
+		return nil, 0, false
+	}
+
+	b, ok := c.cache.Get(c.hostname + endpoint)
+	return b, ttl, ok
+}
@@ -0,0 +1,83 @@
+package goanda
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConnectionCache(t *testing.T) {
+	defer logTestResult(t, "ConnectionCache")
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/accounts/test-account/instruments":
+			json.NewEncoder(w).Encode(map[string]Instruments{"instruments": {{Name: "EUR_USD"}}})
+		default:
+			json.NewEncoder(w).Encode(AccountSummary{LastTransactionID: "1"})
+		}
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+		cache:     NewMemoryCache(),
+	}
+
+	for i := 0; i < 3; i++ {
+		instruments, err := c.GetAccountInstruments("test-account")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(instruments) != 1 || instruments[0].Name != "EUR_USD" {
+			t.Fatalf("Unexpected instruments: %+v", instruments)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("Expected instruments to be fetched once, got %d", n)
+	}
+
+	// Account details are not cached by default
+	c.GetAccountSummary()
+	c.GetAccountSummary()
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("Expected summaries to bypass the cache, got %d requests", n)
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	defer logTestResult(t, "MemoryCacheExpiry")
+
+	mc := NewMemoryCache()
+	mc.Set("key", []byte("value"), 20*time.Millisecond)
+
+	if v, ok := mc.Get("key"); !ok || string(v) != "value" {
+		t.Fatalf("Expected a cached value, got %q %v", v, ok)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := mc.Get("key"); ok {
+		t.Error("Expected the entry to have expired")
+	}
+}
+
+func TestDefaultCacheTTL(t *testing.T) {
+	defer logTestResult(t, "DefaultCacheTTL")
+
+	if DefaultCacheTTL("/accounts") != time.Hour {
+		t.Error("Expected the account list to be cached")
+	}
+	if DefaultCacheTTL("/accounts/abc/instruments?instruments=EUR_USD") != time.Hour {
+		t.Error("Expected account instruments to be cached")
+	}
+	if DefaultCacheTTL("/accounts/abc/pricing?instruments=EUR_USD") != 0 {
+		t.Error("Expected pricing not to be cached")
+	}
+}
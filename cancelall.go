@@ -0,0 +1,119 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults of CancelAllOptions
+const (
+	DefaultCancelConcurrency = 4
+	DefaultCancelsPerSecond  = 20
+)
+
+// CancelAllOptions tunes CancelAllPendingOrders
+type CancelAllOptions struct {
+	// Instrument, if set, only the instrument's orders are cancelled
+	Instrument string
+	// Concurrency is how many cancellations may be in flight at once, it defaults to DefaultCancelConcurrency
+	Concurrency int
+	// PerSecond is the most cancellations started each second, it defaults to DefaultCancelsPerSecond.
+	// It keeps a large cancellation within OANDA's request rate limit.
+	PerSecond int
+}
+
+// OrderCancelResult is the outcome of cancelling one of the orders CancelAllPendingOrders found
+type OrderCancelResult struct {
+	Order OrderInfo
+	// Cancelled is the order's cancellation, nil if it failed
+	Cancelled *OrderCancelTransaction
+	// Err is why the order could not be cancelled, such as it having filled since it was listed
+	Err error
+}
+
+// CancelAllPendingOrders cancels every pending order on the account, or on opts.Instrument, as when a
+// kill switch flattens the account. The orders are cancelled concurrently within the rate in opts, and
+// a result is returned for each whether or not it was cancelled. The error joins every failure, so a
+// nil error means no pending orders are left but those placed since the orders were listed.
+// Cancellations are not started once ctx is done.
+func (c *Connection) CancelAllPendingOrders(ctx context.Context, opts CancelAllOptions) ([]OrderCancelResult, error) {
+	pending, err := c.ListPendingOrders(opts.Instrument)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultCancelConcurrency
+	}
+	perSecond := opts.PerSecond
+	if perSecond <= 0 {
+		perSecond = DefaultCancelsPerSecond
+	}
+
+	results := make([]OrderCancelResult, len(pending.Orders))
+	for i, order := range pending.Orders {
+		results[i].Order = order
+	}
+	started := paced(ctx, len(results), concurrency, perSecond, func(i int) {
+		results[i].Cancelled, results[i].Err = c.cancelListedOrder(results[i].Order)
+	})
+	for i := started; i < len(results); i++ {
+		results[i].Err = ctx.Err()
+	}
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("goanda: cancelling order %s: %w", result.Order.ID, result.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (c *Connection) cancelListedOrder(order OrderInfo) (*OrderCancelTransaction, error) {
+	cancelled, err := c.CancelOrder(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &cancelled.OrderCancelTransaction, nil
+}
+
+// paced calls do with 0 to n-1 on up to concurrency goroutines, starting at most perSecond calls each
+// second, and waits for them to return. It stops starting calls once ctx is done, returning how many
+// it started.
+func paced(ctx context.Context, n int, concurrency int, perSecond int, do func(i int)) int {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				do(i)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(perSecond))
+	defer ticker.Stop()
+	next := 0
+	for ; next < n; next++ {
+		if next > 0 {
+			select {
+			case <-ctx.Done():
+			case <-ticker.C:
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- next
+	}
+	close(jobs)
+	wg.Wait()
+	return next
+}
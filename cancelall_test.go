@@ -0,0 +1,105 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func cancelAllServer(inFlight *int32, peak *int32, onCancel func()) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/accounts/test-account/pendingOrders" {
+			w.Write([]byte(`{"orders":[
+				{"id":"1","type":"LIMIT","state":"PENDING","instrument":"EUR_USD"},
+				{"id":"2","type":"STOP","state":"PENDING","instrument":"EUR_USD"},
+				{"id":"3","type":"LIMIT","state":"PENDING","instrument":"GBP_USD"},
+				{"id":"4","type":"LIMIT","state":"PENDING","instrument":"EUR_USD"}],"lastTransactionID":"4"}`))
+			return
+		}
+
+		onCancel()
+		n := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+		for {
+			p := atomic.LoadInt32(peak)
+			if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+				break
+			}
+		}
+
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/accounts/test-account/orders/"), "/cancel")
+		if id == "2" {
+			// Filled since it was listed
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errorCode":"ORDER_DOESNT_EXIST","errorMessage":"The order does not exist"}`))
+			return
+		}
+		w.Write([]byte(`{"orderCancelTransaction":{"id":"1` + id + `","type":"ORDER_CANCEL","orderID":"` + id + `","reason":"CLIENT_REQUEST"}}`))
+	}))
+}
+
+func TestCancelAllPendingOrders(t *testing.T) {
+	defer logTestResult(t, "CancelAllPendingOrders")
+
+	var inFlight, peak int32
+	server := cancelAllServer(&inFlight, &peak, func() {})
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	results, err := c.CancelAllPendingOrders(context.Background(), CancelAllOptions{Instrument: "EUR_USD", Concurrency: 2, PerSecond: 1000})
+
+	var apiErr APIError
+	if !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "order 2") {
+		t.Errorf("Expected the failure to cancel order 2, got %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected a result for each EUR_USD order, got %d", len(results))
+	}
+	for i, id := range []string{"1", "2", "4"} {
+		result := results[i]
+		if result.Order.ID != id {
+			t.Errorf("Expected the results in the listed order, got %s at %d", result.Order.ID, i)
+		}
+		if id == "2" {
+			if result.Err == nil || result.Cancelled != nil {
+				t.Errorf("Expected order 2 to fail, got %+v", result)
+			}
+			continue
+		}
+		if result.Err != nil || result.Cancelled == nil || result.Cancelled.OrderID != id {
+			t.Errorf("Expected order %s to be cancelled, got %+v", id, result)
+		}
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 cancellations in flight, got %d", peak)
+	}
+}
+
+func TestCancelAllPendingOrdersStopsWithContext(t *testing.T) {
+	defer logTestResult(t, "CancelAllPendingOrdersStopsWithContext")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var once sync.Once
+	var inFlight, peak int32
+	server := cancelAllServer(&inFlight, &peak, func() { once.Do(cancel) })
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	results, err := c.CancelAllPendingOrders(ctx, CancelAllOptions{PerSecond: 10})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the orders left to be reported cancelled by the context, got %v", err)
+	}
+	if len(results) != 4 || results[0].Cancelled == nil {
+		t.Fatalf("Expected the first order to be cancelled, got %+v", results)
+	}
+	if !errors.Is(results[3].Err, context.Canceled) || results[3].Order.ID != "4" {
+		t.Errorf("Expected the last order not to be cancelled, got %+v", results[3])
+	}
+}
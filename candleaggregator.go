@@ -0,0 +1,104 @@
+package goanda
+
+import "time"
+
+// CandleAggregator builds candles of a granularity from the ticks of a price stream, OANDA does not
+// stream candles itself. Buckets are aligned to UTC, as with Downsample, and Volume counts the ticks.
+// Buckets without a tick produce no candle.
+type CandleAggregator struct {
+	instrument  string
+	granularity Granularity
+
+	open     bool
+	start    time.Time
+	last     time.Time
+	bid, ask Candle
+	mid      Candle
+	volume   int
+}
+
+// NewCandleAggregator creates an aggregator for the instrument's prices.
+// Weekly and monthly granularities are not supported, their buckets are not a fixed duration.
+func NewCandleAggregator(instrument string, g Granularity) (*CandleAggregator, error) {
+	if err := checkDownsample(g); err != nil {
+		return nil, err
+	}
+	return &CandleAggregator{instrument: instrument, granularity: g}, nil
+}
+
+// Add adds a tick, returning the candle it completes, if any, followed by the candle in progress.
+// Prices for other instruments, without both a bid and an ask, or from an earlier bucket are ignored.
+func (a *CandleAggregator) Add(price PricingStreamResponse) ([]CandlestickStreamResponse, error) {
+	if price.Type != "PRICE" || price.Instrument != a.instrument {
+		return nil, nil
+	}
+	parsed, err := price.Parse()
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed.Bids) == 0 || len(parsed.Asks) == 0 {
+		return nil, nil
+	}
+
+	start := parsed.Time.UTC().Truncate(a.granularity.Duration())
+	if a.open && start.Before(a.start) {
+		return nil, nil
+	}
+
+	var candles []CandlestickStreamResponse
+	if a.open && start.After(a.start) {
+		candles = append(candles, a.candle(true))
+		a.open = false
+	}
+
+	bid, ask := parsed.Bid(), parsed.Ask()
+	mid := (bid + ask) / 2
+	if !a.open {
+		a.open = true
+		a.start = start
+		a.bid = Candle{Open: bid, High: bid, Low: bid}
+		a.ask = Candle{Open: ask, High: ask, Low: ask}
+		a.mid = Candle{Open: mid, High: mid, Low: mid}
+		a.volume = 0
+	}
+	extend(&a.bid, bid)
+	extend(&a.ask, ask)
+	extend(&a.mid, mid)
+	a.volume++
+	a.last = parsed.Time
+
+	return append(candles, a.candle(false)), nil
+}
+
+// Close completes the candle in progress if now, in OANDA's time, is past the end of its bucket.
+// It lets a candle complete when no tick arrives after it, such as on a heartbeat.
+func (a *CandleAggregator) Close(now time.Time) (CandlestickStreamResponse, bool) {
+	if !a.open || now.Before(a.start.Add(a.granularity.Duration())) {
+		return CandlestickStreamResponse{}, false
+	}
+	a.open = false
+	return a.candle(true), true
+}
+
+func extend(c *Candle, price float64) {
+	c.High = max(c.High, price)
+	c.Low = min(c.Low, price)
+	c.Close = price
+}
+
+func (a *CandleAggregator) candle(complete bool) CandlestickStreamResponse {
+	return CandlestickStreamResponse{
+		Type:        "CANDLESTICK",
+		Time:        a.last.Format(time.RFC3339Nano),
+		Instrument:  a.instrument,
+		Granularity: a.granularity.String(),
+		Candles: []StreamCandle{{
+			Time:     a.start.Format(time.RFC3339Nano),
+			Bid:      a.bid,
+			Ask:      a.ask,
+			Mid:      a.mid,
+			Volume:   a.volume,
+			Complete: complete,
+		}},
+	}
+}
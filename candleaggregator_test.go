@@ -0,0 +1,50 @@
+package goanda
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCandleAggregator(t *testing.T) {
+	defer logTestResult(t, "CandleAggregator")
+
+	a, err := NewCandleAggregator("EUR_USD", GranularityFiveSeconds)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tick := func(instrument string, at string) PricingStreamResponse {
+		return PricingStreamResponse{
+			Type:       "PRICE",
+			Instrument: instrument,
+			Time:       at,
+			Bids:       []RawPriceBucket{{Price: "1.1", Liquidity: 1}},
+			Asks:       []RawPriceBucket{{Price: "1.2", Liquidity: 1}},
+		}
+	}
+
+	if candles, _ := a.Add(tick("GBP_USD", "2024-01-02T15:04:01Z")); len(candles) != 0 {
+		t.Errorf("Expected other instruments to be ignored, got %v", candles)
+	}
+	if candles, _ := a.Add(tick("EUR_USD", "2024-01-02T15:04:06Z")); len(candles) != 1 || candles[0].Candles[0].Complete {
+		t.Errorf("Expected a candle in progress, got %v", candles)
+	}
+	// A late tick from an earlier bucket cannot reopen it
+	if candles, _ := a.Add(tick("EUR_USD", "2024-01-02T15:04:04Z")); len(candles) != 0 {
+		t.Errorf("Expected a late tick to be ignored, got %v", candles)
+	}
+
+	if _, ok := a.Close(time.Date(2024, 1, 2, 15, 4, 9, 0, time.UTC)); ok {
+		t.Error("Expected the candle to stay open until the end of its bucket")
+	}
+	candle, ok := a.Close(time.Date(2024, 1, 2, 15, 4, 10, 0, time.UTC))
+	if !ok || !candle.Candles[0].Complete || candle.Candles[0].Time != "2024-01-02T15:04:05Z" || candle.Candles[0].Volume != 1 {
+		t.Errorf("Unexpected candle: %+v", candle)
+	}
+	if _, ok := a.Close(time.Date(2024, 1, 2, 15, 5, 0, 0, time.UTC)); ok {
+		t.Error("Expected a candle to complete only once")
+	}
+
+	if _, err := NewCandleAggregator("EUR_USD", GranularityMonth); err == nil {
+		t.Error("Expected an error for monthly candles")
+	}
+}
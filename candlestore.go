@@ -0,0 +1,276 @@
+package goanda
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CandleStore is a local store of candles, keyed by instrument and granularity
+type CandleStore interface {
+	// Candles returns the stored candles in time order
+	Candles(instrument string, g Granularity) ([]Candles, error)
+	// Put stores candles, replacing any already stored at the same times
+	Put(instrument string, g Granularity, candles []Candles) error
+	// Delete removes the candles that start before the given time
+	Delete(instrument string, g Granularity, before time.Time) error
+}
+
+// RetentionRule keeps a granularity for a period of time, a zero Keep keeps it forever
+type RetentionRule struct {
+	Granularity Granularity
+	Keep        time.Duration
+}
+
+// RetentionPolicy lists the granularities to keep, from finest to coarsest.
+// Each coarser series is produced by downsampling the one before it.
+type RetentionPolicy []RetentionRule
+
+// DefaultRetentionPolicy keeps M1 candles for 30 days, M15 candles for a year and daily candles forever
+var DefaultRetentionPolicy = RetentionPolicy{
+	{Granularity: GranularityMinute, Keep: 30 * 24 * time.Hour},
+	{Granularity: GranularityFifteenMinutes, Keep: 365 * 24 * time.Hour},
+	{Granularity: GranularityDay},
+}
+
+// Validate checks the policy runs from finer to coarser granularities that divide into each other
+func (rp RetentionPolicy) Validate() error {
+	for i, rule := range rp {
+		if err := checkDownsample(rule.Granularity); err != nil {
+			return err
+		}
+		if i == 0 {
+			continue
+		}
+		finer := rp[i-1].Granularity
+		if rule.Granularity <= finer || rule.Granularity%finer != 0 {
+			return fmt.Errorf("%v cannot be downsampled from %v", rule.Granularity, finer)
+		}
+	}
+	return nil
+}
+
+// Compact applies the retention policy to an instrument's candles in the store.
+// Each coarser series is first filled in from the finer one, then candles older than their rule's Keep
+// are deleted. A finer candle is only deleted once the coarser candle covering it is stored, whole
+// coarser buckets at a time, so nothing is removed before it has been rolled up. A stored complete
+// candle is never replaced by an incomplete one rolled up from what is left of its bucket.
+func Compact(store CandleStore, instrument string, policy RetentionPolicy, now time.Time) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	for i := 1; i < len(policy); i++ {
+		finer, err := store.Candles(instrument, policy[i-1].Granularity)
+		if err != nil {
+			return err
+		}
+
+		rolled, err := Downsample(finer, policy[i-1].Granularity, policy[i].Granularity)
+		if err != nil {
+			return err
+		}
+		stored, err := storedCandles(store, instrument, policy[i].Granularity)
+		if err != nil {
+			return err
+		}
+		var updates []Candles
+		for _, candle := range rolled {
+			if existing, ok := stored[candle.Time.UnixNano()]; ok && existing.Complete && !candle.Complete {
+				continue
+			}
+			updates = append(updates, candle)
+		}
+		if len(updates) > 0 {
+			if err := store.Put(instrument, policy[i].Granularity, updates); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, rule := range policy {
+		if rule.Keep == 0 {
+			continue
+		}
+		before := now.Add(-rule.Keep)
+		if i+1 < len(policy) {
+			var err error
+			if before, err = rolledUpBefore(store, instrument, rule.Granularity, policy[i+1].Granularity, before); err != nil {
+				return err
+			}
+		}
+		if err := store.Delete(instrument, rule.Granularity, before); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rolledUpBefore returns the time, at most before, up to which every candle of the finer series is
+// covered by a stored candle of the coarser one, at the start of a coarser bucket
+func rolledUpBefore(store CandleStore, instrument string, finer Granularity, coarser Granularity, before time.Time) (time.Time, error) {
+	candles, err := store.Candles(instrument, finer)
+	if err != nil {
+		return time.Time{}, err
+	}
+	stored, err := storedCandles(store, instrument, coarser)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	before = before.UTC().Truncate(coarser.Duration())
+	for _, candle := range candles {
+		if !candle.Time.Before(before) {
+			break
+		}
+		bucket := candle.Time.UTC().Truncate(coarser.Duration())
+		if _, ok := stored[bucket.UnixNano()]; !ok {
+			return bucket, nil
+		}
+	}
+	return before, nil
+}
+
+// storedCandles returns the stored candles of a series by the UnixNano of their times
+func storedCandles(store CandleStore, instrument string, g Granularity) (map[int64]Candles, error) {
+	candles, err := store.Candles(instrument, g)
+	if err != nil {
+		return nil, err
+	}
+	byTime := make(map[int64]Candles, len(candles))
+	for _, c := range candles {
+		byTime[c.Time.UnixNano()] = c
+	}
+	return byTime, nil
+}
+
+// Downsample aggregates candles of one granularity into a coarser one.
+// Buckets are aligned to UTC, so daily candles run from midnight UTC rather than OANDA's default
+// 17:00 America/New_York alignment. A bucket is complete when every finer candle is present and
+// complete. Buckets missing some, from a gap in trading or a download cut short, are still returned,
+// with Complete false, so that no candles are lost.
+func Downsample(candles []Candles, from Granularity, to Granularity) ([]Candles, error) {
+	if err := checkDownsample(to); err != nil {
+		return nil, err
+	}
+	if to <= from || to%from != 0 {
+		return nil, fmt.Errorf("%v cannot be downsampled from %v", to, from)
+	}
+
+	expected := int(to / from)
+	var result []Candles
+	var bucket Candles
+	count := 0
+	complete := true
+
+	flush := func() {
+		bucket.Complete = count == expected && complete
+		result = append(result, bucket)
+	}
+
+	for _, candle := range candles {
+		start := candle.Time.UTC().Truncate(to.Duration())
+		if count == 0 || !start.Equal(bucket.Time) {
+			if count > 0 {
+				flush()
+			}
+			bucket = Candles{Time: start, Mid: candle.Mid}
+			count = 0
+			complete = true
+		}
+
+		if candle.Mid.High > bucket.Mid.High {
+			bucket.Mid.High = candle.Mid.High
+		}
+		if candle.Mid.Low < bucket.Mid.Low {
+			bucket.Mid.Low = candle.Mid.Low
+		}
+		bucket.Mid.Close = candle.Mid.Close
+		bucket.Volume += candle.Volume
+		complete = complete && candle.Complete
+		count++
+	}
+	if count > 0 {
+		flush()
+	}
+
+	return result, nil
+}
+
+// checkDownsample rejects weekly and monthly candles, whose buckets are not a fixed duration
+func checkDownsample(g Granularity) error {
+	if _, ok := candlestickGranularity[g]; !ok {
+		return errors.New("no such granularity")
+	}
+	if g > GranularityDay {
+		return fmt.Errorf("cannot downsample to %v", g)
+	}
+	return nil
+}
+
+// MemoryCandleStore is a CandleStore that keeps candles in memory, it is safe for concurrent use
+type MemoryCandleStore struct {
+	mu     sync.Mutex
+	series map[candleSeries][]Candles
+}
+
+type candleSeries struct {
+	instrument  string
+	granularity Granularity
+}
+
+// NewMemoryCandleStore creates an empty MemoryCandleStore
+func NewMemoryCandleStore() *MemoryCandleStore {
+	return &MemoryCandleStore{series: map[candleSeries][]Candles{}}
+}
+
+// Candles returns a copy of the stored candles in time order
+func (m *MemoryCandleStore) Candles(instrument string, g Granularity) ([]Candles, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Candles(nil), m.series[candleSeries{instrument, g}]...), nil
+}
+
+// Put stores candles, replacing any already stored at the same times
+func (m *MemoryCandleStore) Put(instrument string, g Granularity, candles []Candles) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := candleSeries{instrument, g}
+	byTime := map[int64]Candles{}
+	for _, c := range m.series[key] {
+		byTime[c.Time.UnixNano()] = c
+	}
+	for _, c := range candles {
+		byTime[c.Time.UnixNano()] = c
+	}
+
+	merged := make([]Candles, 0, len(byTime))
+	for _, c := range byTime {
+		merged = append(merged, c)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Time.Before(merged[j].Time)
+	})
+	m.series[key] = merged
+	return nil
+}
+
+// Delete removes the candles that start before the given time
+func (m *MemoryCandleStore) Delete(instrument string, g Granularity, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := candleSeries{instrument, g}
+	kept := m.series[key][:0]
+	for _, c := range m.series[key] {
+		if !c.Time.Before(before) {
+			kept = append(kept, c)
+		}
+	}
+	m.series[key] = kept
+	return nil
+}
@@ -0,0 +1,175 @@
+package goanda
+
+import (
+	"testing"
+	"time"
+)
+
+func minuteCandles(start time.Time, n int) []Candles {
+	candles := make([]Candles, n)
+	for i := range candles {
+		price := 1.1 + float64(i)*0.0001
+		candles[i] = Candles{
+			Complete: true,
+			Volume:   1,
+			Time:     start.Add(time.Duration(i) * time.Minute),
+			Mid:      Candle{Open: price, Close: price + 0.00005, Low: price - 0.0001, High: price + 0.0001},
+		}
+	}
+	return candles
+}
+
+func TestDownsample(t *testing.T) {
+	defer logTestResult(t, "Downsample")
+
+	start := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	candles := minuteCandles(start, 40)
+
+	result, err := Downsample(candles, GranularityMinute, GranularityFifteenMinutes)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The third bucket only has 10 of its 15 minutes
+	if len(result) != 3 || result[2].Complete || result[2].Volume != 10 {
+		t.Fatalf("Expected 2 complete buckets and a partial one, got %+v", result)
+	}
+
+	first := result[0]
+	if !first.Time.Equal(start) || first.Volume != 15 || !first.Complete {
+		t.Errorf("Unexpected first bucket: %+v", first)
+	}
+	if first.Mid.Open != candles[0].Mid.Open || first.Mid.Close != candles[14].Mid.Close {
+		t.Errorf("Expected open and close from the first and last minutes, got %+v", first.Mid)
+	}
+	if first.Mid.Low != candles[0].Mid.Low || first.Mid.High != candles[14].Mid.High {
+		t.Errorf("Expected the bucket's extremes, got %+v", first.Mid)
+	}
+
+	candles[20].Complete = false
+	result, _ = Downsample(candles, GranularityMinute, GranularityFifteenMinutes)
+	if len(result) != 3 || !result[0].Complete || result[1].Complete {
+		t.Errorf("Expected the bucket with an incomplete candle to be incomplete, got %+v", result)
+	}
+
+	if _, err := Downsample(candles, GranularityFifteenMinutes, GranularityMinute); err == nil {
+		t.Error("Expected an error downsampling to a finer granularity")
+	}
+	if _, err := Downsample(candles, GranularityDay, GranularityWeek); err == nil {
+		t.Error("Expected an error downsampling to weeks")
+	}
+}
+
+func TestRetentionPolicyValidate(t *testing.T) {
+	defer logTestResult(t, "RetentionPolicyValidate")
+
+	if err := DefaultRetentionPolicy.Validate(); err != nil {
+		t.Errorf("Expected the default policy to be valid, got %v", err)
+	}
+
+	invalid := RetentionPolicy{
+		{Granularity: GranularityFifteenMinutes},
+		{Granularity: GranularityTwoHours},
+		{Granularity: GranularityThreeHours},
+	}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected an error for H3 following H2")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	defer logTestResult(t, "Compact")
+
+	store := NewMemoryCandleStore()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.Put("EUR_USD", GranularityMinute, minuteCandles(start, 3*24*60)); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := RetentionPolicy{
+		{Granularity: GranularityMinute, Keep: 24 * time.Hour},
+		{Granularity: GranularityFifteenMinutes, Keep: 48 * time.Hour},
+		{Granularity: GranularityDay},
+	}
+	now := start.Add(3 * 24 * time.Hour)
+	if err := Compact(store, "EUR_USD", policy, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	minutes, _ := store.Candles("EUR_USD", GranularityMinute)
+	if len(minutes) != 24*60 {
+		t.Errorf("Expected one day of minutes to be kept, got %d", len(minutes))
+	}
+	quarters, _ := store.Candles("EUR_USD", GranularityFifteenMinutes)
+	if len(quarters) != 2*24*4 {
+		t.Errorf("Expected two days of M15 candles to be kept, got %d", len(quarters))
+	}
+	days, _ := store.Candles("EUR_USD", GranularityDay)
+	if len(days) != 3 {
+		t.Fatalf("Expected 3 daily candles, got %d", len(days))
+	}
+	if days[0].Volume != 24*60 {
+		t.Errorf("Expected the daily volume to sum the minutes, got %d", days[0].Volume)
+	}
+
+	// Compacting again rolls up from what is left without losing the coarser history
+	if err := Compact(store, "EUR_USD", policy, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	days, _ = store.Candles("EUR_USD", GranularityDay)
+	if len(days) != 3 || days[0].Volume != 24*60 {
+		t.Errorf("Expected the daily candles to be unchanged, got %d", len(days))
+	}
+}
+
+func TestCompactGaps(t *testing.T) {
+	defer logTestResult(t, "CompactGaps")
+
+	// Two hours of minutes, with no trading from 10:20 to 10:35
+	store := NewMemoryCandleStore()
+	start := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	minutes := minuteCandles(start, 120)
+	minutes = append(minutes[:20:20], minutes[35:]...)
+	store.Put("EUR_USD", GranularityMinute, minutes)
+
+	policy := RetentionPolicy{
+		{Granularity: GranularityMinute, Keep: 50 * time.Minute},
+		{Granularity: GranularityFifteenMinutes, Keep: 24 * time.Hour},
+		{Granularity: GranularityHour},
+	}
+	now := start.Add(120 * time.Minute)
+	if err := Compact(store, "EUR_USD", policy, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	quarters, _ := store.Candles("EUR_USD", GranularityFifteenMinutes)
+	if len(quarters) != 8 || quarters[1].Complete || quarters[1].Volume != 5 || quarters[2].Complete || !quarters[3].Complete {
+		t.Fatalf("Expected the gap's buckets rolled up incomplete, got %+v", quarters)
+	}
+	hours, _ := store.Candles("EUR_USD", GranularityHour)
+	if len(hours) != 2 || hours[0].Complete || hours[0].Volume != 45 || !hours[1].Complete {
+		t.Errorf("Expected the hour with the gap rolled up incomplete, got %+v", hours)
+	}
+	// 11:10 is 50 minutes ago, the minutes are deleted up to the start of its bucket
+	left, _ := store.Candles("EUR_USD", GranularityMinute)
+	if len(left) != 60 || !left[0].Time.Equal(start.Add(time.Hour)) {
+		t.Errorf("Expected the minutes from 11:00 to be kept, got %d from %v", len(left), left[0].Time)
+	}
+
+	// What is left of a bucket doesn't replace its complete candle
+	store.Delete("EUR_USD", GranularityMinute, start.Add(70*time.Minute))
+	if err := Compact(store, "EUR_USD", policy, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hours, _ = store.Candles("EUR_USD", GranularityHour)
+	if len(hours) != 2 || !hours[1].Complete || hours[1].Volume != 60 {
+		t.Errorf("Expected the complete hour to be kept, got %+v", hours[1])
+	}
+
+	// Minutes not yet rolled up are not deleted
+	store = NewMemoryCandleStore()
+	store.Put("EUR_USD", GranularityMinute, minutes)
+	kept, err := rolledUpBefore(store, "EUR_USD", GranularityMinute, GranularityFifteenMinutes, now)
+	if err != nil || !kept.Equal(start) {
+		t.Errorf("Expected nothing to be deletable, got %v (%v)", kept, err)
+	}
+}
@@ -0,0 +1,216 @@
+package goanda
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// CassetteMode selects whether a connection records its traffic to a cassette file or replays it from one
+type CassetteMode int
+
+const (
+	// CassetteOff sends requests to OANDA as normal
+	CassetteOff CassetteMode = iota
+	// CassetteRecord sends requests to OANDA and writes every request/response pair to the cassette
+	CassetteRecord
+	// CassetteReplay serves responses from the cassette without touching the network
+	CassetteReplay
+)
+
+// Cassette is a recording of http interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and its response.
+// The Authorization header is never recorded.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette := &Cassette{}
+	return cassette, json.Unmarshal(b, cassette)
+}
+
+// Save writes the cassette to a file
+func (cs *Cassette) Save(path string) error {
+	b, err := json.MarshalIndent(cs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// RecordingTransport is an http.RoundTripper that records every interaction to a cassette file.
+// Response bodies are captured as they are read, so streams are recorded up to the point they are closed.
+type RecordingTransport struct {
+	Path      string
+	Transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecordingTransport records to the file at path, sending requests through next (http.DefaultTransport if nil)
+func NewRecordingTransport(path string, next http.RoundTripper) *RecordingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &RecordingTransport{Path: path, Transport: next}
+}
+
+// RoundTrip implements http.RoundTripper
+func (rt *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		recorded.Body = string(b)
+	}
+
+	res, err := rt.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = &recordingBody{
+		ReadCloser: res.Body,
+		done: func(body []byte) error {
+			return rt.record(Interaction{
+				Request: recorded,
+				Response: RecordedResponse{
+					StatusCode: res.StatusCode,
+					Header:     res.Header,
+					Body:       string(body),
+				},
+			})
+		},
+	}
+	return res, nil
+}
+
+func (rt *RecordingTransport) record(interaction Interaction) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.cassette.Interactions = append(rt.cassette.Interactions, interaction)
+	return rt.cassette.Save(rt.Path)
+}
+
+// recordingBody keeps a copy of everything read from a response body, handing it over once the body is closed
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	done func([]byte) error
+	once sync.Once
+}
+
+func (rb *recordingBody) Read(p []byte) (int, error) {
+	n, err := rb.ReadCloser.Read(p)
+	rb.buf.Write(p[:n])
+	return n, err
+}
+
+func (rb *recordingBody) Close() error {
+	err := rb.ReadCloser.Close()
+	rb.once.Do(func() {
+		if recordErr := rb.done(rb.buf.Bytes()); recordErr != nil && err == nil {
+			err = recordErr
+		}
+	})
+	return err
+}
+
+// ReplayTransport is an http.RoundTripper that serves responses from a cassette.
+// Requests are matched on method, url and body, each recorded interaction is used once and in order.
+type ReplayTransport struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayTransport loads the cassette at path for replaying
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayTransport{
+		cassette: cassette,
+		used:     make([]bool, len(cassette.Interactions)),
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (rt *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		body = string(b)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	for i, interaction := range rt.cassette.Interactions {
+		if rt.used[i] ||
+			interaction.Request.Method != req.Method ||
+			interaction.Request.URL != req.URL.String() ||
+			interaction.Request.Body != body {
+			continue
+		}
+
+		rt.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewBufferString(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("goanda: no recorded interaction for %s %s", req.Method, req.URL)
+}
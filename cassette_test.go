@@ -0,0 +1,88 @@
+package goanda
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	defer logTestResult(t, "RecordAndReplay")
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("Expected the real request to be authorized")
+		}
+		json.NewEncoder(w).Encode(AccountSummary{LastTransactionID: "77"})
+	}))
+
+	recording := &Connection{
+		hostname:   server.URL,
+		accountID:  "test-account",
+		authHeader: "Bearer test-token",
+		client:     http.Client{Transport: NewRecordingTransport(path, nil)},
+	}
+	recorded, err := recording.GetAccountSummary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	server.Close()
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Unexpected error loading cassette: %v", err)
+	}
+	if len(cassette.Interactions) != 1 {
+		t.Fatalf("Expected 1 interaction, got %d", len(cassette.Interactions))
+	}
+
+	replay, err := NewReplayTransport(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	replaying := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    http.Client{Transport: replay},
+	}
+
+	replayed, err := replaying.GetAccountSummary()
+	if err != nil {
+		t.Fatalf("Unexpected error replaying: %v", err)
+	}
+	if replayed.LastTransactionID != recorded.LastTransactionID {
+		t.Errorf("Expected replayed summary to match, got %s", replayed.LastTransactionID)
+	}
+
+	// Each interaction is only served once
+	if _, err := replaying.GetAccountSummary(); err == nil {
+		t.Error("Expected an error once the cassette is exhausted")
+	}
+}
+
+func TestNewConnectionReplay(t *testing.T) {
+	defer logTestResult(t, "NewConnectionReplay")
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := &Cassette{Interactions: []Interaction{
+		{
+			Request:  RecordedRequest{Method: http.MethodGet, URL: "https://api-fxpractice.oanda.com/v3/accounts/test-account"},
+			Response: RecordedResponse{StatusCode: http.StatusOK, Body: `{"account":{"id":"test-account"}}`},
+		},
+	}}
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err := NewConnection("test-account", "test-token", &ConnectionConfig{
+		CassetteMode: CassetteReplay,
+		CassettePath: path,
+	})
+	if err != nil {
+		t.Errorf("Expected the connection check to be replayed, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package goanda
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrGTDPassed is returned for an order with a good-til-date expiry that has already passed by OANDA's clock
+var ErrGTDPassed = errors.New("goanda: gtdTime has passed")
+
+// clockSkew tracks the difference between OANDA's clock and the local one
+type clockSkew struct {
+	// skew is server time minus local time in nanoseconds, valid once known is set
+	skew  int64
+	known int32
+}
+
+// ClockSkew returns how far OANDA's clock is ahead of the local clock (negative if it is behind),
+// and whether it has been measured yet. It is measured from the Date header of api responses
+// and the timestamps of stream heartbeats.
+func (c *Connection) ClockSkew() (time.Duration, bool) {
+	if atomic.LoadInt32(&c.clock.known) == 0 {
+		return 0, false
+	}
+	return time.Duration(atomic.LoadInt64(&c.clock.skew)), true
+}
+
+// ServerTime estimates OANDA's current time by correcting the local clock for the measured skew
+func (c *Connection) ServerTime() time.Time {
+	skew, _ := c.ClockSkew()
+	return time.Now().Add(skew)
+}
+
+// observeServerTime records a sample of the server's clock, taken at the given local time.
+// Samples with a coarser resolution than the current estimate are ignored when they agree with it.
+func (c *Connection) observeServerTime(server time.Time, local time.Time, resolution time.Duration) {
+	sample := server.Sub(local)
+	if current, ok := c.ClockSkew(); ok {
+		diff := sample - current
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff <= resolution {
+			return
+		}
+	}
+
+	atomic.StoreInt64(&c.clock.skew, int64(sample))
+	atomic.StoreInt32(&c.clock.known, 1)
+}
+
+// observeDateHeader measures the skew from a response's Date header, which has a resolution of one second.
+// The local time used is the midpoint of the request to account for latency.
+func (c *Connection) observeDateHeader(res *http.Response, sent time.Time, received time.Time) {
+	date, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	// The header is truncated to the second, so on average it is half a second behind
+	server := date.Add(500 * time.Millisecond)
+	c.observeServerTime(server, sent.Add(received.Sub(sent)/2), time.Second)
+}
+
+// adjustGTD shifts an order's good-til-date expiry times from the local clock to the server's,
+// so the order lives for as long as intended
+func (c *Connection) adjustGTD(order *OrderBody) {
+	skew, ok := c.ClockSkew()
+	if !ok || skew == 0 {
+		return
+	}
+
+	if !order.GTDTime.IsZero() {
+		order.GTDTime = order.GTDTime.Add(skew)
+	}
+
+	for _, onFill := range []**OnFill{
+		&order.TakeProfitOnFill,
+		&order.StopLossOnFill,
+		&order.GuaranteedStopLossOnFill,
+		&order.TrailingStopLossOnFill,
+	} {
+		if *onFill == nil || (*onFill).GtdTime.IsZero() {
+			continue
+		}
+		// Copy rather than modify the caller's details
+		adjusted := **onFill
+		adjusted.GtdTime = adjusted.GtdTime.Add(skew)
+		*onFill = &adjusted
+	}
+}
+
+// checkGTD checks that an order's good-til-date expiry times are still to come by OANDA's clock, as
+// estimated from the measured skew, which OANDA would otherwise reject the order for
+func (c *Connection) checkGTD(order OrderBody) error {
+	now := c.ServerTime()
+	check := func(name string, timeInForce string, gtd time.Time) error {
+		switch {
+		case timeInForce != "GTD":
+			return nil
+		case gtd.IsZero():
+			return fmt.Errorf("goanda: a GTD %s needs a gtdTime", name)
+		case !gtd.After(now):
+			return fmt.Errorf("%w: the %s expires at %s, OANDA's time is %s", ErrGTDPassed, name,
+				gtd.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	errs := []error{check("order", order.TimeInForce, order.GTDTime)}
+	for _, onFill := range []struct {
+		name    string
+		details *OnFill
+	}{
+		{"take profit", order.TakeProfitOnFill},
+		{"stop loss", order.StopLossOnFill},
+		{"guaranteed stop loss", order.GuaranteedStopLossOnFill},
+		{"trailing stop loss", order.TrailingStopLossOnFill},
+	} {
+		if onFill.details != nil {
+			errs = append(errs, check(onFill.name, onFill.details.TimeInForce, onFill.details.GtdTime))
+		}
+	}
+	return errors.Join(errs...)
+}
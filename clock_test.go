@@ -0,0 +1,161 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClockSkewFromDateHeader(t *testing.T) {
+	defer logTestResult(t, "ClockSkewFromDateHeader")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server's clock runs ten minutes ahead
+		w.Header().Set("Date", time.Now().Add(10*time.Minute).UTC().Format(http.TimeFormat))
+		json.NewEncoder(w).Encode(AccountSummary{})
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	if _, ok := c.ClockSkew(); ok {
+		t.Error("Expected no skew to be known before any request")
+	}
+
+	if _, err := c.GetAccountSummary(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	skew, ok := c.ClockSkew()
+	if !ok {
+		t.Fatal("Expected the skew to be measured")
+	}
+	if skew < 10*time.Minute-time.Second || skew > 10*time.Minute+time.Second {
+		t.Errorf("Expected a skew of about 10m, got %v", skew)
+	}
+	if d := c.ServerTime().Sub(time.Now().Add(10 * time.Minute)); d > time.Second || d < -time.Second {
+		t.Errorf("Expected server time to be corrected by the skew, off by %v", d)
+	}
+}
+
+func TestClockSkewPrefersPreciseSamples(t *testing.T) {
+	defer logTestResult(t, "ClockSkewPrefersPreciseSamples")
+
+	c := &Connection{}
+	now := time.Now()
+
+	c.observeServerTime(now.Add(1500*time.Millisecond), now, 0)
+	// A Date header that agrees to within its one second resolution does not replace the heartbeat sample
+	c.observeServerTime(now.Add(2*time.Second), now, time.Second)
+	if skew, _ := c.ClockSkew(); skew != 1500*time.Millisecond {
+		t.Errorf("Expected the precise sample to be kept, got %v", skew)
+	}
+
+	c.observeServerTime(now.Add(5*time.Second), now, time.Second)
+	if skew, _ := c.ClockSkew(); skew != 5*time.Second {
+		t.Errorf("Expected a disagreeing sample to replace the estimate, got %v", skew)
+	}
+}
+
+func TestAdjustGTDForClockSkew(t *testing.T) {
+	defer logTestResult(t, "AdjustGTDForClockSkew")
+
+	var received OrderPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(OrderResponse{})
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+		skewGTD:   true,
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	c.observeServerTime(now.Add(time.Minute), now, 0)
+
+	gtd := now.Add(time.Hour)
+	stopLoss := &OnFill{Price: "1.0", TimeInForce: "GTD", GtdTime: gtd}
+	_, err := c.CreateOrder(OrderPayload{Order: OrderBody{
+		Type:           "LIMIT",
+		Instrument:     "EUR_USD",
+		Units:          100,
+		Price:          "1.1",
+		TimeInForce:    "GTD",
+		GTDTime:        gtd,
+		StopLossOnFill: stopLoss,
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !received.Order.GTDTime.Equal(gtd.Add(time.Minute)) {
+		t.Errorf("Expected gtdTime to be shifted by the skew, got %v", received.Order.GTDTime)
+	}
+	if !received.Order.StopLossOnFill.GtdTime.Equal(gtd.Add(time.Minute)) {
+		t.Errorf("Expected the stop loss gtdTime to be shifted, got %v", received.Order.StopLossOnFill.GtdTime)
+	}
+	if !stopLoss.GtdTime.Equal(gtd) {
+		t.Error("Expected the caller's stop loss details to be left alone")
+	}
+}
+
+func TestCheckGTDAgainstServerTime(t *testing.T) {
+	defer logTestResult(t, "CheckGTDAgainstServerTime")
+
+	var requests int
+	var body map[string]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if format := r.Header.Get("Accept-Datetime-Format"); format != "RFC3339" {
+			t.Errorf("Expected RFC3339 times to be requested, got %q", format)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(OrderResponse{})
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+	// OANDA's clock runs an hour ahead
+	now := time.Now()
+	c.observeServerTime(now.Add(time.Hour), now, 0)
+
+	order := OrderBody{Type: "LIMIT", Instrument: "EUR_USD", Units: 100, Price: "1.1", TimeInForce: "GTD"}
+	order.GTDTime = now.Add(30 * time.Minute)
+	if _, err := c.CreateOrder(OrderPayload{Order: order}); !errors.Is(err, ErrGTDPassed) {
+		t.Errorf("Expected an expiry past by OANDA's clock to be refused, got %v", err)
+	}
+
+	order.GTDTime = now.Add(2 * time.Hour).In(time.FixedZone("EST", -5*60*60))
+	order.StopLossOnFill = &OnFill{Price: "1.0", TimeInForce: "GTD"}
+	if _, err := c.CreateOrder(OrderPayload{Order: order}); err == nil {
+		t.Error("Expected a GTD stop loss without a gtdTime to be refused")
+	}
+	if requests != 0 {
+		t.Fatalf("Expected nothing to be sent, got %d requests", requests)
+	}
+
+	order.StopLossOnFill = &OnFill{Price: "1.0", TimeInForce: "GTC"}
+	if _, err := c.CreateOrder(OrderPayload{Order: order}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gtd := body["order"]["gtdTime"]; gtd != order.GTDTime.UTC().Format(time.RFC3339Nano) {
+		t.Errorf("Expected the gtdTime in UTC, got %v", gtd)
+	}
+	if _, ok := body["order"]["stopLossOnFill"].(map[string]interface{})["gtdTime"]; ok {
+		t.Error("Expected the stop loss to be sent without a gtdTime")
+	}
+}
@@ -0,0 +1,111 @@
+// Command goanda is a command line companion to the goanda library.
+//
+// Usage:
+//
+//	goanda init [-module path] <name>
+//
+// init scaffolds a runnable strategy runner in the directory <name>, wired to the library's
+// strategy config, stream manager, margin watch, kill switch and metrics.
+package main
+
+import (
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// scaffoldFiles maps the templates to the files they generate
+var scaffoldFiles = map[string]string{
+	"go.mod.tmpl":        "go.mod",
+	"main.go.tmpl":       "main.go",
+	"killswitch.go.tmpl": "killswitch.go",
+	"metrics.go.tmpl":    "metrics.go",
+	"strategy.yaml.tmpl": "strategy.yaml",
+	"env.example.tmpl":   ".env.example",
+	"gitignore.tmpl":     ".gitignore",
+	"README.md.tmpl":     "README.md",
+}
+
+// project is the data the templates are executed with
+type project struct {
+	Name   string
+	Module string
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "goanda:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: goanda init [-module path] <name>")
+	}
+
+	switch args[0] {
+	case "init":
+		return initProject(args[1:], out)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func initProject(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	module := flags.String("module", "", "module path of the new project, defaults to its name")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: goanda init [-module path] <name>")
+	}
+
+	dir := flags.Arg(0)
+	p := project{Name: filepath.Base(dir), Module: *module}
+	if p.Module == "" {
+		p.Module = p.Name
+	}
+
+	if err := scaffold(dir, p); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Created %s. Next:\n\n\tcd %s\n\tgo mod tidy\n\tcp .env.example .env\n\tgo run .\n", dir, dir)
+	return nil
+}
+
+// scaffold writes the project's files into dir, which must not already contain anything
+func scaffold(dir string, p project) error {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s already exists and is not empty", dir)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for name, file := range scaffoldFiles {
+		t, err := template.ParseFS(templates, path.Join("templates", name))
+		if err != nil {
+			return err
+		}
+		var b strings.Builder
+		if err := t.Execute(&b, p); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(b.String()), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rollend/goanda"
+)
+
+func TestInitScaffoldsProject(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mybot")
+	if err := run([]string{"init", "-module", "example.com/mybot", dir}, io.Discard); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, file := range scaffoldFiles {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("Expected %s to be created: %v", file, err)
+		}
+	}
+
+	mod, _ := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if !strings.HasPrefix(string(mod), "module example.com/mybot\n") {
+		t.Errorf("Unexpected go.mod: %s", mod)
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, 0)
+	if err != nil {
+		t.Fatalf("Expected the generated code to parse: %v", err)
+	}
+	if _, ok := pkgs["main"]; !ok || len(pkgs) != 1 {
+		t.Errorf("Expected a single main package, got %v", pkgs)
+	}
+
+	strategy, err := goanda.LoadStrategyConfigFile(filepath.Join(dir, "strategy.yaml"))
+	if err != nil {
+		t.Fatalf("Expected a valid strategy config: %v", err)
+	}
+	if _, err := strategy.Build(goanda.DefaultIndicators); err != nil {
+		t.Errorf("Expected the strategy to build: %v", err)
+	}
+	if strategy.Name != "mybot" {
+		t.Errorf("Expected the strategy to be named after the project, got %q", strategy.Name)
+	}
+
+	if err := run([]string{"init", dir}, io.Discard); err == nil {
+		t.Error("Expected an error scaffolding into a non-empty directory")
+	}
+}
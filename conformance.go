@@ -0,0 +1,174 @@
+package goanda
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SchemaError is a message that does not match the struct it is decoded into: it has a field the
+// struct does not, or lacks one the struct requires. Fields tagged omitempty are optional.
+type SchemaError struct {
+	Type string
+	// Path is the JSON path of the offending field, such as "bids[0].liquidity"
+	Path    string
+	Unknown bool
+	Message []byte
+}
+
+func (e *SchemaError) Error() string {
+	if e.Unknown {
+		return fmt.Sprintf("goanda: %s has unknown field %s", e.Type, e.Path)
+	}
+	return fmt.Sprintf("goanda: %s is missing required field %s", e.Type, e.Path)
+}
+
+// DecodeStrict decodes a message like json.Unmarshal, but returns a *SchemaError if the message has
+// fields v does not or lacks fields v requires. It can be run in CI over recorded streams and responses
+// to catch drift between the library's structs and what OANDA sends.
+func DecodeStrict(data []byte, v interface{}) error {
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if err := conform(generic, t, ""); err != nil {
+		if err.Type == "" {
+			err.Type = t.Name()
+		}
+		err.Message = data
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// schemaChecker is implemented by types decoding themselves whose messages can still be checked, against
+// the types their UnmarshalJSON decodes them into
+type schemaChecker interface {
+	conformSchema(value interface{}, path string) *SchemaError
+}
+
+// conform walks a generically decoded value alongside the type it is meant to decode into
+func conform(value interface{}, t reflect.Type, path string) *SchemaError {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if checker, ok := reflect.New(t).Interface().(schemaChecker); ok {
+		return checker.conformSchema(value, path)
+	}
+	if t.Implements(unmarshalerType) || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		known := map[string]bool{}
+		for _, field := range structFields(t) {
+			name, optional, ok := jsonField(field)
+			if !ok {
+				continue
+			}
+			known[name] = true
+			v, present := object[name]
+			if !present {
+				if !optional {
+					return &SchemaError{Path: join(path, name)}
+				}
+				continue
+			}
+			if err := conform(v, field.Type, join(path, name)); err != nil {
+				return err
+			}
+		}
+		for name := range object {
+			if !known[name] {
+				return &SchemaError{Path: join(path, name), Unknown: true}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		items, _ := value.([]interface{})
+		for i, item := range items {
+			if err := conform(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		object, _ := value.(map[string]interface{})
+		for key, item := range object {
+			if err := conform(item, t.Elem(), join(path, key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// structFields returns the fields of a struct with those of its untagged embedded structs in their
+// place, as encoding/json promotes them
+func structFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		embedded := field.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" && embedded.Kind() == reflect.Struct {
+			fields = append(fields, structFields(embedded)...)
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// jsonField returns the JSON name of a struct field and whether it is optional
+func jsonField(field reflect.StructField) (name string, optional bool, ok bool) {
+	if !field.IsExported() {
+		return "", false, false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(options, "omitempty"), true
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// decode decodes a stream message, strictly if StrictDecoding is set
+func (sc *StreamingConnection) decode(data []byte, v interface{}) error {
+	if !sc.StrictDecoding {
+		return decodeMessage(data, v)
+	}
+	err := DecodeStrict(data, v)
+	var schemaErr *SchemaError
+	if err != nil && !errors.As(err, &schemaErr) {
+		return &malformedMessage{err}
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	defer logTestResult(t, "DecodeStrict")
+
+	price := `{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD","tradeable":true,
+		"bids":[{"price":"1.10000","liquidity":1000000}],"asks":[{"price":"1.10010","liquidity":1000000}],
+		"closeoutBid":"1.09985","closeoutAsk":"1.10025"}`
+	var response PricingStreamResponse
+	if err := DecodeStrict([]byte(price), &response); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.Bids[0].Liquidity != 1000000 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	tests := []struct {
+		message string
+		path    string
+		unknown bool
+	}{
+		{`{"type":"PRICE","time":"t","spread":"0.1"}`, "spread", true},
+		{`{"type":"PRICE"}`, "time", false},
+		{`{"type":"PRICE","time":"t","bids":[{"price":"1.1","liquidity":1},{"price":"1.0"}]}`, "bids[1].liquidity", false},
+		{`{"type":"PRICE","time":"t","homeConversions":[{"currency":"EUR","accountGain":"1","accountLoss":"1","positionValue":"1","rate":"1"}]}`, "homeConversions[0].rate", true},
+	}
+	for _, test := range tests {
+		var response PricingStreamResponse
+		err := DecodeStrict([]byte(test.message), &response)
+		var schemaErr *SchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Errorf("%s: expected a SchemaError, got %v", test.message, err)
+			continue
+		}
+		if schemaErr.Path != test.path || schemaErr.Unknown != test.unknown || schemaErr.Type != "PricingStreamResponse" {
+			t.Errorf("%s: unexpected error %+v", test.message, schemaErr)
+		}
+	}
+}
+
+func TestDecodeStrictTransactions(t *testing.T) {
+	defer logTestResult(t, "DecodeStrictTransactions")
+
+	header := `"id":"6","time":"2024-01-02T15:04:05Z","userID":1,"accountID":"test-account","batchID":"5"`
+	fillWith := func(opened string) string {
+		return `{` + header + `,"type":"ORDER_FILL","orderID":"5","instrument":"EUR_USD","units":"100","price":"1.1",` +
+			`"reason":"MARKET_ORDER","pl":"0","financing":"0","commission":"0","accountBalance":"1000",` +
+			`"tradeOpened":{"tradeID":"6","units":"100","realizedPL":"0","financing":"0"` + opened + `}}`
+	}
+	fill := fillWith("")
+	for _, message := range []string{
+		fill,
+		`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05Z","lastTransactionID":"6"}`,
+		`{"id":"6","type":"ORDER_FILL","time":"2024-01-02T15:04:05Z","transactionID":"6","transaction":` + fill + `}`,
+		`{` + header + `,"type":"SOMETHING_NEW","anything":1}`,
+	} {
+		var response TransactionStreamResponse
+		if err := DecodeStrict([]byte(message), &response); err != nil {
+			t.Errorf("%s: unexpected error %v", message, err)
+		}
+	}
+
+	tests := []struct {
+		message string
+		typ     string
+		path    string
+		unknown bool
+	}{
+		{fill[:len(fill)-1] + `,"bogusDriftField":1}`, "OrderFillTransaction", "bogusDriftField", true},
+		{`{` + header + `,"type":"MARKET_ORDER","timeInForce":"FOK"}`, "MarketOrderTransaction", "reason", false},
+		{`{"type":"HEARTBEAT","time":"t","lastTransactionID":"6","extra":1}`, "HeartbeatResponse", "extra", true},
+		{`{"id":"6","type":"ORDER_FILL","time":"t","transaction":` + fillWith(`,"extra":1`) + `}`,
+			"OrderFillTransaction", "transaction.tradeOpened.extra", true},
+	}
+	for _, test := range tests {
+		var response TransactionStreamResponse
+		err := DecodeStrict([]byte(test.message), &response)
+		var schemaErr *SchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Errorf("%s: expected a SchemaError, got %v", test.message, err)
+			continue
+		}
+		if schemaErr.Path != test.path || schemaErr.Unknown != test.unknown || schemaErr.Type != test.typ {
+			t.Errorf("%s: unexpected error %+v", test.message, schemaErr)
+		}
+	}
+}
+
+func TestStreamStrictDecoding(t *testing.T) {
+	defer logTestResult(t, "StreamStrictDecoding")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD"}` + "\n"))
+		w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:06Z","instrument":"EUR_USD","quoteID":"7"}` + "\n"))
+		w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:07Z","instrument":"EUR_USD"}` + "\n"))
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	// Leniently the unknown field is ignored
+	prices := 0
+	if err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) { prices++ }); err != nil || prices != 3 {
+		t.Fatalf("Expected 3 prices without error, got %d (%v)", prices, err)
+	}
+
+	sc.StrictDecoding = true
+	prices = 0
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) { prices++ })
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || schemaErr.Path != "quoteID" {
+		t.Fatalf("Expected a SchemaError for quoteID, got %v", err)
+	}
+	if prices != 1 {
+		t.Errorf("Expected the stream to end at the nonconforming message, got %d prices", prices)
+	}
+}
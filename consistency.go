@@ -0,0 +1,39 @@
+package goanda
+
+import (
+	"context"
+	"time"
+)
+
+// syncPollInterval is how often SyncUntil checks the account's last transaction ID
+const syncPollInterval = 250 * time.Millisecond
+
+// SyncUntil blocks until the account reflects at least the given transaction ID,
+// such as the LastTransactionID returned when creating or cancelling an order.
+// Reads made after it returns will include the effects of that transaction.
+// It returns ctx.Err() if the context ends first.
+// In dry run mode nothing was sent, so there is nothing to wait for.
+func (c *Connection) SyncUntil(ctx context.Context, lastTransactionID string) error {
+	if c.dryRun {
+		return nil
+	}
+
+	ticker := time.NewTicker(syncPollInterval)
+	defer ticker.Stop()
+
+	for {
+		summary, err := c.GetAccountSummary()
+		if err != nil {
+			return err
+		}
+		if compareTransactionIDs(summary.LastTransactionID, lastTransactionID) >= 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
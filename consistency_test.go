@@ -0,0 +1,80 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSyncUntil(t *testing.T) {
+	defer logTestResult(t, "SyncUntil")
+
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/summary" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		// The account catches up with transaction 10 on the third poll
+		n := atomic.AddInt32(&polls, 1)
+		summary := AccountSummary{LastTransactionID: strconv.Itoa(7 + int(n))}
+		json.NewEncoder(w).Encode(summary)
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	err := c.SyncUntil(context.Background(), "10")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&polls); n != 3 {
+		t.Errorf("Expected 3 polls, got %d", n)
+	}
+}
+
+func TestSyncUntilContextDone(t *testing.T) {
+	defer logTestResult(t, "SyncUntilContextDone")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(AccountSummary{LastTransactionID: "1"})
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := c.SyncUntil(ctx, "10")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestCompareTransactionIDs(t *testing.T) {
+	defer logTestResult(t, "CompareTransactionIDs")
+
+	if compareTransactionIDs("9", "10") != -1 {
+		t.Error("Expected 9 to sort before 10")
+	}
+	if compareTransactionIDs("10", "10") != 0 {
+		t.Error("Expected 10 to equal 10")
+	}
+	if compareTransactionIDs("11", "10") != 1 {
+		t.Error("Expected 11 to sort after 10")
+	}
+}
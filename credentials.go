@@ -0,0 +1,165 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCredentialsInvalid is returned once OANDA has rejected the connection's token with a 401.
+// Requests fail fast with it, rather than each being sent and rejected, until the credentials are replaced
+// with Connection.SetToken or the TokenProvider starts returning a different token.
+// It also matches, with errors.Is, the APIError of the 401 itself.
+var ErrCredentialsInvalid = errors.New("goanda: credentials invalid")
+
+// TokenProvider supplies the API token for each request, allowing tokens to be rotated without a restart
+type TokenProvider interface {
+	Token() (string, error)
+}
+
+// TokenProviderFunc adapts a function to a TokenProvider
+type TokenProviderFunc func() (string, error)
+
+// Token calls the function
+func (f TokenProviderFunc) Token() (string, error) {
+	return f()
+}
+
+// credentialsPollInterval is how often a provider is asked for a new token while the credentials are invalid
+const credentialsPollInterval = 5 * time.Second
+
+// credentials tracks the token in use and whether OANDA has rejected it.
+// Its zero value uses the connection's authHeader.
+type credentials struct {
+	mu        sync.Mutex
+	provider  TokenProvider
+	header    string
+	invalid   bool
+	rejected  string
+	replaced  chan struct{}
+	onInvalid func(error)
+}
+
+// authorization returns the Authorization header to send,
+// or ErrCredentialsInvalid if it is the one OANDA last rejected. The provider is asked for the token
+// without holding the lock, so a slow provider doesn't hold up the rest of the connection.
+func (c *Connection) authorization() (string, error) {
+	cr := &c.creds
+	cr.mu.Lock()
+	header := c.authHeader
+	if cr.header != "" {
+		header = cr.header
+	}
+	provider := cr.provider
+	cr.mu.Unlock()
+
+	if provider != nil {
+		token, err := provider.Token()
+		if err != nil {
+			return "", fmt.Errorf("goanda: token provider: %w", err)
+		}
+		header = "Bearer " + token
+	}
+
+	cr.mu.Lock()
+	if !cr.invalid {
+		cr.mu.Unlock()
+		return header, nil
+	}
+	if header == cr.rejected {
+		cr.mu.Unlock()
+		return "", ErrCredentialsInvalid
+	}
+	cr.clear()
+	cr.mu.Unlock()
+
+	c.logf("goanda: credentials replaced, resuming requests")
+	return header, nil
+}
+
+// rejectCredentials records that OANDA rejected the given Authorization header, alerting the first time
+func (c *Connection) rejectCredentials(header string, err error) {
+	cr := &c.creds
+	cr.mu.Lock()
+	if cr.invalid && cr.rejected == header {
+		cr.mu.Unlock()
+		return
+	}
+	cr.invalid = true
+	cr.rejected = header
+	if cr.replaced == nil {
+		cr.replaced = make(chan struct{})
+	}
+	onInvalid := cr.onInvalid
+	cr.mu.Unlock()
+
+	c.logf("goanda: ALERT credentials rejected by OANDA, requests are suspended until they are replaced: %v", err)
+	if onInvalid != nil {
+		onInvalid(err)
+	}
+}
+
+func (cr *credentials) clear() {
+	cr.invalid = false
+	cr.rejected = ""
+	if cr.replaced != nil {
+		close(cr.replaced)
+		cr.replaced = nil
+	}
+}
+
+// SetToken replaces the connection's API token, resuming requests if the previous token was rejected.
+// It has no effect on a connection using a TokenProvider.
+func (c *Connection) SetToken(token string) {
+	cr := &c.creds
+	cr.mu.Lock()
+	cr.header = "Bearer " + token
+	replaced := cr.invalid && cr.rejected != cr.header && cr.provider == nil
+	if replaced {
+		cr.clear()
+	}
+	cr.mu.Unlock()
+
+	if replaced {
+		c.logf("goanda: credentials replaced, resuming requests")
+	}
+}
+
+// CredentialsValid reports whether the connection's credentials are usable,
+// false once OANDA has rejected them and until they are replaced
+func (c *Connection) CredentialsValid() bool {
+	_, err := c.authorization()
+	return !errors.Is(err, ErrCredentialsInvalid)
+}
+
+// awaitCredentials blocks until the credentials are no longer known to be invalid,
+// polling the TokenProvider if there is one
+func (c *Connection) awaitCredentials(ctx context.Context) error {
+	ticker := time.NewTicker(credentialsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		c.creds.mu.Lock()
+		replaced := c.creds.replaced
+		c.creds.mu.Unlock()
+
+		if _, err := c.authorization(); !errors.Is(err, ErrCredentialsInvalid) || replaced == nil {
+			return nil
+		}
+
+		select {
+		case <-replaced:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+}
+
+// Is reports a 401 Unauthorized as ErrCredentialsInvalid
+func (a APIError) Is(target error) bool {
+	return target == ErrCredentialsInvalid && a.Response != nil && a.Response.StatusCode == http.StatusUnauthorized
+}
@@ -0,0 +1,177 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// authServer accepts requests bearing only the given token
+func authServer(valid *atomic.Value, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+			http.Error(w, `{"errorMessage":"Insufficient authorization to perform request."}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"type":"ORDER_FILL","id":"1"}` + "\n"))
+	}))
+}
+
+func TestCredentialsInvalid(t *testing.T) {
+	defer logTestResult(t, "CredentialsInvalid")
+
+	var valid atomic.Value
+	valid.Store("new-token")
+	var requests int32
+	server := authServer(&valid, &requests)
+	defer server.Close()
+
+	var alerts []error
+	c := &Connection{
+		hostname:   server.URL,
+		authHeader: "Bearer revoked-token",
+		client:     *server.Client(),
+	}
+	c.creds.onInvalid = func(err error) {
+		alerts = append(alerts, err)
+	}
+
+	_, err := c.Get("/accounts")
+	if !errors.Is(err, ErrCredentialsInvalid) {
+		t.Fatalf("Expected the 401 to match ErrCredentialsInvalid, got %v", err)
+	}
+	var apiErr APIError
+	if !errors.As(err, &apiErr) {
+		t.Errorf("Expected the 401 to be returned as an APIError, got %T", err)
+	}
+	if c.CredentialsValid() {
+		t.Error("Expected the credentials to be reported invalid")
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get("/accounts"); !errors.Is(err, ErrCredentialsInvalid) {
+			t.Errorf("Expected requests to fail fast, got %v", err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected only the first request to reach the server, got %d", requests)
+	}
+	if len(alerts) != 1 {
+		t.Errorf("Expected a single alert, got %d", len(alerts))
+	}
+
+	c.SetToken("new-token")
+	if _, err := c.Get("/accounts"); err != nil {
+		t.Errorf("Expected requests to resume with the new token, got %v", err)
+	}
+	if !c.CredentialsValid() {
+		t.Error("Expected the credentials to be valid again")
+	}
+}
+
+func TestTokenProviderRotation(t *testing.T) {
+	defer logTestResult(t, "TokenProviderRotation")
+
+	var valid atomic.Value
+	valid.Store("first")
+	var requests int32
+	server := authServer(&valid, &requests)
+	defer server.Close()
+
+	var current atomic.Value
+	current.Store("first")
+	c := &Connection{
+		hostname: server.URL,
+		client:   *server.Client(),
+	}
+	c.creds.provider = TokenProviderFunc(func() (string, error) {
+		return current.Load().(string), nil
+	})
+
+	if _, err := c.Get("/accounts"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	valid.Store("second")
+	if _, err := c.Get("/accounts"); !errors.Is(err, ErrCredentialsInvalid) {
+		t.Fatalf("Expected the rotated out token to be rejected, got %v", err)
+	}
+	if _, err := c.Get("/accounts"); !errors.Is(err, ErrCredentialsInvalid) {
+		t.Errorf("Expected requests to fail fast until the provider changes, got %v", err)
+	}
+
+	current.Store("second")
+	if _, err := c.Get("/accounts"); err != nil {
+		t.Errorf("Expected requests to resume once the provider returns the new token, got %v", err)
+	}
+
+	// a provider that blocks holds up only the request asking it
+	asked, release := make(chan struct{}), make(chan struct{})
+	c.creds.provider = TokenProviderFunc(func() (string, error) {
+		close(asked)
+		<-release
+		return "second", nil
+	})
+	go c.authorization()
+	<-asked
+	done := make(chan struct{})
+	go func() {
+		c.SetToken("ignored")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected SetToken not to wait for the provider")
+	}
+	close(release)
+}
+
+func TestStreamAwaitsCredentials(t *testing.T) {
+	defer logTestResult(t, "StreamAwaitsCredentials")
+
+	var valid atomic.Value
+	valid.Store("new-token")
+	var requests int32
+	server := authServer(&valid, &requests)
+	defer server.Close()
+
+	sc := &StreamingConnection{
+		Connection: &Connection{authHeader: "Bearer revoked-token", client: *server.Client()},
+		streamURL:  server.URL,
+		Reconnect:  &ReconnectPolicy{InitialBackoff: time.Millisecond},
+	}
+	invalid := make(chan struct{})
+	var once sync.Once
+	sc.creds.onInvalid = func(error) {
+		once.Do(func() { close(invalid) })
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 1)
+	go sc.StreamTransactions(ctx, func(response TransactionStreamResponse) {
+		received <- response.ID
+		cancel()
+	})
+
+	<-invalid
+	time.Sleep(20 * time.Millisecond)
+	if requests != 1 {
+		t.Errorf("Expected the stream to wait rather than reconnect, got %d requests", requests)
+	}
+
+	sc.SetToken("new-token")
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stream to resume after the token was replaced")
+	}
+}
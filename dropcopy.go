@@ -0,0 +1,156 @@
+package goanda
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DropCopy writes every execution to a per-day, append-only file in a stable pipe delimited format,
+// which risk and compliance systems can ingest independently of the application. Each line is
+//
+//	timestamp|account|instrument|side|units|price|orderID|tradeID|transactionID
+//
+// with the timestamp in RFC3339 UTC, side BUY or SELL, and units unsigned. The tradeID is of the trade
+// the fill opened, reduced or closed, comma separated where it touched several. Files are named
+// dropcopy-YYYYMMDD.psv after the UTC day of the executions they hold. It is safe for concurrent use.
+type DropCopy struct {
+	dir string
+
+	mu   sync.Mutex
+	day  string
+	file *os.File
+}
+
+// NewDropCopy creates a drop copy writing into dir, creating it if needed
+func NewDropCopy(dir string) (*DropCopy, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DropCopy{dir: dir}, nil
+}
+
+// Record writes the transaction if it is an execution, an ORDER_FILL, and ignores it otherwise
+func (d *DropCopy) Record(t TransactionDetails) error {
+	if t.Type != "ORDER_FILL" {
+		return nil
+	}
+
+	side := "BUY"
+	units := t.Units
+	if strings.HasPrefix(units, "-") {
+		side = "SELL"
+		units = units[1:]
+	}
+
+	timestamp := t.Time.UTC()
+	line := strings.Join([]string{
+		timestamp.Format(time.RFC3339Nano),
+		dropCopyField(t.AccountID),
+		dropCopyField(t.Instrument),
+		side,
+		dropCopyField(units),
+		dropCopyField(t.Price),
+		dropCopyField(t.OrderID),
+		dropCopyField(dropCopyTradeIDs(t)),
+		dropCopyField(t.ID),
+	}, "|") + "\n"
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.rotate(timestamp.Format("20060102")); err != nil {
+		return err
+	}
+	_, err := d.file.WriteString(line)
+	return err
+}
+
+// RecordStreamed writes a transaction received from the transaction stream, see Record
+func (d *DropCopy) RecordStreamed(response TransactionStreamResponse) error {
+	var t TransactionDetails
+	if len(response.Transaction) != 0 {
+		if err := json.Unmarshal(response.Transaction, &t); err != nil {
+			return fmt.Errorf("drop copy: %w", err)
+		}
+	}
+	if t.Type == "" {
+		t.Type = response.Type
+	}
+	if t.ID == "" {
+		t.ID = response.id()
+	}
+	if t.AccountID == "" {
+		t.AccountID = response.AccountID
+	}
+	if t.Time.IsZero() {
+		t.Time, _ = time.Parse(time.RFC3339Nano, response.Time)
+	}
+	return d.Record(t)
+}
+
+// ResetState closes the current file, as after the account is reset. The executions already written
+// are kept, the drop copy being a record of what happened, and the reset account's are appended.
+func (d *DropCopy) ResetState() error {
+	return d.Close()
+}
+
+// Close closes the current file
+func (d *DropCopy) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	d.day = ""
+	return err
+}
+
+// rotate makes sure the file for the given day is open
+func (d *DropCopy) rotate(day string) error {
+	if d.file != nil && d.day == day {
+		return nil
+	}
+	if d.file != nil {
+		if err := d.file.Close(); err != nil {
+			return err
+		}
+		d.file = nil
+	}
+
+	path := filepath.Join(d.dir, "dropcopy-"+day+".psv")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	d.file = file
+	d.day = day
+	return nil
+}
+
+// dropCopyTradeIDs returns the IDs of the trades the fill opened, reduced and closed, in that order
+func dropCopyTradeIDs(t TransactionDetails) string {
+	var ids []string
+	if t.TradeOpened.TradeID != "" {
+		ids = append(ids, t.TradeOpened.TradeID)
+	}
+	if t.TradeReduced != nil {
+		ids = append(ids, t.TradeReduced.TradeID)
+	}
+	for _, closed := range t.TradesClosed {
+		ids = append(ids, closed.TradeID)
+	}
+	return strings.Join(ids, ",")
+}
+
+// dropCopyField keeps the delimiter and line breaks out of a field
+func dropCopyField(s string) string {
+	return strings.NewReplacer("|", "/", "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,143 @@
+package goanda
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDropCopy(t *testing.T) {
+	defer logTestResult(t, "DropCopy")
+
+	dir := filepath.Join(t.TempDir(), "dropcopy")
+	d, err := NewDropCopy(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	fill := TransactionDetails{
+		AccountID:  "101-001-1",
+		ID:         "6360",
+		Instrument: "EUR_USD",
+		OrderID:    "6359",
+		Price:      "1.10012",
+		Time:       time.Date(2024, 1, 2, 23, 59, 59, 0, time.UTC),
+		Type:       "ORDER_FILL",
+		Units:      "-1000",
+	}
+	fill.TradeOpened.TradeID = "6360"
+
+	if err := d.Record(fill); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := d.Record(TransactionDetails{Type: "MARKET_ORDER", Time: fill.Time}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	next := fill
+	next.Time = fill.Time.Add(2 * time.Second)
+	next.Units = "500"
+	next.ID = "6361"
+	if err := d.Record(next); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	first, err := os.ReadFile(filepath.Join(dir, "dropcopy-20240102.psv"))
+	if err != nil {
+		t.Fatalf("Expected the first day's file: %v", err)
+	}
+	expected := "2024-01-02T23:59:59Z|101-001-1|EUR_USD|SELL|1000|1.10012|6359|6360|6360\n"
+	if string(first) != expected {
+		t.Errorf("Expected %q, got %q", expected, first)
+	}
+
+	second, err := os.ReadFile(filepath.Join(dir, "dropcopy-20240103.psv"))
+	if err != nil {
+		t.Fatalf("Expected a file for the next day: %v", err)
+	}
+	if !strings.Contains(string(second), "|BUY|500|") {
+		t.Errorf("Unexpected second day contents: %q", second)
+	}
+
+	// Reopening appends rather than truncating
+	d, _ = NewDropCopy(dir)
+	d.Record(fill)
+	d.Close()
+	first, _ = os.ReadFile(filepath.Join(dir, "dropcopy-20240102.psv"))
+	if strings.Count(string(first), "\n") != 2 {
+		t.Errorf("Expected the file to be appended to, got %q", first)
+	}
+}
+
+func TestDropCopyRecordStreamed(t *testing.T) {
+	defer logTestResult(t, "DropCopyRecordStreamed")
+
+	dir := t.TempDir()
+	d, _ := NewDropCopy(dir)
+	defer d.Close()
+
+	err := d.RecordStreamed(TransactionStreamResponse{
+		Type:        "ORDER_FILL",
+		Time:        "2024-01-02T15:04:05Z",
+		ID:          "42",
+		AccountID:   "101-001-1",
+		Transaction: []byte(`{"instrument":"USD_JPY","units":"2000","price":"150.123","orderID":"41"}`),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// a fill closing trades records them in place of the one opened
+	err = d.RecordStreamed(TransactionStreamResponse{
+		Type:        "ORDER_FILL",
+		Time:        "2024-01-02T15:04:06Z",
+		ID:          "44",
+		AccountID:   "101-001-1",
+		Transaction: []byte(`{"instrument":"USD_JPY","units":"-3000","price":"150.2","orderID":"43","tradesClosed":[{"tradeID":"40"},{"tradeID":"42"}],"tradeReduced":{"tradeID":"38"}}`),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "dropcopy-20240102.psv"))
+	expected := "2024-01-02T15:04:05Z|101-001-1|USD_JPY|BUY|2000|150.123|41||42\n" +
+		"2024-01-02T15:04:06Z|101-001-1|USD_JPY|SELL|3000|150.2|43|38,40,42|44\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestStreamingDropCopy(t *testing.T) {
+	defer logTestResult(t, "StreamingDropCopy")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"42","type":"ORDER_FILL","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD","units":"10"}` + "\n"))
+		w.Write([]byte(`{"type":"ORDER_CANCEL","time":"2024-01-02T15:04:06Z","id":"43"}` + "\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	sc := &StreamingConnection{
+		Connection: &Connection{client: *server.Client()},
+		streamURL:  server.URL,
+	}
+	sc.DropCopy, _ = NewDropCopy(dir)
+	defer sc.DropCopy.Close()
+
+	if err := sc.StreamTransactions(context.Background(), func(TransactionStreamResponse) {}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "dropcopy-20240102.psv"))
+	if strings.Count(string(data), "\n") != 1 || !strings.Contains(string(data), "|EUR_USD|BUY|10|") {
+		t.Errorf("Expected only the fill to be recorded, got %q", data)
+	}
+}
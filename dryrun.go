@@ -0,0 +1,152 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// dryRunIDPrefix marks the synthetic transaction IDs returned in dry run mode
+const dryRunIDPrefix = "dry-run-"
+
+// simulate validates a mutating request and builds the response OANDA would be expected to return for it
+func (c *Connection) simulate(method string, endpoint string, data []byte) ([]byte, error) {
+	if len(data) != 0 && !json.Valid(data) {
+		return nil, fmt.Errorf("dry run %s %s: request body is not valid json", method, endpoint)
+	}
+
+	c.logf("goanda: dry run %s %s %s", method, endpoint, data)
+
+	id := c.nextDryRunID()
+	now := time.Now().UTC()
+	response := map[string]interface{}{
+		"lastTransactionID":     id,
+		"relatedTransactionIDs": []string{id},
+	}
+
+	path := strings.TrimPrefix(endpoint, "/accounts/"+c.accountID)
+	if i := strings.Index(path, "?"); i != -1 {
+		path = path[:i]
+	}
+
+	switch {
+	case method == "POST" && path == "/orders":
+		payload := OrderPayload{}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, fmt.Errorf("dry run %s %s: %w", method, endpoint, err)
+		}
+		if err := validateDryRunOrder(payload.Order); err != nil {
+			return nil, fmt.Errorf("dry run %s %s: %w", method, endpoint, err)
+		}
+		response["orderCreateTransaction"] = dryRunCreateTransaction(id, now, c.accountID, payload.Order)
+
+	case method == "PUT" && strings.HasPrefix(path, "/orders/") && strings.HasSuffix(path, "/cancel"):
+		orderID := strings.TrimSuffix(strings.TrimPrefix(path, "/orders/"), "/cancel")
+		response["orderCancelTransaction"] = map[string]interface{}{
+			"id":        id,
+			"time":      now,
+			"accountID": c.accountID,
+			"type":      "ORDER_CANCEL",
+			"orderID":   orderID,
+			"reason":    "CLIENT_REQUEST",
+		}
+
+	case method == "PUT" && strings.HasPrefix(path, "/orders/") && strings.HasSuffix(path, "/clientExtensions"):
+		extensions := OrderClientExtensions{}
+		if err := json.Unmarshal(data, &extensions); err != nil {
+			return nil, fmt.Errorf("dry run %s %s: %w", method, endpoint, err)
+		}
+		response["orderClientExtensionsModifyTransaction"] = map[string]interface{}{
+			"id":                          id,
+			"time":                        now,
+			"accountID":                   c.accountID,
+			"type":                        "ORDER_CLIENT_EXTENSIONS_MODIFY",
+			"orderID":                     strings.TrimSuffix(strings.TrimPrefix(path, "/orders/"), "/clientExtensions"),
+			"clientExtensionsModify":      extensions.ClientExtensions,
+			"tradeClientExtensionsModify": extensions.TradeClientExtensions,
+		}
+
+	case method == "PUT" && strings.HasPrefix(path, "/orders/") && strings.Count(path, "/") == 2:
+		payload := OrderPayload{}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, fmt.Errorf("dry run %s %s: %w", method, endpoint, err)
+		}
+		if err := validateDryRunOrder(payload.Order); err != nil {
+			return nil, fmt.Errorf("dry run %s %s: %w", method, endpoint, err)
+		}
+		createID := c.nextDryRunID()
+		orderID := strings.TrimPrefix(path, "/orders/")
+		response["orderCancelTransaction"] = map[string]interface{}{
+			"id":                id,
+			"time":              now,
+			"accountID":         c.accountID,
+			"type":              "ORDER_CANCEL",
+			"orderID":           orderID,
+			"reason":            "CLIENT_REQUEST_REPLACED",
+			"replacedByOrderID": createID,
+		}
+		create := dryRunCreateTransaction(createID, now, c.accountID, payload.Order)
+		create["replacesOrderID"] = orderID
+		response["orderCreateTransaction"] = create
+		response["lastTransactionID"] = createID
+		response["relatedTransactionIDs"] = []string{id, createID}
+	}
+
+	return json.Marshal(response)
+}
+
+func (c *Connection) nextDryRunID() string {
+	return dryRunIDPrefix + strconv.FormatUint(atomic.AddUint64(&c.dryRunIDs, 1), 10)
+}
+
+// validateDryRunOrder performs the checks OANDA would reject an order for without needing market data
+func validateDryRunOrder(order OrderBody) error {
+	if order.Type == "" {
+		return errors.New("order type is required")
+	}
+
+	switch order.Type {
+	case "MARKET", "LIMIT", "STOP", "MARKET_IF_TOUCHED":
+		if order.Instrument == "" {
+			return errors.New("instrument is required")
+		}
+		if order.Units == 0 {
+			return errors.New("units must not be zero")
+		}
+	}
+
+	switch order.Type {
+	case "LIMIT", "STOP", "MARKET_IF_TOUCHED":
+		if order.Price == "" {
+			return fmt.Errorf("price is required for %s orders", order.Type)
+		}
+	}
+
+	return nil
+}
+
+func dryRunCreateTransaction(id string, now time.Time, accountID string, order OrderBody) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                       id,
+		"time":                     now,
+		"accountID":                accountID,
+		"type":                     order.Type + "_ORDER",
+		"instrument":               order.Instrument,
+		"units":                    formatUnits(order.Units),
+		"timeInForce":              order.TimeInForce,
+		"positionFill":             order.PositionFill,
+		"price":                    order.Price,
+		"priceBound":               order.PriceBound,
+		"reason":                   "CLIENT_ORDER",
+		"clientExtensions":         order.ClientExtensions,
+		"takeProfitOnFill":         order.TakeProfitOnFill,
+		"stopLossOnFill":           order.StopLossOnFill,
+		"trailingStopLossOnFill":   order.TrailingStopLossOnFill,
+		"guaranteedStopLossOnFill": order.GuaranteedStopLossOnFill,
+		"tradeClientExtensions":    order.TradeClientExtensions,
+	}
+}
@@ -0,0 +1,118 @@
+package goanda
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func dryRunConnection(t *testing.T) (*Connection, *bytes.Buffer, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Unexpected %s request in dry run mode: %s", r.Method, r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(RetrievedOrders{LastTransactionID: "1"})
+	}))
+
+	logs := &bytes.Buffer{}
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+		dryRun:    true,
+		logger:    log.New(logs, "", 0),
+	}
+	return c, logs, server.Close
+}
+
+func TestDryRunCreateOrder(t *testing.T) {
+	defer logTestResult(t, "DryRunCreateOrder")
+	c, logs, done := dryRunConnection(t)
+	defer done()
+
+	response, err := c.CreateOrder(OrderPayload{Order: OrderBody{
+		Type:        "LIMIT",
+		Instrument:  "EUR_USD",
+		Units:       100,
+		Price:       "1.1000",
+		TimeInForce: "GTC",
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(response.OrderCreateTransaction.ID, dryRunIDPrefix) {
+		t.Errorf("Expected a synthetic transaction ID, got %s", response.OrderCreateTransaction.ID)
+	}
+	if response.OrderCreateTransaction.Type != "LIMIT_ORDER" {
+		t.Errorf("Expected LIMIT_ORDER, got %s", response.OrderCreateTransaction.Type)
+	}
+	if response.OrderCreateTransaction.Units != "100" {
+		t.Errorf("Expected 100 units, got %s", response.OrderCreateTransaction.Units)
+	}
+	if response.GetOrderState() != "PENDING" {
+		t.Errorf("Expected state PENDING, got %s", response.GetOrderState())
+	}
+	if !strings.Contains(logs.String(), "dry run POST /accounts/test-account/orders") {
+		t.Errorf("Expected the request to be logged, got %q", logs.String())
+	}
+
+	// Reads still go to the api
+	if _, err := c.GetOrders(""); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDryRunValidation(t *testing.T) {
+	defer logTestResult(t, "DryRunValidation")
+	c, _, done := dryRunConnection(t)
+	defer done()
+
+	_, err := c.CreateOrder(OrderPayload{Order: OrderBody{Type: "LIMIT", Instrument: "EUR_USD", Units: 100}})
+	if err == nil {
+		t.Error("Expected a limit order without a price to be rejected")
+	}
+
+	_, err = c.CreateOrder(OrderPayload{Order: OrderBody{Type: "MARKET", Instrument: "EUR_USD"}})
+	if err == nil {
+		t.Error("Expected an order without units to be rejected")
+	}
+}
+
+func TestDryRunCancelOrder(t *testing.T) {
+	defer logTestResult(t, "DryRunCancelOrder")
+	c, _, done := dryRunConnection(t)
+	defer done()
+
+	cancelled, err := c.CancelOrder("123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancelled.OrderCancelTransaction.OrderID != "123" {
+		t.Errorf("Expected order 123 to be cancelled, got %s", cancelled.OrderCancelTransaction.OrderID)
+	}
+	if cancelled.OrderCancelTransaction.ID == cancelled.OrderCancelTransaction.OrderID {
+		t.Error("Expected a fresh synthetic transaction ID")
+	}
+}
+
+func TestDryRunSetOrderClientExtensions(t *testing.T) {
+	defer logTestResult(t, "DryRunSetOrderClientExtensions")
+	c, _, done := dryRunConnection(t)
+	defer done()
+
+	modified, err := c.SetOrderClientExtensions("123", OrderClientExtensions{
+		TradeClientExtensions: &OrderExtensions{Comment: "mean reversion"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	transaction := modified.OrderClientExtensionsModifyTransaction
+	if transaction.OrderID != "123" || transaction.TradeClientExtensionsModify == nil || transaction.TradeClientExtensionsModify.Comment != "mean reversion" {
+		t.Errorf("Expected order 123's trade client extensions to be set, got %+v", transaction)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func newAPIError(request *http.Request, response *http.Response) APIError {
@@ -16,11 +18,13 @@ func newAPIError(request *http.Request, response *http.Response) APIError {
 	}{}
 
 	apiErr := APIError{
-		Response: response,
-		Request:  request,
+		Response:   response,
+		Request:    request,
+		RetryAfter: retryAfter(response.Header, time.Now()),
 	}
 
 	b, _ := ioutil.ReadAll(response.Body)
+	apiErr.body = b
 	err := json.Unmarshal(b, &msg)
 	if err != nil {
 		apiErr.Message = string(b)
@@ -38,6 +42,15 @@ type APIError struct {
 	Request  *http.Request
 	Response *http.Response
 	Message  string
+	// RetryAfter is how long the server asked the client to wait before retrying, or zero if it did not say
+	RetryAfter time.Duration
+
+	body []byte
+}
+
+// RateLimited reports whether the request was rejected for exceeding OANDA's rate limit
+func (a APIError) RateLimited() bool {
+	return a.Response != nil && a.Response.StatusCode == http.StatusTooManyRequests
 }
 
 // APIError implements error
@@ -48,3 +61,30 @@ func (a APIError) Error() string {
 		a.Message,
 	)
 }
+
+// retryAfter parses the Retry-After header, given either in seconds or as an HTTP date,
+// falling back to the RateLimit-Reset style headers which give the seconds until the limit resets
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return nonNegative(time.Duration(seconds) * time.Second)
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return nonNegative(date.Sub(now))
+		}
+	}
+
+	for _, name := range []string{"RateLimit-Reset", "X-RateLimit-Reset"} {
+		if seconds, err := strconv.Atoi(header.Get(name)); err == nil && seconds < 1e9 {
+			return nonNegative(time.Duration(seconds) * time.Second)
+		}
+	}
+	return 0
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,134 @@
+package goanda
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultEventBuffer is the number of events Events buffers when no BufferSize is set
+const DefaultEventBuffer = 256
+
+// EventKind identifies what an Event holds
+type EventKind string
+
+const (
+	EventPrice       EventKind = "PRICE"
+	EventTransaction EventKind = "TRANSACTION"
+	EventHeartbeat   EventKind = "HEARTBEAT"
+	// EventError is the error that ended a stream, it is the last event before the channel closes
+	EventError EventKind = "ERROR"
+)
+
+// EventSource is the stream an Event came from
+type EventSource string
+
+const (
+	EventSourcePricing      EventSource = "pricing"
+	EventSourceTransactions EventSource = "transactions"
+)
+
+// Event is a message from one of the streams merged by Events. Kind says which of its fields is set.
+type Event struct {
+	Kind   EventKind
+	Source EventSource
+	// AccountID is the account whose streams the event came from
+	AccountID   string
+	Price       PricingStreamResponse
+	Transaction TransactionStreamResponse
+	Heartbeat   HeartbeatResponse
+	Err         error
+}
+
+// EventsOptions selects the streams merged by Events
+type EventsOptions struct {
+	// Instruments are the instruments to stream prices for, there is no price stream without any
+	Instruments  []string
+	PriceOptions PriceStreamOptions
+	// Transactions adds the account's transaction stream
+	Transactions bool
+	// LastTransactionID, if set, first delivers the transactions after it, see StreamTransactionsSince
+	LastTransactionID string
+	// Heartbeats adds each stream's heartbeats, they also go to OnHeartbeat
+	Heartbeats bool
+	// BufferSize is the channel's capacity, it defaults to DefaultEventBuffer
+	BufferSize int
+}
+
+// Events merges the price and transaction streams into one channel, so a strategy can consume both
+// from a single loop:
+//
+//	for event := range sc.Events(ctx, goanda.EventsOptions{Instruments: instruments, Transactions: true}) {
+//		switch event.Kind {
+//		case goanda.EventPrice:
+//		case goanda.EventTransaction:
+//		}
+//	}
+//
+// Events are delivered in the order they are received. A full channel holds the streams back, as a
+// slow callback would. If a stream fails its error is sent as an EventError and the other streams are
+// closed with it. The channel is closed once every stream has ended, or ctx is done.
+func (sc *StreamingConnection) Events(ctx context.Context, opts EventsOptions) <-chan Event {
+	buffer := opts.BufferSize
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+	events := make(chan Event, buffer)
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	send := func(event Event) error {
+		select {
+		case events <- event:
+			return nil
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+
+	accountID := sc.account()
+	var wg sync.WaitGroup
+	run := func(source EventSource, stream func(*StreamingConnection) error) {
+		// Each stream gets its own copy to tag its heartbeats
+		s := *sc
+		if opts.Heartbeats {
+			onHeartbeat := sc.OnHeartbeat
+			s.OnHeartbeat = func(heartbeat HeartbeatResponse) {
+				if onHeartbeat != nil {
+					onHeartbeat(heartbeat)
+				}
+				send(Event{Kind: EventHeartbeat, Source: source, AccountID: accountID, Heartbeat: heartbeat})
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := stream(&s)
+			if err != nil && ctx.Err() == nil {
+				send(Event{Kind: EventError, Source: source, AccountID: accountID, Err: err})
+				cancel(err)
+			}
+		}()
+	}
+
+	if len(opts.Instruments) > 0 {
+		run(EventSourcePricing, func(s *StreamingConnection) error {
+			return s.streamPrices(ctx, opts.Instruments, opts.PriceOptions, func(price PricingStreamResponse) error {
+				return send(Event{Kind: EventPrice, Source: EventSourcePricing, AccountID: accountID, Price: price})
+			})
+		})
+	}
+	if opts.Transactions {
+		run(EventSourceTransactions, func(s *StreamingConnection) error {
+			return s.streamTransactionsSince(ctx, opts.LastTransactionID, func(tx TransactionStreamResponse) error {
+				return send(Event{Kind: EventTransaction, Source: EventSourceTransactions, AccountID: accountID, Transaction: tx})
+			})
+		})
+	}
+
+	go func() {
+		wg.Wait()
+		cancel(nil)
+		close(events)
+	}()
+	return events
+}
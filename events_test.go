@@ -0,0 +1,98 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	defer logTestResult(t, "Events")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05Z"}` + "\n"))
+		if strings.HasSuffix(r.URL.Path, "/pricing/stream") {
+			w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD"}` + "\n"))
+		} else {
+			w.Write([]byte(`{"type":"ORDER_FILL","time":"2024-01-02T15:04:05Z","id":"6"}` + "\n"))
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+	var heartbeats atomic.Int32
+	sc.OnHeartbeat = func(HeartbeatResponse) { heartbeats.Add(1) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := sc.Events(ctx, EventsOptions{Instruments: []string{"EUR_USD"}, Transactions: true, Heartbeats: true})
+
+	seen := map[EventKind]int{}
+	for event := range events {
+		seen[event.Kind]++
+		switch event.Kind {
+		case EventPrice:
+			if event.Price.Instrument != "EUR_USD" || event.Source != EventSourcePricing {
+				t.Errorf("Unexpected price event: %+v", event)
+			}
+		case EventTransaction:
+			if event.Transaction.ID != "6" || event.Source != EventSourceTransactions {
+				t.Errorf("Unexpected transaction event: %+v", event)
+			}
+		case EventError:
+			t.Errorf("Unexpected error: %v", event.Err)
+		}
+		if seen[EventPrice]+seen[EventTransaction]+seen[EventHeartbeat] == 4 {
+			cancel()
+		}
+	}
+	if seen[EventPrice] != 1 || seen[EventTransaction] != 1 || seen[EventHeartbeat] != 2 {
+		t.Errorf("Unexpected events: %v", seen)
+	}
+	if heartbeats.Load() != 2 {
+		t.Errorf("Expected the heartbeats to reach OnHeartbeat too, got %d", heartbeats.Load())
+	}
+}
+
+func TestEventsStreamFailure(t *testing.T) {
+	defer logTestResult(t, "EventsStreamFailure")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/transactions/stream") {
+			http.Error(w, `{"errorMessage":"bad request"}`, http.StatusBadRequest)
+			return
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	var errs []error
+	for event := range sc.Events(context.Background(), EventsOptions{Instruments: []string{"EUR_USD"}, Transactions: true}) {
+		if event.Kind == EventError {
+			errs = append(errs, event.Err)
+		}
+	}
+	var apiErr APIError
+	if len(errs) != 1 || !errors.As(errs[0], &apiErr) {
+		t.Errorf("Expected the transaction stream's error, then the channel to close, got %v", errs)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -35,23 +37,24 @@ func streams() {
 
 	// Create a WaitGroup to manage our goroutines
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(4)
 
-	// Create a channel to signal goroutines to stop
-	stop := make(chan struct{})
+	// Cancel the streams on an interrupt signal for a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// Start price streaming
 	go func() {
 		defer wg.Done()
 		instruments := []string{"EUR_USD", "USD_JPY", "GBP_USD"}
-		err := streaming.StreamPrices(instruments, func(response goanda.PricingStreamResponse) {
+		err := streaming.StreamPrices(ctx, instruments, func(response goanda.PricingStreamResponse) {
 			fmt.Printf("Price update: %s - %s - Bid: %s, Ask: %s\n",
 				response.Time,
 				response.Instrument,
 				response.Bids[0].Price,
 				response.Asks[0].Price)
 		})
-		if err != nil {
+		if err != nil && !errors.Is(err, context.Canceled) {
 			log.Printf("Error streaming prices: %v", err)
 		}
 	}()
@@ -59,13 +62,13 @@ func streams() {
 	// Start transaction streaming
 	go func() {
 		defer wg.Done()
-		err := streaming.StreamTransactions(func(response goanda.TransactionStreamResponse) {
+		err := streaming.StreamTransactions(ctx, func(response goanda.TransactionStreamResponse) {
 			fmt.Printf("Transaction update: %s - Type: %s, ID: %s\n",
 				response.Time,
 				response.Type,
 				response.TransactionID)
 		})
-		if err != nil {
+		if err != nil && !errors.Is(err, context.Canceled) {
 			log.Printf("Error streaming transactions: %v", err)
 		}
 	}()
@@ -73,12 +76,12 @@ func streams() {
 	// Start account changes streaming
 	go func() {
 		defer wg.Done()
-		err := streaming.StreamAccountChanges(func(response goanda.AccountChangesStreamResponse) {
+		err := streaming.StreamAccountChanges(ctx, func(response goanda.AccountChangesStreamResponse) {
 			fmt.Printf("Account changes: %s - LastTransactionID: %s\n",
 				response.Changes,
 				response.LastTransactionID)
 		})
-		if err != nil {
+		if err != nil && !errors.Is(err, context.Canceled) {
 			log.Printf("Error streaming account changes: %v", err)
 		}
 	}()
@@ -86,7 +89,7 @@ func streams() {
 	// Start candlestick streaming
 	go func() {
 		defer wg.Done()
-		err := streaming.StreamCandles("EUR_USD", "M1", func(response goanda.CandlestickStreamResponse) {
+		err := streaming.StreamCandles(ctx, "EUR_USD", "M1", func(response goanda.CandlestickStreamResponse) {
 			if len(response.Candles) > 0 {
 				candle := response.Candles[0]
 				fmt.Printf("Candlestick update: %s - %s - O: %f, H: %f, L: %f, C: %f\n",
@@ -98,22 +101,15 @@ func streams() {
 					candle.Mid.Close)
 			}
 		})
-		if err != nil {
+		if err != nil && !errors.Is(err, context.Canceled) {
 			log.Printf("Error streaming candles: %v", err)
 		}
 	}()
 
-	// Set up signal handling for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
 	// Wait for interrupt signal
-	<-sigChan
+	<-ctx.Done()
 	fmt.Println("\nReceived interrupt signal. Shutting down...")
 
-	// Signal goroutines to stop
-	close(stop)
-
 	// Wait for goroutines to finish
 	wg.Wait()
 
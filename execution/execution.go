@@ -0,0 +1,288 @@
+// Package execution works a large order into the market over a horizon as market orders of slices of
+// it, so that it doesn't move the price as one order would. A TWAP execution sends equal slices at even
+// times, a VWAP execution sizes them to a volume profile, sending more when more of the day's volume
+// usually trades:
+//
+//	// the same hour yesterday, in five minute candles
+//	history, _ := c.GetTimeFromCandles("EUR_USD", 12, goanda.GranularityFiveMinutes, start.AddDate(0, 0, -1))
+//	vwap, _ := execution.NewVWAP(c, execution.Options{Instrument: "EUR_USD", Units: 500000, Horizon: time.Hour},
+//		execution.VolumeProfile(history))
+//	err := vwap.Run(ctx, sc)
+//
+// The slices are timed by the price stream: a slice is sent on the first tradeable price at or after
+// the time it is due, by the price's time, so nothing is sent while the instrument isn't trading.
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rollend/goanda"
+)
+
+// DefaultSlices is how many slices a TWAP execution is split into when Slices is not set
+const DefaultSlices = 10
+
+// ErrCancelled is returned by Run once Cancel is called
+var ErrCancelled = errors.New("execution: cancelled")
+
+// Orders sends an execution's market orders, *goanda.Connection implements it
+type Orders interface {
+	CreateMarketOrder(instrument string, units float64, opts goanda.OrderOptions) (goanda.OrderCreateResponse, error)
+}
+
+// Options describe the order an execution works
+type Options struct {
+	Instrument string
+	// Units is the order's size, positive to buy and negative to sell
+	Units float64
+	// Horizon is the time the order is worked over, the first slice is sent at its start and the last
+	// a slice's length before its end
+	Horizon time.Duration
+	// Slices is how many slices a TWAP execution is split into, it defaults to DefaultSlices
+	Slices int
+	// UnitsPrecision is the decimal places the units of each order are rounded to, whole units by default
+	UnitsPrecision int
+	// OrderOptions apply to every order. A PriceBound, or a time in force of FOK or IOC, may leave a
+	// slice unfilled, what it didn't fill is added to the next.
+	OrderOptions goanda.OrderOptions
+	// OnProgress, if set, is called after each order with the execution's progress
+	OnProgress func(Progress)
+}
+
+// Progress is how far an execution has got
+type Progress struct {
+	// Slice is how many of the Slices have been sent
+	Slice  int
+	Slices int
+	// Target is the order's Units, Executed is how many of them have filled, at AveragePrice
+	Target       float64
+	Executed     float64
+	AveragePrice float64
+	// Response is the last order's, it is nil until an order is sent
+	Response *goanda.OrderCreateResponse
+}
+
+// Remaining returns the units still to be executed
+func (p Progress) Remaining() float64 {
+	return p.Target - p.Executed
+}
+
+// Execution works an order over its horizon, see Run. It is safe for concurrent use.
+type Execution struct {
+	orders Orders
+	opts   Options
+	// schedule is the fraction of the units due by the end of each slice
+	schedule []float64
+
+	mu        sync.Mutex
+	progress  Progress
+	notional  float64
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// NewTWAP creates an execution sending equal slices evenly over the horizon
+func NewTWAP(orders Orders, opts Options) (*Execution, error) {
+	slices := opts.Slices
+	if slices <= 0 {
+		slices = DefaultSlices
+	}
+	weights := make([]float64, slices)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return newExecution(orders, opts, weights)
+}
+
+// NewVWAP creates an execution splitting the horizon into a slice for each of the profile's volumes,
+// each slice's size in proportion to its volume, see VolumeProfile
+func NewVWAP(orders Orders, opts Options, profile []float64) (*Execution, error) {
+	for _, volume := range profile {
+		if volume < 0 || math.IsNaN(volume) || math.IsInf(volume, 0) {
+			return nil, fmt.Errorf("execution: invalid volume %v in profile", volume)
+		}
+	}
+	return newExecution(orders, opts, profile)
+}
+
+func newExecution(orders Orders, opts Options, weights []float64) (*Execution, error) {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	switch {
+	case opts.Instrument == "":
+		return nil, errors.New("execution: no instrument")
+	case opts.Units == 0:
+		return nil, errors.New("execution: no units")
+	case opts.Horizon <= 0:
+		return nil, errors.New("execution: the horizon must be positive")
+	case len(weights) == 0 || total == 0:
+		return nil, errors.New("execution: the profile has no volume")
+	}
+
+	cumulative := make([]float64, len(weights))
+	sum := 0.0
+	for i, weight := range weights {
+		sum += weight
+		cumulative[i] = sum / total
+	}
+	return &Execution{
+		orders:   orders,
+		opts:     opts,
+		schedule: cumulative,
+		progress: Progress{Slices: len(weights), Target: opts.Units},
+	}, nil
+}
+
+// VolumeProfile returns the volume of each candle of the histories, adding up candles at the same
+// position in each, such as the same hour's candles of the last few days, for NewVWAP
+func VolumeProfile(histories ...goanda.InstrumentHistory) []float64 {
+	var profile []float64
+	for _, history := range histories {
+		for i, candle := range history.Candles {
+			if i == len(profile) {
+				profile = append(profile, 0)
+			}
+			profile[i] += float64(candle.Volume)
+		}
+	}
+	return profile
+}
+
+// Run streams the instrument's prices and sends each slice when it is due, returning once the last is
+// sent. It returns ErrCancelled if Cancel is called, the error of an order that couldn't be sent, or
+// the error ending the stream, leaving the rest of the order unsent. The horizon starts at the first
+// tradeable price. Slices that fall due together, after a gap in the stream, are sent as one order.
+func (e *Execution) Run(ctx context.Context, sc *goanda.StreamingConnection) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	e.mu.Lock()
+	if e.cancelled {
+		e.mu.Unlock()
+		return ErrCancelled
+	}
+	e.cancel = cancel
+	e.mu.Unlock()
+
+	var start time.Time
+	slices := len(e.schedule)
+	if e.Progress().Slice == slices {
+		return nil
+	}
+	for price, err := range sc.Prices(ctx, []string{e.opts.Instrument}) {
+		if err != nil {
+			if e.Cancelled() {
+				return ErrCancelled
+			}
+			return err
+		}
+		if price.Type != "PRICE" || price.Instrument != e.opts.Instrument || !price.Tradeable {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339Nano, price.Time)
+		if err != nil {
+			return fmt.Errorf("execution: invalid price time %q: %w", price.Time, err)
+		}
+		if start.IsZero() {
+			start = at
+		}
+
+		due := e.Progress().Slice
+		for due < slices && !at.Before(start.Add(e.opts.Horizon*time.Duration(due)/time.Duration(slices))) {
+			due++
+		}
+		if due == e.Progress().Slice {
+			continue
+		}
+		if e.Cancelled() {
+			return ErrCancelled
+		}
+		if err := e.send(due); err != nil {
+			return err
+		}
+		if due == slices {
+			return nil
+		}
+	}
+	if e.Cancelled() {
+		return ErrCancelled
+	}
+	return fmt.Errorf("execution: price stream ended after %d of %d slices", e.Progress().Slice, slices)
+}
+
+// send orders what is left of the units scheduled up to the slice, which are due
+func (e *Execution) send(slice int) error {
+	e.mu.Lock()
+	scale := math.Pow(10, float64(e.opts.UnitsPrecision))
+	scheduled := math.Round(e.opts.Units*e.schedule[slice-1]*scale) / scale
+	units := math.Round((scheduled-e.progress.Executed)*scale) / scale
+	e.mu.Unlock()
+
+	var response goanda.OrderCreateResponse
+	if units != 0 && math.Signbit(units) == math.Signbit(e.opts.Units) {
+		var err error
+		if response, err = e.orders.CreateMarketOrder(e.opts.Instrument, units, e.opts.OrderOptions); err != nil {
+			return fmt.Errorf("execution: sending %s units of %s: %w", strconv.FormatFloat(units, 'f', -1, 64), e.opts.Instrument, err)
+		}
+	}
+
+	e.mu.Lock()
+	e.progress.Slice = slice
+	if fill := response.OrderFillTransaction; fill != nil {
+		filled, err := strconv.ParseFloat(fill.Units, 64)
+		if err != nil {
+			e.mu.Unlock()
+			return fmt.Errorf("execution: invalid units %q in fill %s", fill.Units, fill.ID)
+		}
+		price, err := strconv.ParseFloat(fill.Price, 64)
+		if err != nil {
+			e.mu.Unlock()
+			return fmt.Errorf("execution: invalid price %q in fill %s", fill.Price, fill.ID)
+		}
+		e.progress.Executed += filled
+		e.notional += math.Abs(filled) * price
+		e.progress.AveragePrice = e.notional / math.Abs(e.progress.Executed)
+	}
+	if response.OrderCreateTransaction != nil {
+		e.progress.Response = &response
+	}
+	progress := e.progress
+	e.mu.Unlock()
+
+	if e.opts.OnProgress != nil {
+		e.opts.OnProgress(progress)
+	}
+	return nil
+}
+
+// Cancel stops the execution, Run returns ErrCancelled without sending any more orders. The orders
+// already sent are not undone.
+func (e *Execution) Cancel() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cancelled = true
+	if e.cancel != nil {
+		e.cancel()
+	}
+}
+
+// Cancelled reports whether Cancel has been called
+func (e *Execution) Cancelled() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cancelled
+}
+
+// Progress returns how far the execution has got
+func (e *Execution) Progress() Progress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.progress
+}
@@ -0,0 +1,187 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rollend/goanda"
+)
+
+// broker fills each market order at a price a pip higher than the last, for only the share of its
+// units given by fills, in turn, or in full once they run out
+type broker struct {
+	mu    sync.Mutex
+	units []float64
+	fills []float64
+}
+
+func (b *broker) CreateMarketOrder(instrument string, units float64, opts goanda.OrderOptions) (goanda.OrderCreateResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(b.units)
+	b.units = append(b.units, units)
+	filled := units
+	if n < len(b.fills) {
+		filled *= b.fills[n]
+	}
+
+	create := &goanda.MarketOrderTransaction{}
+	create.ID, create.Type = strconv.Itoa(10*n+10), "MARKET_ORDER"
+	fill := &goanda.OrderFillTransaction{
+		OrderID: create.ID,
+		Units:   strconv.FormatFloat(filled, 'f', -1, 64),
+		Price:   fmt.Sprintf("%.4f", 1.1+float64(n)*0.0001),
+	}
+	fill.ID, fill.Type = strconv.Itoa(10*n+11), "ORDER_FILL"
+	return goanda.OrderCreateResponse{OrderCreateTransaction: create, OrderFillTransaction: fill}, nil
+}
+
+func (b *broker) sent() []float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]float64(nil), b.units...)
+}
+
+// prices replays EUR_USD prices at the offsets from a start time
+func prices(t *testing.T, offsets ...time.Duration) *goanda.StreamingConnection {
+	dir := t.TempDir()
+	recorder, err := goanda.NewStreamRecorder(dir)
+	if err != nil {
+		t.Fatalf("Expected a recorder, got %v", err)
+	}
+	stream := "/accounts/test-account/pricing/stream?instruments=EUR_USD"
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	recorder.Record(stream, []byte(`{"type":"HEARTBEAT","time":"2024-01-02T14:59:00Z"}`), time.Now())
+	recorder.Record(stream, []byte(`{"type":"PRICE","time":"2024-01-02T14:59:30Z","instrument":"EUR_USD","tradeable":false}`), time.Now())
+	for _, offset := range offsets {
+		at := start.Add(offset).Format(time.RFC3339Nano)
+		recorder.Record(stream, []byte(`{"type":"PRICE","time":"`+at+`","instrument":"EUR_USD","bids":[{"price":"1.1000","liquidity":1000000}],"asks":[{"price":"1.1002","liquidity":1000000}],"tradeable":true}`), time.Now())
+	}
+	recorder.Close()
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	replayer, err := goanda.NewStreamReplayer(paths...)
+	if err != nil {
+		t.Fatalf("Expected a replayer, got %v", err)
+	}
+	return replayer.StreamingConnection()
+}
+
+func TestTWAP(t *testing.T) {
+	orders := &broker{}
+	var progress []Progress
+	twap, err := NewTWAP(orders, Options{
+		Instrument: "EUR_USD",
+		Units:      1000,
+		Horizon:    4 * time.Minute,
+		Slices:     4,
+		OnProgress: func(p Progress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the last two slices fall due in the gap before the last price
+	sc := prices(t, 0, time.Minute, 90*time.Second, 210*time.Second, 4*time.Minute)
+	if err := twap.Run(context.Background(), sc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sent := fmt.Sprint(orders.sent()); sent != "[250 250 500]" {
+		t.Errorf("Expected the slices sent at their times, got %s", sent)
+	}
+	if len(progress) != 3 || progress[0].Slice != 1 || progress[2].Slice != 4 || progress[2].Slices != 4 {
+		t.Fatalf("Expected progress after each order, got %+v", progress)
+	}
+	final := twap.Progress()
+	average := (250*1.1 + 250*1.1001 + 500*1.1002) / 1000
+	if final.Executed != 1000 || final.Remaining() != 0 || fmt.Sprintf("%.6f", final.AveragePrice) != fmt.Sprintf("%.6f", average) {
+		t.Errorf("Expected 1000 units executed at %f, got %+v", average, final)
+	}
+	if final.Response == nil || final.Response.OrderID() != "30" {
+		t.Errorf("Expected the last order's response, got %+v", final.Response)
+	}
+	if err := twap.Run(context.Background(), sc); err != nil {
+		t.Errorf("Expected a finished execution to stay finished, got %v", err)
+	}
+}
+
+func TestVWAP(t *testing.T) {
+	history := goanda.InstrumentHistory{Candles: []goanda.Candles{{Volume: 10}, {Volume: 20}}}
+	profile := VolumeProfile(history, history)
+	if fmt.Sprint(profile) != "[20 40]" {
+		t.Fatalf("Expected the volumes added up, got %v", profile)
+	}
+
+	// the first order fills for half its units, the shortfall is sent with the next
+	orders := &broker{fills: []float64{0.5}}
+	vwap, err := NewVWAP(orders, Options{Instrument: "EUR_USD", Units: -300, Horizon: 2 * time.Minute}, profile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := vwap.Run(context.Background(), prices(t, 0, time.Minute)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sent := fmt.Sprint(orders.sent()); sent != "[-100 -250]" {
+		t.Errorf("Expected the slices sized to the profile, got %s", sent)
+	}
+	if final := vwap.Progress(); final.Executed != -300 || final.Slice != 2 {
+		t.Errorf("Expected the order executed, got %+v", final)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	orders := &broker{}
+	var twap *Execution
+	twap, err := NewTWAP(orders, Options{
+		Instrument: "EUR_USD",
+		Units:      1000,
+		Horizon:    time.Minute,
+		OnProgress: func(Progress) { twap.Cancel() },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := twap.Run(context.Background(), prices(t, 0, 30*time.Second, time.Minute)); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Expected the execution to be cancelled, got %v", err)
+	}
+	if sent := orders.sent(); len(sent) != 1 || !twap.Cancelled() {
+		t.Errorf("Expected one order before the cancel, got %v", sent)
+	}
+	if err := twap.Run(context.Background(), prices(t, 0)); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Expected a cancelled execution not to run, got %v", err)
+	}
+
+	unfinished, _ := NewTWAP(orders, Options{Instrument: "EUR_USD", Units: 1000, Horizon: time.Minute})
+	if err := unfinished.Run(context.Background(), prices(t, 0)); err == nil {
+		t.Error("Expected an error for the stream ending early")
+	}
+}
+
+func TestNewExecutionErrors(t *testing.T) {
+	for name, create := range map[string]func() (*Execution, error){
+		"no instrument": func() (*Execution, error) { return NewTWAP(&broker{}, Options{Units: 1, Horizon: time.Minute}) },
+		"no units": func() (*Execution, error) {
+			return NewTWAP(&broker{}, Options{Instrument: "EUR_USD", Horizon: time.Minute})
+		},
+		"no horizon": func() (*Execution, error) { return NewTWAP(&broker{}, Options{Instrument: "EUR_USD", Units: 1}) },
+		"no volume": func() (*Execution, error) {
+			return NewVWAP(&broker{}, Options{Instrument: "EUR_USD", Units: 1, Horizon: time.Minute}, []float64{0, 0})
+		},
+		"negative volume": func() (*Execution, error) {
+			return NewVWAP(&broker{}, Options{Instrument: "EUR_USD", Units: 1, Horizon: time.Minute}, []float64{1, -1})
+		},
+	} {
+		if _, err := create(); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package goanda
+
+import (
+	"time"
+)
+
+// CloseReason is why a fill closed or reduced trades, derived from the reason of the ORDER_FILL transaction
+type CloseReason string
+
+// Close reasons
+const (
+	// CloseReasonClientRequest is a voluntary close, a market order or trade or position close requested by the client
+	CloseReasonClientRequest CloseReason = "CLIENT_REQUEST"
+	// CloseReasonOrder is an opposing limit, stop or market-if-touched order reducing the position
+	CloseReasonOrder              CloseReason = "ORDER"
+	CloseReasonTakeProfit         CloseReason = "TAKE_PROFIT_ORDER"
+	CloseReasonStopLoss           CloseReason = "STOP_LOSS_ORDER"
+	CloseReasonGuaranteedStopLoss CloseReason = "GUARANTEED_STOP_LOSS_ORDER"
+	CloseReasonTrailingStopLoss   CloseReason = "TRAILING_STOP_LOSS_ORDER"
+	CloseReasonMarginCloseout     CloseReason = "MARGIN_CLOSEOUT"
+	CloseReasonDelayedTradeClose  CloseReason = "DELAYED_TRADE_CLOSE"
+	CloseReasonUnknown            CloseReason = "UNKNOWN"
+)
+
+// CloseReasonFromFill maps the reason of an ORDER_FILL transaction to a CloseReason
+func CloseReasonFromFill(reason string) CloseReason {
+	switch reason {
+	case "MARKET_ORDER", "MARKET_ORDER_TRADE_CLOSE", "MARKET_ORDER_POSITION_CLOSEOUT":
+		return CloseReasonClientRequest
+	case "MARKET_ORDER_MARGIN_CLOSEOUT":
+		return CloseReasonMarginCloseout
+	case "MARKET_ORDER_DELAYED_TRADE_CLOSE":
+		return CloseReasonDelayedTradeClose
+	case "LIMIT_ORDER", "STOP_ORDER", "MARKET_IF_TOUCHED_ORDER", "FIXED_PRICE_ORDER",
+		"LIMIT_ORDER_REPLACEMENT", "STOP_ORDER_REPLACEMENT", "MARKET_IF_TOUCHED_ORDER_REPLACEMENT":
+		return CloseReasonOrder
+	case "TAKE_PROFIT_ORDER":
+		return CloseReasonTakeProfit
+	case "STOP_LOSS_ORDER":
+		return CloseReasonStopLoss
+	case "GUARANTEED_STOP_LOSS_ORDER":
+		return CloseReasonGuaranteedStopLoss
+	case "TRAILING_STOP_LOSS_ORDER":
+		return CloseReasonTrailingStopLoss
+	}
+	return CloseReasonUnknown
+}
+
+// StoppedOut reports whether the trades were closed by a stop loss of any kind
+func (r CloseReason) StoppedOut() bool {
+	return r == CloseReasonStopLoss || r == CloseReasonGuaranteedStopLoss || r == CloseReasonTrailingStopLoss
+}
+
+// Voluntary reports whether the client chose to close the trades
+func (r CloseReason) Voluntary() bool {
+	return r == CloseReasonClientRequest
+}
+
+// Forced reports whether OANDA closed the trades without an order from the client
+func (r CloseReason) Forced() bool {
+	return r == CloseReasonMarginCloseout || r == CloseReasonDelayedTradeClose
+}
+
+// TradeReduction is the part of a trade closed or reduced by a fill
+type TradeReduction struct {
+	TradeID                     string `json:"tradeID"`
+	ClientTradeID               string `json:"clientTradeID,omitempty"`
+	Units                       string `json:"units"`
+	Price                       string `json:"price,omitempty"`
+	RealizedPL                  string `json:"realizedPL"`
+	Financing                   string `json:"financing"`
+	GuaranteedExecutionFee      string `json:"guaranteedExecutionFee,omitempty"`
+	QuoteGuaranteedExecutionFee string `json:"quoteGuaranteedExecutionFee,omitempty"`
+	HalfSpreadCost              string `json:"halfSpreadCost,omitempty"`
+}
+
+// Fill is an order fill with the trades it opened, closed and reduced
+type Fill struct {
+	TransactionID string
+	OrderID       string
+	Instrument    string
+	Units         string
+	Price         string
+	Time          time.Time
+	// Reason is the fill transaction's reason, such as STOP_LOSS_ORDER
+	Reason string
+	// CloseReason is why any trades were closed or reduced
+	CloseReason  CloseReason
+	TradeOpened  string
+	TradesClosed []TradeReduction
+	TradeReduced *TradeReduction
+}
+
+// Closes reports whether the fill closed or reduced any trades
+func (f Fill) Closes() bool {
+	return len(f.TradesClosed) > 0 || f.TradeReduced != nil
+}
+
+// Fill returns the transaction as a Fill if it is an ORDER_FILL
+func (t TransactionDetails) Fill() (Fill, bool) {
+	if t.Type != "ORDER_FILL" {
+		return Fill{}, false
+	}
+	return Fill{
+		TransactionID: t.ID,
+		OrderID:       t.OrderID,
+		Instrument:    t.Instrument,
+		Units:         t.Units,
+		Price:         t.Price,
+		Time:          t.Time,
+		Reason:        t.Reason,
+		CloseReason:   CloseReasonFromFill(t.Reason),
+		TradeOpened:   t.TradeOpened.TradeID,
+		TradesClosed:  t.TradesClosed,
+		TradeReduced:  t.TradeReduced,
+	}, true
+}
+
+// Fill returns the order's fill, if it was filled
+func (or *OrderResponse) Fill() (Fill, bool) {
+	ft := or.OrderFillTransaction
+	if ft.ID == "" {
+		return Fill{}, false
+	}
+
+	fill := Fill{
+		TransactionID: ft.ID,
+		OrderID:       ft.OrderID,
+		Instrument:    ft.Instrument,
+		Units:         ft.Units,
+		Price:         ft.Price,
+		Time:          ft.Time,
+		Reason:        ft.Reason,
+		CloseReason:   CloseReasonFromFill(ft.Reason),
+		TradeOpened:   ft.TradeOpened.TradeID,
+	}
+	for _, tc := range ft.TradesClosed {
+		fill.TradesClosed = append(fill.TradesClosed, TradeReduction{
+			TradeID:    tc.TradeID,
+			Units:      tc.Units,
+			Price:      tc.Price,
+			RealizedPL: tc.RealizedPL,
+			Financing:  tc.Financing,
+		})
+	}
+	if ft.TradeReduced.TradeID != "" {
+		fill.TradeReduced = &TradeReduction{
+			TradeID:    ft.TradeReduced.TradeID,
+			Units:      ft.TradeReduced.Units,
+			Price:      ft.TradeReduced.Price,
+			RealizedPL: ft.TradeReduced.RealizedPL,
+			Financing:  ft.TradeReduced.Financing,
+		}
+	}
+	return fill, true
+}
+
+// Fill returns the fill of a trade close or reduction
+func (mt *ModifiedTrade) Fill() (Fill, bool) {
+	ft := mt.OrderFillTransaction
+	if ft.ID == "" {
+		return Fill{}, false
+	}
+
+	fill := Fill{
+		TransactionID: ft.ID,
+		OrderID:       ft.OrderID,
+		Instrument:    ft.Instrument,
+		Units:         ft.Units,
+		Price:         ft.Price,
+		Time:          ft.Time,
+		Reason:        ft.Reason,
+		CloseReason:   CloseReasonFromFill(ft.Reason),
+		TradeOpened:   ft.TradeOpened,
+	}
+	for _, tc := range ft.TradesClosed {
+		fill.TradesClosed = append(fill.TradesClosed, TradeReduction{
+			TradeID:    tc.TradeID,
+			Units:      tc.Units,
+			RealizedPL: tc.RealizedPL,
+			Financing:  tc.Financing,
+		})
+	}
+	if ft.TradeReduced.TradeID != "" {
+		fill.TradeReduced = &TradeReduction{
+			TradeID:    ft.TradeReduced.TradeID,
+			Units:      ft.TradeReduced.Units,
+			RealizedPL: ft.TradeReduced.RealizedPL,
+			Financing:  ft.TradeReduced.Financing,
+		}
+	}
+	return fill, true
+}
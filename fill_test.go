@@ -0,0 +1,102 @@
+package goanda
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCloseReasonFromFill(t *testing.T) {
+	defer logTestResult(t, "CloseReasonFromFill")
+
+	tests := []struct {
+		reason     string
+		expected   CloseReason
+		stoppedOut bool
+		voluntary  bool
+		forced     bool
+	}{
+		{"MARKET_ORDER_TRADE_CLOSE", CloseReasonClientRequest, false, true, false},
+		{"MARKET_ORDER_POSITION_CLOSEOUT", CloseReasonClientRequest, false, true, false},
+		{"MARKET_ORDER_MARGIN_CLOSEOUT", CloseReasonMarginCloseout, false, false, true},
+		{"STOP_LOSS_ORDER", CloseReasonStopLoss, true, false, false},
+		{"TRAILING_STOP_LOSS_ORDER", CloseReasonTrailingStopLoss, true, false, false},
+		{"TAKE_PROFIT_ORDER", CloseReasonTakeProfit, false, false, false},
+		{"LIMIT_ORDER", CloseReasonOrder, false, false, false},
+		{"SOMETHING_NEW", CloseReasonUnknown, false, false, false},
+	}
+
+	for _, tt := range tests {
+		r := CloseReasonFromFill(tt.reason)
+		if r != tt.expected {
+			t.Errorf("For %s expected %s, got %s", tt.reason, tt.expected, r)
+		}
+		if r.StoppedOut() != tt.stoppedOut || r.Voluntary() != tt.voluntary || r.Forced() != tt.forced {
+			t.Errorf("Unexpected classification of %s", r)
+		}
+	}
+}
+
+func TestTransactionFill(t *testing.T) {
+	defer logTestResult(t, "TransactionFill")
+
+	var details TransactionDetails
+	err := json.Unmarshal([]byte(`{
+		"id": "6400",
+		"type": "ORDER_FILL",
+		"orderID": "6399",
+		"instrument": "EUR_USD",
+		"units": "-1500",
+		"price": "1.09500",
+		"reason": "STOP_LOSS_ORDER",
+		"tradesClosed": [{"tradeID": "6350", "units": "-1000", "price": "1.09500", "realizedPL": "-5.0000", "financing": "0.0000"}],
+		"tradeReduced": {"tradeID": "6360", "units": "-500", "price": "1.09500", "realizedPL": "-2.5000", "financing": "-0.0100"}
+	}`), &details)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fill, ok := details.Fill()
+	if !ok {
+		t.Fatal("Expected an ORDER_FILL to be a fill")
+	}
+	if !fill.CloseReason.StoppedOut() || !fill.Closes() {
+		t.Errorf("Expected a stop out closing trades, got %+v", fill)
+	}
+	if len(fill.TradesClosed) != 1 || fill.TradesClosed[0].RealizedPL != "-5.0000" {
+		t.Errorf("Unexpected trades closed: %+v", fill.TradesClosed)
+	}
+	if fill.TradeReduced == nil || fill.TradeReduced.Units != "-500" {
+		t.Errorf("Unexpected trade reduced: %+v", fill.TradeReduced)
+	}
+
+	if _, ok := (TransactionDetails{Type: "MARKET_ORDER"}).Fill(); ok {
+		t.Error("Expected other transactions not to be fills")
+	}
+}
+
+func TestOrderResponseFill(t *testing.T) {
+	defer logTestResult(t, "OrderResponseFill")
+
+	var or OrderResponse
+	err := json.Unmarshal([]byte(`{
+		"orderFillTransaction": {
+			"id": "7", "orderID": "6", "instrument": "EUR_USD", "units": "100", "reason": "MARKET_ORDER",
+			"tradeOpened": {"tradeID": "7", "units": "100"}
+		}
+	}`), &or)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fill, ok := or.Fill()
+	if !ok {
+		t.Fatal("Expected a fill")
+	}
+	if fill.TradeOpened != "7" || fill.Closes() || !fill.CloseReason.Voluntary() {
+		t.Errorf("Unexpected fill: %+v", fill)
+	}
+
+	if _, ok := (&OrderResponse{}).Fill(); ok {
+		t.Error("Expected no fill for an unfilled order")
+	}
+}
@@ -0,0 +1,181 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultFillPollInterval is how often SubmitAndWaitForFill checks the account's transactions when
+// PollInterval is not set
+const DefaultFillPollInterval = time.Second
+
+// ErrOrderNotFilled is returned by SubmitAndWaitForFill when the order was cancelled instead of filled
+var ErrOrderNotFilled = errors.New("goanda: order was not filled")
+
+// WaitForFillOptions tunes SubmitAndWaitForFill
+type WaitForFillOptions struct {
+	// PollInterval is how often the account's transactions are fetched, it defaults to DefaultFillPollInterval
+	PollInterval time.Duration
+	// Transactions, if set, are watched for the order's fill in place of polling, such as the C of a
+	// NewTransactionBroker subscriber. They should be subscribed to before the order is submitted. If the
+	// channel is closed the transactions are polled instead.
+	Transactions <-chan TransactionStreamResponse
+}
+
+// FillResult is how an order submitted by SubmitAndWaitForFill ended
+type FillResult struct {
+	// Created is the response to submitting the order
+	Created OrderCreateResponse
+	// OrderID is the order that filled or was cancelled, a replacement of the submitted order if it was replaced
+	OrderID string
+	// Fill is the order's fill, nil if it was cancelled
+	Fill *OrderFillTransaction
+	// Cancelled is the order's cancellation, nil if it filled
+	Cancelled *OrderCancelTransaction
+
+	// Price, TradeID, Financing and Commission are from the fill. TradeID is the trade the fill opened,
+	// or else the trade it reduced or the first it closed.
+	Price      string
+	TradeID    string
+	Financing  string
+	Commission string
+}
+
+// SubmitAndWaitForFill creates an order and waits until it is filled or cancelled, as a pending order
+// is when it expires, returning the fill. A cancelled order returns an error wrapping ErrOrderNotFilled
+// with the cancellation in the result, and an order OANDA rejects returns its APIError.
+// Waiting ends with ctx.Err() when ctx is done, the order is left as it is and the result has its ID.
+func (c *Connection) SubmitAndWaitForFill(ctx context.Context, body OrderPayload, opts WaitForFillOptions) (FillResult, error) {
+	var created OrderCreateResponse
+	if err := c.createOrder(body, &created); err != nil {
+		return FillResult{}, err
+	}
+
+	result := FillResult{Created: created, OrderID: created.OrderID()}
+	if result.settle(created.OrderFillTransaction, created.OrderCancelTransaction) {
+		return result, result.err()
+	}
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultFillPollInterval
+	}
+	last := created.LastTransactionID
+	transactions := opts.Transactions
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var poll <-chan time.Time
+		if transactions == nil {
+			poll = ticker.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+
+		case message, ok := <-transactions:
+			if !ok {
+				c.logf("goanda: transactions closed while waiting for order %s to fill, polling instead", result.OrderID)
+				transactions = nil
+				continue
+			}
+			t, err := message.Decode()
+			if err != nil {
+				// heartbeats have no transaction
+				continue
+			}
+			if result.observe(t) {
+				return result, result.err()
+			}
+
+		case <-poll:
+			fetched, err := c.transactionsAfter(last)
+			if err != nil {
+				c.logf("goanda: checking whether order %s filled: %v", result.OrderID, err)
+				continue
+			}
+			for _, t := range fetched {
+				last = t.Header().ID
+				if result.observe(t) {
+					return result, result.err()
+				}
+			}
+		}
+	}
+}
+
+// transactionsAfter fetches and decodes the account's transactions after id
+func (c *Connection) transactionsAfter(id string) ([]TypedTransaction, error) {
+	var response struct {
+		Transactions []json.RawMessage `json:"transactions"`
+	}
+	if err := c.getAndUnmarshal("/accounts/"+c.accountID+"/transactions/sinceid?id="+id, &response); err != nil {
+		return nil, err
+	}
+
+	transactions := make([]TypedTransaction, 0, len(response.Transactions))
+	for _, raw := range response.Transactions {
+		t, err := DecodeTransaction(raw)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, nil
+}
+
+// observe settles the result if the transaction fills or cancels the order. A cancellation that replaced
+// the order moves the wait on to its replacement.
+func (r *FillResult) observe(t TypedTransaction) bool {
+	switch t := t.(type) {
+	case *OrderFillTransaction:
+		if t.OrderID == r.OrderID {
+			return r.settle(t, nil)
+		}
+	case *OrderCancelTransaction:
+		if t.OrderID != r.OrderID {
+			return false
+		}
+		if t.ReplacedByOrderID != "" {
+			r.OrderID = t.ReplacedByOrderID
+			return false
+		}
+		return r.settle(nil, t)
+	}
+	return false
+}
+
+// settle records the fill or cancellation, reporting whether there was either
+func (r *FillResult) settle(fill *OrderFillTransaction, cancel *OrderCancelTransaction) bool {
+	switch {
+	case fill != nil:
+		r.Fill = fill
+		r.Price = fill.Price
+		r.Financing = fill.Financing
+		r.Commission = fill.Commission
+		switch {
+		case fill.TradeOpened != nil:
+			r.TradeID = fill.TradeOpened.TradeID
+		case fill.TradeReduced != nil:
+			r.TradeID = fill.TradeReduced.TradeID
+		case len(fill.TradesClosed) > 0:
+			r.TradeID = fill.TradesClosed[0].TradeID
+		}
+		return true
+	case cancel != nil:
+		r.Cancelled = cancel
+		return true
+	}
+	return false
+}
+
+func (r FillResult) err() error {
+	if r.Cancelled == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: order %s cancelled: %s", ErrOrderNotFilled, r.OrderID, r.Cancelled.Reason)
+}
@@ -0,0 +1,126 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fillWaitServer creates a pending order 6 at transaction 6 and answers polls of the transactions after
+// it with transactions
+func fillWaitServer(t *testing.T, transactions string) *Connection {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/orders":
+			w.Write([]byte(`{"orderCreateTransaction":{"id":"6","type":"LIMIT_ORDER","instrument":"EUR_USD","units":"1000","price":"1.0850"},
+				"relatedTransactionIDs":["6"],"lastTransactionID":"6"}`))
+		case "/accounts/test-account/transactions/sinceid":
+			if id := r.URL.Query().Get("id"); id != "6" {
+				t.Errorf("Expected the transactions after 6, got after %s", id)
+			}
+			w.Write([]byte(`{"transactions":` + transactions + `,"lastTransactionID":"9"}`))
+		default:
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+}
+
+var pendingEURUSD = OrderPayload{Order: OrderBody{Type: "LIMIT", Instrument: "EUR_USD", Units: 1000, Price: "1.0850"}}
+
+func TestSubmitAndWaitForFillPolling(t *testing.T) {
+	defer logTestResult(t, "SubmitAndWaitForFillPolling")
+	c := fillWaitServer(t, `[
+		{"id":"7","type":"ORDER_FILL","orderID":"5","price":"1.0700"},
+		{"id":"8","type":"ORDER_FILL","orderID":"6","price":"1.0849","financing":"0.0000","commission":"0.1200",
+			"tradeOpened":{"tradeID":"8","units":"1000"}}]`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := c.SubmitAndWaitForFill(ctx, pendingEURUSD, WaitForFillOptions{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Price != "1.0849" || result.TradeID != "8" || result.Commission != "0.1200" || result.Financing != "0.0000" {
+		t.Errorf("Expected order 6's fill, got %+v", result)
+	}
+	if result.Fill == nil || result.Fill.ID != "8" || result.Cancelled != nil {
+		t.Errorf("Expected the fill transaction, got %+v", result.Fill)
+	}
+}
+
+func TestSubmitAndWaitForFillFollowsReplacement(t *testing.T) {
+	defer logTestResult(t, "SubmitAndWaitForFillFollowsReplacement")
+	c := fillWaitServer(t, `[
+		{"id":"7","type":"ORDER_CANCEL","orderID":"6","reason":"CLIENT_REQUEST_REPLACED","replacedByOrderID":"8"},
+		{"id":"8","type":"LIMIT_ORDER","replacesOrderID":"6"},
+		{"id":"9","type":"ORDER_CANCEL","orderID":"8","reason":"TIME_IN_FORCE_EXPIRED"}]`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := c.SubmitAndWaitForFill(ctx, pendingEURUSD, WaitForFillOptions{PollInterval: 10 * time.Millisecond})
+	if !errors.Is(err, ErrOrderNotFilled) {
+		t.Fatalf("Expected ErrOrderNotFilled, got %v", err)
+	}
+	if result.OrderID != "8" || result.Cancelled == nil || result.Cancelled.Reason != "TIME_IN_FORCE_EXPIRED" {
+		t.Errorf("Expected the replacement's expiry, got %+v", result)
+	}
+}
+
+func TestSubmitAndWaitForFillStream(t *testing.T) {
+	defer logTestResult(t, "SubmitAndWaitForFillStream")
+	c := fillWaitServer(t, `[]`)
+
+	transactions := make(chan TransactionStreamResponse, 2)
+	transactions <- TransactionStreamResponse{Type: "HEARTBEAT"}
+	transactions <- TransactionStreamResponse{Type: "ORDER_FILL", ID: "7",
+		Transaction: []byte(`{"id":"7","type":"ORDER_FILL","orderID":"6","price":"1.0850","tradeReduced":{"tradeID":"3","units":"-1000"}}`)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := c.SubmitAndWaitForFill(ctx, pendingEURUSD, WaitForFillOptions{PollInterval: time.Hour, Transactions: transactions})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.TradeID != "3" || result.Price != "1.0850" {
+		t.Errorf("Expected the streamed fill reducing trade 3, got %+v", result)
+	}
+}
+
+func TestSubmitAndWaitForFillImmediate(t *testing.T) {
+	defer logTestResult(t, "SubmitAndWaitForFillImmediate")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/orders" {
+			t.Errorf("Expected no polling, got %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"orderCreateTransaction":{"id":"6","type":"MARKET_ORDER"},
+			"orderCancelTransaction":{"id":"7","type":"ORDER_CANCEL","orderID":"6","reason":"INSUFFICIENT_LIQUIDITY"},
+			"lastTransactionID":"7"}`))
+	}))
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	result, err := c.SubmitAndWaitForFill(context.Background(), OrderPayload{Order: OrderBody{Type: "MARKET", Instrument: "EUR_USD", Units: 1000}}, WaitForFillOptions{})
+	if !errors.Is(err, ErrOrderNotFilled) || result.Cancelled == nil {
+		t.Fatalf("Expected the market order's cancellation, got %+v, %v", result, err)
+	}
+}
+
+func TestSubmitAndWaitForFillTimeout(t *testing.T) {
+	defer logTestResult(t, "SubmitAndWaitForFillTimeout")
+	c := fillWaitServer(t, `[]`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := c.SubmitAndWaitForFill(ctx, pendingEURUSD, WaitForFillOptions{PollInterval: 10 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to pass, got %v", err)
+	}
+	if result.OrderID != "6" || result.Fill != nil {
+		t.Errorf("Expected the pending order's ID, got %+v", result)
+	}
+}
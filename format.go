@@ -0,0 +1,149 @@
+package goanda
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Locale describes how numbers are written
+type Locale struct {
+	DecimalSeparator string
+	GroupSeparator   string
+	// SymbolAfter places the currency symbol after the amount, e.g. "1.234,56 €"
+	SymbolAfter bool
+}
+
+// Common locales
+var (
+	LocaleEnglish  = Locale{DecimalSeparator: ".", GroupSeparator: ","}
+	LocaleEuropean = Locale{DecimalSeparator: ",", GroupSeparator: ".", SymbolAfter: true}
+	LocaleSwiss    = Locale{DecimalSeparator: ".", GroupSeparator: "'"}
+)
+
+var currencySymbols = map[string]string{
+	"AUD": "A$",
+	"CAD": "C$",
+	"EUR": "€",
+	"GBP": "£",
+	"HKD": "HK$",
+	"JPY": "¥",
+	"NZD": "NZ$",
+	"SGD": "S$",
+	"USD": "$",
+}
+
+// currencyDecimals lists currencies that are not written with two decimal places
+var currencyDecimals = map[string]int{
+	"JPY": 0,
+	"HUF": 0,
+}
+
+// Formatter renders money in the account currency and prices at each instrument's display precision,
+// so every output surface formats values the same way
+type Formatter struct {
+	Currency    string
+	Locale      Locale
+	instruments map[string]InstrumentDetails
+}
+
+// NewFormatter creates a formatter for the given account currency and instruments
+func NewFormatter(currency string, locale Locale, instruments Instruments) *Formatter {
+	f := &Formatter{
+		Currency:    currency,
+		Locale:      locale,
+		instruments: map[string]InstrumentDetails{},
+	}
+	for _, i := range instruments {
+		f.instruments[i.Name] = i
+	}
+	return f
+}
+
+// NewFormatter creates a formatter using the account's currency and tradeable instruments
+func (c *Connection) NewFormatter(locale Locale) (*Formatter, error) {
+	summary, err := c.GetAccountSummary()
+	if err != nil {
+		return nil, err
+	}
+
+	instruments, err := c.GetAccountInstruments(c.accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFormatter(summary.Account.Currency, locale, instruments), nil
+}
+
+// FormatMoney formats an amount in the account currency, e.g. "-$1,234.56"
+func (f *Formatter) FormatMoney(amount float64) string {
+	decimals, ok := currencyDecimals[f.Currency]
+	if !ok {
+		decimals = 2
+	}
+
+	number := f.formatNumber(math.Abs(amount), decimals, true)
+	sign := ""
+	if amount < 0 && number != f.formatNumber(0, decimals, true) {
+		sign = "-"
+	}
+
+	symbol, ok := currencySymbols[f.Currency]
+	if !ok {
+		if f.Locale.SymbolAfter {
+			return sign + number + " " + f.Currency
+		}
+		return sign + f.Currency + " " + number
+	}
+	if f.Locale.SymbolAfter {
+		return sign + number + " " + symbol
+	}
+	return sign + symbol + number
+}
+
+// FormatPrice formats a price at the instrument's display precision.
+// Prices of unknown instruments are written with as many decimals as they need.
+func (f *Formatter) FormatPrice(instrument string, price float64) string {
+	details, ok := f.instruments[instrument]
+	if !ok {
+		return f.localize(strconv.FormatFloat(price, 'f', -1, 64), false)
+	}
+	return f.formatNumber(price, details.DisplayPrecision, false)
+}
+
+func (f *Formatter) formatNumber(value float64, decimals int, group bool) string {
+	return f.localize(strconv.FormatFloat(value, 'f', decimals, 64), group)
+}
+
+// localize rewrites a number formatted by strconv using the locale's separators
+func (f *Formatter) localize(number string, group bool) string {
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+
+	whole, fraction := number, ""
+	if i := strings.IndexByte(number, '.'); i != -1 {
+		whole, fraction = number[:i], number[i+1:]
+	}
+
+	if group && f.Locale.GroupSeparator != "" && len(whole) > 3 {
+		var b strings.Builder
+		lead := len(whole) % 3
+		if lead != 0 {
+			b.WriteString(whole[:lead])
+		}
+		for i := lead; i < len(whole); i += 3 {
+			if b.Len() != 0 {
+				b.WriteString(f.Locale.GroupSeparator)
+			}
+			b.WriteString(whole[i : i+3])
+		}
+		whole = b.String()
+	}
+
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + f.Locale.DecimalSeparator + fraction
+}
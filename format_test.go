@@ -0,0 +1,56 @@
+package goanda
+
+import (
+	"testing"
+)
+
+func TestFormatMoney(t *testing.T) {
+	defer logTestResult(t, "FormatMoney")
+
+	tests := []struct {
+		currency string
+		locale   Locale
+		amount   float64
+		expected string
+	}{
+		{"USD", LocaleEnglish, 1234.567, "$1,234.57"},
+		{"USD", LocaleEnglish, -0.5, "-$0.50"},
+		{"USD", LocaleEnglish, -0.001, "$0.00"},
+		{"EUR", LocaleEuropean, 1234567.8, "1.234.567,80 €"},
+		{"JPY", LocaleEnglish, 150000.4, "¥150,000"},
+		{"CHF", LocaleSwiss, 9876.5, "CHF 9'876.50"},
+		{"GBP", LocaleEnglish, 12, "£12.00"},
+	}
+
+	for _, tt := range tests {
+		f := NewFormatter(tt.currency, tt.locale, nil)
+		if got := f.FormatMoney(tt.amount); got != tt.expected {
+			t.Errorf("FormatMoney(%v) in %s: expected %q, got %q", tt.amount, tt.currency, tt.expected, got)
+		}
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	defer logTestResult(t, "FormatPrice")
+
+	instruments := Instruments{
+		{Name: "EUR_USD", DisplayPrecision: 5},
+		{Name: "USD_JPY", DisplayPrecision: 3},
+	}
+
+	f := NewFormatter("USD", LocaleEnglish, instruments)
+	if got := f.FormatPrice("EUR_USD", 1.1); got != "1.10000" {
+		t.Errorf("Expected 1.10000, got %s", got)
+	}
+	if got := f.FormatPrice("USD_JPY", 151.23456); got != "151.235" {
+		t.Errorf("Expected 151.235, got %s", got)
+	}
+	if got := f.FormatPrice("XAU_USD", 2350.25); got != "2350.25" {
+		t.Errorf("Expected unknown instruments to keep their digits, got %s", got)
+	}
+
+	f = NewFormatter("EUR", LocaleEuropean, instruments)
+	if got := f.FormatPrice("EUR_USD", 1.1); got != "1,10000" {
+		t.Errorf("Expected 1,10000, got %s", got)
+	}
+}
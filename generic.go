@@ -0,0 +1,24 @@
+package goanda
+
+// GetJSON performs a get on the api, unmarshalling the response into a T.
+// It is intended for endpoints goanda does not wrap yet, the endpoint is relative to the api root
+// (e.g. "/accounts/<id>/instruments").
+func GetJSON[T any](c *Connection, endpoint string) (T, error) {
+	var response T
+	err := c.getAndUnmarshal(endpoint, &response)
+	return response, err
+}
+
+// PostJSON marshals body, posts it to the api and unmarshals the response into a Resp
+func PostJSON[Req any, Resp any](c *Connection, endpoint string, body Req) (Resp, error) {
+	var response Resp
+	err := c.postAndUnmarshal(endpoint, body, &response)
+	return response, err
+}
+
+// PutJSON marshals body, puts it to the api and unmarshals the response into a Resp
+func PutJSON[Req any, Resp any](c *Connection, endpoint string, body Req) (Resp, error) {
+	var response Resp
+	err := c.putAndUnmarshal(endpoint, body, &response)
+	return response, err
+}
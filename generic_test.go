@@ -0,0 +1,59 @@
+package goanda
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenericHelpers(t *testing.T) {
+	defer logTestResult(t, "GenericHelpers")
+
+	type echo struct {
+		Method string `json:"method"`
+		Value  string `json:"value"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/custom" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		var received echo
+		if r.Method != http.MethodGet {
+			json.NewDecoder(r.Body).Decode(&received)
+		}
+		json.NewEncoder(w).Encode(echo{Method: r.Method, Value: received.Value})
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	got, err := GetJSON[echo](c, "/accounts/test-account/custom")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.Method != http.MethodGet {
+		t.Errorf("Expected GET, got %s", got.Method)
+	}
+
+	got, err = PostJSON[echo, echo](c, "/accounts/test-account/custom", echo{Value: "posted"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.Method != http.MethodPost || got.Value != "posted" {
+		t.Errorf("Unexpected post response: %+v", got)
+	}
+
+	got, err = PutJSON[echo, echo](c, "/accounts/test-account/custom", echo{Value: "put"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.Method != http.MethodPut || got.Value != "put" {
+		t.Errorf("Unexpected put response: %+v", got)
+	}
+}
@@ -1,9 +1,12 @@
 module github.com/rollend/goanda
 
-go 1.15
+go 1.23
 
 require (
 	github.com/davecgh/go-spew v1.1.1
 	github.com/joho/godotenv v1.5.1
-	github.com/stretchr/testify v1.9.0 // indirect
+	github.com/stretchr/testify v1.9.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require github.com/pmezard/go-difflib v1.0.0 // indirect
@@ -2,15 +2,20 @@ package goanda
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	apiUserAgent = "v20-golang/0.0.1"
 	httpTimeout  = time.Second * 5
+
+	rateLimitWait = time.Second
 )
 
 // ConnectionConfig is used to configure new connections
@@ -19,10 +24,75 @@ const (
 //	UserAgent	= v20-golang/0.0.1
 //	Timeout		= 5 seconds
 //	Live		= False
+//
+// The connection pool settings default to those of http.DefaultTransport.
+// High frequency callers should raise MaxIdleConnsPerHost (which defaults to 2)
+// so that connections to OANDA stay warm between bursts of requests.
 type ConnectionConfig struct {
 	UserAgent string
 	Timeout   time.Duration
 	Live      bool
+
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	TLSHandshakeTimeout time.Duration
+
+	// DryRun validates and logs mutating requests, returning simulated responses instead of sending them
+	DryRun bool
+	// Logger receives the library's diagnostic output, nothing is logged if it is nil
+	Logger Logger
+
+	// CassetteMode records the connection's traffic to, or replays it from, the file at CassettePath.
+	// This allows tests to run deterministically without a practice account or network.
+	CassetteMode CassetteMode
+	CassettePath string
+
+	// Cache, if set, stores the responses of slowly changing endpoints.
+	// CacheTTL decides how long each endpoint is cached for, a zero duration disables caching for it.
+	// It defaults to DefaultCacheTTL.
+	Cache    Cache
+	CacheTTL func(endpoint string) time.Duration
+
+	// AdjustGTDForClockSkew shifts the gtdTime of new orders by the measured clock skew,
+	// so that an order meant to live for an hour by the local clock lives for an hour by OANDA's
+	AdjustGTDForClockSkew bool
+
+	// OrderPrecision fits the prices and units of new orders to their instrument's precision before they
+	// are sent, using the account's instruments fetched once an hour. Orders are sent as given by default.
+	OrderPrecision OrderPrecision
+
+	// IdempotentOrders tags every new order with a generated client ID, unless it has one, so that when a
+	// submission fails without an answer from OANDA, as when it times out, the order can be looked up by
+	// it, or by the client ID of the trade it opens, before it is sent again. No order is executed twice.
+	IdempotentOrders bool
+
+	// RateLimitRetries is how many times a request rejected with 429 Too Many Requests is retried
+	// after waiting for the server's Retry-After, or RateLimitWait if none was given.
+	// No retries are made by default, the APIError is returned with its RetryAfter set.
+	RateLimitRetries int
+	// RateLimitWait is the wait used when a 429 response has no Retry-After, it defaults to one second
+	RateLimitWait time.Duration
+	// RateLimitMaxWait, if set, caps the wait for a Retry-After, so a misbehaving header cannot stall a
+	// caller indefinitely. The Retry-After is waited out in full by default.
+	RateLimitMaxWait time.Duration
+
+	// TokenProvider, if set, supplies the token for each request in place of the one given to NewConnection
+	TokenProvider TokenProvider
+	// OnCredentialsInvalid, if set, is called when OANDA first rejects the credentials with a 401
+	OnCredentialsInvalid func(error)
+
+	// CredentialStore, if set, is where NewConnectionFromEnv reads the token from when OANDA_API_KEY is unset
+	CredentialStore CredentialStore
+
+	// StateStore, if set, receives a ShutdownSnapshot of the connection's metrics and open state when it
+	// shuts down. The snapshot is logged either way.
+	StateStore StateStore
+}
+
+// Logger is the interface used for diagnostic output, it is satisfied by *log.Logger
+type Logger interface {
+	Printf(format string, v ...interface{})
 }
 
 // Connection describes a connection to the Oanda v20 API
@@ -33,6 +103,28 @@ type Connection struct {
 	authHeader string
 	userAgent  string
 	client     http.Client
+	logger     Logger
+	dryRun     bool
+	dryRunIDs  uint64
+	cache      Cache
+	cacheTTL   func(endpoint string) time.Duration
+	clock      clockSkew
+	skewGTD    bool
+	precision  OrderPrecision
+	idempotent bool
+	catalog    instrumentCatalog
+	retries    int
+	retryWait  time.Duration
+	maxWait    time.Duration
+	life       lifecycle
+	creds      credentials
+	metrics    connMetrics
+	started    time.Time
+	stateStore StateStore
+	// resets counts the calls to ResetState, for the transaction streams to forget their checkpoints
+	resets atomic.Uint64
+
+	shutdownStates shutdownStates
 }
 
 // NewConnection creates a new connection
@@ -48,6 +140,8 @@ func NewConnection(accountID string, token string, config *ConnectionConfig) (*C
 		client: http.Client{
 			Timeout: httpTimeout,
 		},
+		retryWait: rateLimitWait,
+		started:   time.Now(),
 	}
 
 	// Overwrite things if we've been given configuration for them
@@ -65,11 +159,68 @@ func NewConnection(accountID string, token string, config *ConnectionConfig) (*C
 		if config.UserAgent != "" {
 			nc.userAgent = config.UserAgent
 		}
+
+		if transport := config.transport(); transport != nil {
+			nc.client.Transport = transport
+		}
+
+		switch config.CassetteMode {
+		case CassetteRecord:
+			nc.client.Transport = NewRecordingTransport(config.CassettePath, nc.client.Transport)
+		case CassetteReplay:
+			replay, err := NewReplayTransport(config.CassettePath)
+			if err != nil {
+				return nil, err
+			}
+			nc.client.Transport = replay
+		}
+
+		nc.dryRun = config.DryRun
+		nc.logger = config.Logger
+		nc.cache = config.Cache
+		nc.cacheTTL = config.CacheTTL
+		nc.skewGTD = config.AdjustGTDForClockSkew
+		nc.precision = config.OrderPrecision
+		nc.idempotent = config.IdempotentOrders
+		nc.retries = config.RateLimitRetries
+		if config.RateLimitWait != 0 {
+			nc.retryWait = config.RateLimitWait
+		}
+		nc.maxWait = config.RateLimitMaxWait
+		nc.creds.provider = config.TokenProvider
+		nc.creds.onInvalid = config.OnCredentialsInvalid
+		nc.stateStore = config.StateStore
 	}
 
 	return nc, nc.CheckConnection()
 }
 
+// transport returns a transport with the configured pool settings,
+// or nil if none were set and the default transport should be used
+func (cc *ConnectionConfig) transport() *http.Transport {
+	if cc.MaxIdleConns == 0 &&
+		cc.MaxIdleConnsPerHost == 0 &&
+		cc.IdleConnTimeout == 0 &&
+		cc.TLSHandshakeTimeout == 0 {
+		return nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cc.MaxIdleConns != 0 {
+		transport.MaxIdleConns = cc.MaxIdleConns
+	}
+	if cc.MaxIdleConnsPerHost != 0 {
+		transport.MaxIdleConnsPerHost = cc.MaxIdleConnsPerHost
+	}
+	if cc.IdleConnTimeout != 0 {
+		transport.IdleConnTimeout = cc.IdleConnTimeout
+	}
+	if cc.TLSHandshakeTimeout != 0 {
+		transport.TLSHandshakeTimeout = cc.TLSHandshakeTimeout
+	}
+	return transport
+}
+
 // CheckConnection performs a request, returning any errors encountered
 func (c *Connection) CheckConnection() error {
 	_, err := c.Get("/accounts/" + c.accountID)
@@ -78,16 +229,29 @@ func (c *Connection) CheckConnection() error {
 
 // Get performs a generic http get on the api
 func (c *Connection) Get(endpoint string) ([]byte, error) {
+	cached, ttl, ok := c.cached(endpoint)
+	if ok {
+		return cached, nil
+	}
+
 	req, err := http.NewRequest(http.MethodGet, c.hostname+endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return c.makeRequest(endpoint, c.client, req)
+	body, err := c.makeRequest(endpoint, c.client, req)
+	if err == nil && ttl > 0 {
+		c.cache.Set(c.hostname+endpoint, body, ttl)
+	}
+	return body, err
 }
 
 // Post performs a generic http post on the api
 func (c *Connection) Post(endpoint string, data []byte) ([]byte, error) {
+	if c.dryRun {
+		return c.simulate(http.MethodPost, endpoint, data)
+	}
+
 	req, err := http.NewRequest(http.MethodPost, c.hostname+endpoint, bytes.NewBuffer(data))
 	if err != nil {
 		return nil, err
@@ -98,6 +262,10 @@ func (c *Connection) Post(endpoint string, data []byte) ([]byte, error) {
 
 // Put performs a generic http put on the api
 func (c *Connection) Put(endpoint string, data []byte) ([]byte, error) {
+	if c.dryRun {
+		return c.simulate(http.MethodPut, endpoint, data)
+	}
+
 	req, err := http.NewRequest(http.MethodPut, c.hostname+endpoint, bytes.NewBuffer(data))
 	if err != nil {
 		return nil, err
@@ -106,6 +274,12 @@ func (c *Connection) Put(endpoint string, data []byte) ([]byte, error) {
 	return c.makeRequest(endpoint, c.client, req)
 }
 
+func (c *Connection) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+	}
+}
+
 func (c *Connection) getAndUnmarshal(endpoint string, receive interface{}) error {
 	response, err := c.Get(endpoint)
 	if err != nil {
@@ -144,18 +318,90 @@ func (c *Connection) putAndUnmarshal(endpoint string, send interface{}, receive
 }
 
 func (c *Connection) makeRequest(endpoint string, client http.Client, req *http.Request) ([]byte, error) {
-	req.Header.Set("User-Agent", c.userAgent)
-	req.Header.Set("Authorization", c.authHeader)
-	req.Header.Set("Content-Type", "application/json")
+	ctx, done, err := c.life.request(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	req = req.WithContext(ctx)
 
-	res, err := client.Do(req)
+	authorization, err := c.authorization()
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Datetime-Format", "RFC3339")
 
-	if res.StatusCode >= 400 {
-		return nil, newAPIError(req, res)
+	for attempt := 0; ; attempt++ {
+		sent := time.Now()
+		c.metrics.requests.Add(1)
+		res, err := client.Do(req)
+		if err != nil {
+			c.metrics.errors.Add(1)
+			if cause := context.Cause(ctx); errors.Is(cause, ErrConnectionClosed) {
+				return nil, cause
+			}
+			return nil, err
+		}
+		c.observeDateHeader(res, sent, time.Now())
+
+		if res.StatusCode >= 400 {
+			apiErr := newAPIError(req, res)
+			c.metrics.errors.Add(1)
+			if apiErr.RateLimited() {
+				c.metrics.rateLimited.Add(1)
+			}
+			if errors.Is(apiErr, ErrCredentialsInvalid) {
+				c.rejectCredentials(authorization, apiErr)
+			}
+			if !apiErr.RateLimited() || attempt >= c.retries {
+				return nil, apiErr
+			}
+
+			req, err = c.awaitRetry(req, apiErr.RetryAfter)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		return readBody(res)
+	}
+}
+
+// awaitRetry waits out a rate limit and returns a copy of the request ready to be sent again
+func (c *Connection) awaitRetry(req *http.Request, wait time.Duration) (*http.Request, error) {
+	if wait <= 0 {
+		wait = c.retryWait
 	}
+	if c.maxWait > 0 && wait > c.maxWait {
+		wait = c.maxWait
+	}
+	c.logf("goanda: rate limited on %s %s, retrying in %v", req.Method, req.URL.Path, wait)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
+
+func readBody(res *http.Response) ([]byte, error) {
+	defer res.Body.Close()
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
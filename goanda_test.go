@@ -1,6 +1,13 @@
 package goanda
 
-import "testing"
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
 
 func logTestResult(t *testing.T, name string) {
     if t.Failed() {
@@ -8,4 +15,138 @@ func logTestResult(t *testing.T, name string) {
     } else {
         t.Logf("\n✅ Test passed: %s", name)
 	}
-}
\ No newline at end of file
+}
+
+func TestConnectionConfigTransport(t *testing.T) {
+	defer logTestResult(t, "ConnectionConfigTransport")
+
+	if (&ConnectionConfig{}).transport() != nil {
+		t.Error("Expected the default transport when no pool settings are given")
+	}
+
+	config := &ConnectionConfig{
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     2 * time.Minute,
+	}
+	transport := config.transport()
+	if transport == nil {
+		t.Fatal("Expected a transport")
+	}
+	if transport.MaxIdleConnsPerHost != 32 {
+		t.Errorf("Expected MaxIdleConnsPerHost to be 32, got %d", transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 2*time.Minute {
+		t.Errorf("Expected IdleConnTimeout to be 2m, got %v", transport.IdleConnTimeout)
+	}
+	if transport.MaxIdleConns != http.DefaultTransport.(*http.Transport).MaxIdleConns {
+		t.Errorf("Expected MaxIdleConns to keep its default, got %d", transport.MaxIdleConns)
+	}
+}
+
+func TestRateLimitRetry(t *testing.T) {
+	defer logTestResult(t, "RateLimitRetry")
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, string(body))
+		if len(requests) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"errorMessage":"Requests are being rate limited"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		client:    *server.Client(),
+		retries:   2,
+		retryWait: time.Millisecond,
+	}
+
+	body, err := c.Post("/orders", []byte(`{"order":{}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(body) != `{"ok":true}` {
+		t.Errorf("Unexpected body: %s", body)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(requests))
+	}
+	for _, r := range requests {
+		if r != `{"order":{}}` {
+			t.Errorf("Expected the body to be resent, got %q", r)
+		}
+	}
+}
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	defer logTestResult(t, "RateLimitRetryAfter")
+
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, time.Now())
+		if len(requests) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	// the server's Retry-After is waited out even when it is longer than RateLimitWait
+	c := &Connection{
+		hostname:  server.URL,
+		client:    *server.Client(),
+		retries:   1,
+		retryWait: time.Millisecond,
+	}
+	if _, err := c.Get("/accounts"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(requests) != 2 || requests[1].Sub(requests[0]) < time.Second {
+		t.Fatalf("Expected the retry a second later, got %v", requests)
+	}
+
+	// unless RateLimitMaxWait caps it
+	requests = nil
+	c.maxWait = 10 * time.Millisecond
+	if _, err := c.Get("/accounts"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(requests) != 2 || requests[1].Sub(requests[0]) >= time.Second {
+		t.Errorf("Expected the wait capped, got %v", requests)
+	}
+}
+
+func TestRateLimitWithoutRetries(t *testing.T) {
+	defer logTestResult(t, "RateLimitWithoutRetries")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname: server.URL,
+		client:   *server.Client(),
+	}
+
+	_, err := c.Get("/accounts")
+	var apiErr APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an APIError, got %v", err)
+	}
+	if !apiErr.RateLimited() {
+		t.Error("Expected the error to report a rate limit")
+	}
+	if apiErr.RetryAfter != 7*time.Second {
+		t.Errorf("Expected RetryAfter of 7s, got %v", apiErr.RetryAfter)
+	}
+}
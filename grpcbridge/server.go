@@ -0,0 +1,177 @@
+// Package grpcbridge serves goanda's price, transaction and candle streams over gRPC, so services in
+// other languages can consume OANDA data through goanda. The service is defined in streams.proto:
+//
+//	server := grpc.NewServer()
+//	grpcbridge.RegisterStreamsServer(server, grpcbridge.NewServer(sc))
+//	server.Serve(listener)
+//
+// Each RPC opens its own stream to OANDA, which ends when the client cancels the call.
+package grpcbridge
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative streams.proto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/rollend/goanda"
+)
+
+// Server implements the Streams service with a streaming connection
+type Server struct {
+	UnimplementedStreamsServer
+
+	sc *goanda.StreamingConnection
+}
+
+// NewServer creates a server streaming with the connection's account and settings
+func NewServer(sc *goanda.StreamingConnection) *Server {
+	return &Server{sc: sc}
+}
+
+// Prices streams prices for the requested instruments
+func (s *Server) Prices(req *PricesRequest, stream grpc.ServerStreamingServer[Price]) error {
+	if len(req.Instruments) == 0 {
+		return status.Error(codes.InvalidArgument, "no instruments requested")
+	}
+	for price, err := range s.sc.Prices(stream.Context(), req.Instruments) {
+		if err != nil {
+			return streamError(stream.Context(), err)
+		}
+		if err := stream.Send(toPrice(price)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transactions streams the account's transactions, first sending those after the requested ID if there is one
+func (s *Server) Transactions(req *TransactionsRequest, stream grpc.ServerStreamingServer[Transaction]) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	var sendErr error
+	err := s.sc.StreamTransactionsSince(ctx, req.LastTransactionId, func(tx goanda.TransactionStreamResponse) {
+		if sendErr != nil {
+			return
+		}
+		if sendErr = stream.Send(toTransaction(tx)); sendErr != nil {
+			cancel()
+		}
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	return streamError(stream.Context(), err)
+}
+
+// Candles streams candles for the requested instrument, see goanda.StreamingConnection.StreamCandles
+func (s *Server) Candles(req *CandlesRequest, stream grpc.ServerStreamingServer[CandleUpdate]) error {
+	if req.Instrument == "" {
+		return status.Error(codes.InvalidArgument, "no instrument requested")
+	}
+	if _, err := goanda.ParseGranularity(req.Granularity); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	for response, err := range s.sc.Candles(stream.Context(), req.Instrument, req.Granularity) {
+		if err != nil {
+			return streamError(stream.Context(), err)
+		}
+		for _, candle := range response.Candles {
+			if err := stream.Send(toCandleUpdate(response, candle)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// streamError turns the error that ended a stream into a gRPC status
+func streamError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if errors.Is(err, goanda.ErrCredentialsInvalid) {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	var apiErr goanda.APIError
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		code := codes.Unavailable
+		switch apiErr.Response.StatusCode {
+		case http.StatusBadRequest:
+			code = codes.InvalidArgument
+		case http.StatusUnauthorized:
+			code = codes.Unauthenticated
+		case http.StatusForbidden:
+			code = codes.PermissionDenied
+		case http.StatusNotFound:
+			code = codes.NotFound
+		case http.StatusTooManyRequests:
+			code = codes.ResourceExhausted
+		}
+		return status.Error(code, err.Error())
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
+
+func toPrice(p goanda.PricingStreamResponse) *Price {
+	return &Price{
+		Time:        p.Time,
+		Instrument:  p.Instrument,
+		Bids:        toBuckets(p.Bids),
+		Asks:        toBuckets(p.Asks),
+		CloseoutBid: p.CloseoutBid,
+		CloseoutAsk: p.CloseoutAsk,
+		Tradeable:   p.Tradeable,
+	}
+}
+
+func toBuckets(buckets []goanda.RawPriceBucket) []*PriceBucket {
+	converted := make([]*PriceBucket, len(buckets))
+	for i, b := range buckets {
+		converted[i] = &PriceBucket{Price: b.Price, Liquidity: int64(b.Liquidity)}
+	}
+	return converted
+}
+
+func toTransaction(tx goanda.TransactionStreamResponse) *Transaction {
+	id := tx.ID
+	if id == "" {
+		id = tx.TransactionID
+	}
+	return &Transaction{
+		Type:          tx.Type,
+		Time:          tx.Time,
+		TransactionId: id,
+		AccountId:     tx.AccountID,
+		BatchId:       tx.BatchID,
+		RequestId:     tx.RequestID,
+		Json:          string(tx.Transaction),
+	}
+}
+
+func toCandleUpdate(response goanda.CandlestickStreamResponse, candle goanda.StreamCandle) *CandleUpdate {
+	return &CandleUpdate{
+		Instrument:  response.Instrument,
+		Granularity: response.Granularity,
+		Time:        candle.Time,
+		Bid:         toOHLC(candle.Bid),
+		Ask:         toOHLC(candle.Ask),
+		Mid:         toOHLC(candle.Mid),
+		Volume:      int64(candle.Volume),
+		Complete:    candle.Complete,
+	}
+}
+
+func toOHLC(c goanda.Candle) *OHLC {
+	return &OHLC{Open: c.Open, High: c.High, Low: c.Low, Close: c.Close}
+}
@@ -0,0 +1,166 @@
+package grpcbridge
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/rollend/goanda"
+)
+
+// replay returns a streaming connection replaying the recorded lines of each stream
+func replay(t *testing.T, streams map[string][]string) *goanda.StreamingConnection {
+	t.Helper()
+	dir := t.TempDir()
+	recorder, err := goanda.NewStreamRecorder(dir)
+	if err != nil {
+		t.Fatalf("Expected a recorder, got %v", err)
+	}
+	received := time.Now()
+	for stream, lines := range streams {
+		for _, line := range lines {
+			recorder.Record(stream, []byte(line), received)
+		}
+	}
+	recorder.Close()
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	replayer, err := goanda.NewStreamReplayer(paths...)
+	if err != nil {
+		t.Fatalf("Expected a replayer, got %v", err)
+	}
+	return replayer.StreamingConnection()
+}
+
+// dial serves the bridge in memory and returns a client for it
+func dial(t *testing.T, sc *goanda.StreamingConnection) StreamsClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	RegisterStreamsServer(server, NewServer(sc))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Expected a client, got %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewStreamsClient(conn)
+}
+
+func TestPrices(t *testing.T) {
+	sc := replay(t, map[string][]string{
+		"/accounts/test-account/pricing/stream?instruments=EUR_USD": {
+			`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD","bids":[{"price":"1.1000","liquidity":1000000}],"asks":[{"price":"1.1002","liquidity":1000000}],"tradeable":true}`,
+			`{"type":"HEARTBEAT","time":"2024-01-02T15:04:06Z"}`,
+			`{"type":"PRICE","time":"2024-01-02T15:04:07Z","instrument":"EUR_USD","bids":[{"price":"1.1001","liquidity":1000000}],"asks":[{"price":"1.1003","liquidity":1000000}],"tradeable":true}`,
+		},
+	})
+	client := dial(t, sc)
+
+	stream, err := client.Prices(context.Background(), &PricesRequest{Instruments: []string{"EUR_USD"}})
+	if err != nil {
+		t.Fatalf("Expected a stream, got %v", err)
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Expected a price, got %v", err)
+	}
+	if first.Instrument != "EUR_USD" || first.Bids[0].Price != "1.1000" || first.Asks[0].Liquidity != 1000000 || !first.Tradeable {
+		t.Errorf("Expected the first price, got %v", first)
+	}
+	if second, err := stream.Recv(); err != nil || second.Bids[0].Price != "1.1001" {
+		t.Errorf("Expected the second price without the heartbeat, got %v %v", second, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected the stream to end with the recording, got %v", err)
+	}
+
+	stream, _ = client.Prices(context.Background(), &PricesRequest{})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without instruments, got %v", err)
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	sc := replay(t, map[string][]string{
+		"/accounts/test-account/transactions/stream": {
+			`{"type":"ORDER_FILL","time":"2024-01-02T15:04:05Z","id":"6","accountID":"test-account","batchID":"5"}`,
+		},
+	})
+	client := dial(t, sc)
+
+	stream, err := client.Transactions(context.Background(), &TransactionsRequest{})
+	if err != nil {
+		t.Fatalf("Expected a stream, got %v", err)
+	}
+	tx, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Expected a transaction, got %v", err)
+	}
+	if tx.Type != "ORDER_FILL" || tx.AccountId != "test-account" || tx.BatchId != "5" {
+		t.Errorf("Expected the fill, got %v", tx)
+	}
+
+	// There is no recording of another account's stream, so OANDA's 404 is NotFound
+	missing := dial(t, sc.ForAccount("other-account"))
+	other, err := missing.Transactions(context.Background(), &TransactionsRequest{})
+	if err != nil {
+		t.Fatalf("Expected a stream, got %v", err)
+	}
+	if _, err := other.Recv(); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestCandles(t *testing.T) {
+	sc := replay(t, map[string][]string{
+		"/accounts/test-account/pricing/stream?instruments=EUR_USD": {
+			`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD","bids":[{"price":"1.1000","liquidity":1000000}],"asks":[{"price":"1.1002","liquidity":1000000}]}`,
+			`{"type":"PRICE","time":"2024-01-02T15:05:05Z","instrument":"EUR_USD","bids":[{"price":"1.1010","liquidity":1000000}],"asks":[{"price":"1.1012","liquidity":1000000}]}`,
+		},
+	})
+	client := dial(t, sc)
+
+	stream, err := client.Candles(context.Background(), &CandlesRequest{Instrument: "EUR_USD", Granularity: "M1"})
+	if err != nil {
+		t.Fatalf("Expected a stream, got %v", err)
+	}
+	var updates []*CandleUpdate
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected candles, got %v", err)
+		}
+		updates = append(updates, update)
+	}
+	var complete *CandleUpdate
+	for _, update := range updates {
+		if update.Complete {
+			complete = update
+		}
+	}
+	if complete == nil || complete.Time != "2024-01-02T15:04:00Z" || complete.Bid.Open != 1.1 || complete.Granularity != "M1" {
+		t.Errorf("Expected the first minute's candle to complete, got %v", updates)
+	}
+
+	stream, _ = client.Candles(context.Background(), &CandlesRequest{Instrument: "EUR_USD", Granularity: "M7"})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown granularity, got %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: streams.proto
+
+package grpcbridge
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Streams_Prices_FullMethodName       = "/goanda.v1.Streams/Prices"
+	Streams_Transactions_FullMethodName = "/goanda.v1.Streams/Transactions"
+	Streams_Candles_FullMethodName      = "/goanda.v1.Streams/Candles"
+)
+
+// StreamsClient is the client API for Streams service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Streams exposes goanda's price, transaction and candle streams as server-streaming RPCs
+type StreamsClient interface {
+	// Prices streams prices for the instruments
+	Prices(ctx context.Context, in *PricesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Price], error)
+	// Transactions streams the account's transactions
+	Transactions(ctx context.Context, in *TransactionsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Transaction], error)
+	// Candles streams candles built from an instrument's prices
+	Candles(ctx context.Context, in *CandlesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CandleUpdate], error)
+}
+
+type streamsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStreamsClient(cc grpc.ClientConnInterface) StreamsClient {
+	return &streamsClient{cc}
+}
+
+func (c *streamsClient) Prices(ctx context.Context, in *PricesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Price], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Streams_ServiceDesc.Streams[0], Streams_Prices_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PricesRequest, Price]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Streams_PricesClient = grpc.ServerStreamingClient[Price]
+
+func (c *streamsClient) Transactions(ctx context.Context, in *TransactionsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Transaction], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Streams_ServiceDesc.Streams[1], Streams_Transactions_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TransactionsRequest, Transaction]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Streams_TransactionsClient = grpc.ServerStreamingClient[Transaction]
+
+func (c *streamsClient) Candles(ctx context.Context, in *CandlesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CandleUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Streams_ServiceDesc.Streams[2], Streams_Candles_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CandlesRequest, CandleUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Streams_CandlesClient = grpc.ServerStreamingClient[CandleUpdate]
+
+// StreamsServer is the server API for Streams service.
+// All implementations must embed UnimplementedStreamsServer
+// for forward compatibility.
+//
+// Streams exposes goanda's price, transaction and candle streams as server-streaming RPCs
+type StreamsServer interface {
+	// Prices streams prices for the instruments
+	Prices(*PricesRequest, grpc.ServerStreamingServer[Price]) error
+	// Transactions streams the account's transactions
+	Transactions(*TransactionsRequest, grpc.ServerStreamingServer[Transaction]) error
+	// Candles streams candles built from an instrument's prices
+	Candles(*CandlesRequest, grpc.ServerStreamingServer[CandleUpdate]) error
+	mustEmbedUnimplementedStreamsServer()
+}
+
+// UnimplementedStreamsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedStreamsServer struct{}
+
+func (UnimplementedStreamsServer) Prices(*PricesRequest, grpc.ServerStreamingServer[Price]) error {
+	return status.Error(codes.Unimplemented, "method Prices not implemented")
+}
+func (UnimplementedStreamsServer) Transactions(*TransactionsRequest, grpc.ServerStreamingServer[Transaction]) error {
+	return status.Error(codes.Unimplemented, "method Transactions not implemented")
+}
+func (UnimplementedStreamsServer) Candles(*CandlesRequest, grpc.ServerStreamingServer[CandleUpdate]) error {
+	return status.Error(codes.Unimplemented, "method Candles not implemented")
+}
+func (UnimplementedStreamsServer) mustEmbedUnimplementedStreamsServer() {}
+func (UnimplementedStreamsServer) testEmbeddedByValue()                 {}
+
+// UnsafeStreamsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StreamsServer will
+// result in compilation errors.
+type UnsafeStreamsServer interface {
+	mustEmbedUnimplementedStreamsServer()
+}
+
+func RegisterStreamsServer(s grpc.ServiceRegistrar, srv StreamsServer) {
+	// If the following call panics, it indicates UnimplementedStreamsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Streams_ServiceDesc, srv)
+}
+
+func _Streams_Prices_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PricesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StreamsServer).Prices(m, &grpc.GenericServerStream[PricesRequest, Price]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Streams_PricesServer = grpc.ServerStreamingServer[Price]
+
+func _Streams_Transactions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TransactionsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StreamsServer).Transactions(m, &grpc.GenericServerStream[TransactionsRequest, Transaction]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Streams_TransactionsServer = grpc.ServerStreamingServer[Transaction]
+
+func _Streams_Candles_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CandlesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StreamsServer).Candles(m, &grpc.GenericServerStream[CandlesRequest, CandleUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Streams_CandlesServer = grpc.ServerStreamingServer[CandleUpdate]
+
+// Streams_ServiceDesc is the grpc.ServiceDesc for Streams service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Streams_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "goanda.v1.Streams",
+	HandlerType: (*StreamsServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Prices",
+			Handler:       _Streams_Prices_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Transactions",
+			Handler:       _Streams_Transactions_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Candles",
+			Handler:       _Streams_Candles_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "streams.proto",
+}
@@ -0,0 +1,74 @@
+package goanda
+
+import "context"
+
+// StreamHandle is a stream running in the background, started by StartPrices and the like.
+// It mirrors a context: Done is closed when the stream ends and Err then says why.
+type StreamHandle struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// StartStream runs a blocking stream, such as a call to StreamRaw, in the background
+func StartStream(ctx context.Context, stream func(ctx context.Context) error) *StreamHandle {
+	ctx, cancel := context.WithCancel(ctx)
+	h := &StreamHandle{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(h.done)
+		defer cancel()
+		h.err = stream(ctx)
+	}()
+	return h
+}
+
+// StartPrices runs StreamPrices in the background
+func (sc *StreamingConnection) StartPrices(ctx context.Context, instruments []string, callback func(PricingStreamResponse)) *StreamHandle {
+	return StartStream(ctx, func(ctx context.Context) error {
+		return sc.StreamPrices(ctx, instruments, callback)
+	})
+}
+
+// StartTransactions runs StreamTransactions in the background
+func (sc *StreamingConnection) StartTransactions(ctx context.Context, callback func(TransactionStreamResponse)) *StreamHandle {
+	return StartStream(ctx, func(ctx context.Context) error {
+		return sc.StreamTransactions(ctx, callback)
+	})
+}
+
+// StartAccountChanges runs StreamAccountChanges in the background
+func (sc *StreamingConnection) StartAccountChanges(ctx context.Context, callback func(AccountChangesStreamResponse)) *StreamHandle {
+	return StartStream(ctx, func(ctx context.Context) error {
+		return sc.StreamAccountChanges(ctx, callback)
+	})
+}
+
+// StartCandles runs StreamCandles in the background
+func (sc *StreamingConnection) StartCandles(ctx context.Context, instrument string, granularity string, callback func(CandlestickStreamResponse)) *StreamHandle {
+	return StartStream(ctx, func(ctx context.Context) error {
+		return sc.StreamCandles(ctx, instrument, granularity, callback)
+	})
+}
+
+// Stop ends the stream and waits for it to finish, including a callback in progress.
+// Calling it again, or after the stream has ended, does nothing.
+func (h *StreamHandle) Stop() {
+	h.cancel()
+	<-h.done
+}
+
+// Done returns a channel that is closed when the stream ends
+func (h *StreamHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Err returns nil while the stream is running. Once Done is closed it returns why the stream ended:
+// nil if it ended cleanly, context.Canceled if it was stopped, or the error that ended it.
+func (h *StreamHandle) Err() error {
+	select {
+	case <-h.done:
+		return h.err
+	default:
+		return nil
+	}
+}
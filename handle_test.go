@@ -0,0 +1,58 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamHandle(t *testing.T) {
+	defer logTestResult(t, "StreamHandle")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD"}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	received := make(chan struct{}, 1)
+	h := sc.StartPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) { received <- struct{}{} })
+	<-received
+	if h.Err() != nil {
+		t.Errorf("Expected no error while running, got %v", h.Err())
+	}
+	select {
+	case <-h.Done():
+		t.Fatal("Expected the stream to be running")
+	default:
+	}
+
+	h.Stop()
+	<-h.Done()
+	if !errors.Is(h.Err(), context.Canceled) {
+		t.Errorf("Expected context.Canceled after Stop, got %v", h.Err())
+	}
+	h.Stop()
+
+	failure := errors.New("failed")
+	h = StartStream(context.Background(), func(context.Context) error { return failure })
+	select {
+	case <-h.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the stream to end")
+	}
+	if !errors.Is(h.Err(), failure) {
+		t.Errorf("Expected the stream's error, got %v", h.Err())
+	}
+}
@@ -0,0 +1,189 @@
+package goanda
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// idempotentAttempts is the number of times CreateOrderIdempotent, or an order created with
+// IdempotentOrders, will post an order whose outcome could not be determined before giving up
+const idempotentAttempts = 3
+
+// NewClientID returns a random identifier suitable for use as an order's client extension ID
+func NewClientID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("goanda: unable to read random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// CreateOrderIdempotent creates an order, guaranteeing it is executed at most once.
+// The order is tagged with a client ID (one is generated if ClientExtensions.ID is empty),
+// and when a submission fails without a response from OANDA (a timeout, a dropped connection)
+// the order is looked up by that client ID before it is ever resubmitted, as it is when OANDA refuses
+// the order for its client ID being taken. If the lookup finds the order, the response is
+// reconstructed from the order and its transactions.
+func (c *Connection) CreateOrderIdempotent(body OrderPayload) (OrderResponse, error) {
+	clientID := tagOrder(&body.Order)
+	if err := c.prepareOrder(&body.Order); err != nil {
+		return OrderResponse{}, err
+	}
+
+	var or OrderResponse
+	err := c.submitIdempotent(clientID, func() error {
+		or = OrderResponse{}
+		return c.postOrder(body, &or)
+	}, func() (bool, error) {
+		or = OrderResponse{}
+		return c.recoverOrder(body.Order, &or)
+	})
+	if err != nil {
+		return OrderResponse{}, err
+	}
+	return or, nil
+}
+
+// tagOrder gives the order a generated client ID unless it has one, returning its client ID.
+// The caller's client extensions are copied rather than changed.
+func tagOrder(order *OrderBody) string {
+	extensions := OrderExtensions{}
+	if order.ClientExtensions != nil {
+		extensions = *order.ClientExtensions
+	}
+	if extensions.ID == "" {
+		extensions.ID = NewClientID()
+	}
+	order.ClientExtensions = &extensions
+	return extensions.ID
+}
+
+// submitIdempotent posts the order with the client ID until OANDA answers, and when a post fails
+// without an answer calls lookup to find out whether the order was created before posting it again.
+// An order refused for its client ID being taken, by an earlier post of it, is looked up too.
+func (c *Connection) submitIdempotent(clientID string, post func() error, lookup func() (bool, error)) error {
+	var lastErr error
+	for attempt := 0; attempt < idempotentAttempts; attempt++ {
+		err := post()
+		if err == nil {
+			return nil
+		}
+
+		var rejected OrderRejectedError
+		if errors.As(err, &rejected) && rejected.RejectReason == RejectClientOrderIDAlreadyExists {
+			found, lookupErr := lookup()
+			if lookupErr != nil {
+				return fmt.Errorf("order %s already exists: looking it up failed: %w", clientID, lookupErr)
+			}
+			if !found {
+				return err
+			}
+			return nil
+		}
+		var apiErr APIError
+		if errors.As(err, &apiErr) {
+			// OANDA answered, so the outcome is known
+			return err
+		}
+		lastErr = err
+
+		found, err := lookup()
+		if err != nil {
+			return fmt.Errorf("order %s outcome unknown after %v: reconciliation failed: %w", clientID, lastErr, err)
+		}
+		if found {
+			return nil
+		}
+		c.logf("goanda: order %s was not created after %v, sending it again", clientID, lastErr)
+	}
+
+	return lastErr
+}
+
+// recoverOrder looks for an order whose submission went unanswered by its client ID, or else by the
+// client ID of the trade it opened, decoding the response OANDA would have sent from the order's
+// transactions into receive
+func (c *Connection) recoverOrder(order OrderBody, receive interface{}) (bool, error) {
+	found, err := c.GetOrderByClientID(order.ClientExtensions.ID)
+	info := found.Order
+	if notFound(err) && order.TradeClientExtensions != nil && order.TradeClientExtensions.ID != "" {
+		info, err = c.orderOfTrade(clientSpecifier(order.TradeClientExtensions.ID))
+	}
+	if notFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	response := map[string]interface{}{}
+	related := []string{}
+	last := info.ID
+	for _, t := range []struct {
+		field string
+		id    string
+	}{
+		{"orderCreateTransaction", info.ID},
+		{"orderFillTransaction", info.FillingTransactionID},
+		{"orderCancelTransaction", info.CancellingTransactionID},
+	} {
+		if t.id == "" {
+			continue
+		}
+		raw, lastID, err := c.rawTransaction(t.id)
+		if err != nil {
+			return true, err
+		}
+		response[t.field] = raw
+		related = append(related, t.id)
+		last = lastID
+	}
+	response["relatedTransactionIDs"] = related
+	response["lastTransactionID"] = last
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return true, err
+	}
+	return true, json.Unmarshal(data, receive)
+}
+
+// orderOfTrade returns the order whose fill opened the trade, a trade's ID being that of its fill
+func (c *Connection) orderOfTrade(tradeSpecifier string) (OrderInfo, error) {
+	trade, err := c.GetTrade(tradeSpecifier)
+	if err != nil {
+		return OrderInfo{}, err
+	}
+	raw, _, err := c.rawTransaction(trade.Trade.ID)
+	if err != nil {
+		return OrderInfo{}, err
+	}
+	var fill struct {
+		OrderID string `json:"orderID"`
+	}
+	if err := json.Unmarshal(raw, &fill); err != nil {
+		return OrderInfo{}, err
+	}
+	order, err := c.GetOrder(fill.OrderID)
+	return order.Order, err
+}
+
+// rawTransaction fetches a transaction undecoded, with the account's last transaction ID
+func (c *Connection) rawTransaction(id string) (json.RawMessage, string, error) {
+	var response struct {
+		Transaction       json.RawMessage `json:"transaction"`
+		LastTransactionID string          `json:"lastTransactionID"`
+	}
+	err := c.getAndUnmarshal("/accounts/"+c.accountID+"/transactions/"+id, &response)
+	return response.Transaction, response.LastTransactionID, err
+}
+
+// notFound reports whether err is OANDA answering 404 Not Found
+func notFound(err error) bool {
+	var apiErr APIError
+	return errors.As(err, &apiErr) && apiErr.Response != nil && apiErr.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,284 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCreateOrderIdempotentReconciles(t *testing.T) {
+	defer logTestResult(t, "CreateOrderIdempotentReconciles")
+
+	var posts int32
+	server, _ := idempotentBroker(t, &posts, true, false)
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    http.Client{Timeout: 50 * time.Millisecond},
+	}
+
+	response, err := c.CreateOrderIdempotent(OrderPayload{Order: OrderBody{
+		Type:        "MARKET",
+		Instrument:  "EUR_USD",
+		Units:       100,
+		TimeInForce: "FOK",
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&posts); n != 1 {
+		t.Errorf("Expected the order to be posted once, got %d", n)
+	}
+	if response.OrderCreateTransaction.ID != "42" || response.OrderCreateTransaction.TimeInForce != "FOK" {
+		t.Errorf("Expected reconciled order 42, got %+v", response.OrderCreateTransaction)
+	}
+	if response.OrderFillTransaction.Price != "1.1000" {
+		t.Errorf("Expected fill price 1.1000, got %s", response.OrderFillTransaction.Price)
+	}
+	if response.OrderFillTransaction.TradeOpened.TradeID != "43" {
+		t.Errorf("Expected opened trade 43, got %s", response.OrderFillTransaction.TradeOpened.TradeID)
+	}
+	if response.GetOrderState() != "FILLED" {
+		t.Errorf("Expected state FILLED, got %s", response.GetOrderState())
+	}
+}
+
+func TestCreateOrderIdempotentClientIDTaken(t *testing.T) {
+	defer logTestResult(t, "CreateOrderIdempotentClientIDTaken")
+
+	// The order was created by an earlier post, which OANDA refuses to create again
+	var posts int32
+	server, _ := idempotentBroker(t, &posts, true, true)
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.CreateOrderIdempotent(OrderPayload{Order: OrderBody{
+		Type:             "MARKET",
+		Instrument:       "EUR_USD",
+		Units:            100,
+		ClientExtensions: &OrderExtensions{ID: "my-order"},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&posts); n != 1 || response.OrderCreateTransaction.ID != "42" || response.GetOrderState() != "FILLED" {
+		t.Errorf("Expected the existing order after one post, got %d posts and %+v", n, response)
+	}
+
+	// A client ID taken by an order that can't be found is the refusal
+	server, _ = idempotentBroker(t, &posts, false, true)
+	defer server.Close()
+	c = &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	var rejected OrderRejectedError
+	_, err = c.CreateOrderIdempotent(OrderPayload{Order: OrderBody{Type: "MARKET", Instrument: "EUR_USD", Units: 100}})
+	if !errors.As(err, &rejected) || rejected.RejectReason != RejectClientOrderIDAlreadyExists {
+		t.Errorf("Expected the rejection, got %v", err)
+	}
+}
+
+func TestCreateOrderIdempotentRetriesUnknownOrder(t *testing.T) {
+	defer logTestResult(t, "CreateOrderIdempotentRetriesUnknownOrder")
+
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			if atomic.AddInt32(&posts, 1) == 1 {
+				// The first submission never reaches the order book
+				time.Sleep(200 * time.Millisecond)
+				return
+			}
+			response := OrderResponse{LastTransactionID: "50"}
+			response.OrderCreateTransaction.ID = "50"
+			json.NewEncoder(w).Encode(response)
+		case http.MethodGet:
+			http.Error(w, `{"errorMessage":"Order not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    http.Client{Timeout: 50 * time.Millisecond},
+	}
+
+	extensions := &OrderExtensions{ID: "my-order"}
+	response, err := c.CreateOrderIdempotent(OrderPayload{Order: OrderBody{
+		Type:             "MARKET",
+		Instrument:       "EUR_USD",
+		Units:            100,
+		TimeInForce:      "FOK",
+		ClientExtensions: extensions,
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&posts); n != 2 {
+		t.Errorf("Expected the order to be posted twice, got %d", n)
+	}
+	if response.OrderCreateTransaction.ID != "50" {
+		t.Errorf("Expected order ID 50, got %s", response.OrderCreateTransaction.ID)
+	}
+}
+
+func TestCreateOrderIdempotentDoesNotRetryAPIErrors(t *testing.T) {
+	defer logTestResult(t, "CreateOrderIdempotentDoesNotRetryAPIErrors")
+
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&posts, 1)
+		http.Error(w, `{"errorMessage":"Insufficient margin"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	_, err := c.CreateOrderIdempotent(OrderPayload{Order: OrderBody{Type: "MARKET", Instrument: "EUR_USD", Units: 100}})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if n := atomic.LoadInt32(&posts); n != 1 {
+		t.Errorf("Expected a single submission, got %d", n)
+	}
+}
+
+// idempotentBroker serves an account whose order 42 filled with transaction 43, opening trade 43 with
+// the client ID my-trade, where the order's post never gets an answer in time, or with taken is
+// refused for its client ID being taken. It returns the client ID the order was posted with.
+func idempotentBroker(t *testing.T, posts *int32, orderFound bool, taken bool) (*httptest.Server, func() string) {
+	var mu sync.Mutex
+	var posted string
+	clientID := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return posted
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order := `{"order":{"id":"42","type":"MARKET","instrument":"EUR_USD","units":"100","state":"FILLED",
+			"fillingTransactionID":"43"}}`
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/accounts/test-account/orders":
+			atomic.AddInt32(posts, 1)
+			var payload OrderPayload
+			json.NewDecoder(r.Body).Decode(&payload)
+			if payload.Order.ClientExtensions != nil {
+				mu.Lock()
+				posted = payload.Order.ClientExtensions.ID
+				mu.Unlock()
+			}
+			if taken {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"orderRejectTransaction":{"id":"44","type":"MARKET_ORDER_REJECT","rejectReason":"CLIENT_ORDER_ID_ALREADY_EXISTS"},
+					"lastTransactionID":"44","errorCode":"CLIENT_ORDER_ID_ALREADY_EXISTS"}`))
+				return
+			}
+			time.Sleep(200 * time.Millisecond)
+		case r.URL.Path == "/accounts/test-account/orders/@"+clientID() && orderFound:
+			w.Write([]byte(order))
+		case strings.HasPrefix(r.URL.Path, "/accounts/test-account/orders/@"):
+			http.Error(w, `{"errorMessage":"Order not found"}`, http.StatusNotFound)
+		case r.URL.Path == "/accounts/test-account/orders/42":
+			w.Write([]byte(order))
+		case r.URL.Path == "/accounts/test-account/trades/@my-trade":
+			w.Write([]byte(`{"trade":{"id":"43","instrument":"EUR_USD","currentUnits":"100"},"lastTransactionID":"43"}`))
+		case r.URL.Path == "/accounts/test-account/transactions/42":
+			w.Write([]byte(`{"transaction":{"id":"42","type":"MARKET_ORDER","instrument":"EUR_USD","units":"100",
+				"timeInForce":"FOK"},"lastTransactionID":"43"}`))
+		case r.URL.Path == "/accounts/test-account/transactions/43":
+			w.Write([]byte(`{"transaction":{"id":"43","type":"ORDER_FILL","orderID":"42","units":"100","price":"1.1000",
+				"tradeOpened":{"tradeID":"43","units":"100"}},"lastTransactionID":"43"}`))
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	})), clientID
+}
+
+func TestIdempotentOrders(t *testing.T) {
+	defer logTestResult(t, "IdempotentOrders")
+
+	var posts int32
+	server, clientID := idempotentBroker(t, &posts, true, false)
+	defer server.Close()
+
+	c := &Connection{
+		hostname:   server.URL,
+		accountID:  "test-account",
+		client:     http.Client{Timeout: 50 * time.Millisecond},
+		idempotent: true,
+	}
+	response, err := c.CreateMarketOrder("EUR_USD", 100, OrderOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&posts); n != 1 {
+		t.Errorf("Expected the order to be posted once, got %d", n)
+	}
+	if id := clientID(); len(id) != 32 {
+		t.Errorf("Expected the order to be given a client ID, got %q", id)
+	}
+	if _, ok := response.OrderCreateTransaction.(*MarketOrderTransaction); !ok || response.OrderID() != "42" {
+		t.Errorf("Expected the recovered order's creation, got %#v", response.OrderCreateTransaction)
+	}
+	if !response.Filled() || response.OrderFillTransaction.Price != "1.1000" || response.TradeIDs()[0] != "43" {
+		t.Errorf("Expected the recovered order's fill, got %+v", response.OrderFillTransaction)
+	}
+	if len(response.RelatedTransactionIDs) != 2 || response.LastTransactionID != "43" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
+func TestIdempotentOrdersFindTheTrade(t *testing.T) {
+	defer logTestResult(t, "IdempotentOrdersFindTheTrade")
+
+	var posts int32
+	server, clientID := idempotentBroker(t, &posts, false, false)
+	defer server.Close()
+
+	c := &Connection{
+		hostname:   server.URL,
+		accountID:  "test-account",
+		client:     http.Client{Timeout: 50 * time.Millisecond},
+		idempotent: true,
+	}
+	response, err := c.CreateMarketOrder("EUR_USD", 100, OrderOptions{
+		ClientExtensions:      &OrderExtensions{ID: "my-order"},
+		TradeClientExtensions: &OrderExtensions{ID: "my-trade"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&posts); n != 1 || clientID() != "my-order" {
+		t.Errorf("Expected the order to be posted once with its own client ID, got %d posts of %q", n, clientID())
+	}
+	if response.OrderID() != "42" || !response.Filled() {
+		t.Errorf("Expected the order that opened the trade, got %+v", response)
+	}
+}
+
+func TestNewClientID(t *testing.T) {
+	defer logTestResult(t, "NewClientID")
+
+	a, b := NewClientID(), NewClientID()
+	if len(a) != 32 {
+		t.Errorf("Expected a 32 character ID, got %q", a)
+	}
+	if a == b {
+		t.Errorf("Expected distinct IDs, got %q twice", a)
+	}
+}
@@ -0,0 +1,184 @@
+package goanda
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultInstrumentWatchInterval = 15 * time.Minute
+
+// InstrumentChangeKind identifies an InstrumentChange
+type InstrumentChangeKind string
+
+const (
+	// InstrumentAdded is an instrument the account can now trade
+	InstrumentAdded InstrumentChangeKind = "ADDED"
+	// InstrumentRemoved is an instrument the account can no longer trade, such as an expired contract
+	InstrumentRemoved InstrumentChangeKind = "REMOVED"
+	// InstrumentMarginRateChanged is a change in the margin rate of an instrument
+	InstrumentMarginRateChanged InstrumentChangeKind = "MARGIN_RATE"
+	// InstrumentTradeableChanged is a change in a price's tradeable flag, which happens as an instrument's
+	// trading hours begin and end, or when trading in it is halted
+	InstrumentTradeableChanged InstrumentChangeKind = "TRADEABLE"
+)
+
+// InstrumentChange is a change to an instrument that may affect positions held in it
+type InstrumentChange struct {
+	Kind       InstrumentChangeKind
+	Instrument string
+	Time       time.Time
+	// Previous and Current are the instrument's details before and after the change, Previous is empty
+	// for an added instrument and Current for a removed one. Neither is set for a tradeable change.
+	Previous InstrumentDetails
+	Current  InstrumentDetails
+	// Tradeable is the new tradeable flag of an InstrumentTradeableChanged
+	Tradeable bool
+}
+
+// InstrumentWatch detects changes to the instruments an account can trade, by comparing the instruments
+// endpoint between refreshes and following the tradeable flag of the prices passed to ObservePrice.
+// The first observation of each only records a baseline. It is safe for concurrent use.
+type InstrumentWatch struct {
+	// Interval is how often WatchInstruments refreshes the instruments. It defaults to 15 minutes.
+	// With a Cache configured, changes are only seen once the cached instruments expire.
+	Interval time.Duration
+	// OnChange, if set, is called for every change detected. CheckInstruments also logs them.
+	OnChange func(InstrumentChange)
+
+	mu        sync.Mutex
+	known     map[string]InstrumentDetails
+	tradeable map[string]bool
+}
+
+// ObserveInstruments compares the instruments with those previously observed, returning the changes
+func (w *InstrumentWatch) ObserveInstruments(instruments Instruments) []InstrumentChange {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	current := make(map[string]InstrumentDetails, len(instruments))
+	for _, instrument := range instruments {
+		current[instrument.Name] = instrument
+	}
+	if w.known == nil {
+		w.known = current
+		return nil
+	}
+
+	names := make([]string, 0, len(current)+len(w.known))
+	for name := range current {
+		names = append(names, name)
+	}
+	for name := range w.known {
+		if _, ok := current[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []InstrumentChange
+	now := time.Now()
+	for _, name := range names {
+		previous, was := w.known[name]
+		instrument, is := current[name]
+		change := InstrumentChange{Instrument: name, Time: now, Previous: previous, Current: instrument}
+		switch {
+		case !was:
+			change.Kind = InstrumentAdded
+		case !is:
+			change.Kind = InstrumentRemoved
+		case previous.MarginRate != instrument.MarginRate:
+			change.Kind = InstrumentMarginRateChanged
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	w.known = current
+	w.notify(changes)
+	return changes
+}
+
+// ObservePrice follows the tradeable flag of a streamed price, returning a change when it flips
+func (w *InstrumentWatch) ObservePrice(price PricingStreamResponse) []InstrumentChange {
+	if price.Type != "PRICE" {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.tradeable == nil {
+		w.tradeable = map[string]bool{}
+	}
+	previous, seen := w.tradeable[price.Instrument]
+	w.tradeable[price.Instrument] = price.Tradeable
+	if !seen || previous == price.Tradeable {
+		return nil
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, price.Time)
+	if err != nil {
+		at = time.Now()
+	}
+	changes := []InstrumentChange{{
+		Kind:       InstrumentTradeableChanged,
+		Instrument: price.Instrument,
+		Time:       at,
+		Tradeable:  price.Tradeable,
+	}}
+	w.notify(changes)
+	return changes
+}
+
+func (w *InstrumentWatch) notify(changes []InstrumentChange) {
+	if w.OnChange == nil {
+		return
+	}
+	for _, change := range changes {
+		w.OnChange(change)
+	}
+}
+
+// CheckInstruments fetches the account's instruments and passes them to the watch
+func (c *Connection) CheckInstruments(w *InstrumentWatch) ([]InstrumentChange, error) {
+	instruments, err := c.GetAccountInstruments(c.accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := w.ObserveInstruments(instruments)
+	for _, change := range changes {
+		switch change.Kind {
+		case InstrumentMarginRateChanged:
+			c.logf("goanda: instrument %s margin rate changed from %s to %s",
+				change.Instrument, change.Previous.MarginRate, change.Current.MarginRate)
+		default:
+			c.logf("goanda: instrument %s %s", change.Instrument, change.Kind)
+		}
+	}
+	return changes, nil
+}
+
+// WatchInstruments checks the account's instruments every interval until ctx is cancelled, returning
+// ctx.Err(), or a check fails
+func (c *Connection) WatchInstruments(ctx context.Context, w *InstrumentWatch) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultInstrumentWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.CheckInstruments(w); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package goanda
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckInstruments(t *testing.T) {
+	defer logTestResult(t, "CheckInstruments")
+
+	responses := []string{
+		`{"instruments":[{"name":"EUR_USD","marginRate":"0.02"},{"name":"DE30_EUR","marginRate":"0.05"}]}`,
+		`{"instruments":[{"name":"EUR_USD","marginRate":"0.0333"},{"name":"GBP_USD","marginRate":"0.0333"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/instruments" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, responses[0])
+		responses = responses[1:]
+	}))
+	defer server.Close()
+
+	logs := &bytes.Buffer{}
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+		logger:    log.New(logs, "", 0),
+	}
+
+	var notified []InstrumentChange
+	w := &InstrumentWatch{OnChange: func(change InstrumentChange) {
+		notified = append(notified, change)
+	}}
+	if changes, err := c.CheckInstruments(w); err != nil || len(changes) != 0 {
+		t.Fatalf("Expected the first check to record a baseline, got %v (%v)", changes, err)
+	}
+
+	changes, err := c.CheckInstruments(w)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var summary []string
+	for _, change := range changes {
+		summary = append(summary, change.Instrument+" "+string(change.Kind))
+	}
+	if strings.Join(summary, ", ") != "DE30_EUR REMOVED, EUR_USD MARGIN_RATE, GBP_USD ADDED" {
+		t.Errorf("Unexpected changes: %v", summary)
+	}
+	if changes[1].Previous.MarginRate != "0.02" || changes[1].Current.MarginRate != "0.0333" {
+		t.Errorf("Unexpected margin rate change: %+v", changes[1])
+	}
+	if len(notified) != 3 {
+		t.Errorf("Expected OnChange for every change, got %d calls", len(notified))
+	}
+	if !strings.Contains(logs.String(), "EUR_USD margin rate changed from 0.02 to 0.0333") {
+		t.Errorf("Expected the change to be logged, got %q", logs.String())
+	}
+}
+
+func TestInstrumentWatchObservePrice(t *testing.T) {
+	defer logTestResult(t, "InstrumentWatchObservePrice")
+
+	w := &InstrumentWatch{}
+	price := PricingStreamResponse{Type: "PRICE", Instrument: "EUR_USD", Time: "2024-01-05T21:59:59Z", Tradeable: true}
+	if changes := w.ObservePrice(price); len(changes) != 0 {
+		t.Errorf("Expected no change for the first price, got %v", changes)
+	}
+	if changes := w.ObservePrice(price); len(changes) != 0 {
+		t.Errorf("Expected no change while the flag is unchanged, got %v", changes)
+	}
+
+	price.Tradeable = false
+	price.Time = "2024-01-05T22:00:00Z"
+	changes := w.ObservePrice(price)
+	if len(changes) != 1 || changes[0].Kind != InstrumentTradeableChanged || changes[0].Tradeable {
+		t.Fatalf("Expected the market close to be reported, got %v", changes)
+	}
+	if changes[0].Time.Hour() != 22 {
+		t.Errorf("Expected the change to carry the price's time, got %v", changes[0].Time)
+	}
+}
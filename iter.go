@@ -0,0 +1,145 @@
+package goanda
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prices returns an iterator over the price stream for the given instruments.
+//
+//	for price, err := range sc.Prices(ctx, instruments) {
+//		...
+//	}
+//
+// The stream is opened when iteration starts and closed when the loop exits,
+// whether by break, the context ending or the stream failing.
+// An error ending the stream is yielded once as the final element.
+func (sc *StreamingConnection) Prices(ctx context.Context, instruments []string) iter.Seq2[PricingStreamResponse, error] {
+	return streamSeq(func(handler func(PricingStreamResponse) error) error {
+		return sc.streamPrices(ctx, instruments, PriceStreamOptions{}, handler)
+	})
+}
+
+// TransactionEvents returns an iterator over the account's transaction stream, see Prices
+func (sc *StreamingConnection) TransactionEvents(ctx context.Context) iter.Seq2[TransactionStreamResponse, error] {
+	return streamSeq(func(handler func(TransactionStreamResponse) error) error {
+		return sc.streamTransactions(ctx, handler)
+	})
+}
+
+// AccountChanges returns an iterator over the account's changes, see Prices and StreamAccountChanges
+func (sc *StreamingConnection) AccountChanges(ctx context.Context) iter.Seq2[AccountChangesStreamResponse, error] {
+	return streamSeq(func(handler func(AccountChangesStreamResponse) error) error {
+		return sc.streamAccountChanges(ctx, "", handler)
+	})
+}
+
+// Candles returns an iterator over the candle stream for an instrument, see Prices
+func (sc *StreamingConnection) Candles(ctx context.Context, instrument string, granularity string) iter.Seq2[CandlestickStreamResponse, error] {
+	return streamSeq(func(handler func(CandlestickStreamResponse) error) error {
+		return sc.streamCandles(ctx, instrument, granularity, handler)
+	})
+}
+
+// streamSeq adapts a stream to an iterator, ending the stream when the consumer stops
+// and yielding any error that ends it
+func streamSeq[T any](run func(handler func(T) error) error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		err := run(func(response T) error {
+			if !yield(response, nil) {
+				return errStopStream
+			}
+			return nil
+		})
+		if err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// TransactionsOptions selects the account transactions returned by Connection.Transactions
+// Defaults;
+//
+//	From		= the account's first transaction
+//	To		= now
+//	PageSize	= 100 (the api's default)
+//	Types		= all transaction types
+type TransactionsOptions struct {
+	From     time.Time
+	To       time.Time
+	PageSize int
+	Types    []string
+}
+
+// Transactions returns an iterator over the account's transaction history, fetching it page by page as it is consumed.
+// Iteration stops after yielding an error, and the context is checked between pages.
+func (c *Connection) Transactions(ctx context.Context, opts TransactionsOptions) iter.Seq2[TransactionDetails, error] {
+	return func(yield func(TransactionDetails, error) bool) {
+		query := url.Values{}
+		if !opts.From.IsZero() {
+			query.Set("from", opts.From.Format(time.RFC3339))
+		}
+		if !opts.To.IsZero() {
+			query.Set("to", opts.To.Format(time.RFC3339))
+		}
+		if opts.PageSize != 0 {
+			query.Set("pageSize", strconv.Itoa(opts.PageSize))
+		}
+		if len(opts.Types) != 0 {
+			query.Set("type", strings.Join(opts.Types, ","))
+		}
+
+		endpoint := "/accounts/" + c.accountID + "/transactions"
+		if len(query) != 0 {
+			endpoint += "?" + query.Encode()
+		}
+
+		tp := TransactionPages{}
+		if err := c.getAndUnmarshal(endpoint, &tp); err != nil {
+			yield(TransactionDetails{}, err)
+			return
+		}
+
+		for _, page := range tp.Pages {
+			if err := ctx.Err(); err != nil {
+				yield(TransactionDetails{}, err)
+				return
+			}
+
+			tr := Transactions{}
+			if err := c.getAndUnmarshal(c.relativeEndpoint(page), &tr); err != nil {
+				yield(TransactionDetails{}, err)
+				return
+			}
+
+			for _, t := range tr.Transactions {
+				if !yield(t, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// relativeEndpoint turns an absolute url returned by the api, such as a transaction page,
+// into an endpoint relative to the connection's hostname
+func (c *Connection) relativeEndpoint(absolute string) string {
+	if strings.HasPrefix(absolute, c.hostname) {
+		return strings.TrimPrefix(absolute, c.hostname)
+	}
+
+	u, err := url.Parse(absolute)
+	if err != nil {
+		return absolute
+	}
+	endpoint := strings.TrimPrefix(u.Path, "/v3")
+	if u.RawQuery != "" {
+		endpoint += "?" + u.RawQuery
+	}
+	return endpoint
+}
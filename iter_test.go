@@ -0,0 +1,179 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPricesIterator(t *testing.T) {
+	defer logTestResult(t, "PricesIterator")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, `{"type":"PRICE","instrument":"EUR_USD","time":"%d"}`+"\n", i)
+		}
+	}))
+	defer server.Close()
+
+	conn := &Connection{
+		hostname:   server.URL,
+		accountID:  "test-account",
+		authHeader: "Bearer test-token",
+		client:     *server.Client(),
+	}
+	sc := NewStreamingConnection(conn)
+	sc.streamURL = server.URL
+
+	var received []string
+	for price, err := range sc.Prices(context.Background(), []string{"EUR_USD"}) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		received = append(received, price.Time)
+		if len(received) == 3 {
+			break
+		}
+	}
+
+	if len(received) != 3 {
+		t.Fatalf("Expected to stop after 3 prices, got %d", len(received))
+	}
+	if received[2] != "2" {
+		t.Errorf("Expected prices in order, got %v", received)
+	}
+}
+
+func TestPricesIteratorError(t *testing.T) {
+	defer logTestResult(t, "PricesIteratorError")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errorMessage":"Invalid value specified for 'instruments'"}` + "\n"))
+	}))
+	defer server.Close()
+
+	conn := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+	sc := NewStreamingConnection(conn)
+	sc.streamURL = server.URL
+
+	var errs int
+	for _, err := range sc.Prices(context.Background(), []string{"EUR_USD"}) {
+		if err != nil {
+			errs++
+		}
+	}
+	if errs != 1 {
+		t.Errorf("Expected a single error, got %d", errs)
+	}
+}
+
+func TestStreamIterators(t *testing.T) {
+	defer logTestResult(t, "StreamIterators")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/transactions/stream":
+			w.Write([]byte(`{"type":"ORDER_FILL","id":"7"}` + "\n"))
+		case "/accounts/test-account/summary":
+			w.Write([]byte(`{"lastTransactionID":"7"}`))
+		case "/accounts/test-account/changes":
+			w.Write([]byte(`{"lastTransactionID":"8"}`))
+		case "/accounts/test-account/pricing/stream":
+			w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD",` +
+				`"bids":[{"price":"1.1","liquidity":1}],"asks":[{"price":"1.2","liquidity":1}]}` + "\n"))
+		default:
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	conn := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+	sc := NewStreamingConnection(conn)
+	sc.streamURL = server.URL
+	sc.AccountChangesInterval = time.Millisecond
+	ctx := context.Background()
+
+	var ids []string
+	for tr, err := range sc.TransactionEvents(ctx) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ids = append(ids, tr.ID)
+	}
+	for change, err := range sc.AccountChanges(ctx) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ids = append(ids, change.LastTransactionID)
+		break
+	}
+	for candle, err := range sc.Candles(ctx, "EUR_USD", "M1") {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ids = append(ids, candle.Instrument)
+	}
+
+	if fmt.Sprint(ids) != "[7 8 EUR_USD]" {
+		t.Errorf("Unexpected stream contents: %v", ids)
+	}
+}
+
+func TestTransactionsIterator(t *testing.T) {
+	defer logTestResult(t, "TransactionsIterator")
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/transactions":
+			if r.URL.Query().Get("type") != "ORDER_FILL" {
+				t.Errorf("Unexpected type filter: %s", r.URL.Query().Get("type"))
+			}
+			json.NewEncoder(w).Encode(TransactionPages{
+				Pages: []string{
+					server.URL + "/v3/accounts/test-account/transactions/idrange?from=1&to=2",
+					server.URL + "/v3/accounts/test-account/transactions/idrange?from=3&to=4",
+				},
+			})
+		case "/v3/accounts/test-account/transactions/idrange":
+			from := r.URL.Query().Get("from")
+			to := r.URL.Query().Get("to")
+			json.NewEncoder(w).Encode(Transactions{
+				Transactions: []TransactionDetails{{ID: from}, {ID: to}},
+			})
+		default:
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	var ids []string
+	for tx, err := range c.Transactions(context.Background(), TransactionsOptions{Types: []string{"ORDER_FILL"}}) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ids = append(ids, tx.ID)
+	}
+
+	if fmt.Sprint(ids) != "[1 2 3 4]" {
+		t.Errorf("Expected transactions 1 to 4, got %v", ids)
+	}
+}
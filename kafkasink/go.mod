@@ -0,0 +1,11 @@
+module github.com/rollend/goanda/kafkasink
+
+go 1.23.0
+
+require github.com/segmentio/kafka-go v0.4.51
+
+require (
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+	github.com/stretchr/testify v1.9.0 // indirect
+)
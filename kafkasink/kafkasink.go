@@ -0,0 +1,39 @@
+// Package kafkasink publishes goanda stream events to Kafka, see goanda.EventPublisher:
+//
+//	writer := &kafka.Writer{Addr: kafka.TCP("localhost:9092"), Balancer: &kafka.Hash{}}
+//	publisher := goanda.EventPublisher{Sink: kafkasink.New(writer)}
+//	publisher.Publish(ctx, sc.Events(ctx, goanda.EventsOptions{Instruments: instruments}))
+//
+// With a Hash balancer the events of a key, such as an instrument, keep their order on one partition.
+package kafkasink
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Writer writes messages to Kafka, it is satisfied by *kafka.Writer. The writer must not set
+// its own Topic, as each message carries the topic it is published to.
+type Writer interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+}
+
+// Sink is a goanda.Sink writing each message to Kafka
+type Sink struct {
+	writer Writer
+}
+
+// New creates a sink writing with the writer
+func New(writer Writer) *Sink {
+	return &Sink{writer: writer}
+}
+
+// Publish writes a message, keyed unless key is empty
+func (s *Sink) Publish(ctx context.Context, topic string, key string, value []byte) error {
+	message := kafka.Message{Topic: topic, Value: value}
+	if key != "" {
+		message.Key = []byte(key)
+	}
+	return s.writer.WriteMessages(ctx, message)
+}
@@ -0,0 +1,37 @@
+package kafkasink
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type fakeWriter struct {
+	messages []kafka.Message
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, messages ...kafka.Message) error {
+	w.messages = append(w.messages, messages...)
+	return nil
+}
+
+func TestSink(t *testing.T) {
+	writer := &fakeWriter{}
+	sink := New(writer)
+
+	if err := sink.Publish(context.Background(), "goanda.prices", "EUR_USD", []byte(`{"instrument":"EUR_USD"}`)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sink.Publish(context.Background(), "goanda.transactions", "", []byte(`{}`))
+
+	if len(writer.messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(writer.messages))
+	}
+	if m := writer.messages[0]; m.Topic != "goanda.prices" || string(m.Key) != "EUR_USD" || string(m.Value) != `{"instrument":"EUR_USD"}` {
+		t.Errorf("Expected the keyed price, got %+v", m)
+	}
+	if m := writer.messages[1]; m.Topic != "goanda.transactions" || m.Key != nil {
+		t.Errorf("Expected an unkeyed message, got %+v", m)
+	}
+}
@@ -0,0 +1,129 @@
+package goanda
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// The environment variables read by NewConnectionFromEnv
+const (
+	EnvAccountID = "OANDA_ACCOUNT_ID"
+	EnvAPIKey    = "OANDA_API_KEY"
+	EnvLive      = "OANDA_LIVE"
+)
+
+// KeyringService is the service name tokens are stored under in the OS keyring
+const KeyringService = "goanda"
+
+var (
+	// ErrCredentialNotFound is returned by a CredentialStore that holds no secret for the account
+	ErrCredentialNotFound = errors.New("goanda: credential not found")
+	// ErrKeyringUnsupported is returned by the OS keyring on platforms without one
+	ErrKeyringUnsupported = errors.New("goanda: no keyring is available on this platform")
+)
+
+// CredentialStore stores API tokens outside of plaintext configuration, keyed by service and account ID
+type CredentialStore interface {
+	Get(service string, account string) (string, error)
+	Set(service string, account string, secret string) error
+	Delete(service string, account string) error
+}
+
+// Keyring returns the OS credential store: the Keychain on macOS, the Credential Manager on Windows
+// and the Secret Service, through secret-tool, on Linux
+func Keyring() CredentialStore {
+	return systemKeyring{}
+}
+
+// MemoryCredentialStore is a CredentialStore held in memory, intended for tests
+type MemoryCredentialStore struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// Get returns the stored secret or ErrCredentialNotFound
+func (m *MemoryCredentialStore) Get(service string, account string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	secret, ok := m.secrets[service+"/"+account]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+	return secret, nil
+}
+
+// Set stores the secret, replacing any previous one
+func (m *MemoryCredentialStore) Set(service string, account string, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.secrets == nil {
+		m.secrets = make(map[string]string)
+	}
+	m.secrets[service+"/"+account] = secret
+	return nil
+}
+
+// Delete removes the secret, returning ErrCredentialNotFound if there was none
+func (m *MemoryCredentialStore) Delete(service string, account string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.secrets[service+"/"+account]; !ok {
+		return ErrCredentialNotFound
+	}
+	delete(m.secrets, service+"/"+account)
+	return nil
+}
+
+// StoreToken saves an account's API token in the store under KeyringService
+func StoreToken(store CredentialStore, accountID string, token string) error {
+	return store.Set(KeyringService, accountID, token)
+}
+
+// NewConnectionFromEnv creates a connection for the account named by OANDA_ACCOUNT_ID.
+// The token is taken from OANDA_API_KEY or, when that is unset and config.CredentialStore is given,
+// from the store, so that it need not sit in a plaintext .env file. OANDA_LIVE=true selects the live
+// environment when no config is given.
+func NewConnectionFromEnv(config *ConnectionConfig) (*Connection, error) {
+	var store CredentialStore
+	if config != nil {
+		store = config.CredentialStore
+	}
+	accountID, token, err := credentialsFromEnv(store)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		if live, _ := strconv.ParseBool(os.Getenv(EnvLive)); live {
+			config = &ConnectionConfig{Live: true}
+		}
+	}
+	return NewConnection(accountID, token, config)
+}
+
+// credentialsFromEnv resolves the account ID and token from the environment, falling back to the store for the token
+func credentialsFromEnv(store CredentialStore) (string, string, error) {
+	accountID := os.Getenv(EnvAccountID)
+	if accountID == "" {
+		return "", "", fmt.Errorf("goanda: %s is not set", EnvAccountID)
+	}
+
+	if token := os.Getenv(EnvAPIKey); token != "" {
+		return accountID, token, nil
+	}
+	if store == nil {
+		return "", "", fmt.Errorf("goanda: %s is not set and no credential store was given", EnvAPIKey)
+	}
+
+	token, err := store.Get(KeyringService, accountID)
+	if err != nil {
+		return "", "", fmt.Errorf("goanda: reading the token for %s: %w", accountID, err)
+	}
+	return accountID, token, nil
+}
@@ -0,0 +1,60 @@
+package goanda
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityNotFound is the exit status of security(1) when no matching item is in the keychain
+const securityNotFound = 44
+
+// systemKeyring stores generic passwords in the login Keychain using security(1)
+type systemKeyring struct{}
+
+func (systemKeyring) Get(service string, account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		return "", keychainError(err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// Set runs security(1) interactively, with the command on its stdin, so that the secret isn't in
+// its arguments for any process to read
+func (systemKeyring) Set(service string, account string, secret string) error {
+	if strings.ContainsAny(service+account+secret, "\r\n") {
+		return errors.New("goanda: the keychain can't store a credential spanning lines")
+	}
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		securityQuote(service), securityQuote(account), securityQuote(secret)))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return keychainError(err)
+	}
+	// the interactive mode reports a failed command without exiting with an error
+	if message := strings.TrimSpace(stderr.String()); message != "" {
+		return fmt.Errorf("goanda: storing the secret in the keychain: %s", message)
+	}
+	return nil
+}
+
+func (systemKeyring) Delete(service string, account string) error {
+	return keychainError(exec.Command("security", "delete-generic-password", "-s", service, "-a", account).Run())
+}
+
+// securityQuote quotes an argument of a command for security(1)'s interactive mode
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func keychainError(err error) error {
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == securityNotFound {
+		return ErrCredentialNotFound
+	}
+	return err
+}
@@ -0,0 +1,32 @@
+package goanda
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// systemKeyring stores secrets with the Secret Service (GNOME Keyring, KWallet) using secret-tool(1)
+type systemKeyring struct{}
+
+func (systemKeyring) Get(service string, account string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "account", account).Output()
+	if err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && len(exit.Stderr) == 0 {
+			return "", ErrCredentialNotFound
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (systemKeyring) Set(service string, account string, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label="+service+" "+account, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	return cmd.Run()
+}
+
+func (systemKeyring) Delete(service string, account string) error {
+	return exec.Command("secret-tool", "clear", "service", service, "account", account).Run()
+}
@@ -0,0 +1,18 @@
+//go:build !darwin && !linux && !windows
+
+package goanda
+
+// systemKeyring is unavailable on platforms without a supported keyring
+type systemKeyring struct{}
+
+func (systemKeyring) Get(service string, account string) (string, error) {
+	return "", ErrKeyringUnsupported
+}
+
+func (systemKeyring) Set(service string, account string, secret string) error {
+	return ErrKeyringUnsupported
+}
+
+func (systemKeyring) Delete(service string, account string) error {
+	return ErrKeyringUnsupported
+}
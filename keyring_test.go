@@ -0,0 +1,65 @@
+package goanda
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCredentialsFromEnv(t *testing.T) {
+	defer logTestResult(t, "CredentialsFromEnv")
+
+	store := &MemoryCredentialStore{}
+	if err := StoreToken(store, "001-001-1234567-001", "stored-token"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Setenv(EnvAccountID, "001-001-1234567-001")
+	t.Setenv(EnvAPIKey, "env-token")
+	_, token, err := credentialsFromEnv(store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token != "env-token" {
+		t.Errorf("Expected the environment to take precedence, got %q", token)
+	}
+
+	t.Setenv(EnvAPIKey, "")
+	accountID, token, err := credentialsFromEnv(store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if accountID != "001-001-1234567-001" || token != "stored-token" {
+		t.Errorf("Expected the stored token, got %q for %q", token, accountID)
+	}
+
+	if _, _, err := credentialsFromEnv(nil); err == nil {
+		t.Error("Expected an error without a token or store")
+	}
+
+	t.Setenv(EnvAccountID, "001-001-7654321-001")
+	if _, _, err := credentialsFromEnv(store); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Expected ErrCredentialNotFound, got %v", err)
+	}
+}
+
+func TestMemoryCredentialStore(t *testing.T) {
+	defer logTestResult(t, "MemoryCredentialStore")
+
+	store := &MemoryCredentialStore{}
+	if err := store.Delete(KeyringService, "account"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Expected ErrCredentialNotFound, got %v", err)
+	}
+
+	store.Set(KeyringService, "account", "first")
+	store.Set(KeyringService, "account", "second")
+	if secret, _ := store.Get(KeyringService, "account"); secret != "second" {
+		t.Errorf("Expected the secret to be replaced, got %q", secret)
+	}
+
+	if err := store.Delete(KeyringService, "account"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := store.Get(KeyringService, "account"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("Expected ErrCredentialNotFound after deleting, got %v", err)
+	}
+}
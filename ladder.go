@@ -0,0 +1,214 @@
+package goanda
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ErrInsufficientLiquidity is returned when a ladder cannot fill the requested units
+var ErrInsufficientLiquidity = errors.New("insufficient liquidity")
+
+// LadderLevel is one price on a ladder with the liquidity offered at it.
+// Cumulative is the liquidity at this price and every better one.
+type LadderLevel struct {
+	Price      float64
+	Liquidity  int
+	Cumulative int
+}
+
+// Ladder is the depth of an instrument's market, with bids from the highest price down
+// and asks from the lowest price up
+type Ladder struct {
+	Instrument string
+	Bids       []LadderLevel
+	Asks       []LadderLevel
+}
+
+// LadderFill is what walking a ladder for a number of units would achieve
+type LadderFill struct {
+	Units  int
+	Filled int
+	// VWAP is the volume-weighted average price of the filled units
+	VWAP float64
+	// WorstPrice is the last price level the fill reached
+	WorstPrice float64
+}
+
+// NewLadder sorts and merges price levels into a ladder, combining the liquidity of levels at the same price
+func NewLadder(instrument string, bids []LadderLevel, asks []LadderLevel) Ladder {
+	return Ladder{
+		Instrument: instrument,
+		Bids:       mergeLevels(bids, true),
+		Asks:       mergeLevels(asks, false),
+	}
+}
+
+// Ladder builds a ladder from the buckets of a streamed price
+func (p PricingStreamResponse) Ladder() (Ladder, error) {
+	var bids, asks []LadderLevel
+	for _, b := range p.Bids {
+		level, err := parseLevel(b.Price, b.Liquidity)
+		if err != nil {
+			return Ladder{}, err
+		}
+		bids = append(bids, level)
+	}
+	for _, a := range p.Asks {
+		level, err := parseLevel(a.Price, a.Liquidity)
+		if err != nil {
+			return Ladder{}, err
+		}
+		asks = append(asks, level)
+	}
+	return NewLadder(p.Instrument, bids, asks), nil
+}
+
+// Ladder builds a ladder from the price an order was filled against
+func (fp FullPrice) Ladder(instrument string) (Ladder, error) {
+	var bids, asks []LadderLevel
+	for _, b := range fp.Bids {
+		liquidity, err := strconv.ParseFloat(b.Liquidity, 64)
+		if err != nil {
+			return Ladder{}, err
+		}
+		level, err := parseLevel(b.Price, int(liquidity))
+		if err != nil {
+			return Ladder{}, err
+		}
+		bids = append(bids, level)
+	}
+	for _, a := range fp.Asks {
+		liquidity, err := strconv.ParseFloat(a.Liquidity, 64)
+		if err != nil {
+			return Ladder{}, err
+		}
+		level, err := parseLevel(a.Price, int(liquidity))
+		if err != nil {
+			return Ladder{}, err
+		}
+		asks = append(asks, level)
+	}
+	return NewLadder(instrument, bids, asks), nil
+}
+
+// Ladder builds a ladder for the instrument from every price in the response
+func (p Pricings) Ladder(instrument string) (Ladder, error) {
+	var bids, asks []LadderLevel
+	found := false
+	for _, price := range p.Prices {
+		if price.Instrument != instrument {
+			continue
+		}
+		found = true
+		for _, b := range price.Bids {
+			level, err := parseLevel(b.Price, b.Liquidity)
+			if err != nil {
+				return Ladder{}, err
+			}
+			bids = append(bids, level)
+		}
+		for _, a := range price.Asks {
+			level, err := parseLevel(a.Price, a.Liquidity)
+			if err != nil {
+				return Ladder{}, err
+			}
+			asks = append(asks, level)
+		}
+	}
+	if !found {
+		return Ladder{}, fmt.Errorf("no price for %s", instrument)
+	}
+	return NewLadder(instrument, bids, asks), nil
+}
+
+// Merge combines two ladders for the same instrument, such as successive snapshots from different sources
+func (l Ladder) Merge(other Ladder) Ladder {
+	return NewLadder(
+		l.Instrument,
+		append(append([]LadderLevel(nil), l.Bids...), other.Bids...),
+		append(append([]LadderLevel(nil), l.Asks...), other.Asks...),
+	)
+}
+
+// Fill walks the ladder for an order of the given units, positive to buy from the asks and negative to sell
+// into the bids, and returns the volume-weighted average price achievable. This treats each bucket as
+// separately available liquidity, which makes it a conservative estimate for deciding between a market order
+// and working the order. ErrInsufficientLiquidity is returned, along with the partial fill, if the ladder
+// cannot absorb the whole order.
+func (l Ladder) Fill(units int) (LadderFill, error) {
+	levels := l.Asks
+	remaining := units
+	if units < 0 {
+		levels = l.Bids
+		remaining = -units
+	}
+
+	fill := LadderFill{Units: units}
+	notional := 0.0
+	for _, level := range levels {
+		if remaining == 0 {
+			break
+		}
+		take := level.Liquidity
+		if take > remaining {
+			take = remaining
+		}
+		notional += float64(take) * level.Price
+		fill.Filled += take
+		fill.WorstPrice = level.Price
+		remaining -= take
+	}
+
+	if fill.Filled > 0 {
+		fill.VWAP = notional / float64(fill.Filled)
+	}
+	if units < 0 {
+		fill.Filled = -fill.Filled
+	}
+	if remaining > 0 {
+		return fill, ErrInsufficientLiquidity
+	}
+	return fill, nil
+}
+
+// VWAP returns the volume-weighted average price of filling the given units, see Fill
+func (l Ladder) VWAP(units int) (float64, error) {
+	fill, err := l.Fill(units)
+	return fill.VWAP, err
+}
+
+func parseLevel(price string, liquidity int) (LadderLevel, error) {
+	p, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return LadderLevel{}, err
+	}
+	return LadderLevel{Price: p, Liquidity: liquidity}, nil
+}
+
+// mergeLevels sorts levels best first, combining those at the same price and computing cumulative liquidity
+func mergeLevels(levels []LadderLevel, descending bool) []LadderLevel {
+	byPrice := map[float64]int{}
+	for _, level := range levels {
+		byPrice[level.Price] += level.Liquidity
+	}
+
+	merged := make([]LadderLevel, 0, len(byPrice))
+	for price, liquidity := range byPrice {
+		merged = append(merged, LadderLevel{Price: price, Liquidity: liquidity})
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if descending {
+			return merged[i].Price > merged[j].Price
+		}
+		return merged[i].Price < merged[j].Price
+	})
+
+	cumulative := 0
+	for i := range merged {
+		cumulative += merged[i].Liquidity
+		merged[i].Cumulative = cumulative
+	}
+	return merged
+}
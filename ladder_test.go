@@ -0,0 +1,132 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+const ladderStreamPrice = `{
+	"type": "PRICE",
+	"instrument": "EUR_USD",
+	"bids": [
+		{"price": "1.10000", "liquidity": 1000000},
+		{"price": "1.09990", "liquidity": 2000000},
+		{"price": "1.09995", "liquidity": 500000}
+	],
+	"asks": [
+		{"price": "1.10020", "liquidity": 2000000},
+		{"price": "1.10010", "liquidity": 1000000}
+	]
+}`
+
+func TestPricingStreamResponseLadder(t *testing.T) {
+	defer logTestResult(t, "PricingStreamResponseLadder")
+
+	var price PricingStreamResponse
+	if err := json.Unmarshal([]byte(ladderStreamPrice), &price); err != nil {
+		t.Fatal(err)
+	}
+
+	ladder, err := price.Ladder()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedBids := []LadderLevel{
+		{Price: 1.1, Liquidity: 1000000, Cumulative: 1000000},
+		{Price: 1.09995, Liquidity: 500000, Cumulative: 1500000},
+		{Price: 1.0999, Liquidity: 2000000, Cumulative: 3500000},
+	}
+	for i, want := range expectedBids {
+		if ladder.Bids[i] != want {
+			t.Errorf("Bid %d: expected %+v, got %+v", i, want, ladder.Bids[i])
+		}
+	}
+	if ladder.Asks[0].Price != 1.1001 || ladder.Asks[1].Cumulative != 3000000 {
+		t.Errorf("Expected asks from the lowest price up, got %+v", ladder.Asks)
+	}
+}
+
+func TestLadderFill(t *testing.T) {
+	defer logTestResult(t, "LadderFill")
+
+	ladder := NewLadder("EUR_USD",
+		[]LadderLevel{{Price: 1.1000, Liquidity: 100}, {Price: 1.0990, Liquidity: 100}},
+		[]LadderLevel{{Price: 1.1010, Liquidity: 100}, {Price: 1.1020, Liquidity: 100}, {Price: 1.1010, Liquidity: 100}},
+	)
+
+	if len(ladder.Asks) != 2 || ladder.Asks[0].Liquidity != 200 {
+		t.Fatalf("Expected asks at the same price to merge, got %+v", ladder.Asks)
+	}
+
+	fill, err := ladder.Fill(300)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := (200*1.1010 + 100*1.1020) / 300; math.Abs(fill.VWAP-want) > 1e-12 {
+		t.Errorf("Expected a VWAP of %v, got %v", want, fill.VWAP)
+	}
+	if fill.WorstPrice != 1.1020 || fill.Filled != 300 {
+		t.Errorf("Unexpected fill: %+v", fill)
+	}
+
+	vwap, err := ladder.VWAP(-150)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := (100*1.1000 + 50*1.0990) / 150; math.Abs(vwap-want) > 1e-12 {
+		t.Errorf("Expected a selling VWAP of %v, got %v", want, vwap)
+	}
+
+	fill, err = ladder.Fill(-500)
+	if !errors.Is(err, ErrInsufficientLiquidity) {
+		t.Errorf("Expected ErrInsufficientLiquidity, got %v", err)
+	}
+	if fill.Filled != -200 {
+		t.Errorf("Expected the partial fill to be reported, got %d", fill.Filled)
+	}
+}
+
+func TestPricingsLadder(t *testing.T) {
+	defer logTestResult(t, "PricingsLadder")
+
+	var pricings Pricings
+	err := json.Unmarshal([]byte(`{"prices":[
+		{"instrument":"EUR_USD","bids":[{"price":"1.1","liquidity":10}],"asks":[{"price":"1.2","liquidity":10}]},
+		{"instrument":"EUR_USD","bids":[{"price":"1.1","liquidity":5}],"asks":[]},
+		{"instrument":"USD_JPY","bids":[{"price":"150","liquidity":10}],"asks":[]}
+	]}`), &pricings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ladder, err := pricings.Ladder("EUR_USD")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ladder.Bids) != 1 || ladder.Bids[0].Liquidity != 15 {
+		t.Errorf("Expected the responses to merge, got %+v", ladder.Bids)
+	}
+
+	if _, err := pricings.Ladder("GBP_USD"); err == nil {
+		t.Error("Expected an error for a missing instrument")
+	}
+}
+
+func TestFullPriceLadder(t *testing.T) {
+	defer logTestResult(t, "FullPriceLadder")
+
+	fp := FullPrice{
+		Bids: []PriceLevel{{Price: "1.1", Liquidity: "1000000"}},
+		Asks: []PriceLevel{{Price: "1.2", Liquidity: "250000.0"}},
+	}
+	ladder, err := fp.Ladder("EUR_USD")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ladder.Bids[0].Liquidity != 1000000 || ladder.Asks[0].Liquidity != 250000 {
+		t.Errorf("Unexpected ladder: %+v", ladder)
+	}
+}
@@ -0,0 +1,174 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrConnectionClosed is returned by requests and streams started after the connection has been closed
+var ErrConnectionClosed = errors.New("goanda: connection closed")
+
+// lifecycle tracks the requests and background goroutines of a connection so that they can be stopped.
+// Its zero value is ready to use, the contexts are created when first needed.
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	requests context.Context
+	abort    context.CancelCauseFunc
+	inflight sync.WaitGroup
+	workers  sync.WaitGroup
+
+	// inflightCount and workerCount mirror the wait groups, for the shutdown snapshot
+	inflightCount int
+	workerCount   int
+}
+
+func (l *lifecycle) init() {
+	if l.ctx == nil {
+		l.ctx, l.cancel = context.WithCancelCause(context.Background())
+		l.requests, l.abort = context.WithCancelCause(context.Background())
+	}
+}
+
+// request registers an in-flight request, returning a context that is cancelled if the connection is closed
+// before it completes, and a function to call once it has
+func (l *lifecycle) request(ctx context.Context) (context.Context, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, nil, ErrConnectionClosed
+	}
+	l.init()
+	l.inflight.Add(1)
+	l.inflightCount++
+
+	bound, cancel := bindContext(ctx, l.requests)
+	return bound, func() {
+		cancel()
+		l.mu.Lock()
+		l.inflightCount--
+		l.mu.Unlock()
+		l.inflight.Done()
+	}, nil
+}
+
+// worker registers a background goroutine, such as a stream, returning a context that is cancelled
+// when the connection begins shutting down, and a function to call once the goroutine has returned
+func (l *lifecycle) worker(ctx context.Context) (context.Context, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, nil, ErrConnectionClosed
+	}
+	l.init()
+	l.workers.Add(1)
+	l.workerCount++
+
+	bound, cancel := bindContext(ctx, l.ctx)
+	return bound, func() {
+		cancel()
+		l.mu.Lock()
+		l.workerCount--
+		l.mu.Unlock()
+		l.workers.Done()
+	}, nil
+}
+
+// bindContext returns a context that ends when either ctx or the lifecycle context does,
+// carrying the lifecycle's cause if it ended first
+func bindContext(ctx context.Context, life context.Context) (context.Context, context.CancelFunc) {
+	bound, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(life, func() {
+		cancel(context.Cause(life))
+	})
+	return bound, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// Go runs fn in a background goroutine tied to the connection's lifecycle.
+// The context passed to fn is cancelled when the connection is closed, and Shutdown waits for fn to return.
+// It returns ErrConnectionClosed, without running fn, if the connection is already closed.
+func (c *Connection) Go(fn func(ctx context.Context)) error {
+	ctx, done, err := c.life.worker(context.Background())
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer done()
+		fn(ctx)
+	}()
+	return nil
+}
+
+// Close immediately stops the connection's streams and background goroutines,
+// cancels in-flight requests, and closes idle connections.
+// Requests made after Close return ErrConnectionClosed.
+func (c *Connection) Close() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := c.Shutdown(ctx)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown gracefully closes the connection. New requests are refused, streams and background goroutines
+// are told to stop, and in-flight requests are allowed to complete before idle connections are closed.
+// If ctx ends first, the remaining requests are cancelled and ctx.Err() is returned without waiting further.
+// A ShutdownSnapshot is then logged and saved to the StateStore.
+func (c *Connection) Shutdown(ctx context.Context) error {
+	l := &c.life
+	l.mu.Lock()
+	first := !l.closed
+	l.closed = true
+	l.init()
+	snapshot := ShutdownSnapshot{
+		AccountID:        c.accountID,
+		InflightRequests: l.inflightCount,
+		Workers:          l.workerCount,
+	}
+	l.mu.Unlock()
+
+	l.cancel(ErrConnectionClosed)
+
+	drained := make(chan struct{})
+	go func() {
+		l.inflight.Wait()
+		l.workers.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		l.abort(ErrConnectionClosed)
+	}
+
+	c.client.CloseIdleConnections()
+
+	// Only the first shutdown has a run to record
+	if first {
+		snapshot.Time = time.Now()
+		if !c.started.IsZero() {
+			snapshot.Uptime = snapshot.Time.Sub(c.started)
+		}
+		snapshot.Metrics = c.Metrics()
+		snapshot.CredentialsValid = c.CredentialsValid()
+		snapshot.Drained = err == nil
+		c.writeShutdownSnapshot(snapshot)
+	}
+	return err
+}
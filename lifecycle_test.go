@@ -0,0 +1,152 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainsRequests(t *testing.T) {
+	defer logTestResult(t, "ShutdownDrainsRequests")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname: server.URL,
+		client:   *server.Client(),
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := c.Get("/accounts")
+		result <- err
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- c.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-shutdown:
+		t.Fatal("Expected Shutdown to wait for the in-flight request")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-result; err != nil {
+		t.Errorf("Expected the in-flight request to complete, got %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Unexpected shutdown error: %v", err)
+	}
+
+	if _, err := c.Get("/accounts"); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Expected ErrConnectionClosed after shutdown, got %v", err)
+	}
+}
+
+func TestShutdownDeadlineCancelsRequests(t *testing.T) {
+	defer logTestResult(t, "ShutdownDeadlineCancelsRequests")
+
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname: server.URL,
+		client:   *server.Client(),
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := c.Get("/accounts")
+		result <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the shutdown deadline to be exceeded, got %v", err)
+	}
+	if err := <-result; !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Expected the request to be cancelled with ErrConnectionClosed, got %v", err)
+	}
+}
+
+func TestCloseStopsBackgroundWork(t *testing.T) {
+	defer logTestResult(t, "CloseStopsBackgroundWork")
+
+	c := &Connection{}
+	stopped := make(chan error, 1)
+	if err := c.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		stopped <- context.Cause(ctx)
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Unexpected close error: %v", err)
+	}
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, ErrConnectionClosed) {
+			t.Errorf("Expected ErrConnectionClosed as the cause, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the background goroutine to be stopped")
+	}
+
+	if err := c.Go(func(ctx context.Context) {}); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Expected ErrConnectionClosed starting work after close, got %v", err)
+	}
+}
+
+func TestCloseStopsStreams(t *testing.T) {
+	defer logTestResult(t, "CloseStopsStreams")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05.000000000Z"}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	sc := &StreamingConnection{
+		Connection: &Connection{client: *server.Client()},
+		streamURL:  server.URL,
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- sc.StreamTransactions(context.Background(), func(TransactionStreamResponse) {})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	sc.Close()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrConnectionClosed) {
+			t.Errorf("Expected ErrConnectionClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stream to end when the connection closed")
+	}
+}
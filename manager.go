@@ -0,0 +1,263 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxInstrumentsPerStream is the number of instruments a StreamManager puts on each price stream
+const DefaultMaxInstrumentsPerStream = 20
+
+// StreamManager presents a set of instrument subscriptions as a single logical price stream.
+// When there are more instruments than fit on one stream they are sharded across several,
+// and the shards are rebalanced as subscriptions change. Prices from every shard are delivered
+// to one callback, never concurrently, and heartbeats are merged into a single Health.
+type StreamManager struct {
+	// MaxInstrumentsPerStream defaults to DefaultMaxInstrumentsPerStream
+	MaxInstrumentsPerStream int
+	// Options are used for every shard's price stream
+	Options PriceStreamOptions
+
+	sc *StreamingConnection
+
+	mu          sync.Mutex
+	instruments []string
+	shards      []*priceShard
+	running     bool
+	ctx         context.Context
+	cancel      context.CancelCauseFunc
+	callback    func(PricingStreamResponse)
+	deliverMu   sync.Mutex
+}
+
+// StreamHealth describes a StreamManager's logical stream
+type StreamHealth struct {
+	Instruments int
+	Shards      int
+	// LastHeartbeat is the oldest of the shards' latest heartbeats, so a single stalled shard shows up.
+	// It is zero until every shard has received a heartbeat.
+	LastHeartbeat time.Time
+}
+
+type priceShard struct {
+	instruments []string
+	cancel      context.CancelFunc
+	done        chan struct{}
+	heartbeat   atomic.Int64
+}
+
+// NewStreamManager creates a stream manager using the streaming connection's settings
+func NewStreamManager(sc *StreamingConnection) *StreamManager {
+	return &StreamManager{sc: sc}
+}
+
+// Subscribe adds instruments to the stream, rebalancing the shards if it is running
+func (m *StreamManager) Subscribe(instruments ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, instrument := range instruments {
+		if !slices.Contains(m.instruments, instrument) {
+			m.instruments = append(m.instruments, instrument)
+		}
+	}
+	m.rebalance()
+}
+
+// Unsubscribe removes instruments from the stream, rebalancing the shards if it is running
+func (m *StreamManager) Unsubscribe(instruments ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.instruments[:0]
+	for _, instrument := range m.instruments {
+		if !slices.Contains(instruments, instrument) {
+			kept = append(kept, instrument)
+		}
+	}
+	m.instruments = kept
+	m.rebalance()
+}
+
+// Instruments returns the subscribed instruments
+func (m *StreamManager) Instruments() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.instruments...)
+}
+
+// Run streams prices for the subscribed instruments to callback until ctx is done, returning ctx.Err(),
+// or a shard's stream ends, returning its error. Set a Reconnect policy on the streaming connection
+// to have shards recover from drops rather than ending the logical stream.
+func (m *StreamManager) Run(ctx context.Context, callback func(PricingStreamResponse)) error {
+	m.mu.Lock()
+	if m.running {
+		m.mu.Unlock()
+		return errors.New("goanda: stream manager is already running")
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	m.ctx = ctx
+	m.cancel = cancel
+	m.callback = callback
+	m.running = true
+	m.rebalance()
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	shards := m.shards
+	m.shards = nil
+	m.running = false
+	m.mu.Unlock()
+
+	for _, shard := range shards {
+		shard.cancel()
+		<-shard.done
+	}
+
+	err := context.Cause(ctx)
+	if errors.Is(err, errStopStream) {
+		return nil
+	}
+	return err
+}
+
+// Health reports on the shards of the running stream
+func (m *StreamManager) Health() StreamHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := StreamHealth{
+		Instruments: len(m.instruments),
+		Shards:      len(m.shards),
+	}
+	for i, shard := range m.shards {
+		last := shard.heartbeat.Load()
+		if last == 0 {
+			return StreamHealth{Instruments: health.Instruments, Shards: health.Shards}
+		}
+		if t := time.Unix(0, last); i == 0 || t.Before(health.LastHeartbeat) {
+			health.LastHeartbeat = t
+		}
+	}
+	return health
+}
+
+// rebalance assigns the subscribed instruments to shards, restarting only the shards whose instruments changed.
+// It must be called with m.mu held.
+func (m *StreamManager) rebalance() {
+	if !m.running {
+		return
+	}
+
+	current := make([][]string, len(m.shards))
+	for i, shard := range m.shards {
+		current[i] = shard.instruments
+	}
+	plan := planShards(current, m.instruments, m.maxPerStream())
+
+	var shards []*priceShard
+	for i, instruments := range plan {
+		if i < len(m.shards) {
+			if slices.Equal(m.shards[i].instruments, instruments) {
+				shards = append(shards, m.shards[i])
+				continue
+			}
+			m.shards[i].cancel()
+		}
+		if len(instruments) > 0 {
+			shards = append(shards, m.startShard(instruments))
+		}
+	}
+	for i := len(plan); i < len(m.shards); i++ {
+		m.shards[i].cancel()
+	}
+	m.shards = shards
+}
+
+func (m *StreamManager) startShard(instruments []string) *priceShard {
+	ctx, cancel := context.WithCancel(m.ctx)
+	shard := &priceShard{
+		instruments: instruments,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	sc := *m.sc
+	sc.heartbeat = func(t time.Time) {
+		shard.heartbeat.Store(t.UnixNano())
+	}
+	callback := m.callback
+	opts := m.Options
+
+	go func() {
+		defer close(shard.done)
+		err := sc.streamPrices(ctx, instruments, opts, func(response PricingStreamResponse) error {
+			m.deliverMu.Lock()
+			defer m.deliverMu.Unlock()
+
+			// A shard replaced by a rebalance may still be winding down
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			callback(response)
+			return nil
+		})
+
+		if ctx.Err() == nil {
+			if err == nil {
+				err = errStopStream
+			}
+			m.cancel(err)
+		}
+	}()
+	return shard
+}
+
+func (m *StreamManager) maxPerStream() int {
+	if m.MaxInstrumentsPerStream > 0 {
+		return m.MaxInstrumentsPerStream
+	}
+	return DefaultMaxInstrumentsPerStream
+}
+
+// planShards assigns instruments to shards of at most max instruments, keeping each instrument on its current
+// shard where possible so that as few streams as possible are restarted. The plan is indexed like current,
+// with any new shards appended and emptied shards left empty.
+func planShards(current [][]string, instruments []string, max int) [][]string {
+	assigned := map[string]bool{}
+	plan := make([][]string, len(current))
+	for i, shard := range current {
+		for _, instrument := range shard {
+			if slices.Contains(instruments, instrument) && !assigned[instrument] && len(plan[i]) < max {
+				plan[i] = append(plan[i], instrument)
+				assigned[instrument] = true
+			}
+		}
+	}
+
+	for _, instrument := range instruments {
+		if assigned[instrument] {
+			continue
+		}
+		placed := false
+		for i := range plan {
+			if len(plan[i]) > 0 && len(plan[i]) < max {
+				plan[i] = append(plan[i], instrument)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			plan = append(plan, []string{instrument})
+		}
+		assigned[instrument] = true
+	}
+	return plan
+}
@@ -0,0 +1,124 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPlanShards(t *testing.T) {
+	defer logTestResult(t, "PlanShards")
+
+	plan := planShards(nil, []string{"A", "B", "C", "D", "E"}, 2)
+	if fmt.Sprint(plan) != "[[A B] [C D] [E]]" {
+		t.Errorf("Unexpected initial plan: %v", plan)
+	}
+
+	// Removing C frees a slot that the new instrument F takes, leaving the other shards untouched
+	plan = planShards(plan, []string{"A", "B", "D", "E", "F"}, 2)
+	if fmt.Sprint(plan) != "[[A B] [D F] [E]]" {
+		t.Errorf("Unexpected rebalanced plan: %v", plan)
+	}
+
+	plan = planShards(plan, []string{"E"}, 2)
+	if fmt.Sprint(plan) != "[[] [] [E]]" {
+		t.Errorf("Expected emptied shards to stay in place, got %v", plan)
+	}
+}
+
+// shardServer streams a price for each requested instrument, then a heartbeat, then waits
+type shardServer struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (s *shardServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	instruments := r.URL.Query().Get("instruments")
+	s.mu.Lock()
+	s.requests = append(s.requests, instruments)
+	s.mu.Unlock()
+
+	for _, instrument := range strings.Split(instruments, ",") {
+		fmt.Fprintf(w, `{"type":"PRICE","instrument":"%s"}`+"\n", instrument)
+	}
+	w.Write([]byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05.000000000Z"}` + "\n"))
+	w.(http.Flusher).Flush()
+	<-r.Context().Done()
+}
+
+func (s *shardServer) streams() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func TestStreamManagerShards(t *testing.T) {
+	defer logTestResult(t, "StreamManagerShards")
+
+	backend := &shardServer{}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	sc := &StreamingConnection{
+		Connection: &Connection{accountID: "test-account", client: *server.Client()},
+		streamURL:  server.URL,
+	}
+	m := NewStreamManager(sc)
+	m.MaxInstrumentsPerStream = 2
+	m.Subscribe("EUR_USD", "GBP_USD", "USD_JPY")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	prices := make(chan string, 10)
+	result := make(chan error, 1)
+	go func() {
+		result <- m.Run(ctx, func(price PricingStreamResponse) {
+			prices <- price.Instrument
+		})
+	}()
+
+	received := map[string]bool{}
+	for len(received) < 3 {
+		select {
+		case instrument := <-prices:
+			received[instrument] = true
+		case <-time.After(time.Second):
+			t.Fatalf("Expected prices for every instrument, got %v", received)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for m.Health().LastHeartbeat.IsZero() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	health := m.Health()
+	if health.Shards != 2 || health.Instruments != 3 || health.LastHeartbeat.IsZero() {
+		t.Errorf("Unexpected health: %+v", health)
+	}
+
+	m.Subscribe("AUD_USD")
+	for received := ""; received != "AUD_USD"; {
+		select {
+		case received = <-prices:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the new instrument to be streamed")
+		}
+	}
+
+	// Only the shard with room was restarted
+	streams := backend.streams()
+	if len(streams) != 3 || streams[2] != "USD_JPY,AUD_USD" {
+		t.Errorf("Unexpected streams: %v", streams)
+	}
+
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MarginState describes where an account stands relative to OANDA's margin call and closeout behaviour
+type MarginState string
+
+const (
+	MarginHealthy MarginState = "HEALTHY"
+	MarginCalled  MarginState = "MARGIN_CALL"
+	MarginClosing MarginState = "MARGIN_CLOSEOUT"
+)
+
+const defaultMarginWatchInterval = 30 * time.Second
+
+// DefaultMarginThresholds are the closeout percentages at which a MarginWatch alerts when none are configured
+var DefaultMarginThresholds = []float64{0.5, 0.75, 0.9}
+
+// MarginCloseout is a snapshot of an account's margin closeout figures. The account is closed out
+// once Percent reaches 1.
+type MarginCloseout struct {
+	NAV               float64
+	MarginUsed        float64
+	PositionValue     float64
+	Percent           float64
+	MarginCallPercent float64
+}
+
+// MarginCloseout extracts the margin closeout figures from the account summary
+func (s AccountSummary) MarginCloseout() (MarginCloseout, error) {
+	var m MarginCloseout
+	fields := []struct {
+		name  string
+		value string
+		dest  *float64
+	}{
+		{"marginCloseoutNAV", s.Account.MarginCloseoutNAV, &m.NAV},
+		{"marginCloseoutMarginUsed", s.Account.MarginCloseoutMarginUsed, &m.MarginUsed},
+		{"marginCloseoutPositionValue", s.Account.MarginCloseoutPositionValue, &m.PositionValue},
+		{"marginCloseoutPercent", s.Account.MarginCloseoutPercent, &m.Percent},
+		{"marginCallPercent", s.Account.MarginCallPercent, &m.MarginCallPercent},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(f.value, 64)
+		if err != nil {
+			return MarginCloseout{}, fmt.Errorf("goanda: invalid %s %q: %w", f.name, f.value, err)
+		}
+		*f.dest = v
+	}
+	return m, nil
+}
+
+// State reports whether the account is healthy, on margin call or being closed out
+func (m MarginCloseout) State() MarginState {
+	switch {
+	case m.Percent >= 1:
+		return MarginClosing
+	case m.MarginCallPercent >= 1:
+		return MarginCalled
+	}
+	return MarginHealthy
+}
+
+// Distance is how far the account is from closeout, as a fraction of the closeout level
+func (m MarginCloseout) Distance() float64 {
+	return max(1-m.Percent, 0)
+}
+
+// Headroom is the further loss, in the account's home currency, that would trigger a closeout.
+// The closeout percent scales inversely with NAV, so closeout happens once NAV falls to NAV * Percent.
+func (m MarginCloseout) Headroom() float64 {
+	return max(m.NAV*(1-m.Percent), 0)
+}
+
+// CloseoutProjection is the price at which a single position would take the account to closeout,
+// with every other position held at its current price
+type CloseoutProjection struct {
+	Instrument    string
+	Units         float64
+	Price         float64
+	CloseoutPrice float64
+}
+
+// Project estimates the closeout price of each open position. Long positions are valued at the closeout bid
+// and short positions at the closeout ask, converted to the home currency with the quote conversion factors.
+// Positions without a price are skipped.
+func (m MarginCloseout) Project(positions OpenPositions, pricing Pricings) ([]CloseoutProjection, error) {
+	headroom := m.Headroom()
+	var projections []CloseoutProjection
+	for _, position := range positions.Positions {
+		i := -1
+		for j := range pricing.Prices {
+			if pricing.Prices[j].Instrument == position.Instrument {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			continue
+		}
+		price := pricing.Prices[i]
+
+		for _, side := range []struct {
+			units, price, factor string
+		}{
+			// Short units are negative, so the same projection moves the price up for them
+			{position.Long.Units, price.CloseoutBid, price.QuoteHomeConversionFactors.PositiveUnits},
+			{position.Short.Units, price.CloseoutAsk, price.QuoteHomeConversionFactors.NegativeUnits},
+		} {
+			units, _ := strconv.ParseFloat(side.units, 64)
+			if units == 0 {
+				continue
+			}
+			current, err := strconv.ParseFloat(side.price, 64)
+			if err != nil {
+				return nil, fmt.Errorf("goanda: invalid closeout price for %s: %w", position.Instrument, err)
+			}
+			factor := 1.0
+			if side.factor != "" {
+				if factor, err = strconv.ParseFloat(side.factor, 64); err != nil {
+					return nil, fmt.Errorf("goanda: invalid conversion factor for %s: %w", position.Instrument, err)
+				}
+			}
+			if factor == 0 {
+				continue
+			}
+			projections = append(projections, CloseoutProjection{
+				Instrument:    position.Instrument,
+				Units:         units,
+				Price:         current,
+				CloseoutPrice: current - headroom/(units*factor),
+			})
+		}
+	}
+	return projections, nil
+}
+
+// MarginAlert is raised when an account's closeout percent crosses a MarginWatch threshold
+type MarginAlert struct {
+	Threshold   float64
+	Level       int
+	Closeout    MarginCloseout
+	Projections []CloseoutProjection
+	Time        time.Time
+}
+
+// MarginWatch monitors an account's distance to margin closeout and raises escalating alerts.
+// Each threshold alerts once as the closeout percent rises through it, and is re-armed once the
+// percent falls back below it.
+type MarginWatch struct {
+	// Thresholds are closeout percentages in ascending order. DefaultMarginThresholds is used when empty.
+	Thresholds []float64
+	// Interval is how often WatchMargin polls the account. It defaults to 30 seconds.
+	Interval time.Duration
+	// OnAlert, if set, is called for every threshold crossed. CheckMargin also logs them.
+	OnAlert func(MarginAlert)
+
+	level int
+}
+
+func (w *MarginWatch) thresholds() []float64 {
+	if len(w.Thresholds) == 0 {
+		return DefaultMarginThresholds
+	}
+	return w.Thresholds
+}
+
+// Observe checks a closeout snapshot against the thresholds, returning an alert for each one newly crossed
+func (w *MarginWatch) Observe(m MarginCloseout, projections []CloseoutProjection) []MarginAlert {
+	thresholds := w.thresholds()
+	level := 0
+	for level < len(thresholds) && m.Percent >= thresholds[level] {
+		level++
+	}
+
+	var alerts []MarginAlert
+	now := time.Now()
+	for ; w.level < level; w.level++ {
+		alert := MarginAlert{
+			Threshold:   thresholds[w.level],
+			Level:       w.level + 1,
+			Closeout:    m,
+			Projections: projections,
+			Time:        now,
+		}
+		if w.OnAlert != nil {
+			w.OnAlert(alert)
+		}
+		alerts = append(alerts, alert)
+	}
+	w.level = min(w.level, level)
+	return alerts
+}
+
+// CheckMargin fetches the account summary, positions and prices and passes them to the watch
+func (c *Connection) CheckMargin(w *MarginWatch) ([]MarginAlert, error) {
+	summary, err := c.GetAccountSummary()
+	if err != nil {
+		return nil, err
+	}
+	closeout, err := summary.MarginCloseout()
+	if err != nil {
+		return nil, err
+	}
+
+	var projections []CloseoutProjection
+	if summary.Account.OpenPositionCount > 0 {
+		positions, err := c.GetOpenPositions()
+		if err != nil {
+			return nil, err
+		}
+		instruments := make([]string, 0, len(positions.Positions))
+		for _, p := range positions.Positions {
+			instruments = append(instruments, p.Instrument)
+		}
+		pricing, err := c.GetPricingForInstruments(instruments)
+		if err != nil {
+			return nil, err
+		}
+		if projections, err = closeout.Project(positions, pricing); err != nil {
+			return nil, err
+		}
+	}
+	alerts := w.Observe(closeout, projections)
+	for _, alert := range alerts {
+		c.logf("goanda: ALERT margin level %d: closeout percent %.2f%% crossed %.2f%%, %.2f from closeout",
+			alert.Level, closeout.Percent*100, alert.Threshold*100, closeout.Headroom())
+	}
+	return alerts, nil
+}
+
+// WatchMargin checks the account's margin every interval until ctx is cancelled, returning ctx.Err(),
+// or a check fails
+func (c *Connection) WatchMargin(ctx context.Context, w *MarginWatch) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultMarginWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.CheckMargin(w); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package goanda
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMarginCloseoutProjection(t *testing.T) {
+	defer logTestResult(t, "MarginCloseoutProjection")
+
+	var summary AccountSummary
+	summary.Account.MarginCloseoutNAV = "10000"
+	summary.Account.MarginCloseoutMarginUsed = "4000"
+	summary.Account.MarginCloseoutPercent = "0.2"
+	summary.Account.MarginCallPercent = "0.4"
+
+	m, err := summary.MarginCloseout()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.State() != MarginHealthy {
+		t.Errorf("Expected a healthy account, got %s", m.State())
+	}
+	if m.Headroom() != 8000 {
+		t.Errorf("Expected 8000 of headroom, got %v", m.Headroom())
+	}
+
+	var positions OpenPositions
+	json.Unmarshal([]byte(`{"positions":[
+		{"instrument":"EUR_USD","long":{"units":"100000"},"short":{"units":"0"}},
+		{"instrument":"USD_JPY","long":{"units":"0"},"short":{"units":"-50000"}},
+		{"instrument":"GBP_USD","long":{"units":"1000"},"short":{"units":"0"}}
+	]}`), &positions)
+
+	var pricing Pricings
+	json.Unmarshal([]byte(`{"prices":[
+		{"instrument":"EUR_USD","closeoutBid":"1.10000","closeoutAsk":"1.10010"},
+		{"instrument":"USD_JPY","closeoutBid":"149.990","closeoutAsk":"150.000","quoteHomeConversionFactors":{"negativeUnits":"0.0066"}}
+	]}`), &pricing)
+
+	projections, err := m.Project(positions, pricing)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(projections) != 2 {
+		t.Fatalf("Expected 2 projections, got %d", len(projections))
+	}
+	if math.Abs(projections[0].CloseoutPrice-1.02) > 1e-9 {
+		t.Errorf("Expected the long EUR_USD position to close out at 1.02, got %v", projections[0].CloseoutPrice)
+	}
+	expected := 150 + 8000/(50000*0.0066)
+	if math.Abs(projections[1].CloseoutPrice-expected) > 1e-9 {
+		t.Errorf("Expected the short USD_JPY position to close out at %v, got %v", expected, projections[1].CloseoutPrice)
+	}
+}
+
+func TestMarginWatchEscalates(t *testing.T) {
+	defer logTestResult(t, "MarginWatchEscalates")
+
+	var alerted []int
+	w := &MarginWatch{
+		Thresholds: []float64{0.5, 0.75, 0.9},
+		OnAlert:    func(a MarginAlert) { alerted = append(alerted, a.Level) },
+	}
+
+	for _, percent := range []float64{0.3, 0.6, 0.65, 0.95, 0.8, 0.92, 0.2, 0.55} {
+		w.Observe(MarginCloseout{NAV: 1000, Percent: percent}, nil)
+	}
+
+	expected := []int{1, 2, 3, 3, 1}
+	if len(alerted) != len(expected) {
+		t.Fatalf("Expected alerts %v, got %v", expected, alerted)
+	}
+	for i := range expected {
+		if alerted[i] != expected[i] {
+			t.Fatalf("Expected alerts %v, got %v", expected, alerted)
+		}
+	}
+}
+
+func TestCheckMargin(t *testing.T) {
+	defer logTestResult(t, "CheckMargin")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/summary":
+			w.Write([]byte(`{"account":{"balance":"1000","marginAvailable":"0","marginCloseoutNAV":"1000","marginCloseoutPercent":"0.8","openPositionCount":1}}`))
+		case "/accounts/test-account/openPositions":
+			w.Write([]byte(`{"positions":[{"instrument":"EUR_USD","long":{"units":"10000"},"short":{"units":"0"}}]}`))
+		case "/accounts/test-account/pricing":
+			w.Write([]byte(`{"prices":[{"instrument":"EUR_USD","closeoutBid":"1.10000","closeoutAsk":"1.10010","quoteHomeConversionFactors":{"positiveUnits":"1","negativeUnits":"1"}}]}`))
+		default:
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	alerts, err := c.CheckMargin(&MarginWatch{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("Expected the 50%% and 75%% thresholds to alert, got %d alerts", len(alerts))
+	}
+	projections := alerts[1].Projections
+	if len(projections) != 1 || math.Abs(projections[0].CloseoutPrice-1.08) > 1e-9 {
+		t.Errorf("Expected EUR_USD to close out at 1.08, got %+v", projections)
+	}
+}
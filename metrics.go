@@ -0,0 +1,377 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics are the counters a connection keeps about its requests and streams
+type Metrics struct {
+	Requests    uint64
+	Errors      uint64
+	RateLimited uint64
+
+	StreamsOpened    uint64
+	StreamsActive    int64
+	StreamReconnects uint64
+	StreamMessages   uint64
+	StreamHeartbeats uint64
+	StreamSkipped    uint64
+	StreamDropped    uint64
+	LastHeartbeat    time.Time
+
+	// StreamLatency is the latency of the last stream message, how long after its server time it was
+	// received, and StreamLatencyMean and StreamLatencyMax are over every message since the connection
+	// was created. A rising latency is a sign of a degraded feed.
+	StreamLatency     time.Duration
+	StreamLatencyMean time.Duration
+	StreamLatencyMax  time.Duration
+}
+
+// connMetrics holds a connection's counters, its zero value is ready to use
+type connMetrics struct {
+	requests         atomic.Uint64
+	errors           atomic.Uint64
+	rateLimited      atomic.Uint64
+	streamsOpened    atomic.Uint64
+	streamsActive    atomic.Int64
+	streamReconnects atomic.Uint64
+	streamMessages   atomic.Uint64
+	streamHeartbeats atomic.Uint64
+	streamSkipped    atomic.Uint64
+	streamDropped    atomic.Uint64
+	lastHeartbeat    atomic.Int64
+	latency          atomic.Int64
+	latencySum       atomic.Int64
+	latencyCount     atomic.Int64
+	latencyMax       atomic.Int64
+
+	streamsMu sync.Mutex
+	streams   map[*streamStat]struct{}
+}
+
+// Metrics returns the connection's counters, including those of its streaming connections
+func (c *Connection) Metrics() Metrics {
+	m := &c.metrics
+	metrics := Metrics{
+		Requests:         m.requests.Load(),
+		Errors:           m.errors.Load(),
+		RateLimited:      m.rateLimited.Load(),
+		StreamsOpened:    m.streamsOpened.Load(),
+		StreamsActive:    m.streamsActive.Load(),
+		StreamReconnects: m.streamReconnects.Load(),
+		StreamMessages:   m.streamMessages.Load(),
+		StreamHeartbeats: m.streamHeartbeats.Load(),
+		StreamSkipped:    m.streamSkipped.Load(),
+		StreamDropped:    m.streamDropped.Load(),
+	}
+	if last := m.lastHeartbeat.Load(); last != 0 {
+		metrics.LastHeartbeat = time.Unix(0, last)
+	}
+	if count := m.latencyCount.Load(); count != 0 {
+		metrics.StreamLatency = time.Duration(m.latency.Load())
+		metrics.StreamLatencyMean = time.Duration(m.latencySum.Load() / count)
+		metrics.StreamLatencyMax = time.Duration(m.latencyMax.Load())
+	}
+	return metrics
+}
+
+// observeLatency records the latency of a stream message
+func (m *connMetrics) observeLatency(latency time.Duration) {
+	m.latency.Store(int64(latency))
+	m.latencySum.Add(int64(latency))
+	m.latencyCount.Add(1)
+	for {
+		current := m.latencyMax.Load()
+		if int64(latency) <= current || m.latencyMax.CompareAndSwap(current, int64(latency)) {
+			return
+		}
+	}
+}
+
+// StreamStats are the counters of a single running stream. Reconnects of a stream count towards the
+// same StreamStats, it is removed once the stream returns.
+type StreamStats struct {
+	URL        string
+	Opened     time.Time
+	Messages   uint64
+	Heartbeats uint64
+	// Skipped is the messages that could not be read or decoded
+	Skipped    uint64
+	Reconnects uint64
+	// MessagesPerSecond is the rate over the last one second window
+	MessagesPerSecond float64
+	// LastHeartbeat is zero until the stream receives its first heartbeat
+	LastHeartbeat time.Time
+}
+
+// SinceHeartbeat returns how long the stream has gone without a heartbeat, counted from when it
+// was opened if it has not received one
+func (s StreamStats) SinceHeartbeat(now time.Time) time.Duration {
+	if s.LastHeartbeat.IsZero() {
+		return now.Sub(s.Opened)
+	}
+	return now.Sub(s.LastHeartbeat)
+}
+
+// streamStat accounts for a running stream
+type streamStat struct {
+	url    string
+	opened time.Time
+
+	messages      atomic.Uint64
+	heartbeats    atomic.Uint64
+	skipped       atomic.Uint64
+	reconnects    atomic.Uint64
+	lastHeartbeat atomic.Int64
+
+	mu          sync.Mutex
+	window      time.Time
+	windowCount int
+	rate        float64
+}
+
+// message counts a message received at now, rolling the one second rate window
+func (s *streamStat) message(now time.Time) {
+	s.messages.Add(1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elapsed := now.Sub(s.window); elapsed >= time.Second {
+		s.rate = float64(s.windowCount) / elapsed.Seconds()
+		s.window = now
+		s.windowCount = 0
+	}
+	s.windowCount++
+}
+
+func (s *streamStat) heartbeat(now time.Time) {
+	s.heartbeats.Add(1)
+	s.lastHeartbeat.Store(now.UnixNano())
+}
+
+func (s *streamStat) stats(now time.Time) StreamStats {
+	stats := StreamStats{
+		URL:        s.url,
+		Opened:     s.opened,
+		Messages:   s.messages.Load(),
+		Heartbeats: s.heartbeats.Load(),
+		Skipped:    s.skipped.Load(),
+		Reconnects: s.reconnects.Load(),
+	}
+	if last := s.lastHeartbeat.Load(); last != 0 {
+		stats.LastHeartbeat = time.Unix(0, last)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats.MessagesPerSecond = s.rate
+	// A window left open for over a second has gone quiet, its own rate is the current one
+	if elapsed := now.Sub(s.window); elapsed >= time.Second {
+		stats.MessagesPerSecond = float64(s.windowCount) / elapsed.Seconds()
+	}
+	return stats
+}
+
+// openStream starts accounting for a stream
+func (m *connMetrics) openStream(url string, now time.Time) *streamStat {
+	s := &streamStat{url: url, opened: now, window: now}
+	m.streamsMu.Lock()
+	defer m.streamsMu.Unlock()
+	if m.streams == nil {
+		m.streams = map[*streamStat]struct{}{}
+	}
+	m.streams[s] = struct{}{}
+	return s
+}
+
+func (m *connMetrics) closeStream(s *streamStat) {
+	m.streamsMu.Lock()
+	defer m.streamsMu.Unlock()
+	delete(m.streams, s)
+}
+
+// StreamStats returns the counters of each running stream, oldest first
+func (c *Connection) StreamStats() []StreamStats {
+	now := time.Now()
+	c.metrics.streamsMu.Lock()
+	stats := make([]StreamStats, 0, len(c.metrics.streams))
+	for s := range c.metrics.streams {
+		stats = append(stats, s.stats(now))
+	}
+	c.metrics.streamsMu.Unlock()
+
+	slices.SortFunc(stats, func(a, b StreamStats) int {
+		return a.Opened.Compare(b.Opened)
+	})
+	return stats
+}
+
+// MetricsRegistry binds a connection's metrics to a metrics system, see RegisterMetrics. Each metric is
+// given as a function reading its current value, so it maps onto prometheus.NewCounterFunc and
+// prometheus.NewGaugeFunc, or expvar.Func:
+//
+//	func (expvarRegistry) Gauge(name, help string, value func() float64) {
+//		expvar.Publish(name, expvar.Func(func() any { return value() }))
+//	}
+type MetricsRegistry interface {
+	Counter(name string, help string, value func() float64)
+	Gauge(name string, help string, value func() float64)
+}
+
+// RegisterMetrics registers the connection's metrics, including those of its streams, with a registry.
+// Per stream counters are available from StreamStats, for collectors that label them.
+func (c *Connection) RegisterMetrics(r MetricsRegistry) {
+	counter := func(name string, help string, value func(Metrics) uint64) {
+		r.Counter(name, help, func() float64 { return float64(value(c.Metrics())) })
+	}
+	counter("goanda_requests_total", "REST requests made", func(m Metrics) uint64 { return m.Requests })
+	counter("goanda_request_errors_total", "REST requests that failed", func(m Metrics) uint64 { return m.Errors })
+	counter("goanda_rate_limited_total", "REST requests rejected by the rate limit", func(m Metrics) uint64 { return m.RateLimited })
+	counter("goanda_streams_opened_total", "stream connections opened", func(m Metrics) uint64 { return m.StreamsOpened })
+	counter("goanda_stream_reconnects_total", "stream reconnections", func(m Metrics) uint64 { return m.StreamReconnects })
+	counter("goanda_stream_messages_total", "stream messages received", func(m Metrics) uint64 { return m.StreamMessages })
+	counter("goanda_stream_heartbeats_total", "stream heartbeats received", func(m Metrics) uint64 { return m.StreamHeartbeats })
+	counter("goanda_stream_decode_failures_total", "stream messages skipped as unreadable", func(m Metrics) uint64 { return m.StreamSkipped })
+	counter("goanda_stream_dropped_total", "stream messages dropped by backpressure", func(m Metrics) uint64 { return m.StreamDropped })
+
+	r.Gauge("goanda_streams_active", "streams running", func() float64 {
+		return float64(c.Metrics().StreamsActive)
+	})
+	r.Gauge("goanda_stream_latency_seconds", "latency of the last stream message", func() float64 {
+		return c.Metrics().StreamLatency.Seconds()
+	})
+	r.Gauge("goanda_stream_messages_per_second", "messages a second across the running streams", func() float64 {
+		rate := 0.0
+		for _, s := range c.StreamStats() {
+			rate += s.MessagesPerSecond
+		}
+		return rate
+	})
+	r.Gauge("goanda_stream_heartbeat_age_seconds", "longest time any running stream has gone without a heartbeat", func() float64 {
+		now := time.Now()
+		age := time.Duration(0)
+		for _, s := range c.StreamStats() {
+			age = max(age, s.SinceHeartbeat(now))
+		}
+		return age.Seconds()
+	})
+}
+
+// StateStore persists small documents, such as the snapshot written when a connection shuts down
+type StateStore interface {
+	Save(key string, value []byte) error
+}
+
+// DirStateStore is a StateStore that writes each key to a file in a directory
+type DirStateStore string
+
+// Save writes the value to the key's file, replacing it atomically. The file is synced before it
+// replaces the old one, so a crash leaves one or the other.
+func (d DirStateStore) Save(key string, value []byte) error {
+	if err := os.MkdirAll(string(d), 0o755); err != nil {
+		return err
+	}
+	name := d.file(key)
+	tmp := name + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(value)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// Load reads the key's file, returning nil if it was never saved
+func (d DirStateStore) Load(key string) ([]byte, error) {
+	value, err := os.ReadFile(d.file(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (d DirStateStore) file(key string) string {
+	return filepath.Join(string(d), strings.NewReplacer("/", "_", "\\", "_").Replace(key)+".json")
+}
+
+// ShutdownSnapshot is the final record of a connection, written to its StateStore and log when it shuts down
+// so that there is data from the last healthy run to look at after a crash loop
+type ShutdownSnapshot struct {
+	AccountID string
+	Time      time.Time
+	Uptime    time.Duration
+	Metrics   Metrics
+	// InflightRequests and Workers are the requests and background goroutines still running when
+	// shutdown began
+	InflightRequests int
+	Workers          int
+	CredentialsValid bool
+	// Drained reports whether everything finished before the shutdown deadline
+	Drained bool
+	// State holds the summaries registered with RegisterShutdownState
+	State map[string]interface{} `json:",omitempty"`
+}
+
+// shutdownStates are the summaries included in a connection's shutdown snapshot
+type shutdownStates struct {
+	mu    sync.Mutex
+	funcs map[string]func() interface{}
+}
+
+// RegisterShutdownState adds a summary of open state, such as positions or pending orders, to the snapshot
+// written when the connection shuts down. fn is called once, during shutdown.
+func (c *Connection) RegisterShutdownState(name string, fn func() interface{}) {
+	s := &c.shutdownStates
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.funcs == nil {
+		s.funcs = map[string]func() interface{}{}
+	}
+	s.funcs[name] = fn
+}
+
+// writeShutdownSnapshot logs the snapshot and saves it to the state store, if there is one
+func (c *Connection) writeShutdownSnapshot(snapshot ShutdownSnapshot) {
+	c.shutdownStates.mu.Lock()
+	for name, fn := range c.shutdownStates.funcs {
+		if snapshot.State == nil {
+			snapshot.State = map[string]interface{}{}
+		}
+		snapshot.State[name] = fn()
+	}
+	c.shutdownStates.mu.Unlock()
+
+	m := snapshot.Metrics
+	c.logf("goanda: shutdown after %v: %d requests, %d errors, %d rate limited; %d streams opened, %d reconnects, %d messages, %d skipped; drained=%v",
+		snapshot.Uptime.Round(time.Second), m.Requests, m.Errors, m.RateLimited,
+		m.StreamsOpened, m.StreamReconnects, m.StreamMessages, m.StreamSkipped, snapshot.Drained)
+
+	if c.stateStore == nil {
+		return
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err == nil {
+		err = c.stateStore.Save("goanda/shutdown/"+c.accountID, data)
+	}
+	if err != nil {
+		c.logf("goanda: saving the shutdown snapshot: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package goanda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdownSnapshot(t *testing.T) {
+	defer logTestResult(t, "ShutdownSnapshot")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var logs bytes.Buffer
+	c := &Connection{
+		hostname:   server.URL,
+		accountID:  "test-account",
+		client:     *server.Client(),
+		logger:     log.New(&logs, "", 0),
+		stateStore: DirStateStore(dir),
+	}
+	c.RegisterShutdownState("positions", func() interface{} {
+		return map[string]string{"EUR_USD": "100"}
+	})
+
+	for _, endpoint := range []string{"/accounts", "/limited", "/broken"} {
+		c.Get(endpoint)
+	}
+	if m := c.Metrics(); m.Requests != 3 || m.Errors != 2 || m.RateLimited != 1 {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(logs.String(), "goanda: shutdown after") {
+		t.Errorf("Expected the snapshot to be logged, got %q", logs.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "goanda_shutdown_test-account.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var snapshot ShutdownSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if snapshot.AccountID != "test-account" || !snapshot.Drained || snapshot.Metrics.Requests != 3 {
+		t.Errorf("Unexpected snapshot: %+v", snapshot)
+	}
+	if positions, ok := snapshot.State["positions"].(map[string]interface{}); !ok || positions["EUR_USD"] != "100" {
+		t.Errorf("Unexpected state: %+v", snapshot.State)
+	}
+
+	// Only the first shutdown is recorded
+	os.Remove(filepath.Join(dir, "goanda_shutdown_test-account.json"))
+	c.Shutdown(context.Background())
+	if _, err := os.Stat(filepath.Join(dir, "goanda_shutdown_test-account.json")); err == nil {
+		t.Error("Expected a second shutdown not to write a snapshot")
+	}
+}
+
+func TestStreamMetrics(t *testing.T) {
+	defer logTestResult(t, "StreamMetrics")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"type\":\"HEARTBEAT\",\"time\":\"2024-01-02T15:04:05Z\"}\n"))
+		w.Write([]byte("not json\n"))
+		w.Write([]byte("{\"type\":\"PRICE\",\"instrument\":\"EUR_USD\"}\n"))
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) {})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	m := sc.Metrics()
+	if m.StreamsOpened != 1 || m.StreamsActive != 0 || m.StreamHeartbeats != 1 || m.StreamMessages != 2 ||
+		m.StreamSkipped != 1 || m.LastHeartbeat.IsZero() {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+}
+
+func TestStreamLatency(t *testing.T) {
+	defer logTestResult(t, "StreamLatency")
+
+	sent := time.Now().Add(-2 * time.Second).UTC().Format(time.RFC3339Nano)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"PRICE","instrument":"EUR_USD","time":"` + sent + `"}` + "\n"))
+		w.Write([]byte(`{"type":"PRICE","instrument":"EUR_USD"}` + "\n"))
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	var latencies []time.Duration
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(price PricingStreamResponse) {
+		latencies = append(latencies, price.Latency)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(latencies) != 2 || latencies[0] < 2*time.Second || latencies[0] > time.Minute || latencies[1] != 0 {
+		t.Fatalf("Unexpected latencies: %v", latencies)
+	}
+
+	m := sc.Metrics()
+	if m.StreamLatency != latencies[0] || m.StreamLatencyMean != latencies[0] || m.StreamLatencyMax != latencies[0] {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+}
+
+type testRegistry map[string]func() float64
+
+func (r testRegistry) Counter(name string, help string, value func() float64) { r[name] = value }
+func (r testRegistry) Gauge(name string, help string, value func() float64)   { r[name] = value }
+
+func TestStreamStats(t *testing.T) {
+	defer logTestResult(t, "StreamStats")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"type\":\"HEARTBEAT\",\"time\":\"2024-01-02T15:04:05Z\"}\n"))
+		w.Write([]byte("not json\n"))
+		w.Write([]byte("{\"type\":\"PRICE\",\"instrument\":\"EUR_USD\"}\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+	registry := testRegistry{}
+	sc.RegisterMetrics(registry)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- sc.StreamPrices(ctx, []string{"EUR_USD"}, func(PricingStreamResponse) { close(received) })
+	}()
+	<-received
+
+	stats := sc.StreamStats()
+	if len(stats) != 1 || stats[0].Messages != 2 || stats[0].Heartbeats != 1 || stats[0].Skipped != 1 ||
+		!strings.Contains(stats[0].URL, "/pricing/stream") || stats[0].LastHeartbeat.IsZero() {
+		t.Errorf("Unexpected stream stats: %+v", stats)
+	}
+	if registry["goanda_stream_decode_failures_total"]() != 1 || registry["goanda_streams_active"]() != 1 {
+		t.Errorf("Unexpected registered metrics")
+	}
+	if age := registry["goanda_stream_heartbeat_age_seconds"](); age < 0 || age > 60 {
+		t.Errorf("Unexpected heartbeat age: %v", age)
+	}
+
+	cancel()
+	<-done
+	if stats := sc.StreamStats(); len(stats) != 0 {
+		t.Errorf("Expected no running streams, got %+v", stats)
+	}
+}
+
+func TestStreamStatsRate(t *testing.T) {
+	defer logTestResult(t, "StreamStatsRate")
+
+	start := time.Now()
+	s := &streamStat{opened: start, window: start}
+	for i := 0; i < 10; i++ {
+		s.message(start.Add(time.Duration(i) * 100 * time.Millisecond))
+	}
+	s.message(start.Add(time.Second))
+	if rate := s.stats(start.Add(1500 * time.Millisecond)).MessagesPerSecond; rate != 10 {
+		t.Errorf("Expected 10 messages a second, got %v", rate)
+	}
+	// Once quiet the rate falls
+	if rate := s.stats(start.Add(5 * time.Second)).MessagesPerSecond; rate != 0.25 {
+		t.Errorf("Expected 0.25 messages a second, got %v", rate)
+	}
+}
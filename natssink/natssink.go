@@ -0,0 +1,52 @@
+// Package natssink publishes goanda stream events to NATS, see goanda.EventPublisher:
+//
+//	nc, _ := nats.Connect(nats.DefaultURL)
+//	publisher := goanda.EventPublisher{Sink: natssink.New(nc)}
+//	publisher.Publish(ctx, sc.Events(ctx, goanda.EventsOptions{Instruments: instruments}))
+//
+// NATS has no message keys, so the key is appended to the subject: prices for EUR_USD are published
+// to goanda.prices.EUR_USD, and subscribers choose instruments with subjects such as goanda.prices.*
+package natssink
+
+import (
+	"context"
+	"strings"
+)
+
+// Publisher publishes messages to NATS subjects, it is satisfied by *nats.Conn
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// Sink is a goanda.Sink publishing each message to NATS
+type Sink struct {
+	// Subject returns the subject a message is published to, it defaults to Subject
+	Subject func(topic string, key string) string
+
+	conn Publisher
+}
+
+// New creates a sink publishing with the connection
+func New(conn Publisher) *Sink {
+	return &Sink{conn: conn}
+}
+
+// Subject appends the key to the topic as a subject token, replacing the characters subjects can't hold
+func Subject(topic string, key string) string {
+	if key == "" {
+		return topic
+	}
+	return topic + "." + strings.NewReplacer(".", "_", " ", "_", "*", "_", ">", "_").Replace(key)
+}
+
+// Publish publishes a message to the subject of its topic and key
+func (s *Sink) Publish(ctx context.Context, topic string, key string, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	subject := s.Subject
+	if subject == nil {
+		subject = Subject
+	}
+	return s.conn.Publish(subject(topic, key), value)
+}
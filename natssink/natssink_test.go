@@ -0,0 +1,51 @@
+package natssink
+
+import (
+	"context"
+	"testing"
+)
+
+type published struct {
+	subject string
+	data    string
+}
+
+type fakeConn struct {
+	messages []published
+}
+
+func (c *fakeConn) Publish(subject string, data []byte) error {
+	c.messages = append(c.messages, published{subject, string(data)})
+	return nil
+}
+
+func TestSink(t *testing.T) {
+	conn := &fakeConn{}
+	sink := New(conn)
+
+	sink.Publish(context.Background(), "goanda.prices", "EUR_USD", []byte(`{}`))
+	sink.Publish(context.Background(), "goanda.transactions", "101-001.2", []byte(`{}`))
+	sink.Publish(context.Background(), "goanda.prices", "", []byte(`{}`))
+
+	want := []string{"goanda.prices.EUR_USD", "goanda.transactions.101-001_2", "goanda.prices"}
+	if len(conn.messages) != len(want) {
+		t.Fatalf("Expected %d messages, got %+v", len(want), conn.messages)
+	}
+	for i, subject := range want {
+		if conn.messages[i].subject != subject || conn.messages[i].data != `{}` {
+			t.Errorf("Expected message %d on %s, got %+v", i, subject, conn.messages[i])
+		}
+	}
+
+	sink.Subject = func(topic, key string) string { return topic }
+	sink.Publish(context.Background(), "goanda.prices", "EUR_USD", []byte(`{}`))
+	if last := conn.messages[len(conn.messages)-1]; last.subject != "goanda.prices" {
+		t.Errorf("Expected the custom subject, got %s", last.subject)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sink.Publish(ctx, "goanda.prices", "EUR_USD", nil); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,392 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// OCOStore persists an OCOManager's pairs so that they survive a restart, DirStateStore is one
+type OCOStore interface {
+	StateStore
+	// Load returns the key's value, nil if it was never saved
+	Load(key string) ([]byte, error)
+}
+
+// OCOPair is two pending orders of which only one may fill
+type OCOPair struct {
+	ID   string    `json:"id"`
+	Legs [2]OCOLeg `json:"legs"`
+	// Triggered is the client ID of the leg that filled or was cancelled, empty while both are pending
+	Triggered string `json:"triggered,omitempty"`
+}
+
+// OCOLeg is one of the orders of an OCOPair
+type OCOLeg struct {
+	// ClientID is the order's client extension ID, which the leg is placed with
+	ClientID string `json:"clientID"`
+	// OrderID is the order's ID once it is known, the replacement's if the order was replaced
+	OrderID string `json:"orderID,omitempty"`
+}
+
+// OCOManager links pairs of pending orders so that when one fills, or is cancelled, the other is
+// cancelled, which OANDA can't do for entry orders. The pairs are saved to its store before their orders
+// are placed and whenever they change, and are reconciled against the account's pending orders when it
+// runs, so none is lost to a crash. It is safe for concurrent use.
+type OCOManager struct {
+	// OnResolved, if set, is called when a pair's sibling has been cancelled and it is no longer managed
+	OnResolved func(OCOPair)
+
+	c     *Connection
+	store OCOStore
+	key   string
+
+	mu        sync.Mutex
+	pairs     map[string]*OCOPair
+	placing   map[string]bool
+	resolving map[string]bool
+	resolved  []OCOPair
+}
+
+// NewOCOManager creates a manager for the connection's account, loading the pairs saved in store
+func NewOCOManager(c *Connection, store OCOStore) (*OCOManager, error) {
+	m := &OCOManager{
+		c:         c,
+		store:     store,
+		key:       "oco-" + c.accountID,
+		pairs:     map[string]*OCOPair{},
+		placing:   map[string]bool{},
+		resolving: map[string]bool{},
+	}
+
+	saved, err := store.Load(m.key)
+	if err != nil {
+		return nil, fmt.Errorf("goanda: loading oco pairs: %w", err)
+	}
+	if saved == nil {
+		return m, nil
+	}
+	var pairs []OCOPair
+	if err := json.Unmarshal(saved, &pairs); err != nil {
+		return nil, fmt.Errorf("goanda: loading oco pairs: %w", err)
+	}
+	for i := range pairs {
+		m.pairs[pairs[i].ID] = &pairs[i]
+	}
+	return m, nil
+}
+
+// Place creates the two pending orders as a pair, tagging each with a client ID unless it has one.
+// If the second order can't be created the first is cancelled. The pair is saved before either order
+// is sent, and nothing is sent if it can't be.
+func (m *OCOManager) Place(a OrderPayload, b OrderPayload) (OCOPair, error) {
+	pair := &OCOPair{ID: NewClientID()}
+	orders := [2]OrderPayload{a, b}
+	for i := range orders {
+		extensions := OrderExtensions{}
+		if orders[i].Order.ClientExtensions != nil {
+			extensions = *orders[i].Order.ClientExtensions
+		}
+		if extensions.ID == "" {
+			extensions.ID = NewClientID()
+		}
+		orders[i].Order.ClientExtensions = &extensions
+		pair.Legs[i].ClientID = extensions.ID
+	}
+
+	m.mu.Lock()
+	m.pairs[pair.ID] = pair
+	m.placing[pair.ID] = true
+	if err := m.save(); err != nil {
+		delete(m.pairs, pair.ID)
+		delete(m.placing, pair.ID)
+		m.unlock()
+		return OCOPair{}, err
+	}
+	m.unlock()
+
+	for i, order := range orders {
+		created, err := m.c.CreateOrderIdempotent(order)
+
+		m.mu.Lock()
+		if err != nil {
+			err = fmt.Errorf("goanda: placing oco order %s: %w", pair.Legs[i].ClientID, err)
+			delete(m.placing, pair.ID)
+			if i == 0 {
+				delete(m.pairs, pair.ID)
+				err = errors.Join(err, m.save())
+			} else {
+				if pair.Triggered == "" {
+					pair.Triggered = pair.Legs[i].ClientID
+				}
+				err = errors.Join(err, m.resolve(pair))
+			}
+			m.unlock()
+			return OCOPair{}, err
+		}
+		pair.Legs[i].OrderID = created.OrderCreateTransaction.ID
+		if created.OrderFillTransaction.ID != "" && pair.Triggered == "" {
+			pair.Triggered = pair.Legs[i].ClientID
+		}
+		m.unlock()
+	}
+
+	m.mu.Lock()
+	defer m.unlock()
+
+	delete(m.placing, pair.ID)
+	placed := *pair
+	if pair.Triggered != "" {
+		return placed, m.resolve(pair)
+	}
+	return placed, m.save()
+}
+
+// Pairs returns the pairs being managed, ordered by ID
+func (m *OCOManager) Pairs() []OCOPair {
+	m.mu.Lock()
+	defer m.unlock()
+
+	pairs := make([]OCOPair, 0, len(m.pairs))
+	for _, pair := range m.pairs {
+		pairs = append(pairs, *pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].ID < pairs[j].ID
+	})
+	return pairs
+}
+
+// Run reconciles the pairs with the account's pending orders and then handles the account's transactions
+// from sc, from the point the orders were listed, until ctx is done or the stream fails
+func (m *OCOManager) Run(ctx context.Context, sc *StreamingConnection) error {
+	last, err := m.reconcile()
+	if last == "" {
+		return err
+	}
+	if err != nil {
+		m.c.logf("goanda: reconciling oco pairs: %v", err)
+	}
+
+	return sc.StreamTransactionsSince(ctx, last, func(response TransactionStreamResponse) {
+		t, err := response.Decode()
+		if err != nil {
+			// heartbeats have no transaction
+			return
+		}
+		if err := m.Handle(t); err != nil {
+			m.c.logf("goanda: handling transaction %s for oco pairs: %v", t.Header().ID, err)
+		}
+	})
+}
+
+// Reconcile cancels the sibling of every pair with a leg that is no longer pending, as after fills or
+// cancellations missed while the manager wasn't running. Pairs being placed are left alone.
+func (m *OCOManager) Reconcile() error {
+	_, err := m.reconcile()
+	return err
+}
+
+// reconcile returns the account's last transaction ID when its pending orders were listed, or "" if
+// they couldn't be
+func (m *OCOManager) reconcile() (string, error) {
+	pending, err := m.c.GetPendingOrders()
+	if err != nil {
+		return "", fmt.Errorf("goanda: listing pending orders: %w", err)
+	}
+	byClientID := map[string]OrderInfo{}
+	byID := map[string]OrderInfo{}
+	for _, order := range pending.Orders {
+		byID[order.ID] = order
+		if order.ClientExtensions != nil && order.ClientExtensions.ID != "" {
+			byClientID[order.ClientExtensions.ID] = order
+		}
+	}
+
+	m.mu.Lock()
+	defer m.unlock()
+
+	var triggered []*OCOPair
+	for _, pair := range m.pairs {
+		if m.placing[pair.ID] {
+			continue
+		}
+		for i, leg := range pair.Legs {
+			order, ok := byID[leg.OrderID]
+			if !ok {
+				order, ok = byClientID[leg.ClientID]
+			}
+			if ok {
+				pair.Legs[i].OrderID = order.ID
+			} else if pair.Triggered == "" {
+				pair.Triggered = leg.ClientID
+			}
+		}
+		if pair.Triggered != "" {
+			triggered = append(triggered, pair)
+		}
+	}
+	var errs []error
+	for _, pair := range triggered {
+		errs = append(errs, m.resolve(pair))
+	}
+	errs = append(errs, m.save())
+	return pending.LastTransactionID, errors.Join(errs...)
+}
+
+// Handle cancels the sibling of a leg the transaction fills or cancels, and follows a leg that is replaced
+func (m *OCOManager) Handle(t TypedTransaction) error {
+	var orderID, clientID string
+	var replacedBy string
+	switch t := t.(type) {
+	case *OrderFillTransaction:
+		orderID, clientID = t.OrderID, t.ClientOrderID
+	case *OrderCancelTransaction:
+		orderID, clientID = t.OrderID, t.ClientOrderID
+		replacedBy = t.ReplacedByOrderID
+	default:
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.unlock()
+
+	pair, leg := m.find(orderID, clientID)
+	if pair == nil {
+		return nil
+	}
+	if replacedBy != "" {
+		pair.Legs[leg].OrderID = replacedBy
+		return m.save()
+	}
+	if pair.Triggered != "" {
+		return nil
+	}
+	pair.Triggered = pair.Legs[leg].ClientID
+	if m.placing[pair.ID] {
+		// Place cancels the sibling once it has been created
+		return m.save()
+	}
+	return m.resolve(pair)
+}
+
+// find returns the pair with a leg for the order and the leg's index
+func (m *OCOManager) find(orderID string, clientID string) (*OCOPair, int) {
+	for _, pair := range m.pairs {
+		for i, leg := range pair.Legs {
+			if (orderID != "" && leg.OrderID == orderID) || (clientID != "" && leg.ClientID == clientID) {
+				return pair, i
+			}
+		}
+	}
+	return nil, 0
+}
+
+// resolve cancels the legs of a triggered pair other than the one that triggered it and stops managing
+// it. If a cancellation fails the pair is kept, so that it is retried when the manager next reconciles.
+// The caller holds mu, which is released while the orders are cancelled so that the manager isn't held
+// up by the requests. A pair already being resolved, or resolved since, is left alone.
+func (m *OCOManager) resolve(pair *OCOPair) error {
+	if m.resolving[pair.ID] || m.pairs[pair.ID] != pair {
+		return nil
+	}
+	siblings := siblingOrders(*pair)
+	m.resolving[pair.ID] = true
+	m.mu.Unlock()
+
+	var errs []error
+	for _, specifier := range siblings {
+		_, err := m.c.CancelOrder(specifier)
+		if notFound(err) {
+			// filled, cancelled or never created
+			err = nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("goanda: cancelling oco order %s: %w", specifier, err))
+		}
+	}
+
+	m.mu.Lock()
+	delete(m.resolving, pair.ID)
+	if len(errs) == 0 && !slices.Equal(siblings, siblingOrders(*pair)) {
+		errs = append(errs, fmt.Errorf("goanda: a leg of oco pair %s was replaced while it was being cancelled", pair.ID))
+	}
+	if len(errs) > 0 {
+		errs = append(errs, m.save())
+		return errors.Join(errs...)
+	}
+
+	delete(m.pairs, pair.ID)
+	if err := m.save(); err != nil {
+		return err
+	}
+	m.resolved = append(m.resolved, *pair)
+	return nil
+}
+
+// siblingOrders returns the order specifiers of the legs of a triggered pair but the one that triggered it
+func siblingOrders(pair OCOPair) []string {
+	var specifiers []string
+	for _, leg := range pair.Legs {
+		if leg.ClientID == pair.Triggered {
+			continue
+		}
+		specifier := leg.OrderID
+		if specifier == "" {
+			specifier = clientSpecifier(leg.ClientID)
+		}
+		specifiers = append(specifiers, specifier)
+	}
+	return specifiers
+}
+
+// ResetState stops managing the pairs, as after the account is reset, whose orders are gone, and saves
+// that to the store. Pairs being placed are kept, their orders being placed on the reset account.
+func (m *OCOManager) ResetState() error {
+	m.mu.Lock()
+	defer m.unlock()
+
+	for id := range m.pairs {
+		if !m.placing[id] {
+			delete(m.pairs, id)
+		}
+	}
+	return m.save()
+}
+
+// unlock unlocks mu and then passes the pairs resolved while it was held to OnResolved, so that
+// OnResolved may call the manager
+func (m *OCOManager) unlock() {
+	resolved := m.resolved
+	m.resolved = nil
+	m.mu.Unlock()
+
+	if m.OnResolved != nil {
+		for _, pair := range resolved {
+			m.OnResolved(pair)
+		}
+	}
+}
+
+// save writes the pairs to the store, the caller holds mu
+func (m *OCOManager) save() error {
+	pairs := make([]OCOPair, 0, len(m.pairs))
+	for _, pair := range m.pairs {
+		pairs = append(pairs, *pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].ID < pairs[j].ID
+	})
+	data, err := json.Marshal(pairs)
+	if err != nil {
+		return err
+	}
+	if err := m.store.Save(m.key, data); err != nil {
+		return fmt.Errorf("goanda: saving oco pairs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ocoBroker is a fake account holding pending orders, it rejects the order numbered rejectOrder
+type ocoBroker struct {
+	mu          sync.Mutex
+	next        int
+	pending     map[string]OrderInfo
+	cancelled   []string
+	rejectOrder int
+	url         string
+	// hold, if set, holds up cancellations until it is closed, held is sent to as each arrives
+	hold chan struct{}
+	held chan struct{}
+}
+
+func newOCOBroker(t *testing.T, rejectOrder int) (*ocoBroker, *Connection) {
+	b := &ocoBroker{next: 100, pending: map[string]OrderInfo{}, rejectOrder: rejectOrder}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		hold, held := b.hold, b.held
+		b.mu.Unlock()
+		if hold != nil && r.Method == http.MethodPut {
+			held <- struct{}{}
+			<-hold
+		}
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		path := strings.TrimPrefix(r.URL.Path, "/accounts/test-account")
+		switch {
+		case r.Method == http.MethodPost && path == "/orders":
+			b.next++
+			if b.next-100 == b.rejectOrder {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"errorMessage":"insufficient margin"}`))
+				return
+			}
+			var payload OrderPayload
+			json.NewDecoder(r.Body).Decode(&payload)
+			id := strconv.Itoa(b.next)
+			b.pending[id] = OrderInfo{ID: id, State: "PENDING", ClientExtensions: payload.Order.ClientExtensions}
+			fmt.Fprintf(w, `{"orderCreateTransaction":{"id":%q,"type":"LIMIT_ORDER"},"lastTransactionID":%q}`, id, id)
+		case r.Method == http.MethodPut && strings.HasSuffix(path, "/cancel"):
+			id := strings.TrimSuffix(strings.TrimPrefix(path, "/orders/"), "/cancel")
+			if strings.HasPrefix(id, "@") {
+				for _, order := range b.pending {
+					if "@"+order.ClientExtensions.ID == id {
+						id = order.ID
+					}
+				}
+			}
+			if _, ok := b.pending[id]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"errorCode":"ORDER_DOESNT_EXIST"}`))
+				return
+			}
+			delete(b.pending, id)
+			b.cancelled = append(b.cancelled, id)
+			fmt.Fprintf(w, `{"orderCancelTransaction":{"id":"900","type":"ORDER_CANCEL","orderID":%q}}`, id)
+		case r.Method == http.MethodGet && path == "/pendingOrders":
+			orders := []OrderInfo{}
+			for _, order := range b.pending {
+				orders = append(orders, order)
+			}
+			json.NewEncoder(w).Encode(RetrievedOrders{LastTransactionID: "500", Orders: orders})
+		case r.Method == http.MethodGet && path == "/transactions/sinceid":
+			w.Write([]byte(`{"transactions":[],"lastTransactionID":"500"}`))
+		case r.Method == http.MethodGet && path == "/transactions/stream":
+			w.Write([]byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05Z"}` + "\n"))
+			w.Write([]byte(`{"id":"501","type":"ORDER_FILL","time":"2024-01-02T15:04:06Z","orderID":"101"}` + "\n"))
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	b.url = server.URL
+	return b, &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+}
+
+// fill removes an order from the pending orders, as if it had filled
+func (b *ocoBroker) fill(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.pending, id)
+}
+
+func (b *ocoBroker) cancellations() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.cancelled...)
+}
+
+var (
+	ocoBuy  = OrderPayload{Order: OrderBody{Type: "STOP", Instrument: "EUR_USD", Units: 1000, Price: "1.0900"}}
+	ocoSell = OrderPayload{Order: OrderBody{Type: "STOP", Instrument: "EUR_USD", Units: -1000, Price: "1.0800"}}
+)
+
+func TestOCOManagerCancelsSiblingOnFill(t *testing.T) {
+	defer logTestResult(t, "OCOManagerCancelsSiblingOnFill")
+	broker, c := newOCOBroker(t, 0)
+	m, err := NewOCOManager(c, DirStateStore(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var resolved []OCOPair
+	m.OnResolved = func(pair OCOPair) {
+		resolved = append(resolved, pair)
+		if len(m.Pairs()) != 0 {
+			t.Error("Expected the resolved pair to no longer be managed")
+		}
+	}
+
+	pair, err := m.Place(ocoBuy, ocoSell)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pair.Legs[0].OrderID != "101" || pair.Legs[1].OrderID != "102" || pair.Legs[0].ClientID == "" {
+		t.Fatalf("Expected both legs to be placed, got %+v", pair)
+	}
+
+	broker.fill("101")
+	if err := m.Handle(&OrderFillTransaction{OrderID: "101", ClientOrderID: pair.Legs[0].ClientID}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancelled := broker.cancellations(); len(cancelled) != 1 || cancelled[0] != "102" {
+		t.Errorf("Expected the sell stop to be cancelled, got %v", cancelled)
+	}
+	if len(resolved) != 1 || resolved[0].Triggered != pair.Legs[0].ClientID {
+		t.Errorf("Expected the pair to be resolved by the buy stop, got %+v", resolved)
+	}
+
+	// the cancellation of the sibling is not mistaken for a trigger
+	if err := m.Handle(&OrderCancelTransaction{OrderID: "102", ClientOrderID: pair.Legs[1].ClientID}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(broker.cancellations()) != 1 {
+		t.Error("Expected no further cancellations")
+	}
+}
+
+func TestOCOManagerCancelsWithoutHoldingUp(t *testing.T) {
+	defer logTestResult(t, "OCOManagerCancelsWithoutHoldingUp")
+	broker, c := newOCOBroker(t, 0)
+	m, err := NewOCOManager(c, DirStateStore(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pair, err := m.Place(ocoBuy, ocoSell)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	broker.mu.Lock()
+	broker.hold, broker.held = make(chan struct{}), make(chan struct{})
+	broker.mu.Unlock()
+	broker.fill("101")
+	handled := make(chan error, 1)
+	go func() {
+		handled <- m.Handle(&OrderFillTransaction{OrderID: "101"})
+	}()
+	<-broker.held
+
+	// the manager answers while the sibling is being cancelled, and isn't resolved twice
+	answered := make(chan []OCOPair, 1)
+	go func() {
+		answered <- m.Pairs()
+	}()
+	select {
+	case pairs := <-answered:
+		if len(pairs) != 1 || pairs[0].Triggered != pair.Legs[0].ClientID {
+			t.Errorf("Expected the pair being resolved, got %+v", pairs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the manager not to be held up by the cancellation")
+	}
+	if err := m.Reconcile(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	close(broker.hold)
+	if err := <-handled; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancelled := broker.cancellations(); len(cancelled) != 1 || cancelled[0] != "102" || len(m.Pairs()) != 0 {
+		t.Errorf("Expected the sibling cancelled once and the pair resolved, got %v", cancelled)
+	}
+}
+
+func TestOCOManagerReconcilesAfterRestart(t *testing.T) {
+	defer logTestResult(t, "OCOManagerReconcilesAfterRestart")
+	broker, c := newOCOBroker(t, 0)
+	store := DirStateStore(t.TempDir())
+	m, err := NewOCOManager(c, store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pair, err := m.Place(ocoBuy, ocoSell)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the sell stop fills while nothing is running
+	broker.fill("102")
+
+	restarted, err := NewOCOManager(c, store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pairs := restarted.Pairs(); len(pairs) != 1 || pairs[0] != pair {
+		t.Fatalf("Expected the pair to be loaded, got %+v", pairs)
+	}
+	if err := restarted.Reconcile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancelled := broker.cancellations(); len(cancelled) != 1 || cancelled[0] != "101" {
+		t.Errorf("Expected the buy stop to be cancelled, got %v", cancelled)
+	}
+
+	again, err := NewOCOManager(c, store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pairs := again.Pairs(); len(pairs) != 0 {
+		t.Errorf("Expected the resolved pair to be removed from the store, got %+v", pairs)
+	}
+}
+
+func TestOCOManagerCancelsFirstLegWhenSecondFails(t *testing.T) {
+	defer logTestResult(t, "OCOManagerCancelsFirstLegWhenSecondFails")
+	broker, c := newOCOBroker(t, 2)
+	m, err := NewOCOManager(c, DirStateStore(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := m.Place(ocoBuy, ocoSell); err == nil {
+		t.Fatal("Expected the second leg's rejection")
+	}
+	if cancelled := broker.cancellations(); len(cancelled) != 1 || cancelled[0] != "101" {
+		t.Errorf("Expected the first leg to be cancelled, got %v", cancelled)
+	}
+	if pairs := m.Pairs(); len(pairs) != 0 {
+		t.Errorf("Expected no pairs, got %+v", pairs)
+	}
+}
+
+func TestOCOManagerFollowsReplacement(t *testing.T) {
+	defer logTestResult(t, "OCOManagerFollowsReplacement")
+	broker, c := newOCOBroker(t, 0)
+	m, err := NewOCOManager(c, DirStateStore(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := m.Place(ocoBuy, ocoSell); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := m.Handle(&OrderCancelTransaction{OrderID: "101", Reason: "CLIENT_REQUEST_REPLACED", ReplacedByOrderID: "150"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(broker.cancellations()) != 0 {
+		t.Fatal("Expected a replacement not to trigger the pair")
+	}
+	if err := m.Handle(&OrderFillTransaction{OrderID: "150"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancelled := broker.cancellations(); len(cancelled) != 1 || cancelled[0] != "102" {
+		t.Errorf("Expected the replacement's fill to cancel the sell stop, got %v", cancelled)
+	}
+}
+
+func TestOCOManagerRun(t *testing.T) {
+	defer logTestResult(t, "OCOManagerRun")
+	broker, c := newOCOBroker(t, 0)
+	m, err := NewOCOManager(c, DirStateStore(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := m.Place(ocoBuy, ocoSell); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sc := &StreamingConnection{Connection: c, streamURL: broker.url}
+	if err := m.Run(context.Background(), sc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancelled := broker.cancellations(); len(cancelled) != 1 || cancelled[0] != "102" {
+		t.Errorf("Expected the streamed fill to cancel the sell stop, got %v", cancelled)
+	}
+	if pairs := m.Pairs(); len(pairs) != 0 {
+		t.Errorf("Expected no pairs, got %+v", pairs)
+	}
+}
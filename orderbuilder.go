@@ -0,0 +1,395 @@
+package goanda
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// OrderBuilder builds an order a step at a time, checking it before it is sent so mistakes are caught
+// without a round trip to OANDA:
+//
+//	payload, err := goanda.NewLimitOrder("EUR_USD").
+//		Units(1000).
+//		Price("1.0850").
+//		GTD(time.Now().Add(4 * time.Hour)).
+//		StopLossPips(20).
+//		Precision(details).
+//		Build()
+//
+// Given the instrument's details with Precision, prices and distances are rounded to its display
+// precision and units to its trade units precision, and the units are checked against its limits.
+// Without them, pips are taken as the usual size for the instrument's quote currency.
+type OrderBuilder struct {
+	orderType  string
+	instrument string
+	units      float64
+	price      string
+	opts       OrderOptions
+	details    *InstrumentDetails
+	sizer      *PositionSizer
+
+	takeProfitPips         float64
+	stopLossPips           float64
+	trailingStopLossPips   float64
+	guaranteedStopLossPips float64
+}
+
+// NewMarketOrder starts building a market order
+func NewMarketOrder(instrument string) *OrderBuilder {
+	return &OrderBuilder{orderType: "MARKET", instrument: instrument}
+}
+
+// NewLimitOrder starts building a limit order
+func NewLimitOrder(instrument string) *OrderBuilder {
+	return &OrderBuilder{orderType: "LIMIT", instrument: instrument}
+}
+
+// NewStopOrder starts building a stop order
+func NewStopOrder(instrument string) *OrderBuilder {
+	return &OrderBuilder{orderType: "STOP", instrument: instrument}
+}
+
+// Units sets the units to trade, positive to buy and negative to sell
+func (b *OrderBuilder) Units(units float64) *OrderBuilder {
+	b.units = units
+	return b
+}
+
+// Price sets the price a limit or stop order is filled at
+func (b *OrderBuilder) Price(price string) *OrderBuilder {
+	b.price = price
+	return b
+}
+
+// PriceBound sets the worst price a market or stop order may fill at
+func (b *OrderBuilder) PriceBound(price string) *OrderBuilder {
+	b.opts.PriceBound = price
+	return b
+}
+
+// TimeInForce sets how long the order may remain unfilled
+func (b *OrderBuilder) TimeInForce(timeInForce string) *OrderBuilder {
+	b.opts.TimeInForce = timeInForce
+	return b
+}
+
+// GTD makes a pending order good until t
+func (b *OrderBuilder) GTD(t time.Time) *OrderBuilder {
+	b.opts.TimeInForce = "GTD"
+	b.opts.GTDTime = t
+	return b
+}
+
+// PositionFill sets how the order affects existing positions
+func (b *OrderBuilder) PositionFill(positionFill string) *OrderBuilder {
+	b.opts.PositionFill = positionFill
+	return b
+}
+
+// TriggerCondition sets the price a pending order is triggered by
+func (b *OrderBuilder) TriggerCondition(condition string) *OrderBuilder {
+	b.opts.TriggerCondition = condition
+	return b
+}
+
+// ClientExtensions tags the order
+func (b *OrderBuilder) ClientExtensions(extensions OrderExtensions) *OrderBuilder {
+	b.opts.ClientExtensions = &extensions
+	return b
+}
+
+// TradeClientExtensions tags the trade the order opens
+func (b *OrderBuilder) TradeClientExtensions(extensions OrderExtensions) *OrderBuilder {
+	b.opts.TradeClientExtensions = &extensions
+	return b
+}
+
+// TakeProfit closes the trade the order opens at price
+func (b *OrderBuilder) TakeProfit(price string) *OrderBuilder {
+	b.opts.TakeProfitOnFill = &OnFill{Price: price}
+	b.takeProfitPips = 0
+	return b
+}
+
+// TakeProfitPips closes the trade the order opens once it is pips in profit. A take profit is set by its
+// price, so it is measured from the price of a limit or stop order and cannot be used on a market order.
+func (b *OrderBuilder) TakeProfitPips(pips float64) *OrderBuilder {
+	b.opts.TakeProfitOnFill = nil
+	b.takeProfitPips = pips
+	return b
+}
+
+// StopLoss closes the trade the order opens at price
+func (b *OrderBuilder) StopLoss(price string) *OrderBuilder {
+	b.opts.StopLossOnFill = &OnFill{Price: price}
+	b.stopLossPips = 0
+	return b
+}
+
+// StopLossPips closes the trade the order opens once it is pips at a loss
+func (b *OrderBuilder) StopLossPips(pips float64) *OrderBuilder {
+	b.opts.StopLossOnFill = nil
+	b.stopLossPips = pips
+	return b
+}
+
+// TrailingStopLossPips closes the trade the order opens once the market falls pips from its best price
+func (b *OrderBuilder) TrailingStopLossPips(pips float64) *OrderBuilder {
+	b.trailingStopLossPips = pips
+	return b
+}
+
+// GuaranteedStopLoss closes the trade the order opens at price, however far the market gaps past it
+func (b *OrderBuilder) GuaranteedStopLoss(price string) *OrderBuilder {
+	b.opts.GuaranteedStopLossOnFill = &OnFill{Price: price}
+	b.guaranteedStopLossPips = 0
+	return b
+}
+
+// GuaranteedStopLossPips closes the trade the order opens once it is pips at a loss, however far the
+// market gaps past it
+func (b *OrderBuilder) GuaranteedStopLossPips(pips float64) *OrderBuilder {
+	b.opts.GuaranteedStopLossOnFill = nil
+	b.guaranteedStopLossPips = pips
+	return b
+}
+
+// Precision rounds the order to the instrument's precision and checks it against the instrument's
+// limits, the details come from Connection.GetAccountInstruments
+func (b *OrderBuilder) Precision(details InstrumentDetails) *OrderBuilder {
+	b.details = &details
+	return b
+}
+
+// SizeForRisk sets the units from the sizer when the order is built, so that the order's stop loss, or
+// guaranteed or trailing stop loss, loses the sizer's share of equity. It takes the place of Units, with
+// the direction of the sizer.
+func (b *OrderBuilder) SizeForRisk(sizer PositionSizer) *OrderBuilder {
+	b.sizer = &sizer
+	return b
+}
+
+// Build checks the order and returns the request body creating it, or an error listing every problem found
+func (b *OrderBuilder) Build() (OrderPayload, error) {
+	var errs []error
+	if b.instrument == "" {
+		errs = append(errs, errors.New("goanda: an order needs an instrument"))
+	}
+	if b.details != nil && b.details.Name != "" && b.details.Name != b.instrument {
+		errs = append(errs, fmt.Errorf("goanda: the precision given is for %s, not %s", b.details.Name, b.instrument))
+	}
+
+	units := b.units
+	if b.sizer != nil {
+		var err error
+		if units, err = b.riskUnits(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if b.details != nil {
+		units = roundUnits(units, b.details.TradeUnitsPrecision)
+	}
+	switch {
+	case units == 0 && b.sizer == nil:
+		errs = append(errs, errors.New("goanda: an order needs units"))
+	case units != 0:
+		if err := b.checkUnits(units); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if b.orderType == "MARKET" && b.price != "" {
+		errs = append(errs, errors.New("goanda: a market order is filled at the current price, not a set one"))
+	}
+	if b.orderType == "MARKET" && b.opts.TimeInForce == "GTD" {
+		errs = append(errs, errors.New("goanda: a market order cannot be GTD"))
+	}
+
+	opts := b.opts
+	price := b.formatPrice("price", b.price, &errs)
+	opts.PriceBound = b.formatPrice("price bound", opts.PriceBound, &errs)
+	opts.TakeProfitOnFill = b.onFillPrice("take profit", opts.TakeProfitOnFill, &errs)
+	opts.StopLossOnFill = b.onFillPrice("stop loss", opts.StopLossOnFill, &errs)
+	opts.GuaranteedStopLossOnFill = b.onFillPrice("guaranteed stop loss", opts.GuaranteedStopLossOnFill, &errs)
+
+	if b.takeProfitPips != 0 {
+		opts.TakeProfitOnFill = b.takeProfitFrom(price, units, &errs)
+	}
+	if b.stopLossPips != 0 {
+		opts.StopLossOnFill = &OnFill{Distance: b.distance(b.stopLossPips)}
+	}
+	if b.trailingStopLossPips != 0 {
+		distance := b.distance(b.trailingStopLossPips)
+		if err := b.checkTrailingDistance(distance); err != nil {
+			errs = append(errs, err)
+		}
+		opts.TrailingStopLossOnFill = &OnFill{Distance: distance}
+	}
+	if b.guaranteedStopLossPips != 0 {
+		opts.GuaranteedStopLossOnFill = &OnFill{Distance: b.distance(b.guaranteedStopLossPips)}
+	}
+
+	if len(errs) > 0 {
+		return OrderPayload{}, errors.Join(errs...)
+	}
+	order, err := opts.order(b.orderType, b.instrument, units, price)
+	if err != nil {
+		return OrderPayload{}, err
+	}
+	return OrderPayload{Order: order}, nil
+}
+
+// SubmitOrder builds the order and creates it, nothing is sent if it fails to build
+func (c *Connection) SubmitOrder(b *OrderBuilder) (OrderCreateResponse, error) {
+	payload, err := b.Build()
+	if err != nil {
+		return OrderCreateResponse{}, err
+	}
+	return c.submitOrder(payload.Order)
+}
+
+// riskUnits sizes the order with its sizer from the distance to the stop that closes it: a stop loss, a
+// guaranteed stop loss or a trailing stop loss, in that order
+func (b *OrderBuilder) riskUnits() (float64, error) {
+	distance := 0.0
+	for _, stop := range []struct {
+		pips    float64
+		details *OnFill
+	}{
+		{b.stopLossPips, b.opts.StopLossOnFill},
+		{b.guaranteedStopLossPips, b.opts.GuaranteedStopLossOnFill},
+		{b.trailingStopLossPips, b.opts.TrailingStopLossOnFill},
+	} {
+		var err error
+		switch {
+		case stop.pips != 0:
+			distance = math.Abs(b.pips(stop.pips))
+		case stop.details != nil && stop.details.Distance != "":
+			distance, err = strconv.ParseFloat(stop.details.Distance, 64)
+		case stop.details != nil && stop.details.Price != "":
+			var entry, exit float64
+			if entry, err = strconv.ParseFloat(b.price, 64); err != nil {
+				return 0, fmt.Errorf("goanda: a stop set by its price is measured from the order's price, which a %s order does not have", b.orderType)
+			}
+			exit, err = strconv.ParseFloat(stop.details.Price, 64)
+			distance = math.Abs(entry - exit)
+		default:
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("goanda: invalid stop for sizing the order: %w", err)
+		}
+		break
+	}
+	if distance == 0 {
+		return 0, errors.New("goanda: sizing an order for risk needs a stop loss")
+	}
+	return b.sizer.Units(distance)
+}
+
+// formatPrice checks a price is a number, rounding it to the instrument's display precision
+func (b *OrderBuilder) formatPrice(name string, price string, errs *[]error) string {
+	if price == "" {
+		return ""
+	}
+	value, err := strconv.ParseFloat(price, 64)
+	if err != nil || value <= 0 {
+		*errs = append(*errs, fmt.Errorf("goanda: invalid %s %q", name, price))
+		return price
+	}
+	if b.details == nil {
+		return price
+	}
+	rounded := roundToPrecision(value, b.details.DisplayPrecision, roundNearest)
+	return strconv.FormatFloat(rounded, 'f', b.details.DisplayPrecision, 64)
+}
+
+// onFillPrice rounds the price of a dependent order, copying the details rather than changing the caller's
+func (b *OrderBuilder) onFillPrice(name string, details *OnFill, errs *[]error) *OnFill {
+	if details == nil || details.Price == "" {
+		return details
+	}
+	rounded := *details
+	rounded.Price = b.formatPrice(name, details.Price, errs)
+	return &rounded
+}
+
+// takeProfitFrom sets a take profit pips from a pending order's price, above it for a buy and below for a sell
+func (b *OrderBuilder) takeProfitFrom(price string, units float64, errs *[]error) *OnFill {
+	entry, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("goanda: a take profit in pips is measured from the order's price, which a %s order does not have", b.orderType))
+		return nil
+	}
+	distance := b.pips(b.takeProfitPips)
+	if units < 0 {
+		distance = -distance
+	}
+	return &OnFill{Price: strconv.FormatFloat(entry+distance, 'f', b.decimals(), 64)}
+}
+
+// distance formats pips as a price distance
+func (b *OrderBuilder) distance(pips float64) string {
+	return strconv.FormatFloat(math.Abs(b.pips(pips)), 'f', b.decimals(), 64)
+}
+
+// pips converts pips to a price difference
+func (b *OrderBuilder) pips(pips float64) float64 {
+	location := guessPipLocation(b.instrument)
+	if b.details != nil {
+		location = b.details.PipLocation
+	}
+	return pips * math.Pow10(location)
+}
+
+// decimals is the number of decimal places prices are written with: the display precision, or a tenth
+// of a pip without the instrument's details
+func (b *OrderBuilder) decimals() int {
+	if b.details != nil {
+		return b.details.DisplayPrecision
+	}
+	if decimals := 1 - guessPipLocation(b.instrument); decimals > 0 {
+		return decimals
+	}
+	return 0
+}
+
+// checkUnits checks the size of an order against the instrument's minimum and maximum
+func (b *OrderBuilder) checkUnits(units float64) error {
+	if b.details == nil {
+		return nil
+	}
+	size := math.Abs(units)
+	if minimum, err := strconv.ParseFloat(b.details.MinimumTradeSize, 64); err == nil && size < minimum {
+		return fmt.Errorf("goanda: %v units is below the minimum trade size of %s for %s", size, b.details.MinimumTradeSize, b.instrument)
+	}
+	if maximum, err := strconv.ParseFloat(b.details.MaximumOrderUnits, 64); err == nil && maximum > 0 && size > maximum {
+		return fmt.Errorf("goanda: %v units is above the maximum order size of %s for %s", size, b.details.MaximumOrderUnits, b.instrument)
+	}
+	return nil
+}
+
+// checkTrailingDistance checks a trailing stop's distance against the instrument's minimum and maximum
+func (b *OrderBuilder) checkTrailingDistance(distance string) error {
+	if b.details == nil {
+		return nil
+	}
+	value, _ := strconv.ParseFloat(distance, 64)
+	if minimum, err := strconv.ParseFloat(b.details.MinimumTrailingStopDistance, 64); err == nil && value < minimum {
+		return fmt.Errorf("goanda: a trailing stop distance of %s is below the minimum of %s for %s", distance, b.details.MinimumTrailingStopDistance, b.instrument)
+	}
+	if maximum, err := strconv.ParseFloat(b.details.MaximumTrailingStopDistance, 64); err == nil && maximum > 0 && value > maximum {
+		return fmt.Errorf("goanda: a trailing stop distance of %s is above the maximum of %s for %s", distance, b.details.MaximumTrailingStopDistance, b.instrument)
+	}
+	return nil
+}
+
+// roundUnits rounds units toward zero to the precision, so an order never trades more than asked.
+// A tolerance keeps units already at the precision from being pushed down by floating point noise.
+func roundUnits(units float64, precision int) float64 {
+	scale := math.Pow10(precision)
+	return math.Trunc(units*scale+math.Copysign(1e-6, units)) / scale
+}
@@ -0,0 +1,129 @@
+package goanda
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var eurusdDetails = InstrumentDetails{
+	Name:                        "EUR_USD",
+	DisplayPrecision:            5,
+	PipLocation:                 -4,
+	TradeUnitsPrecision:         0,
+	MinimumTradeSize:            "1",
+	MaximumOrderUnits:           "100000000",
+	MinimumTrailingStopDistance: "0.00050",
+	MaximumTrailingStopDistance: "1.00000",
+}
+
+func TestOrderBuilder(t *testing.T) {
+	defer logTestResult(t, "OrderBuilder")
+
+	expiry := time.Date(2024, 1, 3, 17, 0, 0, 0, time.UTC)
+	payload, err := NewLimitOrder("EUR_USD").
+		Units(1000.7).
+		Price("1.085004").
+		GTD(expiry).
+		StopLossPips(20).
+		TakeProfitPips(40).
+		TrailingStopLossPips(15).
+		ClientExtensions(OrderExtensions{ID: "entry-1"}).
+		Precision(eurusdDetails).
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	order := payload.Order
+	if order.Type != "LIMIT" || order.Instrument != "EUR_USD" || order.Units != 1000 || order.Price != "1.08500" {
+		t.Errorf("Expected the rounded limit order, got %+v", order)
+	}
+	if order.TimeInForce != "GTD" || !order.GTDTime.Equal(expiry) {
+		t.Errorf("Expected a GTD order, got %s %v", order.TimeInForce, order.GTDTime)
+	}
+	if order.StopLossOnFill == nil || order.StopLossOnFill.Distance != "0.00200" {
+		t.Errorf("Expected a stop loss 20 pips away, got %+v", order.StopLossOnFill)
+	}
+	if order.TakeProfitOnFill == nil || order.TakeProfitOnFill.Price != "1.08900" {
+		t.Errorf("Expected a take profit 40 pips above the entry, got %+v", order.TakeProfitOnFill)
+	}
+	if order.TrailingStopLossOnFill == nil || order.TrailingStopLossOnFill.Distance != "0.00150" {
+		t.Errorf("Expected a trailing stop loss 15 pips away, got %+v", order.TrailingStopLossOnFill)
+	}
+	if order.ClientExtensions == nil || order.ClientExtensions.ID != "entry-1" {
+		t.Errorf("Expected the client extensions, got %+v", order.ClientExtensions)
+	}
+
+	sell, err := NewStopOrder("USD_JPY").Units(-500).Price("149.50").TakeProfitPips(50).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sell.Order.TakeProfitOnFill.Price != "149.000" || sell.Order.TimeInForce != "GTC" {
+		t.Errorf("Expected a take profit 50 pips below the entry of a sell, got %+v", sell.Order)
+	}
+}
+
+func TestOrderBuilderValidation(t *testing.T) {
+	defer logTestResult(t, "OrderBuilderValidation")
+
+	for name, test := range map[string]struct {
+		builder *OrderBuilder
+		want    string
+	}{
+		"no units":                     {NewMarketOrder("EUR_USD"), "needs units"},
+		"units rounded to nothing":     {NewMarketOrder("EUR_USD").Units(0.4).Precision(eurusdDetails), "needs units"},
+		"too many units":               {NewMarketOrder("EUR_USD").Units(2e8).Precision(eurusdDetails), "maximum order size"},
+		"no instrument":                {NewMarketOrder("").Units(100), "needs an instrument"},
+		"no price":                     {NewLimitOrder("EUR_USD").Units(100), "needs a price"},
+		"a market order price":         {NewMarketOrder("EUR_USD").Units(100).Price("1.1"), "current price"},
+		"a market order GTD":           {NewMarketOrder("EUR_USD").Units(100).GTD(time.Now()), "cannot be GTD"},
+		"an invalid price":             {NewLimitOrder("EUR_USD").Units(100).Price("1,1"), "invalid price"},
+		"a market take profit in pips": {NewMarketOrder("EUR_USD").Units(100).TakeProfitPips(10), "measured from the order's price"},
+		"a tight trailing stop":        {NewMarketOrder("EUR_USD").Units(100).TrailingStopLossPips(1).Precision(eurusdDetails), "below the minimum"},
+		"another instrument's detail":  {NewMarketOrder("GBP_USD").Units(100).Precision(eurusdDetails), "for EUR_USD"},
+	} {
+		_, err := test.builder.Build()
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Expected an error for %s containing %q, got %v", name, test.want, err)
+		}
+	}
+
+	_, err := NewLimitOrder("EUR_USD").Price("x").Build()
+	if err == nil || !strings.Contains(err.Error(), "needs units") || !strings.Contains(err.Error(), "invalid price") {
+		t.Errorf("Expected every problem to be reported, got %v", err)
+	}
+}
+
+func TestSubmitOrder(t *testing.T) {
+	defer logTestResult(t, "SubmitOrder")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload OrderPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if payload.Order.Type != "MARKET" || payload.Order.TimeInForce != "FOK" || payload.Order.StopLossOnFill.Distance != "0.00100" {
+			t.Errorf("Expected the built market order, got %+v", payload.Order)
+		}
+		w.Write([]byte(`{"orderCreateTransaction":{"id":"6","type":"MARKET_ORDER"},
+			"orderFillTransaction":{"id":"7","type":"ORDER_FILL","orderID":"6","tradeOpened":{"tradeID":"7"}},"lastTransactionID":"7"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.SubmitOrder(NewMarketOrder("EUR_USD").Units(100).StopLossPips(10))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids := response.TradeIDs(); len(ids) != 1 || ids[0] != "7" {
+		t.Errorf("Expected the opened trade, got %v", ids)
+	}
+
+	if _, err := c.SubmitOrder(NewMarketOrder("EUR_USD")); err == nil {
+		t.Error("Expected an order that fails to build not to be sent")
+	}
+}
@@ -0,0 +1,399 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// OrderOptions are the optional details of an order created with CreateMarketOrder and the other typed
+// order calls. Fields left empty are decided by OANDA, except TimeInForce which defaults per order type.
+type OrderOptions struct {
+	// TimeInForce is how long the order may remain unfilled: FOK or IOC for a market order, which
+	// defaults to FOK, and GTC, GTD, GFD, FOK or IOC for a pending order, which defaults to GTC, or GTD
+	// if GTDTime is set
+	TimeInForce string
+	// GTDTime is when a GTD order expires. An order that has already expired by OANDA's clock, see
+	// Connection.ServerTime, is refused with ErrGTDPassed without being sent.
+	GTDTime time.Time
+	// PositionFill is how the order affects existing positions: DEFAULT, OPEN_ONLY, REDUCE_FIRST or REDUCE_ONLY
+	PositionFill string
+	// PriceBound is the worst price a market or stop order may fill at
+	PriceBound string
+	// TriggerCondition is the price a pending order is triggered by: DEFAULT, INVERSE, BID, ASK or MID
+	TriggerCondition string
+	// ClientExtensions tag the order, their ID can be used in place of the order's, see GetOrder
+	ClientExtensions *OrderExtensions
+	// TradeClientExtensions tag the trade the order opens
+	TradeClientExtensions *OrderExtensions
+
+	// TakeProfitOnFill, StopLossOnFill and TrailingStopLossOnFill bracket the trade the order opens,
+	// creating its dependent orders in the same request. A take profit is set by its Price, a stop loss
+	// by its Price or its Distance from the fill, and a trailing stop loss by its Distance.
+	TakeProfitOnFill       *OnFill
+	StopLossOnFill         *OnFill
+	TrailingStopLossOnFill *OnFill
+	// GuaranteedStopLossOnFill is a stop loss filled at its price however far the market gaps past it,
+	// for a fee, set by its Price or Distance. It is only available on accounts in some regions, and
+	// takes the place of StopLossOnFill.
+	GuaranteedStopLossOnFill *OnFill
+}
+
+// OrderCreateResponse is OANDA's response to creating an order, with its transactions decoded
+type OrderCreateResponse struct {
+	// OrderCreateTransaction is the creation of the order, such as a *MarketOrderTransaction
+	OrderCreateTransaction TypedTransaction
+	// OrderFillTransaction is the order's fill, if it was filled immediately
+	OrderFillTransaction *OrderFillTransaction
+	// OrderCancelTransaction is the order's cancellation, if it was cancelled immediately, as a market
+	// order is when it cannot be filled, or partly filled
+	OrderCancelTransaction *OrderCancelTransaction
+	// OrderReissueTransaction is the creation of an order for the units left unfilled, if the order was
+	// partly filled and is reissued, such as a *LimitOrderTransaction, and OrderReissueRejectTransaction
+	// is its rejection if it could not be
+	OrderReissueTransaction       TypedTransaction
+	OrderReissueRejectTransaction *OrderRejectTransaction
+	RelatedTransactionIDs         []string
+	LastTransactionID             string
+}
+
+// UnmarshalJSON decodes the response's transactions into their concrete types
+func (r *OrderCreateResponse) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		OrderCreateTransaction json.RawMessage `json:"orderCreateTransaction"`
+		OrderFillTransaction   json.RawMessage `json:"orderFillTransaction"`
+		OrderCancelTransaction json.RawMessage `json:"orderCancelTransaction"`
+		OrderReissue           json.RawMessage `json:"orderReissueTransaction"`
+		OrderReissueReject     json.RawMessage `json:"orderReissueRejectTransaction"`
+		RelatedTransactionIDs  []string        `json:"relatedTransactionIDs"`
+		LastTransactionID      string          `json:"lastTransactionID"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*r = OrderCreateResponse{
+		RelatedTransactionIDs: raw.RelatedTransactionIDs,
+		LastTransactionID:     raw.LastTransactionID,
+	}
+	var err error
+	if r.OrderCreateTransaction, err = decodeOptionalTransaction(raw.OrderCreateTransaction); err != nil {
+		return err
+	}
+	if r.OrderFillTransaction, err = decodeFill(raw.OrderFillTransaction); err != nil {
+		return err
+	}
+	if r.OrderCancelTransaction, err = decodeCancel(raw.OrderCancelTransaction); err != nil {
+		return err
+	}
+	if r.OrderReissueTransaction, err = decodeOptionalTransaction(raw.OrderReissue); err != nil {
+		return err
+	}
+	r.OrderReissueRejectTransaction, err = decodeReject(raw.OrderReissueReject)
+	return err
+}
+
+// Filled reports whether the order was filled when it was created, in full or in part
+func (r OrderCreateResponse) Filled() bool {
+	return r.OrderFillTransaction != nil
+}
+
+// PartlyFilled reports whether the order was filled for only some of its units, the rest being
+// cancelled or reissued
+func (r OrderCreateResponse) PartlyFilled() bool {
+	return r.Filled() && (r.OrderCancelTransaction != nil || r.OrderReissueTransaction != nil || r.OrderReissueRejectTransaction != nil)
+}
+
+// Cancelled reports whether the order, or the units of it left unfilled, was cancelled when it was
+// created, as a market order is when it can't be filled
+func (r OrderCreateResponse) Cancelled() bool {
+	return r.OrderCancelTransaction != nil
+}
+
+// Pending reports whether the order, or its reissue, is waiting to be filled
+func (r OrderCreateResponse) Pending() bool {
+	if r.OrderReissueTransaction != nil {
+		return true
+	}
+	return r.OrderCreateTransaction != nil && !r.Filled() && !r.Cancelled()
+}
+
+// OrderID returns the ID of the created order
+func (r OrderCreateResponse) OrderID() string {
+	if r.OrderCreateTransaction == nil {
+		return ""
+	}
+	return r.OrderCreateTransaction.Header().ID
+}
+
+// ReissuedOrderID returns the ID of the order reissued for the units left unfilled, if there is one
+func (r OrderCreateResponse) ReissuedOrderID() string {
+	if r.OrderReissueTransaction == nil {
+		return ""
+	}
+	return r.OrderReissueTransaction.Header().ID
+}
+
+// TradeIDs returns the trades the order's fill opened, reduced and closed, in that order
+func (r OrderCreateResponse) TradeIDs() []string {
+	fill := r.OrderFillTransaction
+	if fill == nil {
+		return nil
+	}
+
+	var ids []string
+	if fill.TradeOpened != nil {
+		ids = append(ids, fill.TradeOpened.TradeID)
+	}
+	if fill.TradeReduced != nil {
+		ids = append(ids, fill.TradeReduced.TradeID)
+	}
+	for _, closed := range fill.TradesClosed {
+		ids = append(ids, closed.TradeID)
+	}
+	return ids
+}
+
+// CreateMarketOrder creates an order filled immediately at the current price, for positive units to buy
+// or negative to sell. Whether it filled is in the response: a market order that cannot be filled, such
+// as one outside its PriceBound, is cancelled instead.
+func (c *Connection) CreateMarketOrder(instrument string, units float64, opts OrderOptions) (OrderCreateResponse, error) {
+	order, err := opts.order("MARKET", instrument, units, "")
+	if err != nil {
+		return OrderCreateResponse{}, err
+	}
+	return c.submitOrder(order)
+}
+
+// CreateLimitOrder creates an order filled at price or better, for positive units to buy or negative
+// to sell. It stays pending until the market reaches the price or its time in force runs out.
+func (c *Connection) CreateLimitOrder(instrument string, units float64, price string, opts OrderOptions) (OrderCreateResponse, error) {
+	order, err := opts.order("LIMIT", instrument, units, price)
+	if err != nil {
+		return OrderCreateResponse{}, err
+	}
+	return c.submitOrder(order)
+}
+
+// CreateStopOrder creates an order filled once the market reaches price, for positive units to buy at or
+// above it or negative to sell at or below it. Set PriceBound to limit how far past price it may fill.
+func (c *Connection) CreateStopOrder(instrument string, units float64, price string, opts OrderOptions) (OrderCreateResponse, error) {
+	order, err := opts.order("STOP", instrument, units, price)
+	if err != nil {
+		return OrderCreateResponse{}, err
+	}
+	return c.submitOrder(order)
+}
+
+// CreateTrailingStopLossOrder creates a trailing stop loss on an open trade, closing it once the market
+// moves distance against the trade from the best price it has reached since the order was created.
+// A trade has at most one, SetTradeTrailingStopLoss creates, changes or cancels it in its place.
+// Only the time in force, GTDTime, TriggerCondition and ClientExtensions of opts are used.
+func (c *Connection) CreateTrailingStopLossOrder(tradeID string, distance string, opts OrderOptions) (OrderCreateResponse, error) {
+	if distance == "" {
+		return OrderCreateResponse{}, errors.New("goanda: a trailing stop loss needs a distance")
+	}
+	order, err := opts.dependent("TRAILING_STOP_LOSS", tradeID)
+	if err != nil {
+		return OrderCreateResponse{}, err
+	}
+	order.Distance = distance
+	return c.submitOrder(order)
+}
+
+// submitOrder creates an order built by the typed order calls
+func (c *Connection) submitOrder(order OrderBody) (OrderCreateResponse, error) {
+	var response OrderCreateResponse
+	err := c.createOrder(OrderPayload{Order: order}, &response)
+	return response, err
+}
+
+// order builds a MARKET, LIMIT or STOP order from the options
+func (opts OrderOptions) order(orderType string, instrument string, units float64, price string) (OrderBody, error) {
+	var order OrderBody
+	var err error
+	switch orderType {
+	case "MARKET":
+		order, err = opts.body(orderType, instrument, units, "FOK")
+	case "LIMIT", "STOP":
+		order, err = opts.pending(orderType, instrument, units, price)
+	default:
+		return OrderBody{}, fmt.Errorf("goanda: unsupported order type %q", orderType)
+	}
+	if err != nil {
+		return OrderBody{}, err
+	}
+	if orderType != "LIMIT" {
+		order.PriceBound = opts.PriceBound
+	}
+	return order, nil
+}
+
+// body builds an order of the type from the options, with the type's default time in force
+func (opts OrderOptions) body(orderType string, instrument string, units float64, timeInForce string) (OrderBody, error) {
+	if opts.TimeInForce != "" {
+		timeInForce = opts.TimeInForce
+	}
+
+	for _, onFill := range []struct {
+		name     string
+		details  *OnFill
+		price    bool
+		distance bool
+	}{
+		{"take profit", opts.TakeProfitOnFill, true, false},
+		{"stop loss", opts.StopLossOnFill, true, true},
+		{"trailing stop loss", opts.TrailingStopLossOnFill, false, true},
+		{"guaranteed stop loss", opts.GuaranteedStopLossOnFill, true, true},
+	} {
+		if err := validateOnFill(onFill.name, onFill.details, onFill.price, onFill.distance); err != nil {
+			return OrderBody{}, err
+		}
+	}
+	if opts.StopLossOnFill != nil && opts.GuaranteedStopLossOnFill != nil {
+		return OrderBody{}, errors.New("goanda: a trade takes a stop loss or a guaranteed stop loss, not both")
+	}
+
+	return OrderBody{
+		Type:                   orderType,
+		Instrument:             instrument,
+		Units:                  units,
+		TimeInForce:            timeInForce,
+		PositionFill:           opts.PositionFill,
+		ClientExtensions:       opts.ClientExtensions,
+		TradeClientExtensions:  opts.TradeClientExtensions,
+		TakeProfitOnFill:       opts.TakeProfitOnFill,
+		StopLossOnFill:         opts.StopLossOnFill,
+		TrailingStopLossOnFill: opts.TrailingStopLossOnFill,
+
+		GuaranteedStopLossOnFill: opts.GuaranteedStopLossOnFill,
+	}, nil
+}
+
+// validateOnFill checks a dependent order is set by exactly one of the price and distance it may be
+// set by, and has an expiry if it is GTD
+func validateOnFill(name string, details *OnFill, price bool, distance bool) error {
+	if details == nil {
+		return nil
+	}
+
+	switch {
+	case details.Price != "" && !price:
+		return fmt.Errorf("goanda: a %s is set by its distance, not a price", name)
+	case details.Distance != "" && !distance:
+		return fmt.Errorf("goanda: a %s is set by its price, not a distance", name)
+	case details.Price != "" && details.Distance != "":
+		return fmt.Errorf("goanda: a %s is set by its price or its distance, not both", name)
+	case details.Price == "" && details.Distance == "":
+		return fmt.Errorf("goanda: a %s needs a price or a distance", name)
+	case details.TimeInForce == "GTD" && details.GtdTime.IsZero():
+		return fmt.Errorf("goanda: a GTD %s needs a GtdTime", name)
+	}
+	return nil
+}
+
+// pending builds an order waiting on a price, checking its expiry against its time in force
+func (opts OrderOptions) pending(orderType string, instrument string, units float64, price string) (OrderBody, error) {
+	timeInForce := "GTC"
+	if !opts.GTDTime.IsZero() {
+		timeInForce = "GTD"
+	}
+	order, err := opts.body(orderType, instrument, units, timeInForce)
+	if err != nil {
+		return OrderBody{}, err
+	}
+	order.Price = price
+	order.TriggerCondition = opts.TriggerCondition
+
+	if price == "" {
+		return OrderBody{}, fmt.Errorf("goanda: a %s order needs a price", orderType)
+	}
+	return order, opts.expire(&order)
+}
+
+// dependent builds an order on an open trade, such as a trailing stop loss, from the options
+func (opts OrderOptions) dependent(orderType string, tradeID string) (OrderBody, error) {
+	if tradeID == "" {
+		return OrderBody{}, fmt.Errorf("goanda: a %s order needs a trade", orderType)
+	}
+	order := OrderBody{
+		Type:             orderType,
+		TradeID:          tradeID,
+		TimeInForce:      opts.TimeInForce,
+		TriggerCondition: opts.TriggerCondition,
+		ClientExtensions: opts.ClientExtensions,
+	}
+	switch {
+	case order.TimeInForce != "":
+	case !opts.GTDTime.IsZero():
+		order.TimeInForce = "GTD"
+	default:
+		order.TimeInForce = "GTC"
+	}
+	return order, opts.expire(&order)
+}
+
+// expire gives a GTD order its GTDTime, checking the time is set only for GTD
+func (opts OrderOptions) expire(order *OrderBody) error {
+	switch {
+	case order.TimeInForce == "GTD" && opts.GTDTime.IsZero():
+		return errors.New("goanda: a GTD order needs a GTDTime")
+	case order.TimeInForce == "GTD":
+		order.GTDTime = opts.GTDTime
+	case !opts.GTDTime.IsZero():
+		return fmt.Errorf("goanda: GTDTime is only used by GTD orders, not %s", order.TimeInForce)
+	}
+	return nil
+}
+
+func decodeOptionalTransaction(data json.RawMessage) (TypedTransaction, error) {
+	if !present(data) {
+		return nil, nil
+	}
+	return DecodeTransaction(data)
+}
+
+func decodeCancel(data json.RawMessage) (*OrderCancelTransaction, error) {
+	if !present(data) {
+		return nil, nil
+	}
+	cancel := &OrderCancelTransaction{}
+	if err := json.Unmarshal(data, cancel); err != nil {
+		return nil, fmt.Errorf("goanda: decoding ORDER_CANCEL transaction: %w", err)
+	}
+	return cancel, nil
+}
+
+func decodeFill(data json.RawMessage) (*OrderFillTransaction, error) {
+	if !present(data) {
+		return nil, nil
+	}
+	fill := &OrderFillTransaction{}
+	if err := json.Unmarshal(data, fill); err != nil {
+		return nil, fmt.Errorf("goanda: decoding ORDER_FILL transaction: %w", err)
+	}
+	return fill, nil
+}
+
+func decodeReject(data json.RawMessage) (*OrderRejectTransaction, error) {
+	if !present(data) {
+		return nil, nil
+	}
+	reject := &OrderRejectTransaction{}
+	if err := json.Unmarshal(data, reject); err != nil {
+		return nil, fmt.Errorf("goanda: decoding reject transaction: %w", err)
+	}
+	return reject, nil
+}
+
+// present reports whether a response included a field
+func present(data json.RawMessage) bool {
+	return len(data) > 0 && string(data) != "null"
+}
+
+// formatUnits formats units as the decimal OANDA expects, without an exponent or trailing zeros
+func formatUnits(units float64) string {
+	return strconv.FormatFloat(units, 'f', -1, 64)
+}
@@ -0,0 +1,425 @@
+package goanda
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateMarketOrder(t *testing.T) {
+	defer logTestResult(t, "CreateMarketOrder")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/accounts/test-account/orders" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var payload struct {
+			Order map[string]interface{} `json:"order"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		order := payload.Order
+		if order["type"] != "MARKET" || order["instrument"] != "EUR_USD" || order["units"] != -100.5 {
+			t.Errorf("Expected a market order to sell 100.5 EUR_USD, got %v", order)
+		}
+		if order["timeInForce"] != "FOK" || order["priceBound"] != "1.0990" || order["positionFill"] != "REDUCE_FIRST" {
+			t.Errorf("Expected the order's options, got %v", order)
+		}
+
+		w.Write([]byte(`{
+			"orderCreateTransaction":{"id":"6","type":"MARKET_ORDER","instrument":"EUR_USD","units":"-100.5",
+				"timeInForce":"FOK","priceBound":"1.0990","reason":"CLIENT_ORDER"},
+			"orderFillTransaction":{"id":"7","type":"ORDER_FILL","orderID":"6","instrument":"EUR_USD","units":"-100.5",
+				"price":"1.0995","reason":"MARKET_ORDER","tradeReduced":{"tradeID":"3","units":"-50"},
+				"tradesClosed":[{"tradeID":"2","units":"-25"}],"tradeOpened":{"tradeID":"7","units":"-25.5"}},
+			"relatedTransactionIDs":["6","7"],"lastTransactionID":"7"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.CreateMarketOrder("EUR_USD", -100.5, OrderOptions{PriceBound: "1.0990", PositionFill: "REDUCE_FIRST"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	create, ok := response.OrderCreateTransaction.(*MarketOrderTransaction)
+	if !ok || create.PriceBound != "1.0990" || response.OrderID() != "6" {
+		t.Errorf("Expected the market order's creation, got %#v", response.OrderCreateTransaction)
+	}
+	if response.OrderFillTransaction == nil || response.OrderFillTransaction.Price != "1.0995" {
+		t.Fatalf("Expected the order's fill, got %+v", response.OrderFillTransaction)
+	}
+	ids := response.TradeIDs()
+	if len(ids) != 3 || ids[0] != "7" || ids[1] != "3" || ids[2] != "2" {
+		t.Errorf("Expected the opened, reduced and closed trades, got %v", ids)
+	}
+	if response.OrderCancelTransaction != nil || len(response.RelatedTransactionIDs) != 2 || response.LastTransactionID != "7" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
+func TestCreateMarketOrderCancelled(t *testing.T) {
+	defer logTestResult(t, "CreateMarketOrderCancelled")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload OrderPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload.Order.TimeInForce != "IOC" || payload.Order.ClientExtensions == nil || payload.Order.ClientExtensions.ID != "my-order" {
+			t.Errorf("Expected the time in force and client extensions to be sent, got %+v", payload.Order)
+		}
+		w.Write([]byte(`{
+			"orderCreateTransaction":{"id":"6","type":"MARKET_ORDER","instrument":"EUR_USD","units":"100"},
+			"orderCancelTransaction":{"id":"7","type":"ORDER_CANCEL","orderID":"6","reason":"BOUNDS_VIOLATION"},
+			"relatedTransactionIDs":["6","7"],"lastTransactionID":"7"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.CreateMarketOrder("EUR_USD", 100, OrderOptions{
+		TimeInForce:      "IOC",
+		ClientExtensions: &OrderExtensions{ID: "my-order"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.OrderFillTransaction != nil || response.TradeIDs() != nil {
+		t.Errorf("Expected no fill, got %+v", response.OrderFillTransaction)
+	}
+	if response.OrderCancelTransaction == nil || response.OrderCancelTransaction.Reason != "BOUNDS_VIOLATION" {
+		t.Errorf("Expected the order's cancellation, got %+v", response.OrderCancelTransaction)
+	}
+	if response.Filled() || response.PartlyFilled() || !response.Cancelled() || response.Pending() {
+		t.Errorf("Expected a cancelled order, got %+v", response)
+	}
+}
+
+func TestCreateOrderPartlyFilled(t *testing.T) {
+	defer logTestResult(t, "CreateOrderPartlyFilled")
+
+	responses := []string{
+		`{"orderCreateTransaction":{"id":"6","type":"LIMIT_ORDER","instrument":"EUR_USD","units":"100","price":"1.1000"},
+			"orderFillTransaction":{"id":"7","type":"ORDER_FILL","orderID":"6","units":"40","price":"1.1000"},
+			"orderReissueTransaction":{"id":"8","type":"LIMIT_ORDER","instrument":"EUR_USD","units":"60","price":"1.1000",
+				"reason":"PARTIAL_FILL","replacesOrderID":"6"},
+			"relatedTransactionIDs":["6","7","8"],"lastTransactionID":"8"}`,
+		`{"orderCreateTransaction":{"id":"6","type":"LIMIT_ORDER","instrument":"EUR_USD","units":"100","price":"1.1000"},
+			"orderFillTransaction":{"id":"7","type":"ORDER_FILL","orderID":"6","units":"40","price":"1.1000"},
+			"orderReissueRejectTransaction":{"id":"8","type":"LIMIT_ORDER_REJECT","instrument":"EUR_USD","units":"60",
+				"reason":"PARTIAL_FILL","rejectReason":"INSUFFICIENT_MARGIN"},
+			"relatedTransactionIDs":["6","7","8"],"lastTransactionID":"8"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(responses[0]))
+		responses = responses[1:]
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	reissued, err := c.CreateLimitOrder("EUR_USD", 100, "1.1000", OrderOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reissued.Filled() || !reissued.PartlyFilled() || reissued.Cancelled() || !reissued.Pending() {
+		t.Errorf("Expected a partly filled order with the rest pending, got %+v", reissued)
+	}
+	reissue, ok := reissued.OrderReissueTransaction.(*LimitOrderTransaction)
+	if !ok || reissue.ID != "8" || reissue.Units != "60" || reissued.ReissuedOrderID() != "8" {
+		t.Errorf("Expected the rest of the order to be reissued, got %#v", reissued.OrderReissueTransaction)
+	}
+
+	rejected, err := c.CreateLimitOrder("EUR_USD", 100, "1.1000", OrderOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !rejected.PartlyFilled() || rejected.Pending() || rejected.OrderReissueTransaction != nil {
+		t.Errorf("Expected a partly filled order with nothing pending, got %+v", rejected)
+	}
+	if rejected.OrderReissueRejectTransaction == nil || rejected.OrderReissueRejectTransaction.RejectReason != "INSUFFICIENT_MARGIN" {
+		t.Errorf("Expected the reissue's rejection, got %+v", rejected.OrderReissueRejectTransaction)
+	}
+}
+
+func TestCreateLimitOrder(t *testing.T) {
+	defer logTestResult(t, "CreateLimitOrder")
+
+	expiry := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Order map[string]interface{} `json:"order"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		order := payload.Order
+		if order["type"] != "LIMIT" || order["price"] != "1.0850" || order["units"] != 1000.0 {
+			t.Errorf("Expected a limit order to buy 1000 at 1.0850, got %v", order)
+		}
+		if order["timeInForce"] != "GTD" || order["gtdTime"] != expiry.Format(time.RFC3339) || order["triggerCondition"] != "MID" {
+			t.Errorf("Expected a GTD order triggered by the mid, got %v", order)
+		}
+		for _, field := range []string{"createTime", "filledTime", "cancelledTime", "priceBound", "id", "state"} {
+			if _, ok := order[field]; ok {
+				t.Errorf("Expected %s to be left out of the request, got %v", field, order[field])
+			}
+		}
+
+		fmt.Fprintf(w, `{
+			"orderCreateTransaction":{"id":"6","type":"LIMIT_ORDER","instrument":"EUR_USD","units":"1000","price":"1.0850",
+				"timeInForce":"GTD","gtdTime":%q,"triggerCondition":"MID","reason":"CLIENT_ORDER"},
+			"relatedTransactionIDs":["6"],"lastTransactionID":"6"}`, expiry.Format(time.RFC3339Nano))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.CreateLimitOrder("EUR_USD", 1000, "1.0850", OrderOptions{GTDTime: expiry, TriggerCondition: "MID"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	limit, ok := response.OrderCreateTransaction.(*LimitOrderTransaction)
+	if !ok || !limit.GTDTime.Equal(expiry) || limit.TriggerCondition != "MID" {
+		t.Errorf("Expected the limit order's creation, got %#v", response.OrderCreateTransaction)
+	}
+	if response.OrderFillTransaction != nil {
+		t.Errorf("Expected the order to be pending, got a fill %+v", response.OrderFillTransaction)
+	}
+}
+
+func TestCreateLimitOrderValidation(t *testing.T) {
+	defer logTestResult(t, "CreateLimitOrderValidation")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload OrderPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload.Order.TimeInForce != "GTC" {
+			t.Errorf("Expected a GTC order by default, got %s", payload.Order.TimeInForce)
+		}
+		w.Write([]byte(`{"orderCreateTransaction":{"id":"6","type":"LIMIT_ORDER"},"lastTransactionID":"6"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	if _, err := c.CreateLimitOrder("EUR_USD", 1000, "1.0850", OrderOptions{}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	for name, opts := range map[string]OrderOptions{
+		"GTD without a time": {TimeInForce: "GTD"},
+		"a time without GTD": {TimeInForce: "GTC", GTDTime: time.Now().Add(time.Hour)},
+	} {
+		if _, err := c.CreateLimitOrder("EUR_USD", 1000, "1.0850", opts); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+	if _, err := c.CreateLimitOrder("EUR_USD", 1000, "", OrderOptions{}); err == nil {
+		t.Error("Expected an error without a price")
+	}
+}
+
+func TestCreateStopOrder(t *testing.T) {
+	defer logTestResult(t, "CreateStopOrder")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Order map[string]interface{} `json:"order"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		order := payload.Order
+		if order["type"] != "STOP" || order["price"] != "1.0800" || order["priceBound"] != "1.0795" || order["units"] != -500.0 {
+			t.Errorf("Expected a bounded stop order to sell 500 at 1.0800, got %v", order)
+		}
+		if order["timeInForce"] != "GFD" || order["triggerCondition"] != "BID" {
+			t.Errorf("Expected a GFD order triggered by the bid, got %v", order)
+		}
+		if _, ok := order["gtdTime"]; ok {
+			t.Errorf("Expected no gtdTime, got %v", order["gtdTime"])
+		}
+
+		w.Write([]byte(`{
+			"orderCreateTransaction":{"id":"6","type":"STOP_ORDER","instrument":"EUR_USD","units":"-500","price":"1.0800",
+				"priceBound":"1.0795","timeInForce":"GFD","triggerCondition":"BID","reason":"CLIENT_ORDER"},
+			"relatedTransactionIDs":["6"],"lastTransactionID":"6"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.CreateStopOrder("EUR_USD", -500, "1.0800", OrderOptions{
+		PriceBound:       "1.0795",
+		TimeInForce:      "GFD",
+		TriggerCondition: "BID",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stop, ok := response.OrderCreateTransaction.(*StopOrderTransaction)
+	if !ok || stop.Price != "1.0800" || stop.PriceBound != "1.0795" || response.OrderID() != "6" {
+		t.Errorf("Expected the stop order's creation, got %#v", response.OrderCreateTransaction)
+	}
+}
+
+func TestCreateBracketOrder(t *testing.T) {
+	defer logTestResult(t, "CreateBracketOrder")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload OrderPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		order := payload.Order
+		if order.TakeProfitOnFill == nil || order.TakeProfitOnFill.Price != "1.1100" || order.TakeProfitOnFill.ClientExtensions.Tag != "bracket" {
+			t.Errorf("Expected the take profit, got %+v", order.TakeProfitOnFill)
+		}
+		if order.StopLossOnFill == nil || order.StopLossOnFill.Distance != "0.0020" || order.StopLossOnFill.TimeInForce != "GTC" {
+			t.Errorf("Expected the stop loss, got %+v", order.StopLossOnFill)
+		}
+		if order.TrailingStopLossOnFill == nil || order.TrailingStopLossOnFill.Distance != "0.0030" {
+			t.Errorf("Expected the trailing stop loss, got %+v", order.TrailingStopLossOnFill)
+		}
+
+		w.Write([]byte(`{
+			"orderCreateTransaction":{"id":"6","type":"MARKET_ORDER","instrument":"EUR_USD","units":"1000",
+				"takeProfitOnFill":{"price":"1.1100","timeInForce":"GTC"},"stopLossOnFill":{"distance":"0.0020","timeInForce":"GTC"},
+				"trailingStopLossOnFill":{"distance":"0.0030","timeInForce":"GTC"}},
+			"orderFillTransaction":{"id":"7","type":"ORDER_FILL","orderID":"6","units":"1000","price":"1.1000",
+				"tradeOpened":{"tradeID":"7","units":"1000"}},
+			"relatedTransactionIDs":["6","7","8","9","10"],"lastTransactionID":"10"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.CreateMarketOrder("EUR_USD", 1000, OrderOptions{
+		TakeProfitOnFill:       &OnFill{Price: "1.1100", ClientExtensions: &OrderExtensions{Tag: "bracket"}},
+		StopLossOnFill:         &OnFill{Distance: "0.0020", TimeInForce: "GTC"},
+		TrailingStopLossOnFill: &OnFill{Distance: "0.0030"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	market, ok := response.OrderCreateTransaction.(*MarketOrderTransaction)
+	if !ok || market.StopLossOnFill == nil || market.StopLossOnFill.Distance != "0.0020" {
+		t.Errorf("Expected the bracket on the order's creation, got %#v", response.OrderCreateTransaction)
+	}
+	if ids := response.TradeIDs(); len(ids) != 1 || ids[0] != "7" {
+		t.Errorf("Expected the bracketed trade, got %v", ids)
+	}
+}
+
+func TestCreateBracketOrderValidation(t *testing.T) {
+	defer logTestResult(t, "CreateBracketOrderValidation")
+
+	c := &Connection{hostname: "http://127.0.0.1:0", accountID: "test-account"}
+	for name, opts := range map[string]OrderOptions{
+		"a take profit distance":         {TakeProfitOnFill: &OnFill{Distance: "0.0010"}},
+		"a trailing stop price":          {TrailingStopLossOnFill: &OnFill{Price: "1.0900"}},
+		"a stop loss price and distance": {StopLossOnFill: &OnFill{Price: "1.0900", Distance: "0.0010"}},
+		"an empty stop loss":             {StopLossOnFill: &OnFill{}},
+		"a GTD stop loss without a time": {StopLossOnFill: &OnFill{Price: "1.0900", TimeInForce: "GTD"}},
+	} {
+		if _, err := c.CreateLimitOrder("EUR_USD", 1000, "1.0950", opts); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestCreateOrderWithGuaranteedStopLoss(t *testing.T) {
+	defer logTestResult(t, "CreateOrderWithGuaranteedStopLoss")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload OrderPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if gslo := payload.Order.GuaranteedStopLossOnFill; gslo == nil || gslo.Price != "1.0900" {
+			t.Errorf("Expected the guaranteed stop loss, got %+v", gslo)
+		}
+		w.Write([]byte(`{
+			"orderCreateTransaction":{"id":"6","type":"MARKET_ORDER","instrument":"EUR_USD","units":"1000",
+				"guaranteedStopLossOnFill":{"price":"1.0900","timeInForce":"GTC"}},
+			"orderFillTransaction":{"id":"7","type":"ORDER_FILL","orderID":"6","units":"1000","price":"1.1000",
+				"guaranteedExecutionFee":"0.0936","quoteGuaranteedExecutionFee":"0.1",
+				"tradeOpened":{"tradeID":"7","units":"1000","guaranteedExecutionFee":"0.0936","quoteGuaranteedExecutionFee":"0.1"}},
+			"relatedTransactionIDs":["6","7","8"],"lastTransactionID":"8"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.CreateMarketOrder("EUR_USD", 1000, OrderOptions{GuaranteedStopLossOnFill: &OnFill{Price: "1.0900"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fill := response.OrderFillTransaction
+	if fill == nil || fill.TradeOpened == nil || fill.TradeOpened.QuoteGuaranteedExecutionFee != "0.1" {
+		t.Fatalf("Expected the fill to carry the trade's fee, got %+v", fill)
+	}
+	fee, err := fill.GuaranteedFee()
+	if err != nil || fee != (GuaranteedExecutionFee{Fee: 0.0936, QuoteFee: 0.1}) {
+		t.Errorf("Expected the parsed fee, got %+v (%v)", fee, err)
+	}
+
+	if fee, err := (&OrderFillTransaction{}).GuaranteedFee(); err != nil || fee != (GuaranteedExecutionFee{}) {
+		t.Errorf("Expected no fee without a guaranteed stop loss, got %+v (%v)", fee, err)
+	}
+
+	_, err = c.CreateMarketOrder("EUR_USD", 1000, OrderOptions{
+		StopLossOnFill:           &OnFill{Price: "1.0900"},
+		GuaranteedStopLossOnFill: &OnFill{Price: "1.0900"},
+	})
+	if err == nil {
+		t.Error("Expected an error for both a stop loss and a guaranteed stop loss")
+	}
+}
+
+func TestCreateTrailingStopLossOrder(t *testing.T) {
+	defer logTestResult(t, "CreateTrailingStopLossOrder")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Order map[string]interface{} `json:"order"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		order := payload.Order
+		if order["type"] != "TRAILING_STOP_LOSS" || order["tradeID"] != "4" || order["distance"] != "0.00150" || order["timeInForce"] != "GTC" {
+			t.Errorf("Expected a trailing stop loss on trade 4, got %v", order)
+		}
+		if _, ok := order["instrument"]; ok {
+			t.Errorf("Expected the trade's instrument not to be sent, got %v", order)
+		}
+		if _, ok := order["units"]; ok {
+			t.Errorf("Expected the trade's units not to be sent, got %v", order)
+		}
+		w.Write([]byte(`{"orderCreateTransaction":{"id":"8","type":"TRAILING_STOP_LOSS_ORDER","tradeID":"4","distance":"0.00150"},
+			"relatedTransactionIDs":["8"],"lastTransactionID":"8"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.CreateTrailingStopLossOrder("4", "0.00150", OrderOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	trailing, ok := response.OrderCreateTransaction.(*TrailingStopLossOrderTransaction)
+	if !ok || trailing.Distance != "0.00150" || !response.Pending() {
+		t.Errorf("Expected the trailing stop's creation, got %#v", response.OrderCreateTransaction)
+	}
+
+	for name, create := range map[string]func() (OrderCreateResponse, error){
+		"no distance": func() (OrderCreateResponse, error) { return c.CreateTrailingStopLossOrder("4", "", OrderOptions{}) },
+		"no trade": func() (OrderCreateResponse, error) {
+			return c.CreateTrailingStopLossOrder("", "0.00150", OrderOptions{})
+		},
+		"GTD without a time": func() (OrderCreateResponse, error) {
+			return c.CreateTrailingStopLossOrder("4", "0.00150", OrderOptions{TimeInForce: "GTD"})
+		},
+	} {
+		if _, err := create(); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package goanda
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OrderPrecision is how a connection fits new orders to their instrument's precision, see
+// ConnectionConfig.OrderPrecision
+type OrderPrecision int
+
+const (
+	// PrecisionAsGiven sends orders as they are given, for OANDA to reject those it can't accept
+	PrecisionAsGiven OrderPrecision = iota
+	// PrecisionRound rounds prices and distances to the nearest step of the instrument's display
+	// precision, and units toward zero to its trade units precision
+	PrecisionRound
+	// PrecisionStrict refuses orders with a price, distance or units finer than the instrument's
+	// precision with an error wrapping ErrPrecision, rather than rounding them
+	PrecisionStrict
+)
+
+// instrumentCatalogTTL is how long the account's instruments are kept before they are fetched again
+const instrumentCatalogTTL = time.Hour
+
+// ErrPrecision is returned for an order that isn't at its instrument's precision under PrecisionStrict
+var ErrPrecision = errors.New("goanda: finer than the instrument's precision")
+
+// instrumentCatalog is the account's instruments, fetched when first needed
+type instrumentCatalog struct {
+	mu          sync.Mutex
+	instruments map[string]InstrumentDetails
+	fetched     time.Time
+}
+
+// Instrument returns the details of one of the account's instruments. They are fetched for every
+// instrument at once and kept for an hour, or until an instrument that isn't among them is asked for.
+func (c *Connection) Instrument(name string) (InstrumentDetails, error) {
+	c.catalog.mu.Lock()
+	defer c.catalog.mu.Unlock()
+
+	details, ok := c.catalog.instruments[name]
+	if ok && time.Since(c.catalog.fetched) < instrumentCatalogTTL {
+		return details, nil
+	}
+
+	instruments, err := c.GetAccountInstruments(c.accountID)
+	if err != nil {
+		return InstrumentDetails{}, err
+	}
+	c.catalog.instruments = make(map[string]InstrumentDetails, len(instruments))
+	for _, i := range instruments {
+		c.catalog.instruments[i.Name] = i
+	}
+	c.catalog.fetched = time.Now()
+
+	if details, ok = c.catalog.instruments[name]; !ok {
+		return InstrumentDetails{}, fmt.Errorf("goanda: instrument %s is not tradeable by account %s", name, c.accountID)
+	}
+	return details, nil
+}
+
+// fitPrecision fits an order's prices, distances and units to its instrument as the connection's
+// OrderPrecision says, refusing units below the instrument's minimum trade size. An order on a trade,
+// which has no instrument of its own, is fitted to the trade's. The caller's dependent order details
+// are copied rather than changed.
+func (c *Connection) fitPrecision(order *OrderBody) error {
+	if c.precision == PrecisionAsGiven {
+		return nil
+	}
+	instrument := order.Instrument
+	if instrument == "" && order.TradeID != "" {
+		trade, err := c.GetTrade(order.TradeID)
+		if err != nil {
+			return fmt.Errorf("goanda: fitting the order to the precision of trade %s: %w", order.TradeID, err)
+		}
+		instrument = trade.Trade.Instrument
+	}
+	if instrument == "" {
+		return nil
+	}
+	details, err := c.Instrument(instrument)
+	if err != nil {
+		return fmt.Errorf("goanda: fitting the order to %s's precision: %w", instrument, err)
+	}
+
+	var errs []error
+	fitPrice := func(name string, price *string) {
+		if *price == "" {
+			return
+		}
+		value, err := strconv.ParseFloat(*price, 64)
+		if err != nil {
+			// left for OANDA to reject
+			return
+		}
+		rounded := roundToPrecision(value, details.DisplayPrecision, roundNearest)
+		switch {
+		case withinPrecision(value, rounded, details.DisplayPrecision):
+		case c.precision == PrecisionStrict:
+			errs = append(errs, fmt.Errorf("%w: the %s %s has more than %d decimal places", ErrPrecision, name, *price, details.DisplayPrecision))
+		default:
+			*price = strconv.FormatFloat(rounded, 'f', details.DisplayPrecision, 64)
+		}
+	}
+
+	fitPrice("price", &order.Price)
+	fitPrice("price bound", &order.PriceBound)
+	fitPrice("distance", &order.Distance)
+	for _, onFill := range []struct {
+		name    string
+		details **OnFill
+	}{
+		{"take profit", &order.TakeProfitOnFill},
+		{"stop loss", &order.StopLossOnFill},
+		{"guaranteed stop loss", &order.GuaranteedStopLossOnFill},
+		{"trailing stop loss", &order.TrailingStopLossOnFill},
+	} {
+		if *onFill.details == nil {
+			continue
+		}
+		fitted := **onFill.details
+		fitPrice(onFill.name+" price", &fitted.Price)
+		fitPrice(onFill.name+" distance", &fitted.Distance)
+		*onFill.details = &fitted
+	}
+
+	if order.Units != 0 {
+		units := roundUnits(order.Units, details.TradeUnitsPrecision)
+		minimum, _ := strconv.ParseFloat(details.MinimumTradeSize, 64)
+		switch {
+		case units != order.Units && c.precision == PrecisionStrict:
+			errs = append(errs, fmt.Errorf("%w: %s units have more than %d decimal places", ErrPrecision, formatUnits(order.Units), details.TradeUnitsPrecision))
+		case math.Abs(units) < minimum:
+			errs = append(errs, fmt.Errorf("goanda: %s units are below %s's minimum trade size of %s", formatUnits(order.Units), details.Name, details.MinimumTradeSize))
+		default:
+			order.Units = units
+		}
+	}
+	return errors.Join(errs...)
+}
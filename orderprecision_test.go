@@ -0,0 +1,108 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func precisionServer(t *testing.T, fetches *int, received *OrderPayload) *Connection {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/instruments":
+			*fetches++
+			json.NewEncoder(w).Encode(map[string]Instruments{"instruments": {eurusdDetails}})
+		case "/accounts/test-account/trades/7":
+			w.Write([]byte(`{"trade":{"id":"7","instrument":"EUR_USD"},"lastTransactionID":"6"}`))
+		case "/accounts/test-account/orders":
+			json.NewDecoder(r.Body).Decode(received)
+			w.Write([]byte(`{"orderCreateTransaction":{"id":"6","type":"LIMIT_ORDER"},"lastTransactionID":"6"}`))
+		default:
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+}
+
+func TestOrderPrecisionRound(t *testing.T) {
+	defer logTestResult(t, "OrderPrecisionRound")
+
+	var fetches int
+	var received OrderPayload
+	c := precisionServer(t, &fetches, &received)
+	c.precision = PrecisionRound
+
+	stopLoss := &OnFill{Distance: "0.00123456"}
+	order := OrderBody{Type: "LIMIT", Instrument: "EUR_USD", Units: 1000.7, Price: "1.085037", TimeInForce: "GTC", StopLossOnFill: stopLoss}
+	if _, err := c.CreateOrder(OrderPayload{Order: order}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.Order.Price != "1.08504" || received.Order.Units != 1000 {
+		t.Errorf("Expected the price and units to be rounded, got %s and %v", received.Order.Price, received.Order.Units)
+	}
+	if received.Order.StopLossOnFill.Distance != "0.00123" {
+		t.Errorf("Expected the stop loss distance to be rounded, got %s", received.Order.StopLossOnFill.Distance)
+	}
+	if stopLoss.Distance != "0.00123456" {
+		t.Error("Expected the caller's stop loss details to be left alone")
+	}
+
+	order.Price = "1.0850"
+	if _, err := c.CreateOrder(OrderPayload{Order: order}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.Order.Price != "1.0850" {
+		t.Errorf("Expected a price at precision to be sent as given, got %s", received.Order.Price)
+	}
+	if fetches != 1 {
+		t.Errorf("Expected the instruments to be fetched once, got %d", fetches)
+	}
+
+	// an order on a trade is fitted to the trade's instrument
+	onTrade := OrderBody{Type: "STOP_LOSS", TradeID: "7", Price: "1.079996", TimeInForce: "GTC"}
+	if _, err := c.CreateOrder(OrderPayload{Order: onTrade}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.Order.Price != "1.08000" {
+		t.Errorf("Expected the stop loss on the trade to be rounded, got %s", received.Order.Price)
+	}
+
+	order.Units = 0.4
+	if _, err := c.CreateOrder(OrderPayload{Order: order}); err == nil {
+		t.Error("Expected units below the minimum trade size to be refused")
+	}
+	order.Units, order.Instrument = 1000, "XAU_USD"
+	if _, err := c.CreateOrder(OrderPayload{Order: order}); err == nil {
+		t.Error("Expected an instrument the account can't trade to be refused")
+	}
+}
+
+func TestOrderPrecisionStrict(t *testing.T) {
+	defer logTestResult(t, "OrderPrecisionStrict")
+
+	var fetches int
+	var received OrderPayload
+	c := precisionServer(t, &fetches, &received)
+	c.precision = PrecisionStrict
+
+	order := OrderBody{Type: "LIMIT", Instrument: "EUR_USD", Units: 1000.5, Price: "1.085037", TimeInForce: "GTC",
+		TakeProfitOnFill: &OnFill{Price: "1.09"}}
+	_, err := c.CreateOrder(OrderPayload{Order: order})
+	if !errors.Is(err, ErrPrecision) {
+		t.Fatalf("Expected ErrPrecision, got %v", err)
+	}
+	if received.Order.Instrument != "" {
+		t.Error("Expected the order not to be sent")
+	}
+
+	order.Units, order.Price = 1000, "1.08504"
+	if _, err := c.CreateOrder(OrderPayload{Order: order}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.Order.Price != "1.08504" || received.Order.TakeProfitOnFill.Price != "1.09" {
+		t.Errorf("Expected an order at precision to be sent as given, got %+v", received.Order)
+	}
+}
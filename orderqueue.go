@@ -0,0 +1,163 @@
+package goanda
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OrderQueueOptions tunes an OrderQueue
+type OrderQueueOptions struct {
+	// MaxInFlight is how many requests may be in flight at once, it defaults to 1 so that each request
+	// is answered before the next is sent
+	MaxInFlight int
+	// MinSpacing is the least time between the start of one request and the next, such as
+	// time.Second/20 to stay within 20 orders a second. Zero doesn't space them.
+	MinSpacing time.Duration
+}
+
+// OrderQueue sends a connection's order requests one after another, in the order they are made, at
+// most MaxInFlight at a time and MinSpacing apart. A strategy sending its orders, replacements and
+// cancellations through one queue keeps a burst of signals within OANDA's order rate limits, and
+// with one in flight a modification can't overtake the order it modifies.
+// It is safe for concurrent use.
+type OrderQueue struct {
+	c           *Connection
+	maxInFlight int
+	spacing     time.Duration
+
+	mu       sync.Mutex
+	inFlight int
+	waiting  []chan time.Time
+	next     time.Time
+}
+
+// NewOrderQueue creates a queue for the connection's order requests
+func (c *Connection) NewOrderQueue(opts OrderQueueOptions) *OrderQueue {
+	maxInFlight := opts.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = 1
+	}
+	return &OrderQueue{c: c, maxInFlight: maxInFlight, spacing: opts.MinSpacing}
+}
+
+// Submit creates the order once it is its turn
+func (q *OrderQueue) Submit(ctx context.Context, order OrderRequest) (OrderCreateResponse, error) {
+	payload, err := order.Build()
+	if err != nil {
+		return OrderCreateResponse{}, err
+	}
+	var created OrderCreateResponse
+	err = q.Do(ctx, func() error {
+		return q.c.createOrder(payload, &created)
+	})
+	return created, err
+}
+
+// Replace replaces the pending order once it is its turn, see ReplaceOrder
+func (q *OrderQueue) Replace(ctx context.Context, orderSpecifier string, body OrderPayload, opts ReplaceOrderOptions) (OrderReplaceResponse, error) {
+	var replaced OrderReplaceResponse
+	err := q.Do(ctx, func() error {
+		var err error
+		replaced, err = q.c.ReplaceOrder(orderSpecifier, body, opts)
+		return err
+	})
+	return replaced, err
+}
+
+// Cancel cancels the pending order once it is its turn
+func (q *OrderQueue) Cancel(ctx context.Context, orderSpecifier string) (CancelledOrder, error) {
+	var cancelled CancelledOrder
+	err := q.Do(ctx, func() error {
+		var err error
+		cancelled, err = q.c.CancelOrder(orderSpecifier)
+		return err
+	})
+	return cancelled, err
+}
+
+// Do waits for its turn and then calls request, for order requests the queue has no method for, such
+// as closing a trade. It returns ctx.Err() without calling request if ctx is done first.
+func (q *OrderQueue) Do(ctx context.Context, request func() error) error {
+	start, err := q.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer q.release()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return request()
+}
+
+// Waiting returns how many requests are queued behind those in flight
+func (q *OrderQueue) Waiting() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting)
+}
+
+// acquire waits for a place in flight, returning when the request may start
+func (q *OrderQueue) acquire(ctx context.Context) (time.Time, error) {
+	q.mu.Lock()
+	if q.inFlight < q.maxInFlight && len(q.waiting) == 0 {
+		q.inFlight++
+		start := q.reserve()
+		q.mu.Unlock()
+		return start, nil
+	}
+	turn := make(chan time.Time, 1)
+	q.waiting = append(q.waiting, turn)
+	q.mu.Unlock()
+
+	select {
+	case start := <-turn:
+		return start, nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	for i, waiting := range q.waiting {
+		if waiting == turn {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			q.mu.Unlock()
+			return time.Time{}, ctx.Err()
+		}
+	}
+	q.mu.Unlock()
+	// given the place as ctx was done, pass it on
+	<-turn
+	q.release()
+	return time.Time{}, ctx.Err()
+}
+
+// release gives the place in flight to the first request waiting
+func (q *OrderQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.waiting) == 0 {
+		q.inFlight--
+		return
+	}
+	turn := q.waiting[0]
+	q.waiting = q.waiting[1:]
+	turn <- q.reserve()
+}
+
+// reserve returns when the next request may start and spaces the one after it, the caller holds mu
+func (q *OrderQueue) reserve() time.Time {
+	start := time.Now()
+	if start.Before(q.next) {
+		start = q.next
+	}
+	q.next = start.Add(q.spacing)
+	return start
+}
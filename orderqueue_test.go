@@ -0,0 +1,172 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrderQueue(t *testing.T) {
+	defer logTestResult(t, "OrderQueue")
+
+	var inFlight, peak int32
+	var mu sync.Mutex
+	var requests []string
+	var starts []time.Time
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		if n > atomic.LoadInt32(&peak) {
+			atomic.StoreInt32(&peak, n)
+		}
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		starts = append(starts, time.Now())
+		first := len(requests) == 1
+		mu.Unlock()
+		if first {
+			<-release
+		}
+
+		switch {
+		case r.Method == http.MethodPost:
+			w.Write([]byte(`{"orderCreateTransaction":{"id":"6","type":"LIMIT_ORDER"},"lastTransactionID":"6"}`))
+		case strings.HasSuffix(r.URL.Path, "/cancel"):
+			w.Write([]byte(`{"orderCancelTransaction":{"id":"8","type":"ORDER_CANCEL","orderID":"7"},"lastTransactionID":"8"}`))
+		default:
+			w.Write([]byte(`{"orderCreateTransaction":{"id":"7","type":"LIMIT_ORDER"},"lastTransactionID":"7"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	q := c.NewOrderQueue(OrderQueueOptions{MinSpacing: 20 * time.Millisecond})
+	limit := OrderPayload{Order: OrderBody{Type: "LIMIT", Instrument: "EUR_USD", Units: 100, Price: "1.1000"}}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	queue := func(i int, request func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = request()
+		}()
+	}
+	queue(0, func() error {
+		created, err := q.Submit(context.Background(), limit)
+		if err == nil && created.OrderID() != "6" {
+			t.Errorf("Expected the created order, got %+v", created)
+		}
+		return err
+	})
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(requests) == 1
+	})
+	queue(1, func() error {
+		_, err := q.Replace(context.Background(), "6", limit, ReplaceOrderOptions{})
+		return err
+	})
+	waitFor(t, func() bool { return q.Waiting() == 1 })
+	queue(2, func() error {
+		cancelled, err := q.Cancel(context.Background(), "7")
+		if err == nil && cancelled.OrderCancelTransaction.ID != "8" {
+			t.Errorf("Expected the cancellation, got %+v", cancelled)
+		}
+		return err
+	})
+	waitFor(t, func() bool { return q.Waiting() == 2 })
+
+	close(release)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error from request %d: %v", i, err)
+		}
+	}
+
+	expected := []string{"POST /accounts/test-account/orders", "PUT /accounts/test-account/orders/6", "PUT /accounts/test-account/orders/7/cancel"}
+	if len(requests) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, requests)
+	}
+	for i := range expected {
+		if requests[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, requests)
+			break
+		}
+	}
+	if peak != 1 {
+		t.Errorf("Expected one request in flight at a time, got %d", peak)
+	}
+	if gap := starts[2].Sub(starts[1]); gap < 15*time.Millisecond {
+		t.Errorf("Expected the requests to be spaced, the last two started %v apart", gap)
+	}
+}
+
+func TestOrderQueueContextDone(t *testing.T) {
+	defer logTestResult(t, "OrderQueueContextDone")
+
+	release := make(chan struct{})
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			<-release
+		}
+		w.Write([]byte(`{"orderCreateTransaction":{"id":"6","type":"MARKET_ORDER"},"lastTransactionID":"6"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	q := c.NewOrderQueue(OrderQueueOptions{})
+	market := OrderPayload{Order: OrderBody{Type: "MARKET", Instrument: "EUR_USD", Units: 100}}
+
+	done := make(chan error)
+	go func() {
+		_, err := q.Submit(context.Background(), market)
+		done <- err
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&requests) == 1 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		waitFor(t, func() bool { return q.Waiting() == 1 })
+		cancel()
+	}()
+	if _, err := q.Submit(ctx, market); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the queued order to give up, got %v", err)
+	}
+	if q.Waiting() != 0 {
+		t.Errorf("Expected the order to leave the queue, %d waiting", q.Waiting())
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := q.Submit(context.Background(), market); err != nil {
+		t.Errorf("Expected the queue to be free, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected the cancelled order not to be sent, got %d requests", requests)
+	}
+}
+
+// waitFor polls until condition holds, failing the test if it doesn't within a second
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Error("Timed out waiting")
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,108 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// RejectReason is OANDA's reason for rejecting a transaction, the rejectReason of a *_REJECT transaction
+type RejectReason string
+
+// Reject reasons an order is commonly refused for, OANDA has many more
+const (
+	RejectInsufficientMargin         RejectReason = "INSUFFICIENT_MARGIN"
+	RejectInsufficientLiquidity      RejectReason = "INSUFFICIENT_LIQUIDITY"
+	RejectMarketHalted               RejectReason = "MARKET_HALTED"
+	RejectInstrumentNotTradeable     RejectReason = "INSTRUMENT_NOT_TRADEABLE"
+	RejectAccountLocked              RejectReason = "ACCOUNT_LOCKED"
+	RejectAccountNotActive           RejectReason = "ACCOUNT_NOT_ACTIVE"
+	RejectPricePrecisionExceeded     RejectReason = "PRICE_PRECISION_EXCEEDED"
+	RejectPriceDistancePrecision     RejectReason = "PRICE_DISTANCE_PRECISION_EXCEEDED"
+	RejectUnitsPrecisionExceeded     RejectReason = "UNITS_PRECISION_EXCEEDED"
+	RejectUnitsMinimumNotMet         RejectReason = "UNITS_MINIMUM_NOT_MET"
+	RejectUnitsLimitExceeded         RejectReason = "UNITS_LIMIT_EXCEEDED"
+	RejectPositionSizeExceeded       RejectReason = "POSITION_SIZE_EXCEEDED"
+	RejectPendingOrdersAllowed       RejectReason = "PENDING_ORDERS_ALLOWED_EXCEEDED"
+	RejectGTDTimestampInPast         RejectReason = "TIME_IN_FORCE_GTD_TIMESTAMP_IN_PAST"
+	RejectClientOrderIDAlreadyExists RejectReason = "CLIENT_ORDER_ID_ALREADY_EXISTS"
+)
+
+// OrderRejectedError is returned when OANDA refuses an order it was sent to create or replace, or to set
+// on a trade, with the reason it gave. It wraps the request's APIError.
+type OrderRejectedError struct {
+	APIError
+	// RejectReason is why the order was refused, the reject transaction's reason or else OANDA's error code
+	RejectReason RejectReason
+	// Transaction is the order's reject transaction, nil if OANDA recorded none
+	Transaction           *OrderRejectTransaction
+	RelatedTransactionIDs []string
+	LastTransactionID     string
+}
+
+// Error implements error
+func (e OrderRejectedError) Error() string {
+	return "goanda: order rejected: " + string(e.RejectReason)
+}
+
+// Unwrap returns the request's APIError
+func (e OrderRejectedError) Unwrap() error {
+	return e.APIError
+}
+
+// orderRejection returns err as an OrderRejectedError if it is OANDA refusing an order, which it
+// reports with an order reject transaction or an error code, and err otherwise
+func orderRejection(err error) error {
+	var apiErr APIError
+	if !errors.As(err, &apiErr) || len(apiErr.body) == 0 {
+		return err
+	}
+
+	var body struct {
+		OrderRejectTransaction                   json.RawMessage `json:"orderRejectTransaction"`
+		TakeProfitOrderRejectTransaction         json.RawMessage `json:"takeProfitOrderRejectTransaction"`
+		StopLossOrderRejectTransaction           json.RawMessage `json:"stopLossOrderRejectTransaction"`
+		TrailingStopLossOrderRejectTransaction   json.RawMessage `json:"trailingStopLossOrderRejectTransaction"`
+		GuaranteedStopLossOrderRejectTransaction json.RawMessage `json:"guaranteedStopLossOrderRejectTransaction"`
+		ClientExtensionsModifyRejectTransaction  json.RawMessage `json:"orderClientExtensionsModifyRejectTransaction"`
+		RelatedTransactionIDs                    []string        `json:"relatedTransactionIDs"`
+		LastTransactionID                        string          `json:"lastTransactionID"`
+		ErrorCode                                string          `json:"errorCode"`
+	}
+	if json.Unmarshal(apiErr.body, &body) != nil {
+		return err
+	}
+	// a trade's orders are refused with the reject transaction of the order that couldn't be set, and an
+	// order's client extensions with that of their modification
+	var reject *OrderRejectTransaction
+	for _, data := range []json.RawMessage{
+		body.OrderRejectTransaction,
+		body.TakeProfitOrderRejectTransaction,
+		body.StopLossOrderRejectTransaction,
+		body.TrailingStopLossOrderRejectTransaction,
+		body.GuaranteedStopLossOrderRejectTransaction,
+		body.ClientExtensionsModifyRejectTransaction,
+	} {
+		var decodeErr error
+		if reject, decodeErr = decodeReject(data); decodeErr != nil {
+			return err
+		}
+		if reject != nil {
+			break
+		}
+	}
+	if reject == nil && body.ErrorCode == "" {
+		return err
+	}
+
+	rejected := OrderRejectedError{
+		APIError:              apiErr,
+		RejectReason:          RejectReason(body.ErrorCode),
+		Transaction:           reject,
+		RelatedTransactionIDs: body.RelatedTransactionIDs,
+		LastTransactionID:     body.LastTransactionID,
+	}
+	if reject != nil && reject.RejectReason != "" {
+		rejected.RejectReason = RejectReason(reject.RejectReason)
+	}
+	return rejected
+}
@@ -0,0 +1,94 @@
+package goanda
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOrderRejectedError(t *testing.T) {
+	defer logTestResult(t, "OrderRejectedError")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"orderRejectTransaction":{"id":"9","type":"MARKET_ORDER_REJECT","instrument":"EUR_USD",
+			"units":"1000000","rejectReason":"INSUFFICIENT_MARGIN"},
+			"relatedTransactionIDs":["9"],"lastTransactionID":"9",
+			"errorCode":"INSUFFICIENT_MARGIN","errorMessage":"Insufficient margin"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	_, err := c.CreateMarketOrder("EUR_USD", 1000000, OrderOptions{})
+
+	var rejected OrderRejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("Expected an OrderRejectedError, got %v", err)
+	}
+	if rejected.RejectReason != RejectInsufficientMargin || rejected.LastTransactionID != "9" || len(rejected.RelatedTransactionIDs) != 1 {
+		t.Errorf("Unexpected rejection: %+v", rejected)
+	}
+	if rejected.Transaction == nil || rejected.Transaction.ID != "9" || rejected.Transaction.Units != "1000000" {
+		t.Errorf("Expected the reject transaction, got %+v", rejected.Transaction)
+	}
+	var apiErr APIError
+	if !errors.As(err, &apiErr) || apiErr.Response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected the rejection to wrap its APIError, got %v", err)
+	}
+	if err.Error() != "goanda: order rejected: INSUFFICIENT_MARGIN" {
+		t.Errorf("Unexpected message: %v", err)
+	}
+}
+
+func TestOrderClientExtensionsRejected(t *testing.T) {
+	defer logTestResult(t, "OrderClientExtensionsRejected")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"orderClientExtensionsModifyRejectTransaction":{"id":"9","type":"ORDER_CLIENT_EXTENSIONS_MODIFY_REJECT",
+			"orderID":"6","rejectReason":"CLIENT_ORDER_ID_ALREADY_EXISTS"},
+			"lastTransactionID":"9","errorCode":"CLIENT_ORDER_ID_ALREADY_EXISTS"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	_, err := c.SetOrderClientExtensions("6", OrderClientExtensions{ClientExtensions: &OrderExtensions{ID: "taken"}})
+
+	var rejected OrderRejectedError
+	if !errors.As(err, &rejected) || rejected.RejectReason != RejectClientOrderIDAlreadyExists {
+		t.Fatalf("Expected an OrderRejectedError, got %v", err)
+	}
+	if rejected.Transaction == nil || rejected.Transaction.OrderID != "6" {
+		t.Errorf("Expected the modification's reject transaction, got %+v", rejected.Transaction)
+	}
+}
+
+func TestOrderRejectedErrorFromErrorCode(t *testing.T) {
+	defer logTestResult(t, "OrderRejectedErrorFromErrorCode")
+
+	status := http.StatusBadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		if status == http.StatusBadRequest {
+			w.Write([]byte(`{"errorCode":"PRICE_PRECISION_EXCEEDED","errorMessage":"Precision exceeded"}`))
+			return
+		}
+		w.Write([]byte(`{"errorMessage":"Internal server error"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	_, err := c.CreateLimitOrder("EUR_USD", 100, "1.123456789", OrderOptions{})
+	var rejected OrderRejectedError
+	if !errors.As(err, &rejected) || rejected.RejectReason != RejectPricePrecisionExceeded || rejected.Transaction != nil {
+		t.Errorf("Expected a rejection for the error code, got %#v", err)
+	}
+
+	status = http.StatusInternalServerError
+	_, err = c.CreateLimitOrder("EUR_USD", 100, "1.1000", OrderOptions{})
+	var apiErr APIError
+	if errors.As(err, &rejected) || !errors.As(err, &apiErr) {
+		t.Errorf("Expected a plain APIError for a server error, got %#v", err)
+	}
+}
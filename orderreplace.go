@@ -0,0 +1,124 @@
+package goanda
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ReplaceOrderOptions decides what a replacement order keeps from the order it replaces. OANDA keeps
+// nothing: a replacement is a new order, and whatever it doesn't set is gone.
+type ReplaceOrderOptions struct {
+	// KeepClientExtensions gives the replacement the replaced order's client extensions, unless it sets its own.
+	// Without them the order can no longer be found by its client ID.
+	KeepClientExtensions bool
+	// KeepTradeDetails gives the replacement the replaced order's trade, for an order on a trade such as a
+	// stop loss, and the take profit, stop loss, trailing stop loss, guaranteed stop loss and client
+	// extensions of the trade it opens, each unless the replacement sets its own
+	KeepTradeDetails bool
+}
+
+// OrderReplaceResponse is OANDA's response to replacing an order, with its transactions decoded
+type OrderReplaceResponse struct {
+	// OrderCancelTransaction is the cancellation of the replaced order
+	OrderCancelTransaction *OrderCancelTransaction
+	// OrderCreateTransaction is the creation of the replacement, such as a *LimitOrderTransaction
+	OrderCreateTransaction TypedTransaction
+	// OrderFillTransaction is the replacement's fill, if it was filled immediately
+	OrderFillTransaction *OrderFillTransaction
+	// ReplacingOrderCancelTransaction is the replacement's cancellation, if it was cancelled immediately
+	ReplacingOrderCancelTransaction *OrderCancelTransaction
+	RelatedTransactionIDs           []string
+	LastTransactionID               string
+}
+
+// UnmarshalJSON decodes the response's transactions into their concrete types
+func (r *OrderReplaceResponse) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		OrderCancelTransaction          json.RawMessage `json:"orderCancelTransaction"`
+		OrderCreateTransaction          json.RawMessage `json:"orderCreateTransaction"`
+		OrderFillTransaction            json.RawMessage `json:"orderFillTransaction"`
+		ReplacingOrderCancelTransaction json.RawMessage `json:"replacingOrderCancelTransaction"`
+		RelatedTransactionIDs           []string        `json:"relatedTransactionIDs"`
+		LastTransactionID               string          `json:"lastTransactionID"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*r = OrderReplaceResponse{
+		RelatedTransactionIDs: raw.RelatedTransactionIDs,
+		LastTransactionID:     raw.LastTransactionID,
+	}
+	var err error
+	if r.OrderCreateTransaction, err = decodeOptionalTransaction(raw.OrderCreateTransaction); err != nil {
+		return err
+	}
+	if r.OrderCancelTransaction, err = decodeCancel(raw.OrderCancelTransaction); err != nil {
+		return err
+	}
+	if r.ReplacingOrderCancelTransaction, err = decodeCancel(raw.ReplacingOrderCancelTransaction); err != nil {
+		return err
+	}
+	r.OrderFillTransaction, err = decodeFill(raw.OrderFillTransaction)
+	return err
+}
+
+// OrderID returns the ID of the replacement order
+func (r OrderReplaceResponse) OrderID() string {
+	if r.OrderCreateTransaction == nil {
+		return ""
+	}
+	return r.OrderCreateTransaction.Header().ID
+}
+
+// ReplaceOrder cancels a pending order and creates body in its place in one request, so there is no
+// moment without the order. The order is given by its ID, or by its client ID prefixed with @.
+// What the replacement keeps from the order it replaces is set by opts, which fetches the order first.
+func (c *Connection) ReplaceOrder(orderSpecifier string, body OrderPayload, opts ReplaceOrderOptions) (OrderReplaceResponse, error) {
+	if opts.KeepClientExtensions || opts.KeepTradeDetails {
+		current, err := c.GetOrder(orderSpecifier)
+		if err != nil {
+			return OrderReplaceResponse{}, fmt.Errorf("goanda: fetching order %s to replace: %w", orderSpecifier, err)
+		}
+		body.Order = opts.carryOver(current.Order, body.Order)
+	}
+	if err := c.prepareOrder(&body.Order); err != nil {
+		return OrderReplaceResponse{}, err
+	}
+
+	var response OrderReplaceResponse
+	err := c.putAndUnmarshal("/accounts/"+c.accountID+"/orders/"+orderSpecifier, body, &response)
+	return response, orderRejection(err)
+}
+
+// carryOver copies what the options keep from the current order to its replacement, where the
+// replacement leaves it unset
+func (opts ReplaceOrderOptions) carryOver(current OrderInfo, replacement OrderBody) OrderBody {
+	if opts.KeepClientExtensions && replacement.ClientExtensions == nil {
+		replacement.ClientExtensions = current.ClientExtensions
+	}
+	if !opts.KeepTradeDetails {
+		return replacement
+	}
+
+	if replacement.TradeID == "" {
+		replacement.TradeID = current.TradeID
+	}
+	if replacement.TradeClientExtensions == nil {
+		replacement.TradeClientExtensions = current.TradeClientExtensions
+	}
+	for _, onFill := range []struct {
+		current     *OnFill
+		replacement **OnFill
+	}{
+		{current.TakeProfitOnFill, &replacement.TakeProfitOnFill},
+		{current.StopLossOnFill, &replacement.StopLossOnFill},
+		{current.TrailingStopLossOnFill, &replacement.TrailingStopLossOnFill},
+		{current.GuaranteedStopLossOnFill, &replacement.GuaranteedStopLossOnFill},
+	} {
+		if *onFill.replacement == nil {
+			*onFill.replacement = onFill.current
+		}
+	}
+	return replacement
+}
@@ -0,0 +1,97 @@
+package goanda
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReplaceOrder(t *testing.T) {
+	defer logTestResult(t, "ReplaceOrder")
+
+	var replacement OrderBody
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/orders/@entry-1" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"order":{"id":"6","type":"LIMIT","state":"PENDING","instrument":"EUR_USD","units":"1000","price":"1.0850",
+				"clientExtensions":{"id":"entry-1","tag":"breakout"},"tradeClientExtensions":{"tag":"breakout-trade"},
+				"stopLossOnFill":{"distance":"0.0020","timeInForce":"GTC"},"takeProfitOnFill":{"price":"1.0950","timeInForce":"GTC"}}}`))
+		case http.MethodPut:
+			var payload OrderPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			replacement = payload.Order
+			w.Write([]byte(`{
+				"orderCancelTransaction":{"id":"7","type":"ORDER_CANCEL","orderID":"6","reason":"CLIENT_REQUEST_REPLACED","replacedByOrderID":"8"},
+				"orderCreateTransaction":{"id":"8","type":"LIMIT_ORDER","instrument":"EUR_USD","units":"1000","price":"1.0840","replacesOrderID":"6"},
+				"relatedTransactionIDs":["7","8"],"lastTransactionID":"8"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	payload, err := NewLimitOrder("EUR_USD").Units(1000).Price("1.0840").TakeProfit("1.0990").Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	response, err := c.ReplaceOrder("@entry-1", payload, ReplaceOrderOptions{KeepClientExtensions: true, KeepTradeDetails: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if replacement.ClientExtensions == nil || replacement.ClientExtensions.ID != "entry-1" || replacement.TradeClientExtensions.Tag != "breakout-trade" {
+		t.Errorf("Expected the client extensions to be carried over, got %+v", replacement)
+	}
+	if replacement.StopLossOnFill == nil || replacement.StopLossOnFill.Distance != "0.0020" {
+		t.Errorf("Expected the stop loss to be carried over, got %+v", replacement.StopLossOnFill)
+	}
+	if replacement.TakeProfitOnFill == nil || replacement.TakeProfitOnFill.Price != "1.0990" {
+		t.Errorf("Expected the replacement's own take profit to be kept, got %+v", replacement.TakeProfitOnFill)
+	}
+
+	if response.OrderCancelTransaction == nil || response.OrderCancelTransaction.ReplacedByOrderID != "8" {
+		t.Errorf("Expected the replaced order's cancellation, got %+v", response.OrderCancelTransaction)
+	}
+	limit, ok := response.OrderCreateTransaction.(*LimitOrderTransaction)
+	if !ok || limit.ReplacesOrderID != "6" || response.OrderID() != "8" {
+		t.Errorf("Expected the replacement's creation, got %#v", response.OrderCreateTransaction)
+	}
+	if response.OrderFillTransaction != nil || response.ReplacingOrderCancelTransaction != nil {
+		t.Errorf("Expected the replacement to be pending, got %+v", response)
+	}
+}
+
+func TestReplaceOrderWithoutCarryingOver(t *testing.T) {
+	defer logTestResult(t, "ReplaceOrderWithoutCarryingOver")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Expected the order not to be fetched, got %s", r.Method)
+		}
+		var payload OrderPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload.Order.ClientExtensions != nil {
+			t.Errorf("Expected no client extensions, got %+v", payload.Order.ClientExtensions)
+		}
+		w.Write([]byte(`{
+			"orderCancelTransaction":{"id":"7","type":"ORDER_CANCEL","orderID":"6"},
+			"orderCreateTransaction":{"id":"8","type":"MARKET_ORDER"},
+			"replacingOrderCancelTransaction":{"id":"9","type":"ORDER_CANCEL","orderID":"8","reason":"INSUFFICIENT_LIQUIDITY"},
+			"lastTransactionID":"9"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.ReplaceOrder("6", OrderPayload{Order: OrderBody{Type: "MARKET", Instrument: "EUR_USD", Units: 1000}}, ReplaceOrderOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.ReplacingOrderCancelTransaction == nil || response.ReplacingOrderCancelTransaction.Reason != "INSUFFICIENT_LIQUIDITY" {
+		t.Errorf("Expected the replacement's cancellation, got %+v", response.ReplacingOrderCancelTransaction)
+	}
+}
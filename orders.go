@@ -3,6 +3,12 @@ package goanda
 // Supporting OANDA docs - http://developer.oanda.com/rest-live-v20/order-ep/
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,17 +22,29 @@ type OnFill struct {
 	TimeInForce      string           `json:"timeInForce,omitempty"`
 	Price            string           `json:"price,omitempty"` // must be a string for float precision
 	Distance         string           `json:"distance,omitempty"`
-	GtdTime          string           `json:"gtdTime,omitempty"`
+	GtdTime          time.Time        `json:"gtdTime,omitempty"`
 	ClientExtensions *OrderExtensions `json:"clientExtensions,omitempty"`
 }
 
+// MarshalJSON leaves out GtdTime when it is not set, as OrderBody.MarshalJSON does
+func (o OnFill) MarshalJSON() ([]byte, error) {
+	type onFill OnFill
+	return json.Marshal(struct {
+		onFill
+		GtdTime *time.Time `json:"gtdTime,omitempty"`
+	}{
+		onFill:  onFill(o),
+		GtdTime: setTime(o.GtdTime),
+	})
+}
+
 type OrderBody struct {
 	ID                       string           `json:"id,omitempty"`
 	CreateTime               time.Time        `json:"createTime,omitempty"`
 	State                    string           `json:"state,omitempty"`
 	ClientExtensions         *OrderExtensions `json:"clientExtensions,omitempty"`
 	Instrument               string           `json:"instrument"`
-	Units                    int              `json:"units"`
+	Units                    float64          `json:"units"` // positive to buy, negative to sell, and a float64 for fractional units
 	TimeInForce              string           `json:"timeInForce"`
 	PriceBound               string           `json:"priceBound,omitempty"`
 	Type                     string           `json:"type"`
@@ -52,6 +70,44 @@ type OrderBody struct {
 	Distance                 string           `json:"distance,omitempty"`
 }
 
+// MarshalJSON leaves out the times that are not set, which omitempty does not do for a time.Time,
+// and the instrument and units of an order on a trade, which takes them from the trade, so an order
+// is sent with only the fields OANDA accepts for it
+func (o OrderBody) MarshalJSON() ([]byte, error) {
+	type body OrderBody
+	instrument, units := &o.Instrument, &o.Units
+	switch o.Type {
+	case "TAKE_PROFIT", "STOP_LOSS", "GUARANTEED_STOP_LOSS", "TRAILING_STOP_LOSS":
+		instrument, units = nil, nil
+	}
+	return json.Marshal(struct {
+		body
+		Instrument    *string    `json:"instrument,omitempty"`
+		Units         *float64   `json:"units,omitempty"`
+		CreateTime    *time.Time `json:"createTime,omitempty"`
+		FilledTime    *time.Time `json:"filledTime,omitempty"`
+		CancelledTime *time.Time `json:"cancelledTime,omitempty"`
+		GTDTime       *time.Time `json:"gtdTime,omitempty"`
+	}{
+		body:          body(o),
+		Instrument:    instrument,
+		Units:         units,
+		CreateTime:    setTime(o.CreateTime),
+		FilledTime:    setTime(o.FilledTime),
+		CancelledTime: setTime(o.CancelledTime),
+		GTDTime:       setTime(o.GTDTime),
+	})
+}
+
+// setTime returns a pointer to t in UTC, the RFC3339 form the connection exchanges times in, or nil if it is zero
+func setTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
 type OrderPayload struct {
 	Order OrderBody `json:"order"`
 }
@@ -141,6 +197,22 @@ func (or *OrderResponse) GetOrderState() string {
 	return "UNKNOWN"
 }
 
+// OrderClientExtensions are the client extensions SetOrderClientExtensions sets, either may be nil to
+// leave it as it is
+type OrderClientExtensions struct {
+	// ClientExtensions are the order's own
+	ClientExtensions *OrderExtensions `json:"clientExtensions,omitempty"`
+	// TradeClientExtensions are given to the trade the order opens when it fills
+	TradeClientExtensions *OrderExtensions `json:"tradeClientExtensions,omitempty"`
+}
+
+// ModifiedOrderClientExtensions is the response to setting an order's client extensions
+type ModifiedOrderClientExtensions struct {
+	OrderClientExtensionsModifyTransaction OrderClientExtensionsModifyTransaction `json:"orderClientExtensionsModifyTransaction"`
+	RelatedTransactionIDs                  []string                               `json:"relatedTransactionIDs"`
+	LastTransactionID                      string                                 `json:"lastTransactionID"`
+}
+
 type OrderInfo struct {
 	ID                       string           `json:"id"`
 	CreateTime               time.Time        `json:"createTime"`
@@ -172,6 +244,8 @@ type OrderInfo struct {
 	GTDTime                  time.Time        `json:"gtdTime,omitempty"`
 	PartialFill              string           `json:"partialFill,omitempty"`
 	Distance                 string           `json:"distance,omitempty"`
+	ClientTradeID            string           `json:"clientTradeID,omitempty"`
+	TrailingStopValue        string           `json:"trailingStopValue,omitempty"`
 }
 
 type RetrievedOrders struct {
@@ -183,30 +257,53 @@ type RetrievedOrder struct {
 	Order OrderInfo `json:"order"`
 }
 
+// CancelledOrder is the response to cancelling an order
 type CancelledOrder struct {
-	OrderCancelTransaction struct {
-		ID                string    `json:"id"`
-		Time              time.Time `json:"time"`
-		UserID            int       `json:"userID"`
-		AccountID         string    `json:"accountID"`
-		BatchID           string    `json:"batchID"`
-		RequestID         string    `json:"requestID"`
-		Type              string    `json:"type"`
-		OrderID           string    `json:"orderID"`
-		ClientOrderID     string    `json:"clientOrderID"`
-		Reason            string    `json:"reason"`
-		ReplacedByOrderID string    `json:"replacedByOrderID"`
-	} `json:"orderCancelTransaction"`
-	RelatedTransactionIDs []string `json:"relatedTransactionIDs"`
-	LastTransactionID     string   `json:"lastTransactionID"`
+	OrderCancelTransaction OrderCancelTransaction `json:"orderCancelTransaction"`
+	RelatedTransactionIDs  []string               `json:"relatedTransactionIDs"`
+	LastTransactionID      string                 `json:"lastTransactionID"`
 }
 
 func (c *Connection) CreateOrder(body OrderPayload) (OrderResponse, error) {
 	or := OrderResponse{}
-	err := c.postAndUnmarshal("/accounts/"+c.accountID+"/orders", body, &or)
+	err := c.createOrder(body, &or)
 	return or, err
 }
 
+// createOrder posts an order, decoding OANDA's response into receive. With IdempotentOrders the order
+// is tagged with a client ID and posted by submitIdempotent.
+func (c *Connection) createOrder(body OrderPayload, receive interface{}) error {
+	if err := c.prepareOrder(&body.Order); err != nil {
+		return err
+	}
+	if !c.idempotent {
+		return c.postOrder(body, receive)
+	}
+
+	clientID := tagOrder(&body.Order)
+	return c.submitIdempotent(clientID, func() error {
+		return c.postOrder(body, receive)
+	}, func() (bool, error) {
+		return c.recoverOrder(body.Order, receive)
+	})
+}
+
+// prepareOrder adjusts and checks a new order's expiry and fits it to its instrument's precision
+func (c *Connection) prepareOrder(order *OrderBody) error {
+	if c.skewGTD {
+		c.adjustGTD(order)
+	}
+	if err := c.checkGTD(*order); err != nil {
+		return err
+	}
+	return c.fitPrecision(order)
+}
+
+// postOrder sends a prepared order
+func (c *Connection) postOrder(body OrderPayload, receive interface{}) error {
+	return orderRejection(c.postAndUnmarshal("/accounts/"+c.accountID+"/orders", body, receive))
+}
+
 func (c *Connection) GetOrders(instrument string) (RetrievedOrders, error) {
 	endpoint := "/accounts/" + c.accountID + "/orders"
 	if instrument != "" {
@@ -218,12 +315,97 @@ func (c *Connection) GetOrders(instrument string) (RetrievedOrders, error) {
 	return ro, err
 }
 
+// OrdersOptions filters the orders returned by ListOrders, fields left empty are not filtered on
+type OrdersOptions struct {
+	// IDs lists the orders to return
+	IDs []string
+	// State is PENDING, the default, FILLED, TRIGGERED, CANCELLED or ALL
+	State      string
+	Instrument string
+	// Count is the most orders returned, OANDA returns 50 by default and at most 500
+	Count int
+	// BeforeID returns only the orders older than it, to page back through the account's orders
+	BeforeID string
+}
+
+func (o OrdersOptions) query() string {
+	return listQuery(o.IDs, o.State, o.Instrument, o.Count, o.BeforeID)
+}
+
+// listQuery returns the query string of the filters the orders and trades endpoints share, leaving out
+// those that are empty
+func listQuery(ids []string, state string, instrument string, count int, beforeID string) string {
+	query := url.Values{}
+	if len(ids) != 0 {
+		query.Set("ids", strings.Join(ids, ","))
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	if instrument != "" {
+		query.Set("instrument", instrument)
+	}
+	if count != 0 {
+		query.Set("count", strconv.Itoa(count))
+	}
+	if beforeID != "" {
+		query.Set("beforeID", beforeID)
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// ListOrders returns the account's orders selected by opts, newest first
+func (c *Connection) ListOrders(opts OrdersOptions) (RetrievedOrders, error) {
+	ro := RetrievedOrders{}
+	err := c.getAndUnmarshal("/accounts/"+c.accountID+"/orders"+opts.query(), &ro)
+	return ro, err
+}
+
+// ListPendingOrders returns every pending order on the instrument, or on the account if instrument is
+// empty. Unlike ListOrders it is not limited to a page of orders. The orders dependent on a trade,
+// such as its take profit or stop loss, have no instrument of their own and are matched by their trade's.
+func (c *Connection) ListPendingOrders(instrument string) (RetrievedOrders, error) {
+	ro, err := c.GetPendingOrders()
+	if err != nil || instrument == "" {
+		return ro, err
+	}
+
+	var trades map[string]bool
+	var orders []OrderInfo
+	for _, order := range ro.Orders {
+		if order.Instrument == "" && order.TradeID != "" {
+			if trades == nil {
+				open, err := c.ListOpenTrades(instrument)
+				if err != nil {
+					return RetrievedOrders{}, fmt.Errorf("goanda: listing the trades of dependent orders: %w", err)
+				}
+				trades = map[string]bool{}
+				for _, trade := range open.Trades {
+					trades[trade.ID] = true
+				}
+			}
+			if !trades[order.TradeID] {
+				continue
+			}
+		} else if order.Instrument != instrument {
+			continue
+		}
+		orders = append(orders, order)
+	}
+	ro.Orders = orders
+	return ro, nil
+}
+
 func (c *Connection) GetPendingOrders() (RetrievedOrders, error) {
 	ro := RetrievedOrders{}
 	err := c.getAndUnmarshal("/accounts/"+c.accountID+"/pendingOrders", &ro)
 	return ro, err
 }
 
+// GetOrder returns an order by its ID, or by its client ID prefixed with @, see GetOrderByClientID
 func (c *Connection) GetOrder(orderSpecifier string) (RetrievedOrder, error) {
 	ro := RetrievedOrder{}
 	err := c.getAndUnmarshal(
@@ -236,6 +418,17 @@ func (c *Connection) GetOrder(orderSpecifier string) (RetrievedOrder, error) {
 	return ro, err
 }
 
+// GetOrderByClientID returns the order tagged with the client ID in its client extensions.
+// An order that does not exist is an APIError with a 404 status.
+func (c *Connection) GetOrderByClientID(clientID string) (RetrievedOrder, error) {
+	return c.GetOrder(clientSpecifier(clientID))
+}
+
+// clientSpecifier refers to an order or trade by its client ID in place of OANDA's
+func clientSpecifier(clientID string) string {
+	return "@" + url.PathEscape(clientID)
+}
+
 func (c *Connection) UpdateOrder(orderSpecifier string, body OrderPayload) (RetrievedOrder, error) {
 	ro := RetrievedOrder{}
 	err := c.putAndUnmarshal(
@@ -249,6 +442,7 @@ func (c *Connection) UpdateOrder(orderSpecifier string, body OrderPayload) (Retr
 	return ro, err
 }
 
+// CancelOrder cancels a pending order by its ID, or by its client ID prefixed with @
 func (c *Connection) CancelOrder(orderSpecifier string) (CancelledOrder, error) {
 	co := CancelledOrder{}
 	err := c.putAndUnmarshal(
@@ -262,3 +456,30 @@ func (c *Connection) CancelOrder(orderSpecifier string) (CancelledOrder, error)
 	)
 	return co, err
 }
+
+// CancelOrderByClientID cancels the pending order tagged with the client ID in its client extensions
+func (c *Connection) CancelOrderByClientID(clientID string) (CancelledOrder, error) {
+	return c.CancelOrder(clientSpecifier(clientID))
+}
+
+// SetOrderClientExtensions tags, comments or gives a client ID to an order after it was created, so it
+// can be found by GetOrderByClientID or attributed to a strategy. The order is given by its ID, or by its
+// client ID prefixed with @. OANDA doesn't allow this for accounts linked to MT4, a modification OANDA
+// refuses returns an OrderRejectedError.
+func (c *Connection) SetOrderClientExtensions(orderSpecifier string, extensions OrderClientExtensions) (ModifiedOrderClientExtensions, error) {
+	if extensions.ClientExtensions == nil && extensions.TradeClientExtensions == nil {
+		return ModifiedOrderClientExtensions{}, errors.New("goanda: no client extensions to set")
+	}
+
+	modified := ModifiedOrderClientExtensions{}
+	err := c.putAndUnmarshal(
+		"/accounts/"+
+			c.accountID+
+			"/orders/"+
+			orderSpecifier+
+			"/clientExtensions",
+		extensions,
+		&modified,
+	)
+	return modified, orderRejection(err)
+}
@@ -23,7 +23,7 @@ func TestCreateOrder(t *testing.T) {
 		err := json.NewDecoder(r.Body).Decode(&payload)
 		assert.NoError(t, err)
 
-		units := strconv.Itoa(payload.Order.Units)
+		units := formatUnits(payload.Order.Units)
 
 		response := OrderResponse{
 			LastTransactionID: "1000",
@@ -156,6 +156,100 @@ func TestGetOrders(t *testing.T) {
 	assert.Equal(t, "DEFAULT", order.TriggerCondition)
 }
 
+func TestListOrders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/accounts/test-account/orders", r.URL.Path)
+
+		query := r.URL.Query()
+		assert.Equal(t, "3,5", query.Get("ids"))
+		assert.Equal(t, "ALL", query.Get("state"))
+		assert.Equal(t, "EUR_USD", query.Get("instrument"))
+		assert.Equal(t, "10", query.Get("count"))
+		assert.Equal(t, "7", query.Get("beforeID"))
+
+		w.Write([]byte(`{"orders":[
+			{"id":"5","type":"TRAILING_STOP_LOSS","state":"PENDING","tradeID":"4","clientTradeID":"my-trade","distance":"0.0050","trailingStopValue":"1.0950"},
+			{"id":"3","type":"LIMIT","state":"CANCELLED","instrument":"EUR_USD","units":"100","price":"1.1000"}],
+			"lastTransactionID":"8"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	orders, err := c.ListOrders(OrdersOptions{
+		IDs:        []string{"3", "5"},
+		State:      "ALL",
+		Instrument: "EUR_USD",
+		Count:      10,
+		BeforeID:   "7",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "8", orders.LastTransactionID)
+	assert.Len(t, orders.Orders, 2)
+	assert.Equal(t, "1.0950", orders.Orders[0].TrailingStopValue)
+	assert.Equal(t, "my-trade", orders.Orders[0].ClientTradeID)
+	assert.Equal(t, "CANCELLED", orders.Orders[1].State)
+}
+
+func TestListOrdersWithoutFilters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "", r.URL.RawQuery)
+		w.Write([]byte(`{"orders":[],"lastTransactionID":"8"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	orders, err := c.ListOrders(OrdersOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, orders.Orders)
+}
+
+func TestListPendingOrders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/accounts/test-account/openTrades" {
+			w.Write([]byte(`{"trades":[
+				{"id":"2","instrument":"EUR_USD","currentUnits":"100","state":"OPEN"},
+				{"id":"4","instrument":"GBP_USD","currentUnits":"100","state":"OPEN"}],"lastTransactionID":"8"}`))
+			return
+		}
+		assert.Equal(t, "/accounts/test-account/pendingOrders", r.URL.Path)
+		w.Write([]byte(`{"orders":[
+			{"id":"5","type":"LIMIT","state":"PENDING","instrument":"GBP_USD","units":"100","price":"1.2500"},
+			{"id":"3","type":"STOP","state":"PENDING","instrument":"EUR_USD","units":"-100","price":"1.0500"},
+			{"id":"6","type":"STOP_LOSS","state":"PENDING","tradeID":"2","price":"1.0400"},
+			{"id":"7","type":"TAKE_PROFIT","state":"PENDING","tradeID":"4","price":"1.3000"}],
+			"lastTransactionID":"8"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	all, err := c.ListPendingOrders("")
+	assert.NoError(t, err)
+	assert.Len(t, all.Orders, 4)
+
+	// the stop loss on trade 2 is on the trade's instrument
+	eurusd, err := c.ListPendingOrders("EUR_USD")
+	assert.NoError(t, err)
+	assert.Len(t, eurusd.Orders, 2)
+	assert.Equal(t, "3", eurusd.Orders[0].ID)
+	assert.Equal(t, "6", eurusd.Orders[1].ID)
+	assert.Equal(t, "8", eurusd.LastTransactionID)
+}
+
 func TestGetOrder(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/accounts/test-account/orders/1", r.URL.Path)
@@ -211,6 +305,26 @@ func TestGetOrder(t *testing.T) {
 	assert.Equal(t, "0.0100", order.Order.TrailingStopLossOnFill.Distance)
 }
 
+func TestGetOrderByClientID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/accounts/test-account/orders/@my order/1", r.URL.Path)
+		assert.Equal(t, "/accounts/test-account/orders/@my%20order%2F1", r.URL.RawPath)
+		w.Write([]byte(`{"order":{"id":"6","type":"LIMIT","state":"PENDING","clientExtensions":{"id":"my order/1"}},"lastTransactionID":"6"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	order, err := c.GetOrderByClientID("my order/1")
+	assert.NoError(t, err)
+	assert.Equal(t, "6", order.Order.ID)
+	assert.Equal(t, "my order/1", order.Order.ClientExtensions.ID)
+}
+
 func TestUpdateOrder(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/accounts/test-account/orders/1", r.URL.Path)
@@ -220,7 +334,7 @@ func TestUpdateOrder(t *testing.T) {
 		err := json.NewDecoder(r.Body).Decode(&payload)
 		assert.NoError(t, err)
 
-		units := strconv.Itoa(payload.Order.Units)
+		units := formatUnits(payload.Order.Units)
 
 		response := RetrievedOrder{
 			Order: OrderInfo{
@@ -292,23 +406,10 @@ func TestCancelOrder(t *testing.T) {
 		assert.Equal(t, "PUT", r.Method)
 
 		response := CancelledOrder{
-			OrderCancelTransaction: struct {
-				ID                string    `json:"id"`
-				Time              time.Time `json:"time"`
-				UserID            int       `json:"userID"`
-				AccountID         string    `json:"accountID"`
-				BatchID           string    `json:"batchID"`
-				RequestID         string    `json:"requestID"`
-				Type              string    `json:"type"`
-				OrderID           string    `json:"orderID"`
-				ClientOrderID     string    `json:"clientOrderID"`
-				Reason            string    `json:"reason"`
-				ReplacedByOrderID string    `json:"replacedByOrderID"`
-			}{
-				ID:      "1000",
-				Type:    "ORDER_CANCEL",
-				OrderID: "1",
-				Reason:  "CLIENT_REQUEST",
+			OrderCancelTransaction: OrderCancelTransaction{
+				TransactionHeader: TransactionHeader{ID: "1000", Type: "ORDER_CANCEL"},
+				OrderID:           "1",
+				Reason:            "CLIENT_REQUEST",
 			},
 			RelatedTransactionIDs: []string{"1000"},
 			LastTransactionID:     "1000",
@@ -334,6 +435,62 @@ func TestCancelOrder(t *testing.T) {
 	assert.Equal(t, "1000", cancelledOrder.LastTransactionID)
 }
 
+func TestCancelOrderByClientID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/accounts/test-account/orders/@entry-1/cancel", r.URL.Path)
+		assert.Equal(t, "PUT", r.Method)
+		w.Write([]byte(`{"orderCancelTransaction":{"id":"7","time":"2024-01-02T15:04:05.000000000Z","type":"ORDER_CANCEL",
+			"orderID":"6","clientOrderID":"entry-1","reason":"CLIENT_REQUEST"},"relatedTransactionIDs":["7"],"lastTransactionID":"7"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	cancelled, err := c.CancelOrderByClientID("entry-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "6", cancelled.OrderCancelTransaction.OrderID)
+	assert.Equal(t, "entry-1", cancelled.OrderCancelTransaction.ClientOrderID)
+	assert.Equal(t, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), cancelled.OrderCancelTransaction.Time)
+}
+
+func TestSetOrderClientExtensions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/accounts/test-account/orders/6/clientExtensions", r.URL.Path)
+		assert.Equal(t, "PUT", r.Method)
+
+		var body map[string]map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]string{"id": "entry-1", "tag": "breakout"}, body["clientExtensions"])
+		assert.NotContains(t, body, "tradeClientExtensions")
+
+		w.Write([]byte(`{"orderClientExtensionsModifyTransaction":{"id":"7","type":"ORDER_CLIENT_EXTENSIONS_MODIFY",
+			"orderID":"6","clientExtensionsModify":{"id":"entry-1","tag":"breakout"}},"relatedTransactionIDs":["7"],"lastTransactionID":"7"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	modified, err := c.SetOrderClientExtensions("6", OrderClientExtensions{
+		ClientExtensions: &OrderExtensions{ID: "entry-1", Tag: "breakout"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "6", modified.OrderClientExtensionsModifyTransaction.OrderID)
+	assert.Equal(t, "breakout", modified.OrderClientExtensionsModifyTransaction.ClientExtensionsModify.Tag)
+	assert.Nil(t, modified.OrderClientExtensionsModifyTransaction.TradeClientExtensionsModify)
+	assert.Equal(t, "7", modified.LastTransactionID)
+
+	_, err = c.SetOrderClientExtensions("6", OrderClientExtensions{})
+	assert.Error(t, err)
+}
+
 func TestOrderIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
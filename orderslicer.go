@@ -0,0 +1,315 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SlicePlan is how SliceOrder splits an order into child orders
+type SlicePlan struct {
+	// Slices is how many child orders the units are split into, it defaults to one for each of Prices
+	Slices int
+	// Interval is the time between one child order being sent and the next
+	Interval time.Duration
+	// Prices, if set, scales in or out at price levels: each child order is a limit order at the price
+	// of its slice, which are one for each price. Otherwise the child orders are market orders.
+	Prices []string
+	// Options apply to every child order. PriceBound limits the price each market slice may fill at,
+	// and the client IDs are given to each child with its slice's number appended.
+	Options OrderOptions
+}
+
+// OrderSlice is one of the child orders of a SlicedOrder
+type OrderSlice struct {
+	Units float64
+	// Price is the limit price of the slice, empty for a market slice
+	Price   string
+	OrderID string
+	// State is the child order's state, empty until it is sent
+	State OrderState
+	// Filled is how many of the slice's units have filled, at the average FillPrice
+	Filled    float64
+	FillPrice float64
+	// Err is why the child order couldn't be created
+	Err error
+}
+
+// SlicedOrder is an order worked as child orders, see SliceOrder. Its child orders are sent by Run,
+// while the fills of limit slices that don't fill when they are placed are applied by Handle, as the
+// transactions arrive. It is safe for concurrent use.
+type SlicedOrder struct {
+	Instrument string
+	// Units is the target, positive to buy and negative to sell
+	Units float64
+
+	c        *Connection
+	plan     SlicePlan
+	mu       sync.Mutex
+	slices   []OrderSlice
+	notional []float64
+	// sent is how many slices have been sent, running is set while Run is
+	sent    int
+	running bool
+}
+
+// SliceOrder splits units of the instrument into equal child orders, whole units apart from the last
+// which takes the remainder, to be sent by Run as market orders or as limit orders at the plan's prices.
+// Nothing is sent until Run is called, so that the order's transactions can be handed to Handle from
+// the start:
+//
+//	sliced, err := c.SliceOrder("EUR_USD", 100000, goanda.SlicePlan{Slices: 10, Interval: time.Minute})
+//	go sc.StreamTransactions(ctx, func(response goanda.TransactionStreamResponse) {
+//		if t, err := response.Decode(); err == nil {
+//			sliced.Handle(t)
+//		}
+//	})
+//	err = sliced.Run(ctx)
+func (c *Connection) SliceOrder(instrument string, units float64, plan SlicePlan) (*SlicedOrder, error) {
+	n := plan.Slices
+	if n <= 0 {
+		n = len(plan.Prices)
+	}
+	switch {
+	case n <= 0:
+		return nil, errors.New("goanda: slicing an order needs a number of slices or prices")
+	case len(plan.Prices) > 0 && len(plan.Prices) != n:
+		return nil, fmt.Errorf("goanda: %d slices need %d prices, got %d", n, n, len(plan.Prices))
+	case units == 0:
+		return nil, errors.New("goanda: slicing an order needs units")
+	}
+
+	slice := math.Trunc(units / float64(n))
+	if slice == 0 {
+		return nil, fmt.Errorf("goanda: %s units can't be split into %d slices", formatUnits(units), n)
+	}
+	s := &SlicedOrder{
+		Instrument: instrument,
+		Units:      units,
+		c:          c,
+		plan:       plan,
+		slices:     make([]OrderSlice, n),
+		notional:   make([]float64, n),
+	}
+	for i := range s.slices {
+		s.slices[i].Units = slice
+		if len(plan.Prices) > 0 {
+			s.slices[i].Price = plan.Prices[i]
+		}
+	}
+	s.slices[n-1].Units = units - slice*float64(n-1)
+	return s, nil
+}
+
+// Run sends the slices not yet sent, Interval apart. It returns once every slice is sent, or with
+// ctx.Err() once ctx is done with the rest unsent, or with a slice's error, which stops the slicing.
+// Run may be called again to send the rest, but not while it is running.
+func (s *SlicedOrder) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errors.New("goanda: the sliced order is already running")
+	}
+	s.running = true
+	first := s.sent
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	n := len(s.slices)
+	for i := first; i < n; i++ {
+		if i > 0 && s.plan.Interval > 0 {
+			timer := time.NewTimer(s.plan.Interval)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.send(i, s.plan.Options); err != nil {
+			return fmt.Errorf("goanda: sending slice %d of %d: %w", i+1, n, err)
+		}
+	}
+	return nil
+}
+
+// send creates the slice's child order and records its outcome
+func (s *SlicedOrder) send(i int, opts OrderOptions) error {
+	s.mu.Lock()
+	slice := s.slices[i]
+	s.mu.Unlock()
+
+	opts.ClientExtensions = numberExtensions(opts.ClientExtensions, i)
+	opts.TradeClientExtensions = numberExtensions(opts.TradeClientExtensions, i)
+	var response OrderCreateResponse
+	var err error
+	if slice.Price == "" {
+		response, err = s.c.CreateMarketOrder(s.Instrument, slice.Units, opts)
+	} else {
+		response, err = s.c.CreateLimitOrder(s.Instrument, slice.Units, slice.Price, opts)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.slices[i].Err = err
+		return err
+	}
+	s.sent = i + 1
+	s.slices[i].OrderID = response.OrderID()
+	s.slices[i].State = OrderPending
+	if response.OrderFillTransaction != nil {
+		s.fill(i, response.OrderFillTransaction)
+	}
+	if reissued := response.ReissuedOrderID(); reissued != "" {
+		s.slices[i].OrderID = reissued
+		s.slices[i].State = OrderPending
+	} else if response.OrderCancelTransaction != nil {
+		s.slices[i].State = OrderCancelled
+	}
+	return nil
+}
+
+// numberExtensions appends the slice's number to the client ID, which OANDA refuses to reuse
+func numberExtensions(extensions *OrderExtensions, i int) *OrderExtensions {
+	if extensions == nil || extensions.ID == "" {
+		return extensions
+	}
+	numbered := *extensions
+	numbered.ID = fmt.Sprintf("%s-%d", numbered.ID, i+1)
+	return &numbered
+}
+
+// Handle applies a transaction filling or cancelling one of the child orders, others are ignored
+func (s *SlicedOrder) Handle(t TypedTransaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch t := t.(type) {
+	case *OrderFillTransaction:
+		if i := s.find(t.OrderID); i >= 0 && !s.slices[i].State.Done() {
+			s.fill(i, t)
+		}
+	case *OrderCancelTransaction:
+		i := s.find(t.OrderID)
+		if i < 0 || s.slices[i].State.Done() {
+			return
+		}
+		if t.ReplacedByOrderID != "" {
+			s.slices[i].OrderID = t.ReplacedByOrderID
+			return
+		}
+		s.slices[i].State = OrderCancelled
+	}
+}
+
+// Cancel cancels the child orders that are still pending, such as the limit slices not yet reached
+func (s *SlicedOrder) Cancel() error {
+	s.mu.Lock()
+	var pending []string
+	for _, slice := range s.slices {
+		if slice.State == OrderPending || slice.State == OrderTriggered {
+			pending = append(pending, slice.OrderID)
+		}
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, id := range pending {
+		_, err := s.c.CancelOrder(id)
+		if notFound(err) {
+			// filled or cancelled since, Handle records which
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("goanda: cancelling slice order %s: %w", id, err))
+			continue
+		}
+		s.mu.Lock()
+		if i := s.find(id); i >= 0 && !s.slices[i].State.Done() {
+			s.slices[i].State = OrderCancelled
+		}
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Slices returns the slices as they stand
+func (s *SlicedOrder) Slices() []OrderSlice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]OrderSlice(nil), s.slices...)
+}
+
+// Executed returns the units filled across the slices, signed as Units is, and their average price
+func (s *SlicedOrder) Executed() (units float64, averagePrice float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notional := 0.0
+	for i, slice := range s.slices {
+		units += slice.Filled
+		notional += s.notional[i]
+	}
+	if units != 0 {
+		averagePrice = notional / math.Abs(units)
+	}
+	return units, averagePrice
+}
+
+// Done reports whether every slice was sent and has filled or been cancelled
+func (s *SlicedOrder) Done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, slice := range s.slices {
+		if !slice.State.Done() {
+			return false
+		}
+	}
+	return true
+}
+
+// fill adds a fill to the slice, which is filled once all its units are, the caller holds mu
+func (s *SlicedOrder) fill(i int, fill *OrderFillTransaction) {
+	units, err := strconv.ParseFloat(fill.Units, 64)
+	if err != nil {
+		s.c.logf("goanda: invalid units %q in fill %s of slice order %s", fill.Units, fill.ID, fill.OrderID)
+		return
+	}
+	price, err := strconv.ParseFloat(fill.Price, 64)
+	if err != nil {
+		s.c.logf("goanda: invalid price %q in fill %s of slice order %s", fill.Price, fill.ID, fill.OrderID)
+		return
+	}
+
+	slice := &s.slices[i]
+	s.notional[i] += math.Abs(units) * price
+	slice.Filled += units
+	if slice.Filled != 0 {
+		slice.FillPrice = s.notional[i] / math.Abs(slice.Filled)
+	}
+	if math.Abs(slice.Filled) >= math.Abs(slice.Units) {
+		slice.State = OrderFilled
+	}
+}
+
+// find returns the index of the slice with the child order, or -1, the caller holds mu
+func (s *SlicedOrder) find(orderID string) int {
+	for i, slice := range s.slices {
+		if slice.OrderID != "" && slice.OrderID == orderID {
+			return i
+		}
+	}
+	return -1
+}
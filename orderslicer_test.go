@@ -0,0 +1,187 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sliceBroker fills market orders at prices in turn, the last fill being of only half the units, and
+// leaves limit orders pending
+func sliceBroker(t *testing.T, prices ...string) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var orders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "/cancel") {
+			orders = append(orders, "cancel "+strings.Split(r.URL.Path, "/")[4])
+			w.Write([]byte(`{"orderCancelTransaction":{"id":"99","type":"ORDER_CANCEL","reason":"CLIENT_REQUEST"},"lastTransactionID":"99"}`))
+			return
+		}
+
+		var payload struct {
+			Order struct {
+				Type             string           `json:"type"`
+				Units            json.Number      `json:"units"`
+				Price            string           `json:"price"`
+				ClientExtensions *OrderExtensions `json:"clientExtensions"`
+			} `json:"order"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Unexpected body: %v", err)
+		}
+		order := payload.Order
+		n := len(orders)
+		description := []string{order.Type, order.Units.String()}
+		if order.Price != "" {
+			description = append(description, order.Price)
+		}
+		if order.ClientExtensions != nil {
+			description = append(description, "@"+order.ClientExtensions.ID)
+		}
+		orders = append(orders, strings.Join(description, " "))
+
+		id := 10 * (n + 1)
+		if order.Type == "LIMIT" {
+			fmt.Fprintf(w, `{"orderCreateTransaction":{"id":"%d","type":"LIMIT_ORDER"},"lastTransactionID":"%d"}`, id, id)
+			return
+		}
+		units := order.Units.String()
+		cancel := ""
+		if n == len(prices)-1 {
+			half, _ := order.Units.Float64()
+			units = formatUnits(half / 2)
+			cancel = fmt.Sprintf(`,"orderCancelTransaction":{"id":"%d","type":"ORDER_CANCEL","orderID":"%d","reason":"MARKET_HALTED"}`, id+2, id)
+		}
+		fmt.Fprintf(w, `{"orderCreateTransaction":{"id":"%d","type":"MARKET_ORDER"},
+			"orderFillTransaction":{"id":"%d","type":"ORDER_FILL","orderID":"%d","units":"%s","price":"%s"}%s,"lastTransactionID":"%d"}`,
+			id, id+1, id, units, prices[n], cancel, id+2)
+	}))
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), orders...)
+	}
+}
+
+func TestSliceOrder(t *testing.T) {
+	defer logTestResult(t, "SliceOrder")
+
+	server, orders := sliceBroker(t, "1.1000", "1.1010", "1.1020")
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	sliced, err := c.SliceOrder("EUR_USD", -1000, SlicePlan{
+		Slices:  3,
+		Options: OrderOptions{ClientExtensions: &OrderExtensions{ID: "exit"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(orders()) != 0 {
+		t.Fatalf("Expected nothing sent before Run, got %v", orders())
+	}
+	if err := sliced.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"MARKET -333 @exit-1", "MARKET -333 @exit-2", "MARKET -334 @exit-3"}
+	if sent := orders(); strings.Join(sent, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected %v, got %v", expected, sent)
+	}
+	slices := sliced.Slices()
+	if slices[0].State != OrderFilled || slices[0].Filled != -333 || slices[0].FillPrice != 1.1 || slices[0].OrderID != "10" {
+		t.Errorf("Expected the first slice filled, got %+v", slices[0])
+	}
+	if slices[2].State != OrderCancelled || slices[2].Filled != -167 {
+		t.Errorf("Expected the last slice partly filled, got %+v", slices[2])
+	}
+	units, price := sliced.Executed()
+	average := (333*1.1000 + 333*1.1010 + 167*1.1020) / 833
+	if units != -833 || fmt.Sprintf("%.6f", price) != fmt.Sprintf("%.6f", average) {
+		t.Errorf("Expected -833 units at %f, got %v at %f", average, units, price)
+	}
+	if !sliced.Done() {
+		t.Error("Expected every slice to be done")
+	}
+}
+
+func TestSliceOrderAtPrices(t *testing.T) {
+	defer logTestResult(t, "SliceOrderAtPrices")
+
+	server, orders := sliceBroker(t)
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	sliced, err := c.SliceOrder("EUR_USD", 200, SlicePlan{Prices: []string{"1.0990", "1.0980"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sliced.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sliced.Done() {
+		t.Error("Expected the limit slices to be pending")
+	}
+
+	fill := &OrderFillTransaction{OrderID: "10", Units: "100", Price: "1.0990"}
+	fill.ID, fill.Type = "30", "ORDER_FILL"
+	sliced.Handle(fill)
+	sliced.Handle(fill)
+	if err := sliced.Cancel(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"LIMIT 100 1.0990", "LIMIT 100 1.0980", "cancel 20"}
+	if sent := orders(); strings.Join(sent, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected %v, got %v", expected, sent)
+	}
+	if units, price := sliced.Executed(); units != 100 || price != 1.099 {
+		t.Errorf("Expected 100 units at 1.099, got %v at %v", units, price)
+	}
+	if slices := sliced.Slices(); slices[0].State != OrderFilled || slices[1].State != OrderCancelled || !sliced.Done() {
+		t.Errorf("Expected one slice filled and one cancelled, got %+v", slices)
+	}
+}
+
+func TestSliceOrderStops(t *testing.T) {
+	defer logTestResult(t, "SliceOrderStops")
+
+	server, orders := sliceBroker(t, "1.1000", "1.1010")
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	sliced, err := c.SliceOrder("EUR_USD", 100, SlicePlan{Slices: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sliced.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the slicing to stop, got %v", err)
+	}
+	if units, _ := sliced.Executed(); units != 0 || len(orders()) != 0 {
+		t.Errorf("Expected nothing sent, got %v units from %v", units, orders())
+	}
+	// run again, the slices are sent
+	if err := sliced.Run(context.Background()); err != nil || len(orders()) != 2 {
+		t.Errorf("Expected the slices sent once run again, got %v with %v", err, orders())
+	}
+	if err := sliced.Run(context.Background()); err != nil || len(orders()) != 2 {
+		t.Errorf("Expected nothing more sent, got %v with %v", err, orders())
+	}
+
+	if _, err := c.SliceOrder("EUR_USD", 1, SlicePlan{Slices: 2}); err == nil {
+		t.Error("Expected an error for too few units to slice")
+	}
+	if _, err := c.SliceOrder("EUR_USD", 100, SlicePlan{Slices: 3, Prices: []string{"1.1"}}); err == nil {
+		t.Error("Expected an error for too few prices")
+	}
+}
@@ -0,0 +1,436 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultTrackerRetention is how many filled and cancelled orders an OrderTracker remembers when
+// Retention is not set
+const DefaultTrackerRetention = 1000
+
+// OrderState is where an order is in its life, as OANDA names it
+type OrderState string
+
+// Order states
+const (
+	OrderPending OrderState = "PENDING"
+	// OrderTriggered is an order whose price has been reached but which hasn't filled yet
+	OrderTriggered OrderState = "TRIGGERED"
+	OrderFilled    OrderState = "FILLED"
+	OrderCancelled OrderState = "CANCELLED"
+)
+
+// Done reports whether the order can no longer change, having filled or been cancelled
+func (s OrderState) Done() bool {
+	return s == OrderFilled || s == OrderCancelled
+}
+
+// TrackedOrder is an order's live state as an OrderTracker knows it
+type TrackedOrder struct {
+	ID       string
+	ClientID string
+	// Type is the order's type, such as LIMIT or TRAILING_STOP_LOSS
+	Type       string
+	Instrument string
+	Units      string
+	Price      string
+	// TradeID is the trade an order such as a stop loss is on
+	TradeID string
+	State   OrderState
+
+	// Fill is the order's fill and Cancel its cancellation, if the tracker saw them. An order found
+	// filled or cancelled when the tracker reconciled has only FillTransactionID or CancelTransactionID.
+	Fill                *OrderFillTransaction
+	FillTransactionID   string
+	Cancel              *OrderCancelTransaction
+	CancelTransactionID string
+	// ReplacesOrderID is the order this one replaced, and ReplacedByOrderID the order that replaced it
+	ReplacesOrderID   string
+	ReplacedByOrderID string
+	// LastTransactionID is the last transaction that changed the order
+	LastTransactionID string
+}
+
+// OrderTracker follows the account's orders from its transactions, keeping each order's state as it is
+// created, triggered, filled, cancelled or replaced, and calling back the subscribers of an order when it
+// changes. It is safe for concurrent use.
+type OrderTracker struct {
+	// OnChange, if set, is called with every order that changes
+	OnChange func(TrackedOrder)
+	// Retention is how many filled and cancelled orders are remembered, the oldest being forgotten first.
+	// It defaults to DefaultTrackerRetention.
+	Retention int
+
+	c *Connection
+
+	mu          sync.Mutex
+	orders      map[string]*TrackedOrder
+	done        []string
+	subscribers map[string][]*orderSubscription
+	changed     []TrackedOrder
+}
+
+type orderSubscription struct {
+	fn func(TrackedOrder)
+}
+
+// NewOrderTracker creates a tracker for the connection's account, see Run
+func NewOrderTracker(c *Connection) *OrderTracker {
+	return &OrderTracker{
+		c:           c,
+		orders:      map[string]*TrackedOrder{},
+		subscribers: map[string][]*orderSubscription{},
+	}
+}
+
+// Run loads the account's pending orders and then handles the account's transactions from sc, from the
+// point the orders were listed, until ctx is done or the stream fails
+func (t *OrderTracker) Run(ctx context.Context, sc *StreamingConnection) error {
+	last, err := t.reconcile()
+	if err != nil {
+		return err
+	}
+	return sc.StreamTransactionsSince(ctx, last, func(response TransactionStreamResponse) {
+		transaction, err := response.Decode()
+		if err != nil {
+			// heartbeats have no transaction
+			return
+		}
+		t.Handle(transaction)
+	})
+}
+
+// Reconcile loads the account's pending orders, and looks up the orders tracked as pending that no
+// longer are, as after transactions missed while the tracker wasn't running
+func (t *OrderTracker) Reconcile() error {
+	_, err := t.reconcile()
+	return err
+}
+
+// reconcile returns the account's last transaction ID when its pending orders were listed
+func (t *OrderTracker) reconcile() (string, error) {
+	pending, err := t.c.GetPendingOrders()
+	if err != nil {
+		return "", fmt.Errorf("goanda: listing pending orders: %w", err)
+	}
+	listed := map[string]bool{}
+	for _, order := range pending.Orders {
+		listed[order.ID] = true
+	}
+
+	t.mu.Lock()
+	var missing []string
+	for id, order := range t.orders {
+		if !order.State.Done() && !listed[id] {
+			missing = append(missing, id)
+		}
+	}
+	for _, order := range pending.Orders {
+		if !t.stale(order.ID, pending.LastTransactionID) {
+			t.update(trackedInfo(order, pending.LastTransactionID))
+		}
+	}
+	t.unlock()
+
+	sort.Strings(missing)
+	for _, id := range missing {
+		found, err := t.c.GetOrder(id)
+		if err != nil {
+			return "", fmt.Errorf("goanda: looking up order %s: %w", id, err)
+		}
+		t.mu.Lock()
+		if !t.stale(id, pending.LastTransactionID) {
+			t.update(trackedInfo(found.Order, pending.LastTransactionID))
+		}
+		t.unlock()
+	}
+	return pending.LastTransactionID, nil
+}
+
+// Handle applies a transaction to the orders it creates, fills or cancels, others are ignored
+func (t *OrderTracker) Handle(transaction TypedTransaction) {
+	t.mu.Lock()
+	defer t.unlock()
+
+	id := transaction.Header().ID
+	switch transaction := transaction.(type) {
+	case *OrderFillTransaction:
+		order := t.order(transaction.OrderID, id)
+		if order.State.Done() {
+			return
+		}
+		if order.ClientID == "" {
+			order.ClientID = transaction.ClientOrderID
+		}
+		if order.Instrument == "" {
+			order.Instrument = transaction.Instrument
+		}
+		order.State = OrderFilled
+		order.Fill = transaction
+		order.FillTransactionID = id
+		order.LastTransactionID = id
+		t.update(order)
+
+	case *OrderCancelTransaction:
+		order := t.order(transaction.OrderID, id)
+		if order.State.Done() {
+			return
+		}
+		if order.ClientID == "" {
+			order.ClientID = transaction.ClientOrderID
+		}
+		order.State = OrderCancelled
+		order.Cancel = transaction
+		order.CancelTransactionID = id
+		order.ReplacedByOrderID = transaction.ReplacedByOrderID
+		order.LastTransactionID = id
+		if order.ReplacedByOrderID != "" {
+			// a subscription follows the order to its replacement
+			t.subscribers[order.ReplacedByOrderID] = append(t.subscribers[order.ReplacedByOrderID], t.subscribers[order.ID]...)
+		}
+		t.update(order)
+
+	default:
+		if order, ok := trackedCreation(transaction); ok && !t.stale(order.ID, id) {
+			t.update(order)
+		}
+	}
+}
+
+// Order returns the order with the ID, or with the client ID prefixed with @
+func (t *OrderTracker) Order(orderSpecifier string) (TrackedOrder, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if order := t.find(orderSpecifier); order != nil {
+		return *order, true
+	}
+	return TrackedOrder{}, false
+}
+
+// Pending returns the orders that are pending or triggered, ordered by ID
+func (t *OrderTracker) Pending() []TrackedOrder {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var pending []TrackedOrder
+	for _, order := range t.orders {
+		if !order.State.Done() {
+			pending = append(pending, *order)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return compareTransactionIDs(pending[i].ID, pending[j].ID) < 0
+	})
+	return pending
+}
+
+// Subscribe calls fn each time the order with the ID, or with the client ID prefixed with @, changes,
+// following it to the orders that replace it, until the returned function is called. An order
+// subscribed to by its client ID is subscribed to by its ID once the tracker knows it.
+func (t *OrderTracker) Subscribe(orderSpecifier string, fn func(TrackedOrder)) (unsubscribe func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := orderSpecifier
+	if order := t.find(orderSpecifier); order != nil {
+		key = order.ID
+	}
+	subscription := &orderSubscription{fn: fn}
+	t.subscribers[key] = append(t.subscribers[key], subscription)
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for key, subscriptions := range t.subscribers {
+			for i, s := range subscriptions {
+				if s == subscription {
+					t.subscribers[key] = append(subscriptions[:i:i], subscriptions[i+1:]...)
+					break
+				}
+			}
+			if len(t.subscribers[key]) == 0 {
+				delete(t.subscribers, key)
+			}
+		}
+	}
+}
+
+// ResetState forgets every order, as after the account is reset, and the subscriptions made by order ID,
+// the reset account's orders reusing the IDs. Subscriptions made by client ID are kept.
+func (t *OrderTracker) ResetState() error {
+	t.mu.Lock()
+	defer t.unlock()
+
+	t.orders = map[string]*TrackedOrder{}
+	t.done = nil
+	for key := range t.subscribers {
+		if !strings.HasPrefix(key, "@") {
+			delete(t.subscribers, key)
+		}
+	}
+	return nil
+}
+
+// find returns the order for the specifier, the caller holds mu
+func (t *OrderTracker) find(orderSpecifier string) *TrackedOrder {
+	if clientID, ok := strings.CutPrefix(orderSpecifier, "@"); ok {
+		for _, order := range t.orders {
+			if order.ClientID == clientID {
+				return order
+			}
+		}
+		return nil
+	}
+	return t.orders[orderSpecifier]
+}
+
+// stale reports whether the order is tracked as of the transaction or a later one, the caller holds mu
+func (t *OrderTracker) stale(id string, transactionID string) bool {
+	order, ok := t.orders[id]
+	return ok && compareTransactionIDs(order.LastTransactionID, transactionID) >= 0
+}
+
+// order returns a copy of the tracked order for a fill or cancellation of it, or a new order if the
+// tracker hasn't seen it, as for a market order filled as soon as it was created
+func (t *OrderTracker) order(id string, transactionID string) TrackedOrder {
+	if order, ok := t.orders[id]; ok {
+		return *order
+	}
+	return TrackedOrder{ID: id, State: OrderPending, LastTransactionID: transactionID}
+}
+
+// update stores the order and queues it for its subscribers, the caller holds mu
+func (t *OrderTracker) update(order TrackedOrder) {
+	current, known := t.orders[order.ID]
+	if known && *current == order {
+		return
+	}
+	if order.State.Done() && (!known || !current.State.Done()) {
+		t.retire(order.ID)
+	}
+	stored := order
+	t.orders[order.ID] = &stored
+
+	// subscriptions made by client ID move to the order's ID
+	if order.ClientID != "" {
+		byClientID := "@" + order.ClientID
+		if subscriptions, ok := t.subscribers[byClientID]; ok {
+			t.subscribers[order.ID] = append(t.subscribers[order.ID], subscriptions...)
+			delete(t.subscribers, byClientID)
+		}
+	}
+	t.changed = append(t.changed, order)
+}
+
+// retire remembers that the order is done, forgetting the oldest done orders beyond the retention
+func (t *OrderTracker) retire(id string) {
+	t.done = append(t.done, id)
+	retention := t.Retention
+	if retention <= 0 {
+		retention = DefaultTrackerRetention
+	}
+	for len(t.done) > retention {
+		forgotten := t.done[0]
+		t.done = t.done[1:]
+		delete(t.orders, forgotten)
+		delete(t.subscribers, forgotten)
+	}
+}
+
+// unlock unlocks mu and then calls back about the orders changed while it was held, so that the
+// callbacks may call the tracker
+func (t *OrderTracker) unlock() {
+	changed := t.changed
+	t.changed = nil
+	type call struct {
+		fn    func(TrackedOrder)
+		order TrackedOrder
+	}
+	var calls []call
+	for _, order := range changed {
+		for _, s := range t.subscribers[order.ID] {
+			calls = append(calls, call{s.fn, order})
+		}
+	}
+	onChange := t.OnChange
+	t.mu.Unlock()
+
+	if onChange != nil {
+		for _, order := range changed {
+			onChange(order)
+		}
+	}
+	for _, call := range calls {
+		call.fn(call.order)
+	}
+}
+
+// trackedCreation returns the order a transaction creates, if it creates one
+func trackedCreation(t TypedTransaction) (TrackedOrder, bool) {
+	var common OrderTransaction
+	var price, tradeID string
+	switch t := t.(type) {
+	case *MarketOrderTransaction:
+		common = t.OrderTransaction
+	case *LimitOrderTransaction:
+		common, price = t.OrderTransaction, t.Price
+	case *StopOrderTransaction:
+		common, price = t.OrderTransaction, t.Price
+	case *MarketIfTouchedOrderTransaction:
+		common, price = t.OrderTransaction, t.Price
+	case *FixedPriceOrderTransaction:
+		common, price = t.OrderTransaction, t.Price
+	case *TakeProfitOrderTransaction:
+		common, price, tradeID = t.OrderTransaction, t.Price, t.TradeID
+	case *StopLossOrderTransaction:
+		common, price, tradeID = t.OrderTransaction, t.Price, t.TradeID
+	case *GuaranteedStopLossOrderTransaction:
+		common, price, tradeID = t.OrderTransaction, t.Price, t.TradeID
+	case *TrailingStopLossOrderTransaction:
+		common, tradeID = t.OrderTransaction, t.TradeID
+	default:
+		return TrackedOrder{}, false
+	}
+
+	order := TrackedOrder{
+		ID:                common.ID,
+		Type:              strings.TrimSuffix(common.Type, "_ORDER"),
+		Instrument:        common.Instrument,
+		Units:             common.Units,
+		Price:             price,
+		TradeID:           tradeID,
+		State:             OrderPending,
+		ReplacesOrderID:   common.ReplacesOrderID,
+		LastTransactionID: common.ID,
+	}
+	if common.ClientExtensions != nil {
+		order.ClientID = common.ClientExtensions.ID
+	}
+	return order, true
+}
+
+// trackedInfo returns an order as OANDA lists it, as of the last transaction
+func trackedInfo(info OrderInfo, lastTransactionID string) TrackedOrder {
+	order := TrackedOrder{
+		ID:                  info.ID,
+		Type:                info.Type,
+		Instrument:          info.Instrument,
+		Units:               info.Units,
+		Price:               info.Price,
+		TradeID:             info.TradeID,
+		State:               OrderState(info.State),
+		FillTransactionID:   info.FillingTransactionID,
+		CancelTransactionID: info.CancellingTransactionID,
+		ReplacesOrderID:     info.ReplacesOrderID,
+		ReplacedByOrderID:   info.ReplacedByOrderID,
+		LastTransactionID:   lastTransactionID,
+	}
+	if info.ClientExtensions != nil {
+		order.ClientID = info.ClientExtensions.ID
+	}
+	return order
+}
@@ -0,0 +1,138 @@
+package goanda
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOrderTracker(t *testing.T) {
+	defer logTestResult(t, "OrderTracker")
+
+	tracker := NewOrderTracker(&Connection{accountID: "test-account"})
+	var changes int
+	tracker.OnChange = func(TrackedOrder) { changes++ }
+	var seen []TrackedOrder
+	tracker.Subscribe("@my-order", func(order TrackedOrder) {
+		// the tracker may be called from a subscription
+		if _, ok := tracker.Order(order.ID); !ok {
+			t.Errorf("Expected order %s to be tracked", order.ID)
+		}
+		seen = append(seen, order)
+	})
+
+	limit := &LimitOrderTransaction{Price: "1.1000"}
+	limit.ID, limit.Type, limit.Instrument, limit.Units = "6", "LIMIT_ORDER", "EUR_USD", "100"
+	limit.ClientExtensions = &OrderExtensions{ID: "my-order"}
+	tracker.Handle(limit)
+	if order, ok := tracker.Order("@my-order"); !ok || order.ID != "6" || order.State != OrderPending || order.Price != "1.1000" || order.Type != "LIMIT" {
+		t.Errorf("Expected the pending limit order, got %+v", order)
+	}
+
+	cancel := &OrderCancelTransaction{OrderID: "6", Reason: "CLIENT_REQUEST_REPLACED", ReplacedByOrderID: "8"}
+	cancel.ID, cancel.Type = "7", "ORDER_CANCEL"
+	tracker.Handle(cancel)
+	replacement := &LimitOrderTransaction{Price: "1.0990"}
+	replacement.ID, replacement.Type, replacement.Instrument, replacement.Units = "8", "LIMIT_ORDER", "EUR_USD", "100"
+	replacement.ReplacesOrderID = "6"
+	tracker.Handle(replacement)
+	fill := &OrderFillTransaction{OrderID: "8", Instrument: "EUR_USD", Units: "100", Price: "1.0990"}
+	fill.ID, fill.Type = "9", "ORDER_FILL"
+	tracker.Handle(fill)
+	// a transaction delivered again changes nothing
+	tracker.Handle(replacement)
+	tracker.Handle(fill)
+
+	expected := []struct {
+		id    string
+		state OrderState
+	}{{"6", OrderPending}, {"6", OrderCancelled}, {"8", OrderPending}, {"8", OrderFilled}}
+	if len(seen) != len(expected) {
+		t.Fatalf("Expected %d changes to be seen, got %+v", len(expected), seen)
+	}
+	for i, e := range expected {
+		if seen[i].ID != e.id || seen[i].State != e.state {
+			t.Errorf("Expected change %d to be %s %s, got %s %s", i, e.id, e.state, seen[i].ID, seen[i].State)
+		}
+	}
+	if seen[1].ReplacedByOrderID != "8" || seen[2].ReplacesOrderID != "6" || seen[3].Fill != fill || seen[3].FillTransactionID != "9" {
+		t.Errorf("Unexpected changes: %+v", seen)
+	}
+	if changes != 4 || len(tracker.Pending()) != 0 {
+		t.Errorf("Expected 4 changes and nothing pending, got %d and %+v", changes, tracker.Pending())
+	}
+}
+
+func TestOrderTrackerUnsubscribeAndRetention(t *testing.T) {
+	defer logTestResult(t, "OrderTrackerUnsubscribeAndRetention")
+
+	tracker := NewOrderTracker(&Connection{accountID: "test-account"})
+	tracker.Retention = 1
+	var calls int
+	unsubscribe := tracker.Subscribe("10", func(TrackedOrder) { calls++ })
+
+	// a market order filled as soon as it was created is tracked from its fill
+	fill := &OrderFillTransaction{OrderID: "10", ClientOrderID: "market", Instrument: "EUR_USD", Units: "100"}
+	fill.ID, fill.Type = "11", "ORDER_FILL"
+	tracker.Handle(fill)
+	if order, ok := tracker.Order("@market"); !ok || order.State != OrderFilled || order.Instrument != "EUR_USD" || calls != 1 {
+		t.Errorf("Expected the filled market order, got %+v after %d calls", order, calls)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	stop := &StopLossOrderTransaction{Price: "1.0950"}
+	stop.ID, stop.Type, stop.TradeID = "12", "STOP_LOSS_ORDER", "11"
+	tracker.Handle(stop)
+	cancel := &OrderCancelTransaction{OrderID: "12", Reason: "LINKED_TRADE_CLOSED"}
+	cancel.ID, cancel.Type = "13", "ORDER_CANCEL"
+	tracker.Handle(cancel)
+
+	if calls != 1 {
+		t.Errorf("Expected no calls after unsubscribing, got %d", calls)
+	}
+	if _, ok := tracker.Order("10"); ok {
+		t.Error("Expected the oldest done order to be forgotten")
+	}
+	if order, ok := tracker.Order("12"); !ok || order.State != OrderCancelled || order.TradeID != "11" || order.Cancel.Reason != "LINKED_TRADE_CLOSED" {
+		t.Errorf("Expected the cancelled stop loss, got %+v", order)
+	}
+}
+
+func TestOrderTrackerReconcile(t *testing.T) {
+	defer logTestResult(t, "OrderTrackerReconcile")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/pendingOrders":
+			w.Write([]byte(`{"orders":[{"id":"20","type":"STOP","state":"TRIGGERED","instrument":"EUR_USD","units":"100",
+				"price":"1.1100","clientExtensions":{"id":"breakout"}}],"lastTransactionID":"25"}`))
+		case "/accounts/test-account/orders/6":
+			w.Write([]byte(`{"order":{"id":"6","type":"LIMIT","state":"FILLED","instrument":"EUR_USD","units":"100",
+				"fillingTransactionID":"21"}}`))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	tracker := NewOrderTracker(c)
+	limit := &LimitOrderTransaction{Price: "1.1000"}
+	limit.ID, limit.Type, limit.Instrument, limit.Units = "6", "LIMIT_ORDER", "EUR_USD", "100"
+	tracker.Handle(limit)
+
+	var filled TrackedOrder
+	tracker.Subscribe("6", func(order TrackedOrder) { filled = order })
+	if err := tracker.Reconcile(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if filled.State != OrderFilled || filled.FillTransactionID != "21" || filled.Fill != nil {
+		t.Errorf("Expected the order filled while untracked, got %+v", filled)
+	}
+	pending := tracker.Pending()
+	if len(pending) != 1 || pending[0].ID != "20" || pending[0].State != OrderTriggered || pending[0].ClientID != "breakout" {
+		t.Errorf("Expected the listed order, got %+v", pending)
+	}
+}
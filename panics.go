@@ -0,0 +1,37 @@
+package goanda
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicPolicy decides what a stream does after a user callback panics
+type PanicPolicy int
+
+const (
+	// PanicStop ends the stream, closing its connection, and the stream returns the *PanicError
+	PanicStop PanicPolicy = iota
+	// PanicContinue reports the panic to OnError and carries on with the next message
+	PanicContinue
+)
+
+// PanicError is a panic recovered from a user callback
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// PanicError implements error
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("goanda: callback panicked: %v", p.Value)
+}
+
+// callSafely runs fn, converting a panic into a *PanicError
+func callSafely(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,179 @@
+package goanda
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func panicStreamingConnection(server *httptest.Server) *StreamingConnection {
+	conn := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+	sc := NewStreamingConnection(conn)
+	sc.streamURL = server.URL
+	return sc
+}
+
+func TestStreamCallbackPanicStops(t *testing.T) {
+	defer logTestResult(t, "StreamCallbackPanicStops")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, `{"type":"PRICE","instrument":"EUR_USD"}`+"\n")
+		}
+	}))
+	defer server.Close()
+
+	sc := panicStreamingConnection(server)
+
+	calls := 0
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) {
+		calls++
+		panic("boom")
+	})
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected a PanicError, got %v", err)
+	}
+	if panicErr.Value != "boom" {
+		t.Errorf("Expected the panic value to be kept, got %v", panicErr.Value)
+	}
+	if !strings.Contains(string(panicErr.Stack), "panics_test.go") {
+		t.Error("Expected the stack trace to include the panicking callback")
+	}
+	if calls != 1 {
+		t.Errorf("Expected the stream to stop after the first panic, got %d calls", calls)
+	}
+}
+
+func TestStreamCallbackPanicContinues(t *testing.T) {
+	defer logTestResult(t, "StreamCallbackPanicContinues")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, `{"type":"PRICE","instrument":"EUR_USD"}`+"\n")
+		}
+	}))
+	defer server.Close()
+
+	sc := panicStreamingConnection(server)
+	sc.PanicPolicy = PanicContinue
+
+	var reported []*PanicError
+	sc.OnPanic = func(p *PanicError) {
+		reported = append(reported, p)
+	}
+
+	calls := 0
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) {
+		calls++
+		if calls == 2 {
+			panic("second message")
+		}
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected all 3 messages to be delivered, got %d", calls)
+	}
+	if len(reported) != 1 || reported[0].Value != "second message" {
+		t.Errorf("Expected one panic to be reported, got %v", reported)
+	}
+}
+
+func TestStreamCallbackPanicReportedToOnError(t *testing.T) {
+	defer logTestResult(t, "StreamCallbackPanicReportedToOnError")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"type":"PRICE","instrument":"EUR_USD"}`+"\n")
+		fmt.Fprintf(w, `{"type":"PRICE","instrument":"GBP_USD"}`+"\n")
+	}))
+	defer server.Close()
+
+	sc := panicStreamingConnection(server)
+	sc.logger = log.New(&bytes.Buffer{}, "", 0)
+	sc.PanicPolicy = PanicContinue
+
+	var errs []error
+	sc.OnError = func(err error) {
+		errs = append(errs, err)
+	}
+	var events []StreamEventKind
+	sc.OnEvent = func(event StreamEvent) {
+		events = append(events, event.Kind)
+	}
+
+	var instruments []string
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD", "GBP_USD"}, func(price PricingStreamResponse) {
+		if price.Instrument == "EUR_USD" {
+			panic("boom")
+		}
+		instruments = append(instruments, price.Instrument)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(instruments) != 1 || instruments[0] != "GBP_USD" {
+		t.Errorf("Expected the stream to continue after the panic, got %v", instruments)
+	}
+
+	if len(errs) != 1 {
+		t.Fatalf("Expected the panic to be passed to OnError, got %v", errs)
+	}
+	var streamErr *StreamError
+	var panicErr *PanicError
+	if !errors.As(errs[0], &streamErr) || !errors.As(errs[0], &panicErr) {
+		t.Fatalf("Expected a StreamError wrapping a PanicError, got %v", errs[0])
+	}
+	if !strings.Contains(streamErr.URL, "/pricing/stream") || !strings.Contains(string(streamErr.Message), "EUR_USD") {
+		t.Errorf("Expected the stream and message the callback panicked on, got %s %s", streamErr.URL, streamErr.Message)
+	}
+	if panicErr.Value != "boom" {
+		t.Errorf("Expected the panic value to be kept, got %v", panicErr.Value)
+	}
+	if len(events) != 1 || events[0] != StreamEventPanic {
+		t.Errorf("Expected a panic event, got %v", events)
+	}
+}
+
+func TestStreamHookPanicIsContained(t *testing.T) {
+	defer logTestResult(t, "StreamHookPanicIsContained")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"type":"HEARTBEAT","time":"2024-01-02T15:04:05Z"}`+"\n")
+		fmt.Fprintf(w, `not json`+"\n")
+		fmt.Fprintf(w, `{"type":"PRICE","instrument":"EUR_USD"}`+"\n")
+	}))
+	defer server.Close()
+
+	logs := &bytes.Buffer{}
+	sc := panicStreamingConnection(server)
+	sc.logger = log.New(logs, "", 0)
+	sc.OnEvent = func(StreamEvent) { panic("event hook") }
+	sc.OnError = func(error) { panic("error hook") }
+
+	calls := 0
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) {
+		calls++
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the price after the panicking hooks to be delivered, got %d calls", calls)
+	}
+	if !strings.Contains(logs.String(), "OnEvent panicked: event hook") || !strings.Contains(logs.String(), "OnError panicked: error hook") {
+		t.Errorf("Expected the hook panics to be logged, got %q", logs.String())
+	}
+}
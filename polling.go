@@ -0,0 +1,60 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// poll runs check every interval until ctx is cancelled, returning ctx.Err(), the connection is closed,
+// returning ErrConnectionClosed, or check fails. A *dropError is a failed request: with a Reconnect
+// policy it is logged and passed to OnError and polling continues, until MaxRetries consecutive checks
+// have failed. Requests that can't succeed by retrying, such as ones refused with a 4xx, end polling.
+func (sc *StreamingConnection) poll(ctx context.Context, endpoint string, interval time.Duration, check func() error) error {
+	ctx, done, err := sc.life.worker(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		err := check()
+		var drop *dropError
+		switch {
+		case errors.As(err, &drop) && terminal(drop.err):
+			return drop.err
+		case errors.As(err, &drop):
+			failures++
+			if sc.Reconnect == nil || (sc.Reconnect.MaxRetries > 0 && failures > sc.Reconnect.MaxRetries) {
+				return drop.err
+			}
+			sc.logf("goanda: polling %s failed, retrying in %v: %v", endpoint, interval, drop.err)
+			sc.recovered(sc.hostname+endpoint, fmt.Errorf("goanda: polling %s: %w", endpoint, drop.err), nil)
+		case err != nil:
+			return stopped(err)
+		default:
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// terminal reports whether a failed request can't succeed by retrying: the connection was closed, or
+// the request was refused for a reason other than the rate limit or a server error
+func terminal(err error) bool {
+	var apiErr APIError
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		return !apiErr.RateLimited() && apiErr.Response.StatusCode < 500
+	}
+	return errors.Is(err, ErrConnectionClosed)
+}
@@ -0,0 +1,118 @@
+package goanda
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PositionSizer works out how many units to trade so that a trade stopped out loses a set share of the
+// account. The units are those of one direction, as the quote currency converts to the home currency at
+// a different rate for each.
+type PositionSizer struct {
+	// Equity is what the risk is a share of, such as the account's balance or NAV, in the home currency
+	Equity float64
+	// Risk is the share of Equity lost if the stop is hit, such as 0.01 for 1%
+	Risk float64
+	// Instrument decides the size of a pip and how the units are rounded and limited
+	Instrument InstrumentDetails
+	// Conversion converts the instrument's quote currency to the home currency, the price's
+	// quoteHomeConversionFactors for the direction traded. Zero is taken as 1, for an instrument quoted
+	// in the home currency.
+	Conversion float64
+	// Sell sizes a sell, its units are negative
+	Sell bool
+}
+
+// NewPositionSizer creates a sizer risking a share of the account's NAV on a trade in instrument, from the
+// account's summary, the instrument's details and its current home conversion factors
+func (c *Connection) NewPositionSizer(instrument string, risk float64, sell bool) (PositionSizer, error) {
+	summary, err := c.GetAccountSummary()
+	if err != nil {
+		return PositionSizer{}, err
+	}
+	nav, err := strconv.ParseFloat(summary.Account.NAV, 64)
+	if err != nil {
+		return PositionSizer{}, fmt.Errorf("goanda: invalid NAV %q: %w", summary.Account.NAV, err)
+	}
+	details, err := c.Instrument(instrument)
+	if err != nil {
+		return PositionSizer{}, err
+	}
+	pricing, err := c.GetPricingForInstruments([]string{instrument})
+	if err != nil {
+		return PositionSizer{}, err
+	}
+	if len(pricing.Prices) == 0 {
+		return PositionSizer{}, fmt.Errorf("goanda: no current price for %s", instrument)
+	}
+
+	factors := pricing.Prices[0].QuoteHomeConversionFactors
+	factor := factors.PositiveUnits
+	if sell {
+		factor = factors.NegativeUnits
+	}
+	conversion := 1.0
+	switch {
+	case factor != "":
+		if conversion, err = strconv.ParseFloat(factor, 64); err != nil {
+			return PositionSizer{}, fmt.Errorf("goanda: invalid conversion factor for %s: %w", instrument, err)
+		}
+	case !strings.HasSuffix(instrument, "_"+summary.Account.Currency):
+		return PositionSizer{}, fmt.Errorf("goanda: no conversion from %s's quote currency to %s", instrument, summary.Account.Currency)
+	}
+
+	return PositionSizer{
+		Equity:     nav,
+		Risk:       risk,
+		Instrument: details,
+		Conversion: conversion,
+		Sell:       sell,
+	}, nil
+}
+
+// PipValue is what a pip is worth for one unit, in the home currency
+func (s PositionSizer) PipValue() float64 {
+	return math.Pow10(s.Instrument.PipLocation) * s.conversion()
+}
+
+// Units returns the units to trade with a stop stopDistance from the entry price, rounded toward zero to
+// the instrument's trade units precision and capped at its maximum order size. An error is returned if
+// the risk affords less than the instrument's minimum trade size.
+func (s PositionSizer) Units(stopDistance float64) (float64, error) {
+	switch {
+	case s.Equity <= 0:
+		return 0, errors.New("goanda: sizing a position needs equity")
+	case s.Risk <= 0 || s.Risk >= 1:
+		return 0, fmt.Errorf("goanda: risk %v is not a share of equity between 0 and 1", s.Risk)
+	case stopDistance <= 0:
+		return 0, fmt.Errorf("goanda: invalid stop distance %v", stopDistance)
+	}
+
+	units := roundUnits(s.Equity*s.Risk/(stopDistance*s.conversion()), s.Instrument.TradeUnitsPrecision)
+	if maximum, err := strconv.ParseFloat(s.Instrument.MaximumOrderUnits, 64); err == nil && maximum > 0 && units > maximum {
+		units = maximum
+	}
+	if minimum, err := strconv.ParseFloat(s.Instrument.MinimumTradeSize, 64); (err == nil && units < minimum) || units == 0 {
+		return 0, fmt.Errorf("goanda: risking %v of %v with a stop %v away affords %v units of %s, below its minimum trade size",
+			s.Risk, s.Equity, stopDistance, units, s.Instrument.Name)
+	}
+	if s.Sell {
+		units = -units
+	}
+	return units, nil
+}
+
+// UnitsForPips returns the units to trade with a stop pips from the entry price, see Units
+func (s PositionSizer) UnitsForPips(pips float64) (float64, error) {
+	return s.Units(pips * math.Pow10(s.Instrument.PipLocation))
+}
+
+func (s PositionSizer) conversion() float64 {
+	if s.Conversion == 0 {
+		return 1
+	}
+	return s.Conversion
+}
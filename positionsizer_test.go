@@ -0,0 +1,105 @@
+package goanda
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPositionSizerUnits(t *testing.T) {
+	defer logTestResult(t, "PositionSizerUnits")
+
+	sizer := PositionSizer{Equity: 10000, Risk: 0.01, Instrument: eurusdDetails}
+	if units, err := sizer.UnitsForPips(20); err != nil || units != 50000 {
+		t.Errorf("Expected 50000 units to risk 100 over 20 pips, got %v, %v", units, err)
+	}
+	if value := sizer.PipValue(); value != 0.0001 {
+		t.Errorf("Expected a pip to be worth 0.0001 a unit, got %v", value)
+	}
+
+	sizer.Sell = true
+	if units, err := sizer.Units(0.0030); err != nil || units != -33333 {
+		t.Errorf("Expected to sell 33333 units, rounded toward zero, got %v, %v", units, err)
+	}
+
+	usdjpy := InstrumentDetails{Name: "USD_JPY", PipLocation: -2, DisplayPrecision: 3, MinimumTradeSize: "1", MaximumOrderUnits: "100000000"}
+	sizer = PositionSizer{Equity: 10000, Risk: 0.02, Instrument: usdjpy, Conversion: 0.0064}
+	if units, err := sizer.UnitsForPips(25); err != nil || units != 125000 {
+		t.Errorf("Expected 125000 units with a yen quote converted to the home currency, got %v, %v", units, err)
+	}
+
+	sizer.Instrument.MaximumOrderUnits = "100000"
+	if units, _ := sizer.UnitsForPips(25); units != 100000 {
+		t.Errorf("Expected the units to be capped at the maximum order size, got %v", units)
+	}
+
+	for _, s := range []PositionSizer{
+		{Equity: 10, Risk: 0.01, Instrument: eurusdDetails},
+		{Equity: 10000, Risk: 1.5, Instrument: eurusdDetails},
+		{Risk: 0.01, Instrument: eurusdDetails},
+	} {
+		if _, err := s.Units(1); err == nil {
+			t.Errorf("Expected %+v to be refused", s)
+		}
+	}
+	if _, err := (PositionSizer{Equity: 10000, Risk: 0.01, Instrument: eurusdDetails}).Units(0); err == nil {
+		t.Error("Expected a zero stop distance to be refused")
+	}
+}
+
+func TestOrderBuilderSizeForRisk(t *testing.T) {
+	defer logTestResult(t, "OrderBuilderSizeForRisk")
+
+	sizer := PositionSizer{Equity: 10000, Risk: 0.01, Instrument: eurusdDetails}
+	payload, err := NewMarketOrder("EUR_USD").SizeForRisk(sizer).StopLossPips(20).Precision(eurusdDetails).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if payload.Order.Units != 50000 || payload.Order.StopLossOnFill.Distance != "0.00200" {
+		t.Errorf("Expected 50000 units with a 20 pip stop, got %+v", payload.Order)
+	}
+
+	sizer.Sell = true
+	payload, err = NewLimitOrder("EUR_USD").Price("1.0850").StopLoss("1.0875").SizeForRisk(sizer).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if payload.Order.Units != -40000 {
+		t.Errorf("Expected to sell 40000 units with a stop 25 pips above the price, got %v", payload.Order.Units)
+	}
+
+	if _, err := NewMarketOrder("EUR_USD").SizeForRisk(sizer).Build(); err == nil {
+		t.Error("Expected an order without a stop not to be sized")
+	}
+	if _, err := NewMarketOrder("EUR_USD").SizeForRisk(sizer).StopLoss("1.0875").Build(); err == nil {
+		t.Error("Expected a market order with a stop price not to be sized")
+	}
+}
+
+func TestNewPositionSizer(t *testing.T) {
+	defer logTestResult(t, "NewPositionSizer")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/summary":
+			w.Write([]byte(`{"account":{"NAV":"25000.50","balance":"25000","currency":"GBP"}}`))
+		case "/accounts/test-account/instruments":
+			json.NewEncoder(w).Encode(map[string]Instruments{"instruments": {eurusdDetails}})
+		case "/accounts/test-account/pricing":
+			w.Write([]byte(`{"prices":[{"instrument":"EUR_USD","quoteHomeConversionFactors":{"positiveUnits":"0.78","negativeUnits":"0.79"}}]}`))
+		default:
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	sizer, err := c.NewPositionSizer("EUR_USD", 0.01, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sizer.Equity != 25000.50 || sizer.Conversion != 0.79 || !sizer.Sell || sizer.Instrument.Name != "EUR_USD" {
+		t.Errorf("Expected a sizer for selling EUR_USD against the NAV, got %+v", sizer)
+	}
+}
@@ -0,0 +1,163 @@
+package goanda
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// PriceSide describes which side of the current market a price has to sit on to be accepted
+type PriceSide int
+
+const (
+	// PriceBelowMarket is for prices that must be below the bid,
+	// such as buy limit orders and stop losses on long trades
+	PriceBelowMarket PriceSide = iota
+	// PriceAboveMarket is for prices that must be above the ask,
+	// such as sell limit orders, buy stop orders and stop losses on short trades
+	PriceAboveMarket
+)
+
+// PriceAdjustment records how a requested price was changed to make it acceptable to OANDA
+type PriceAdjustment struct {
+	Instrument string
+	Requested  float64
+	Price      float64
+	// Formatted is Price at the instrument's display precision, ready to use in an order body
+	Formatted string
+	// Reasons explains each change made to the requested price, it is empty if none were needed
+	Reasons []string
+}
+
+// Adjusted reports whether the price had to be changed
+func (pa PriceAdjustment) Adjusted() bool {
+	return len(pa.Reasons) > 0
+}
+
+// Find returns the details of the named instrument
+func (in Instruments) Find(name string) (InstrumentDetails, bool) {
+	for _, i := range in {
+		if i.Name == name {
+			return i, true
+		}
+	}
+	return InstrumentDetails{}, false
+}
+
+// ClampToValidPrice moves a price to the nearest one OANDA will accept for the instrument,
+// fetching the instrument's details and current market from the API. See ClampPrice.
+func (c *Connection) ClampToValidPrice(instrument string, price float64, side PriceSide) (PriceAdjustment, error) {
+	var response struct {
+		Instruments Instruments `json:"instruments"`
+	}
+	err := c.getAndUnmarshal(
+		"/accounts/"+
+			c.accountID+
+			"/instruments?instruments="+
+			instrument,
+		&response,
+	)
+	if err != nil {
+		return PriceAdjustment{}, err
+	}
+
+	details, ok := response.Instruments.Find(instrument)
+	if !ok {
+		return PriceAdjustment{}, fmt.Errorf("instrument %s not found", instrument)
+	}
+
+	pricing, err := c.GetPricingForInstruments([]string{instrument})
+	if err != nil {
+		return PriceAdjustment{}, err
+	}
+	if len(pricing.Prices) == 0 || len(pricing.Prices[0].Bids) == 0 || len(pricing.Prices[0].Asks) == 0 {
+		return PriceAdjustment{}, fmt.Errorf("no current price for %s", instrument)
+	}
+
+	bid, err := strconv.ParseFloat(pricing.Prices[0].Bids[0].Price, 64)
+	if err != nil {
+		return PriceAdjustment{}, err
+	}
+	ask, err := strconv.ParseFloat(pricing.Prices[0].Asks[0].Price, 64)
+	if err != nil {
+		return PriceAdjustment{}, err
+	}
+
+	return ClampPrice(details, price, side, bid, ask), nil
+}
+
+// ClampPrice moves a price to the nearest one OANDA will accept for the instrument given the current bid and ask.
+// Prices below the market are kept at least the instrument's minimum distance under the bid,
+// prices above it at least that distance over the ask, and the result is rounded away from the
+// market to the instrument's display precision. The minimum distance used is the instrument's
+// minimumTrailingStopDistance, which is the tightest distance OANDA publishes for dependent orders.
+func ClampPrice(details InstrumentDetails, price float64, side PriceSide, bid float64, ask float64) PriceAdjustment {
+	pa := PriceAdjustment{
+		Instrument: details.Name,
+		Requested:  price,
+		Price:      price,
+	}
+
+	minDistance, _ := strconv.ParseFloat(details.MinimumTrailingStopDistance, 64)
+	switch side {
+	case PriceBelowMarket:
+		if limit := bid - minDistance; pa.Price > limit {
+			pa.Price = limit
+			pa.Reasons = append(pa.Reasons, fmt.Sprintf(
+				"moved below bid %v by the minimum distance of %v", bid, minDistance,
+			))
+		}
+	case PriceAboveMarket:
+		if limit := ask + minDistance; pa.Price < limit {
+			pa.Price = limit
+			pa.Reasons = append(pa.Reasons, fmt.Sprintf(
+				"moved above ask %v by the minimum distance of %v", ask, minDistance,
+			))
+		}
+	}
+
+	direction := roundDown
+	if side == PriceAboveMarket {
+		direction = roundUp
+	}
+	rounded := roundToPrecision(pa.Price, details.DisplayPrecision, direction)
+	if rounded != pa.Price && !withinPrecision(pa.Price, rounded, details.DisplayPrecision) {
+		pa.Reasons = append(pa.Reasons, fmt.Sprintf(
+			"rounded to display precision of %d decimal places", details.DisplayPrecision,
+		))
+	}
+	pa.Price = rounded
+	pa.Formatted = strconv.FormatFloat(pa.Price, 'f', details.DisplayPrecision, 64)
+
+	return pa
+}
+
+// rounding is the direction roundToPrecision rounds in
+type rounding int
+
+const (
+	roundNearest rounding = iota
+	roundDown
+	roundUp
+)
+
+// roundToPrecision rounds to the given number of decimal places, in the direction given.
+// A tolerance is applied so that values already on the boundary are not pushed to the next step
+// by floating point noise.
+func roundToPrecision(value float64, precision int, direction rounding) float64 {
+	scale := math.Pow10(precision)
+	scaled := value * scale
+	switch direction {
+	case roundUp:
+		scaled = math.Ceil(scaled - 1e-6)
+	case roundDown:
+		scaled = math.Floor(scaled + 1e-6)
+	default:
+		scaled = math.Round(scaled)
+	}
+	return scaled / scale
+}
+
+func withinPrecision(a float64, b float64, precision int) bool {
+	return math.Abs(a-b) < math.Pow10(-precision)*1e-6
+}
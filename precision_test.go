@@ -0,0 +1,89 @@
+package goanda
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var eurUsdDetails = InstrumentDetails{
+	Name:                        "EUR_USD",
+	DisplayPrecision:            5,
+	PipLocation:                 -4,
+	MinimumTrailingStopDistance: "0.00050",
+	MinimumTradeSize:            "1",
+}
+
+func TestClampPrice(t *testing.T) {
+	defer logTestResult(t, "ClampPrice")
+
+	tests := []struct {
+		name     string
+		price    float64
+		side     PriceSide
+		expected string
+		adjusted bool
+	}{
+		{"valid below", 1.09000, PriceBelowMarket, "1.09000", false},
+		{"valid above", 1.11000, PriceAboveMarket, "1.11000", false},
+		{"precision below rounds down", 1.090004, PriceBelowMarket, "1.09000", true},
+		{"precision above rounds up", 1.110001, PriceAboveMarket, "1.11001", true},
+		{"too close to bid", 1.09990, PriceBelowMarket, "1.09950", true},
+		{"too close to ask", 1.10010, PriceAboveMarket, "1.10060", true},
+		{"wrong side of market", 1.20000, PriceBelowMarket, "1.09950", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pa := ClampPrice(eurUsdDetails, tt.price, tt.side, 1.10000, 1.10010)
+			if pa.Formatted != tt.expected {
+				t.Errorf("Expected %s, got %s (%v)", tt.expected, pa.Formatted, pa.Reasons)
+			}
+			if pa.Adjusted() != tt.adjusted {
+				t.Errorf("Expected adjusted to be %v, got %v (%v)", tt.adjusted, pa.Adjusted(), pa.Reasons)
+			}
+			if pa.Requested != tt.price {
+				t.Errorf("Expected requested price %v to be recorded, got %v", tt.price, pa.Requested)
+			}
+		})
+	}
+}
+
+func TestClampToValidPrice(t *testing.T) {
+	defer logTestResult(t, "ClampToValidPrice")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/instruments":
+			if r.URL.Query().Get("instruments") != "EUR_USD" {
+				t.Errorf("Unexpected instruments: %s", r.URL.Query().Get("instruments"))
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"instruments": Instruments{eurUsdDetails},
+			})
+		case "/accounts/test-account/pricing":
+			w.Write([]byte(`{"prices":[{"instrument":"EUR_USD","bids":[{"price":"1.10000","liquidity":1000000}],"asks":[{"price":"1.10010","liquidity":1000000}]}]}`))
+		default:
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	pa, err := c.ClampToValidPrice("EUR_USD", 1.1, PriceAboveMarket)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pa.Formatted != "1.10060" {
+		t.Errorf("Expected 1.10060, got %s", pa.Formatted)
+	}
+	if !pa.Adjusted() {
+		t.Error("Expected the price to be adjusted")
+	}
+}
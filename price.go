@@ -0,0 +1,126 @@
+package goanda
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// PriceBucket is one level of a price's depth, with the liquidity available at it, parsed from the
+// RawPriceBucket OANDA sends
+type PriceBucket struct {
+	Price     float64
+	Liquidity int
+}
+
+// ParsedHomeConversion is a HomeConversion with its factors parsed
+type ParsedHomeConversion struct {
+	Currency      string
+	AccountGain   float64
+	AccountLoss   float64
+	PositionValue float64
+}
+
+// ParsedPrice is a PricingStreamResponse with its time and prices parsed
+type ParsedPrice struct {
+	Instrument      string
+	Time            time.Time
+	Status          string
+	Tradeable       bool
+	Bids            []PriceBucket
+	Asks            []PriceBucket
+	CloseoutBid     float64
+	CloseoutAsk     float64
+	HomeConversions []ParsedHomeConversion
+	// Latency is how long after Time the price was received on a stream
+	Latency time.Duration
+}
+
+// Bid returns the best bid, or zero if there is none
+func (p ParsedPrice) Bid() float64 {
+	if len(p.Bids) == 0 {
+		return 0
+	}
+	return p.Bids[0].Price
+}
+
+// Ask returns the best ask, or zero if there is none
+func (p ParsedPrice) Ask() float64 {
+	if len(p.Asks) == 0 {
+		return 0
+	}
+	return p.Asks[0].Price
+}
+
+// Parse converts the response's strings into times and numbers. Closeout prices, which OANDA may omit,
+// are left at zero when absent.
+func (p PricingStreamResponse) Parse() (ParsedPrice, error) {
+	parsed := ParsedPrice{
+		Instrument: p.Instrument,
+		Status:     p.Status,
+		Tradeable:  p.Tradeable,
+		Latency:    p.Latency,
+	}
+
+	var err error
+	if parsed.Time, err = time.Parse(time.RFC3339Nano, p.Time); err != nil {
+		return ParsedPrice{}, fmt.Errorf("goanda: invalid price time %q: %w", p.Time, err)
+	}
+
+	for _, b := range p.Bids {
+		price, err := parsePrice("bid", b.Price)
+		if err != nil {
+			return ParsedPrice{}, err
+		}
+		parsed.Bids = append(parsed.Bids, PriceBucket{Price: price, Liquidity: b.Liquidity})
+	}
+	for _, a := range p.Asks {
+		price, err := parsePrice("ask", a.Price)
+		if err != nil {
+			return ParsedPrice{}, err
+		}
+		parsed.Asks = append(parsed.Asks, PriceBucket{Price: price, Liquidity: a.Liquidity})
+	}
+
+	if parsed.CloseoutBid, err = parseOptionalPrice("closeout bid", p.CloseoutBid); err != nil {
+		return ParsedPrice{}, err
+	}
+	if parsed.CloseoutAsk, err = parseOptionalPrice("closeout ask", p.CloseoutAsk); err != nil {
+		return ParsedPrice{}, err
+	}
+
+	for _, hc := range p.HomeConversions {
+		conversion := ParsedHomeConversion{Currency: hc.Currency}
+		for _, f := range []struct {
+			name  string
+			value string
+			dest  *float64
+		}{
+			{"accountGain", hc.AccountGain, &conversion.AccountGain},
+			{"accountLoss", hc.AccountLoss, &conversion.AccountLoss},
+			{"positionValue", hc.PositionValue, &conversion.PositionValue},
+		} {
+			if *f.dest, err = parseOptionalPrice(hc.Currency+" "+f.name, f.value); err != nil {
+				return ParsedPrice{}, err
+			}
+		}
+		parsed.HomeConversions = append(parsed.HomeConversions, conversion)
+	}
+
+	return parsed, nil
+}
+
+func parsePrice(name string, value string) (float64, error) {
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("goanda: invalid %s %q: %w", name, value, err)
+	}
+	return price, nil
+}
+
+func parseOptionalPrice(name string, value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return parsePrice(name, value)
+}
@@ -0,0 +1,46 @@
+package goanda
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParsePricingStreamResponse(t *testing.T) {
+	defer logTestResult(t, "ParsePricingStreamResponse")
+
+	var response PricingStreamResponse
+	err := json.Unmarshal([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:05.123456789Z","instrument":"EUR_USD",
+		"bids":[{"price":"1.10000","liquidity":1000000},{"price":"1.09990","liquidity":5000000}],
+		"asks":[{"price":"1.10010","liquidity":1000000}],
+		"closeoutBid":"1.09985","closeoutAsk":"1.10025","tradeable":true,
+		"homeConversions":[{"currency":"EUR","accountGain":"1.1","accountLoss":"1.2","positionValue":"1.15"}]}`), &response)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	price, err := response.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !price.Time.Equal(time.Date(2024, 1, 2, 15, 4, 5, 123456789, time.UTC)) {
+		t.Errorf("Unexpected time: %v", price.Time)
+	}
+	if price.Bid() != 1.1 || price.Ask() != 1.1001 {
+		t.Errorf("Unexpected best prices: %v / %v", price.Bid(), price.Ask())
+	}
+	if len(price.Bids) != 2 || price.Bids[1] != (PriceBucket{Price: 1.0999, Liquidity: 5000000}) {
+		t.Errorf("Unexpected bids: %+v", price.Bids)
+	}
+	if price.CloseoutBid != 1.09985 || price.CloseoutAsk != 1.10025 || !price.Tradeable {
+		t.Errorf("Unexpected closeout prices: %+v", price)
+	}
+	if len(price.HomeConversions) != 1 || price.HomeConversions[0].AccountLoss != 1.2 {
+		t.Errorf("Unexpected home conversions: %+v", price.HomeConversions)
+	}
+
+	response.Asks[0].Price = "not a price"
+	if _, err := response.Parse(); err == nil {
+		t.Error("Expected an error for an invalid price")
+	}
+}
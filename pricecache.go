@@ -0,0 +1,113 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrStalePrice is returned by PriceCache.Fresh for a price older than allowed
+var ErrStalePrice = errors.New("goanda: price is stale")
+
+// CachedPrice is the latest price of an instrument and when it was received
+type CachedPrice struct {
+	PricingStreamResponse
+	// Received is the local time the price arrived
+	Received time.Time
+}
+
+// Age returns how long ago the price was received
+func (p CachedPrice) Age() time.Duration {
+	return time.Since(p.Received)
+}
+
+// Stale reports whether the price was received longer than maxAge ago. OANDA only sends a price when
+// it changes, so a quiet market can leave a valid price stale, a stream that has dropped always does.
+func (p CachedPrice) Stale(maxAge time.Duration) bool {
+	return p.Age() > maxAge
+}
+
+// PriceCache keeps the latest price of each instrument fed to it by a price stream,
+// so code placing orders can read the current price without waiting on the stream.
+// It is safe for concurrent use.
+type PriceCache struct {
+	mu     sync.RWMutex
+	prices map[string]CachedPrice
+}
+
+// NewPriceCache creates an empty cache
+func NewPriceCache() *PriceCache {
+	return &PriceCache{prices: map[string]CachedPrice{}}
+}
+
+// Run streams prices for the instruments into the cache until ctx is done or the stream ends,
+// returning its error. Set a Reconnect policy on the streaming connection to keep it running.
+func (c *PriceCache) Run(ctx context.Context, sc *StreamingConnection, instruments []string) error {
+	return sc.StreamPrices(ctx, instruments, c.Update)
+}
+
+// Update stores a price as its instrument's latest, it can be passed to StreamPrices directly.
+// A price older than the one cached, as a reconnected stream's snapshot may be, is ignored.
+func (c *PriceCache) Update(price PricingStreamResponse) {
+	if price.Instrument == "" {
+		return
+	}
+	received := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, ok := c.prices[price.Instrument]; ok && olderPrice(price.Time, current.Time) {
+		return
+	}
+	c.prices[price.Instrument] = CachedPrice{PricingStreamResponse: price, Received: received}
+}
+
+// Latest returns the instrument's latest price, and whether one has been received
+func (c *PriceCache) Latest(instrument string) (CachedPrice, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	price, ok := c.prices[instrument]
+	return price, ok
+}
+
+// Fresh returns the instrument's latest price if it was received within maxAge, or an error wrapping
+// ErrNoPrice if none has been received or ErrStalePrice if it is older
+func (c *PriceCache) Fresh(instrument string, maxAge time.Duration) (CachedPrice, error) {
+	price, ok := c.Latest(instrument)
+	if !ok {
+		return CachedPrice{}, fmt.Errorf("%w: %s has not been priced", ErrNoPrice, instrument)
+	}
+	if age := price.Age(); age > maxAge {
+		return price, fmt.Errorf("%w: %s was last priced %v ago", ErrStalePrice, instrument, age.Round(time.Millisecond))
+	}
+	return price, nil
+}
+
+// Instruments returns the instruments with a cached price, sorted
+func (c *PriceCache) Instruments() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	instruments := make([]string, 0, len(c.prices))
+	for instrument := range c.prices {
+		instruments = append(instruments, instrument)
+	}
+	sort.Strings(instruments)
+	return instruments
+}
+
+// olderPrice reports whether a price's time is before the cached one's, times that don't parse are
+// taken as newer so the cache is never stuck on an old price
+func olderPrice(t string, cached string) bool {
+	priced, err := time.Parse(time.RFC3339Nano, t)
+	if err != nil {
+		return false
+	}
+	current, err := time.Parse(time.RFC3339Nano, cached)
+	if err != nil {
+		return false
+	}
+	return priced.Before(current)
+}
@@ -0,0 +1,88 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriceCache(t *testing.T) {
+	defer logTestResult(t, "PriceCache")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD","bids":[{"price":"1.1000"}],"asks":[{"price":"1.1002"}]}`)
+		fmt.Fprintln(w, `{"type":"PRICE","time":"2024-01-02T15:04:06Z","instrument":"USD_JPY","bids":[{"price":"150.10"}],"asks":[{"price":"150.12"}]}`)
+		fmt.Fprintln(w, `{"type":"PRICE","time":"2024-01-02T15:04:07Z","instrument":"EUR_USD","bids":[{"price":"1.1001"}],"asks":[{"price":"1.1003"}]}`)
+		// Arriving late, it must not replace the newer price
+		fmt.Fprintln(w, `{"type":"PRICE","time":"2024-01-02T15:04:04Z","instrument":"EUR_USD","bids":[{"price":"1.0999"}],"asks":[{"price":"1.1001"}]}`)
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	cache := NewPriceCache()
+	if _, err := cache.Fresh("EUR_USD", time.Minute); !errors.Is(err, ErrNoPrice) {
+		t.Errorf("Expected ErrNoPrice before the stream, got %v", err)
+	}
+	if err := cache.Run(context.Background(), sc, []string{"EUR_USD", "USD_JPY"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	price, ok := cache.Latest("EUR_USD")
+	if !ok || price.Time != "2024-01-02T15:04:07Z" || price.Bids[0].Price != "1.1001" {
+		t.Errorf("Expected the newest EUR_USD price, got %+v", price)
+	}
+	if price.Received.IsZero() || price.Stale(time.Minute) {
+		t.Errorf("Expected the price to have just been received, got %v", price.Received)
+	}
+	if instruments := cache.Instruments(); len(instruments) != 2 || instruments[0] != "EUR_USD" || instruments[1] != "USD_JPY" {
+		t.Errorf("Expected both instruments to be cached, got %v", instruments)
+	}
+
+	if _, err := cache.Fresh("USD_JPY", time.Minute); err != nil {
+		t.Errorf("Expected a fresh price, got %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	stale, err := cache.Fresh("USD_JPY", time.Millisecond)
+	if !errors.Is(err, ErrStalePrice) || stale.Instrument != "USD_JPY" {
+		t.Errorf("Expected ErrStalePrice with the stale price, got %+v (%v)", stale, err)
+	}
+}
+
+func TestPriceCacheConcurrentUse(t *testing.T) {
+	defer logTestResult(t, "PriceCacheConcurrentUse")
+
+	cache := NewPriceCache()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cache.Update(PricingStreamResponse{Instrument: "EUR_USD", Time: time.Now().UTC().Format(time.RFC3339Nano)})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cache.Latest("EUR_USD")
+				cache.Instruments()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, ok := cache.Latest("EUR_USD"); !ok {
+		t.Error("Expected a cached price")
+	}
+}
@@ -0,0 +1,94 @@
+package goanda
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// priceFilter skips the prices a PriceStreamOptions asks not to be delivered
+type priceFilter struct {
+	tradeableOnly bool
+	only          []string
+	minChange     float64
+	pipLocations  map[string]int
+	// last holds the best bid and ask of the last price delivered for each instrument
+	last map[string][2]float64
+}
+
+// newPriceFilter returns the filter for the options, or nil if they filter nothing
+func newPriceFilter(opts PriceStreamOptions) *priceFilter {
+	if !opts.TradeableOnly && len(opts.Only) == 0 && opts.MinChangePips <= 0 {
+		return nil
+	}
+	return &priceFilter{
+		tradeableOnly: opts.TradeableOnly,
+		only:          opts.Only,
+		minChange:     opts.MinChangePips,
+		pipLocations:  opts.PipLocations,
+		last:          map[string][2]float64{},
+	}
+}
+
+// allow reports whether a price should be delivered. Messages other than prices always are.
+func (f *priceFilter) allow(price PricingStreamResponse) bool {
+	if price.Type != "PRICE" {
+		return true
+	}
+	if f.tradeableOnly && !price.Tradeable {
+		return false
+	}
+	if len(f.only) > 0 && !slices.Contains(f.only, price.Instrument) {
+		return false
+	}
+	if f.minChange <= 0 || len(price.Bids) == 0 || len(price.Asks) == 0 {
+		return true
+	}
+
+	bid, err := strconv.ParseFloat(price.Bids[0].Price, 64)
+	if err != nil {
+		return true
+	}
+	ask, err := strconv.ParseFloat(price.Asks[0].Price, 64)
+	if err != nil {
+		return true
+	}
+	if last, ok := f.last[price.Instrument]; ok {
+		threshold := f.minChange * f.pip(price.Instrument)
+		// A small tolerance keeps a move of exactly the threshold from being lost to rounding
+		epsilon := threshold * 1e-9
+		if math.Abs(bid-last[0]) < threshold-epsilon && math.Abs(ask-last[1]) < threshold-epsilon {
+			return false
+		}
+	}
+	f.last[price.Instrument] = [2]float64{bid, ask}
+	return true
+}
+
+// pip returns the size of a pip of the instrument
+func (f *priceFilter) pip(instrument string) float64 {
+	location, ok := f.pipLocations[instrument]
+	if !ok {
+		location = guessPipLocation(instrument)
+	}
+	return math.Pow10(location)
+}
+
+// guessPipLocation is the pip location of most instruments quoted in the currency, for when the
+// instrument's details are not known
+func guessPipLocation(instrument string) int {
+	if strings.HasSuffix(instrument, "_JPY") {
+		return -2
+	}
+	return -4
+}
+
+// PipLocations returns the pip location of each instrument, for PriceStreamOptions.PipLocations
+func (in Instruments) PipLocations() map[string]int {
+	locations := make(map[string]int, len(in))
+	for _, i := range in {
+		locations[i.Name] = i.PipLocation
+	}
+	return locations
+}
@@ -0,0 +1,67 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamPricesFilters(t *testing.T) {
+	defer logTestResult(t, "StreamPricesFilters")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		price := func(instrument string, tradeable bool, bid, ask string) {
+			fmt.Fprintf(w, `{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"%s","tradeable":%t,`+
+				`"bids":[{"price":"%s","liquidity":1}],"asks":[{"price":"%s","liquidity":1}]}`+"\n", instrument, tradeable, bid, ask)
+		}
+		price("EUR_USD", true, "1.10000", "1.10010")
+		price("EUR_USD", true, "1.10005", "1.10015") // half a pip
+		price("EUR_USD", true, "1.10010", "1.10020") // a pip from the last delivered
+		price("EUR_USD", false, "1.10100", "1.10110")
+		price("USD_JPY", true, "150.000", "150.010")
+		price("USD_JPY", true, "150.005", "150.015") // half a pip in JPY
+		price("GBP_USD", true, "1.30000", "1.30010")
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	tests := []struct {
+		opts     PriceStreamOptions
+		expected string
+	}{
+		{PriceStreamOptions{}, "[1.10000 1.10005 1.10010 1.10100 150.000 150.005 1.30000]"},
+		{PriceStreamOptions{TradeableOnly: true}, "[1.10000 1.10005 1.10010 150.000 150.005 1.30000]"},
+		{PriceStreamOptions{Only: []string{"USD_JPY", "GBP_USD"}}, "[150.000 150.005 1.30000]"},
+		{PriceStreamOptions{MinChangePips: 1}, "[1.10000 1.10010 1.10100 150.000 1.30000]"},
+		{PriceStreamOptions{MinChangePips: 1, PipLocations: map[string]int{"EUR_USD": -5}}, "[1.10000 1.10005 1.10010 1.10100 150.000 1.30000]"},
+	}
+	for _, test := range tests {
+		var bids []string
+		err := sc.StreamPricesWithOptions(context.Background(), []string{"EUR_USD", "USD_JPY", "GBP_USD"}, test.opts, func(price PricingStreamResponse) {
+			bids = append(bids, price.Bids[0].Price)
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fmt.Sprint(bids) != test.expected {
+			t.Errorf("%+v: expected %s, got %v", test.opts, test.expected, bids)
+		}
+	}
+}
+
+func TestInstrumentsPipLocations(t *testing.T) {
+	defer logTestResult(t, "InstrumentsPipLocations")
+
+	locations := Instruments{{Name: "EUR_USD", PipLocation: -4}, {Name: "XAU_USD", PipLocation: -2}}.PipLocations()
+	if len(locations) != 2 || locations["XAU_USD"] != -2 {
+		t.Errorf("Unexpected pip locations: %v", locations)
+	}
+}
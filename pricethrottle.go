@@ -0,0 +1,115 @@
+package goanda
+
+import (
+	"sync"
+	"time"
+)
+
+// priceThrottle delivers at most one price per interval for each instrument. A price arriving
+// too soon after the last one delivered is held, replacing any held before it, and delivered when
+// the interval is up, so the latest price always gets through without waiting for the next tick.
+type priceThrottle struct {
+	interval time.Duration
+	// direct delivers the prices that are not held, release those that were
+	direct  func(PricingStreamResponse) error
+	release func(PricingStreamResponse) error
+	// fail ends the stream with the error of a held price's delivery
+	fail func(error)
+
+	// mu is held for each delivery, so the callback is never run concurrently
+	mu          sync.Mutex
+	instruments map[string]*throttledInstrument
+	order       []string
+	stopped     bool
+}
+
+type throttledInstrument struct {
+	last    time.Time
+	pending *PricingStreamResponse
+	timer   *time.Timer
+}
+
+func newPriceThrottle(perSecond int, direct, release func(PricingStreamResponse) error, fail func(error)) *priceThrottle {
+	return &priceThrottle{
+		interval:    time.Second / time.Duration(perSecond),
+		direct:      direct,
+		release:     release,
+		fail:        fail,
+		instruments: map[string]*throttledInstrument{},
+	}
+}
+
+// admit delivers a price now, or holds it until its instrument's interval is up.
+// Messages other than prices are delivered at once.
+func (t *priceThrottle) admit(price PricingStreamResponse) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if price.Type != "PRICE" {
+		return t.direct(price)
+	}
+	s, ok := t.instruments[price.Instrument]
+	if !ok {
+		s = &throttledInstrument{}
+		t.instruments[price.Instrument] = s
+		t.order = append(t.order, price.Instrument)
+	}
+
+	now := time.Now()
+	if s.pending == nil && now.Sub(s.last) >= t.interval {
+		s.last = now
+		return t.direct(price)
+	}
+	s.pending = &price
+	if s.timer == nil {
+		instrument := price.Instrument
+		s.timer = time.AfterFunc(s.last.Add(t.interval).Sub(now), func() {
+			t.releaseHeld(instrument)
+		})
+	}
+	return nil
+}
+
+// releaseHeld delivers the price held for an instrument once its interval is up
+func (t *priceThrottle) releaseHeld(instrument string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.instruments[instrument]
+	s.timer = nil
+	if t.stopped || s.pending == nil {
+		return
+	}
+	price := *s.pending
+	s.pending = nil
+	s.last = time.Now()
+	if err := t.release(price); err != nil {
+		t.stopped = true
+		t.fail(err)
+	}
+}
+
+// stop cancels the held prices' timers. If flush is set the held prices are delivered first,
+// as when the stream ends cleanly.
+func (t *priceThrottle) stop(flush bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return nil
+	}
+	t.stopped = true
+	for _, instrument := range t.order {
+		s := t.instruments[instrument]
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		if flush && s.pending != nil {
+			if err := t.release(*s.pending); err != nil {
+				return err
+			}
+		}
+		s.pending = nil
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamPricesThrottle(t *testing.T) {
+	defer logTestResult(t, "StreamPricesThrottle")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, `{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD","bids":[{"price":"1.1000%d","liquidity":1}]}`+"\n", i)
+			fmt.Fprintf(w, `{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"GBP_USD","bids":[{"price":"1.3000%d","liquidity":1}]}`+"\n", i)
+		}
+		w.(http.Flusher).Flush()
+		// Long enough for the held prices to be released by their timers
+		time.Sleep(300 * time.Millisecond)
+		fmt.Fprintf(w, `{"type":"PRICE","time":"2024-01-02T15:04:06Z","instrument":"EUR_USD","bids":[{"price":"1.10005","liquidity":1}]}`+"\n")
+		fmt.Fprintf(w, `{"type":"PRICE","time":"2024-01-02T15:04:06Z","instrument":"EUR_USD","bids":[{"price":"1.10006","liquidity":1}]}`+"\n")
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	var mu sync.Mutex
+	var bids []string
+	opts := PriceStreamOptions{MaxUpdatesPerSecond: 10}
+	err := sc.StreamPricesWithOptions(context.Background(), []string{"EUR_USD", "GBP_USD"}, opts, func(price PricingStreamResponse) {
+		mu.Lock()
+		defer mu.Unlock()
+		bids = append(bids, price.Bids[0].Price)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The first of each burst is delivered at once, the last after the interval, and the price
+	// still held when the stream ends is flushed
+	expected := "[1.10000 1.30000 1.10004 1.30004 1.10005 1.10006]"
+	if fmt.Sprint(bids) != expected && fmt.Sprint(bids) != "[1.10000 1.30000 1.30004 1.10004 1.10005 1.10006]" {
+		t.Errorf("Expected %s, got %v", expected, bids)
+	}
+}
+
+func TestStreamPricesThrottleStop(t *testing.T) {
+	defer logTestResult(t, "StreamPricesThrottleStop")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD"}` + "\n"))
+		w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD"}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	// A held price's callback ending the stream, as the iterators do, stops it cleanly
+	prices := 0
+	err := sc.streamPrices(context.Background(), []string{"EUR_USD"}, PriceStreamOptions{MaxUpdatesPerSecond: 20}, func(PricingStreamResponse) error {
+		prices++
+		if prices == 2 {
+			return errStopStream
+		}
+		return nil
+	})
+	if err != nil || prices != 2 {
+		t.Errorf("Expected the stream to stop after 2 prices, got %d (%v)", prices, err)
+	}
+}
@@ -0,0 +1,159 @@
+package goanda
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// StreamQuota limits the bandwidth and message rate of each stream, measured over one second windows.
+// A zero limit is not enforced.
+//
+// Exceeding a soft limit logs a warning and calls OnSoftLimit, once per window.
+// Exceeding a hard limit conflates prices for the rest of the window: only the latest price for each
+// instrument is kept, and those are delivered when the window ends. Other messages are always delivered.
+type StreamQuota struct {
+	SoftBytesPerSecond    int
+	SoftMessagesPerSecond int
+	HardBytesPerSecond    int
+	HardMessagesPerSecond int
+
+	OnSoftLimit func(StreamUsage)
+}
+
+// StreamUsage is a stream's accounting at the time a limit was exceeded
+type StreamUsage struct {
+	URL string
+	// Bytes and Messages received during the current one second window
+	Bytes    int
+	Messages int
+	// TotalBytes and TotalMessages received since the stream was opened
+	TotalBytes    int64
+	TotalMessages int64
+	// Conflating is true while the hard limit is exceeded
+	Conflating bool
+}
+
+// streamMeter accounts for a single stream's usage and holds prices back while conflating
+type streamMeter struct {
+	quota       *StreamQuota
+	logf        func(format string, v ...interface{})
+	usage       StreamUsage
+	windowStart time.Time
+	warned      bool
+
+	pending map[string][]byte
+	order   []string
+	ready   [][]byte
+}
+
+func newStreamMeter(quota *StreamQuota, url string, now time.Time, logf func(format string, v ...interface{})) *streamMeter {
+	return &streamMeter{
+		quota:       quota,
+		logf:        logf,
+		usage:       StreamUsage{URL: url},
+		windowStart: now,
+		pending:     map[string][]byte{},
+	}
+}
+
+// observe accounts for a message that is not subject to conflation, such as a heartbeat
+func (m *streamMeter) observe(size int, now time.Time) {
+	m.roll(now)
+	m.count(size)
+}
+
+// admit accounts for a message, returning the messages that should be delivered now
+func (m *streamMeter) admit(message []byte, now time.Time) [][]byte {
+	m.roll(now)
+	m.count(len(message))
+
+	if m.usage.Conflating {
+		if key := conflationKey(message); key != "" {
+			if _, ok := m.pending[key]; !ok {
+				m.order = append(m.order, key)
+			}
+			m.pending[key] = message
+			return m.flush()
+		}
+	}
+
+	return append(m.flush(), message)
+}
+
+// flush returns the conflated messages released by the end of a window
+func (m *streamMeter) flush() [][]byte {
+	ready := m.ready
+	m.ready = nil
+	return ready
+}
+
+// drain releases every message still held back
+func (m *streamMeter) drain() [][]byte {
+	m.release()
+	return m.flush()
+}
+
+func (m *streamMeter) roll(now time.Time) {
+	if now.Sub(m.windowStart) < time.Second {
+		return
+	}
+
+	m.windowStart = now
+	m.usage.Bytes = 0
+	m.usage.Messages = 0
+	m.usage.Conflating = false
+	m.warned = false
+	m.release()
+}
+
+func (m *streamMeter) release() {
+	for _, key := range m.order {
+		m.ready = append(m.ready, m.pending[key])
+		delete(m.pending, key)
+	}
+	m.order = m.order[:0]
+}
+
+func (m *streamMeter) count(size int) {
+	m.usage.Bytes += size
+	m.usage.Messages++
+	m.usage.TotalBytes += int64(size)
+	m.usage.TotalMessages++
+
+	if m.quota == nil {
+		return
+	}
+
+	if !m.usage.Conflating && (exceeds(m.usage.Bytes, m.quota.HardBytesPerSecond) ||
+		exceeds(m.usage.Messages, m.quota.HardMessagesPerSecond)) {
+		m.usage.Conflating = true
+		m.logf("goanda: stream %s exceeded its hard limit, conflating prices (%d bytes, %d messages this second)",
+			m.usage.URL, m.usage.Bytes, m.usage.Messages)
+	}
+
+	if !m.warned && (exceeds(m.usage.Bytes, m.quota.SoftBytesPerSecond) ||
+		exceeds(m.usage.Messages, m.quota.SoftMessagesPerSecond)) {
+		m.warned = true
+		m.logf("goanda: stream %s exceeded its soft limit (%d bytes, %d messages this second)",
+			m.usage.URL, m.usage.Bytes, m.usage.Messages)
+		if m.quota.OnSoftLimit != nil {
+			m.quota.OnSoftLimit(m.usage)
+		}
+	}
+}
+
+func exceeds(value int, limit int) bool {
+	return limit > 0 && value > limit
+}
+
+// conflationKey returns the instrument of a price message, or "" when the message must not be conflated
+func conflationKey(message []byte) string {
+	var header struct {
+		Type       string `json:"type"`
+		Instrument string `json:"instrument"`
+	}
+	if err := json.Unmarshal(message, &header); err != nil || header.Type != "PRICE" {
+		return ""
+	}
+	return header.Instrument
+}
@@ -0,0 +1,93 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamMeterConflatesOverHardLimit(t *testing.T) {
+	defer logTestResult(t, "StreamMeterConflatesOverHardLimit")
+
+	start := time.Now()
+	m := newStreamMeter(&StreamQuota{HardMessagesPerSecond: 2}, "test", start, func(string, ...interface{}) {})
+
+	price := func(instrument string, n int) []byte {
+		return []byte(fmt.Sprintf(`{"type":"PRICE","instrument":"%s","time":"%d"}`, instrument, n))
+	}
+
+	var delivered [][]byte
+	delivered = append(delivered, m.admit(price("EUR_USD", 1), start)...)
+	delivered = append(delivered, m.admit(price("EUR_USD", 2), start)...)
+	// Over the limit: only the latest price per instrument is kept
+	delivered = append(delivered, m.admit(price("EUR_USD", 3), start)...)
+	delivered = append(delivered, m.admit(price("USD_JPY", 4), start)...)
+	delivered = append(delivered, m.admit(price("EUR_USD", 5), start)...)
+	// Non price messages are never held back
+	delivered = append(delivered, m.admit([]byte(`{"type":"ORDER_FILL"}`), start)...)
+
+	if len(delivered) != 3 {
+		t.Fatalf("Expected 3 messages before the window ends, got %d", len(delivered))
+	}
+
+	// The next window releases the conflated prices in arrival order
+	m.observe(10, start.Add(time.Second))
+	released := m.flush()
+	if len(released) != 2 {
+		t.Fatalf("Expected 2 conflated prices, got %d", len(released))
+	}
+	if string(released[0]) != string(price("EUR_USD", 5)) {
+		t.Errorf("Expected the latest EUR_USD price, got %s", released[0])
+	}
+	if string(released[1]) != string(price("USD_JPY", 4)) {
+		t.Errorf("Expected the USD_JPY price, got %s", released[1])
+	}
+}
+
+func TestStreamQuotaSoftLimit(t *testing.T) {
+	defer logTestResult(t, "StreamQuotaSoftLimit")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, `{"type":"PRICE","instrument":"EUR_USD"}`+"\n")
+		}
+	}))
+	defer server.Close()
+
+	conn := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+	sc := NewStreamingConnection(conn)
+	sc.streamURL = server.URL
+
+	var warnings []StreamUsage
+	sc.Quota = &StreamQuota{
+		SoftMessagesPerSecond: 3,
+		OnSoftLimit: func(usage StreamUsage) {
+			warnings = append(warnings, usage)
+		},
+	}
+
+	delivered := 0
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) {
+		delivered++
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if delivered != 5 {
+		t.Errorf("Expected soft limits not to drop messages, got %d", delivered)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("Expected a single warning, got %d", len(warnings))
+	}
+	if warnings[0].Messages != 4 {
+		t.Errorf("Expected the warning on the 4th message, got %d", warnings[0].Messages)
+	}
+}
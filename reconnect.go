@@ -0,0 +1,74 @@
+package goanda
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// ReconnectPolicy decides how dropped streams are reconnected.
+// The delay before each attempt doubles from InitialBackoff up to MaxBackoff,
+// and is reduced by a random fraction of up to Jitter so that many clients do not reconnect in step.
+// The attempt count resets once a reconnected stream delivers a message.
+type ReconnectPolicy struct {
+	// InitialBackoff is the first delay, it defaults to a second
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay, it defaults to a minute
+	MaxBackoff time.Duration
+	// Jitter is between 0 and 1
+	Jitter float64
+	// MaxRetries is how many consecutive attempts are made before the stream's error is returned,
+	// zero retries forever
+	MaxRetries int
+}
+
+// DefaultReconnectPolicy retries forever, backing off from one second to a minute
+var DefaultReconnectPolicy = ReconnectPolicy{
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Jitter:         0.5,
+}
+
+// ReconnectEvent describes a stream about to be reconnected
+type ReconnectEvent struct {
+	URL     string
+	Attempt int
+	// Err is why the stream dropped, io.EOF if the server ended it
+	Err   error
+	Delay time.Duration
+}
+
+// backoff returns the delay before the given attempt, counting from one
+func (rp *ReconnectPolicy) backoff(attempt int) time.Duration {
+	maxBackoff := rp.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = time.Minute
+	}
+
+	delay := rp.InitialBackoff
+	if delay <= 0 {
+		delay = time.Second
+	}
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	if rp.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * rp.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// dropError marks a stream failure that reconnecting could recover from
+type dropError struct {
+	err error
+}
+
+func (d *dropError) Error() string {
+	return d.err.Error()
+}
+
+func (d *dropError) Unwrap() error {
+	return d.err
+}
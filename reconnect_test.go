@@ -0,0 +1,190 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReconnectPolicyBackoff(t *testing.T) {
+	defer logTestResult(t, "ReconnectPolicyBackoff")
+
+	rp := &ReconnectPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := rp.backoff(i + 1); got != want {
+			t.Errorf("Attempt %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+
+	rp.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := rp.backoff(3); got < 2*time.Second || got > 4*time.Second {
+			t.Fatalf("Expected a jittered delay between 2s and 4s, got %v", got)
+		}
+	}
+
+	// without an initial backoff, it starts from a second rather than retrying at once
+	if got := (&ReconnectPolicy{}).backoff(2); got != 2*time.Second {
+		t.Errorf("Expected the default initial backoff, got %v", got)
+	}
+}
+
+func TestStreamReconnects(t *testing.T) {
+	defer logTestResult(t, "StreamReconnects")
+
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the transactions missed while reconnecting are backfilled
+		if strings.HasSuffix(r.URL.Path, "/transactions/sinceid") {
+			w.Write([]byte(`{"transactions":[],"lastTransactionID":"1"}`))
+			return
+		}
+		switch atomic.AddInt32(&connections, 1) {
+		case 1:
+			w.Write([]byte(`{"type":"ORDER_FILL","id":"1"}` + "\n"))
+		case 2:
+			http.Error(w, "restarting", http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"type":"ORDER_FILL","id":"2"}` + "\n"))
+		}
+	}))
+	defer server.Close()
+
+	sc := &StreamingConnection{
+		Connection: &Connection{hostname: server.URL, client: *server.Client()},
+		streamURL:  server.URL,
+		Reconnect:  &ReconnectPolicy{InitialBackoff: time.Millisecond},
+	}
+
+	var events []ReconnectEvent
+	sc.OnReconnect = func(e ReconnectEvent) {
+		events = append(events, e)
+	}
+
+	var ids []string
+	err := sc.StreamTransactions(context.Background(), func(response TransactionStreamResponse) {
+		ids = append(ids, response.id())
+		if len(ids) == 2 {
+			sc.Reconnect = nil
+		}
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 reconnects, got %d", len(events))
+	}
+	if events[0].Err != io.EOF || events[0].Attempt != 1 {
+		t.Errorf("Expected the first reconnect to follow the stream ending, got %+v", events[0])
+	}
+	var apiErr APIError
+	if !errors.As(events[1].Err, &apiErr) || events[1].Attempt != 2 {
+		t.Errorf("Expected the second reconnect to follow the 503, got %+v", events[1])
+	}
+}
+
+func TestStreamReconnectGivesUp(t *testing.T) {
+	defer logTestResult(t, "StreamReconnectGivesUp")
+
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&connections, 1)
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	sc := &StreamingConnection{
+		Connection: &Connection{client: *server.Client()},
+		streamURL:  server.URL,
+		Reconnect:  &ReconnectPolicy{InitialBackoff: time.Millisecond, MaxRetries: 2},
+	}
+
+	err := sc.StreamTransactions(context.Background(), func(TransactionStreamResponse) {})
+	var apiErr APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected the last APIError once retries ran out, got %v", err)
+	}
+	if connections != 3 {
+		t.Errorf("Expected the initial attempt and 2 retries, got %d", connections)
+	}
+}
+
+func TestStreamDoesNotReconnectClientErrors(t *testing.T) {
+	defer logTestResult(t, "StreamDoesNotReconnectClientErrors")
+
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&connections, 1)
+		http.Error(w, `{"errorMessage":"Invalid value specified for 'instruments'"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sc := &StreamingConnection{
+		Connection: &Connection{client: *server.Client()},
+		streamURL:  server.URL,
+		Reconnect:  &DefaultReconnectPolicy,
+	}
+
+	if err := sc.StreamTransactions(context.Background(), func(TransactionStreamResponse) {}); err == nil {
+		t.Error("Expected the 400 to be returned")
+	}
+	if connections != 1 {
+		t.Errorf("Expected no reconnects, got %d connections", connections)
+	}
+}
+
+func TestStreamHeartbeatTimeout(t *testing.T) {
+	defer logTestResult(t, "StreamHeartbeatTimeout")
+
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&connections, 1)
+		w.Write([]byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05.000000000Z"}` + "\n"))
+		w.(http.Flusher).Flush()
+		if n > 1 {
+			w.Write([]byte(`{"type":"ORDER_FILL","id":"1"}` + "\n"))
+			return
+		}
+		// Go silent without closing the connection
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	sc := &StreamingConnection{
+		Connection:       &Connection{client: *server.Client()},
+		streamURL:        server.URL,
+		HeartbeatTimeout: 50 * time.Millisecond,
+	}
+
+	err := sc.StreamTransactions(context.Background(), func(TransactionStreamResponse) {})
+	if !errors.Is(err, ErrStaleStream) {
+		t.Fatalf("Expected ErrStaleStream, got %v", err)
+	}
+
+	var reasons []error
+	sc.Reconnect = &ReconnectPolicy{InitialBackoff: time.Millisecond, MaxRetries: 1}
+	sc.OnReconnect = func(e ReconnectEvent) {
+		reasons = append(reasons, e.Err)
+	}
+	atomic.StoreInt32(&connections, 0)
+
+	var received []string
+	sc.StreamTransactions(context.Background(), func(response TransactionStreamResponse) {
+		received = append(received, response.ID)
+		sc.Reconnect = nil
+	})
+	if len(reasons) == 0 || reasons[0] != ErrStaleStream {
+		t.Errorf("Expected a reconnect for the stale stream, got %v", reasons)
+	}
+	if len(received) != 1 {
+		t.Errorf("Expected the reconnected stream to deliver, got %v", received)
+	}
+}
@@ -0,0 +1,127 @@
+package goanda
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultRecordingFileSize is the size at which a StreamRecorder starts a new file when no MaxFileSize is set
+const DefaultRecordingFileSize = 64 << 20
+
+// RecordedLine is a line of a stream recording
+type RecordedLine struct {
+	// Received is the local time the line was read
+	Received time.Time `json:"received"`
+	// Stream is the stream's path and query, such as /accounts/<id>/pricing/stream?instruments=EUR_USD
+	Stream string `json:"stream"`
+	// Message is the line as received, or Text holds it if it was not valid JSON
+	Message json.RawMessage `json:"message,omitempty"`
+	Text    string          `json:"text,omitempty"`
+}
+
+// Line returns the line as it was received
+func (l RecordedLine) Line() []byte {
+	if l.Message != nil {
+		return l.Message
+	}
+	return []byte(l.Text)
+}
+
+// StreamRecorder appends every line received on a connection's streams, heartbeats included, to
+// newline delimited JSON files of RecordedLine, for research and to replay with a StreamReplayer.
+// Files are named stream-YYYYMMDD-HHMMSS.ffffff.ndjson after the UTC time they were started, and a new one
+// is started each UTC day and when the current one reaches MaxFileSize. It is safe for concurrent use.
+type StreamRecorder struct {
+	// MaxFileSize is the size in bytes at which a new file is started, it defaults to DefaultRecordingFileSize
+	MaxFileSize int64
+
+	dir string
+
+	mu   sync.Mutex
+	day  string
+	size int64
+	file *os.File
+}
+
+// NewStreamRecorder creates a recorder writing into dir, creating it if needed
+func NewStreamRecorder(dir string) (*StreamRecorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &StreamRecorder{dir: dir}, nil
+}
+
+// Record appends a line received on a stream
+func (r *StreamRecorder) Record(stream string, line []byte, received time.Time) error {
+	recorded := RecordedLine{Received: received.UTC(), Stream: stream}
+	if json.Valid(line) {
+		recorded.Message = line
+	} else {
+		recorded.Text = string(line)
+	}
+	data, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.rotate(recorded.Received, int64(len(data))); err != nil {
+		return err
+	}
+	n, err := r.file.Write(data)
+	r.size += int64(n)
+	return err
+}
+
+// Close closes the current file
+func (r *StreamRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	r.day = ""
+	return err
+}
+
+// rotate makes sure a file is open for the day with room for the next n bytes
+func (r *StreamRecorder) rotate(now time.Time, n int64) error {
+	maxSize := r.MaxFileSize
+	if maxSize <= 0 {
+		maxSize = DefaultRecordingFileSize
+	}
+	day := now.Format("20060102")
+	if r.file != nil && r.day == day && (r.size == 0 || r.size+n <= maxSize) {
+		return nil
+	}
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+		r.file = nil
+	}
+
+	path := filepath.Join(r.dir, "stream-"+now.Format("20060102-150405.000000")+".ndjson")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.day = day
+	r.size = info.Size()
+	return nil
+}
@@ -0,0 +1,99 @@
+package goanda
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStreamRecorder(t *testing.T) {
+	defer logTestResult(t, "StreamRecorder")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05Z"}` + "\n"))
+		w.Write([]byte("not json\n"))
+		w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:06Z","instrument":"EUR_USD"}` + "\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder, err := NewStreamRecorder(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+	sc.Recorder = recorder
+
+	if err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) {}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "stream-*.ndjson"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one recording, got %v", files)
+	}
+	file, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	var lines []RecordedLine
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line RecordedLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 || string(lines[1].Line()) != "not json" || lines[2].Stream != "/accounts/test-account/pricing/stream?instruments=EUR_USD" {
+		t.Fatalf("Unexpected recording: %+v", lines)
+	}
+	if string(lines[0].Line()) != `{"type":"HEARTBEAT","time":"2024-01-02T15:04:05Z"}` || lines[0].Received.IsZero() {
+		t.Errorf("Unexpected heartbeat: %+v", lines[0])
+	}
+}
+
+func TestStreamRecorderRotation(t *testing.T) {
+	defer logTestResult(t, "StreamRecorderRotation")
+
+	dir := t.TempDir()
+	recorder, err := NewStreamRecorder(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer recorder.Close()
+	recorder.MaxFileSize = 250
+
+	day := time.Date(2024, 1, 2, 23, 59, 0, 0, time.UTC)
+	line := []byte(`{"type":"PRICE","instrument":"EUR_USD"}`)
+	for i := 0; i < 3; i++ {
+		if err := recorder.Record("/pricing/stream", line, day.Add(time.Duration(i)*time.Millisecond)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	// The next day starts a new file even with room in the current one
+	if err := recorder.Record("/pricing/stream", line, day.Add(2*time.Minute)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "stream-*.ndjson"))
+	if len(files) != 3 {
+		t.Errorf("Expected the recording to rotate into 3 files, got %v", files)
+	}
+}
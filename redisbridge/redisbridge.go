@@ -0,0 +1,98 @@
+// Package redisbridge shares a goanda price stream through Redis, so many small services can read
+// prices from one OANDA connection. The latest price of each instrument is kept in a hash:
+//
+//	HGETALL goanda:price:EUR_USD
+//	bid 1.10012 ask 1.10025 closeoutBid 1.09997 closeoutAsk 1.10040 time 2024-01-02T15:04:05.123456789Z tradeable true
+//
+// and each tick is published as the stream's JSON on a channel per instrument:
+//
+//	SUBSCRIBE goanda:ticks:EUR_USD
+package redisbridge
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/rollend/goanda"
+)
+
+// Prefixes of the keys and channels a Publisher writes when none are set
+const (
+	DefaultKeyPrefix     = "goanda:price:"
+	DefaultChannelPrefix = "goanda:ticks:"
+)
+
+// Publisher writes prices to Redis
+type Publisher struct {
+	// KeyPrefix is prepended to an instrument for the key of its hash, it defaults to DefaultKeyPrefix
+	KeyPrefix string
+	// ChannelPrefix is prepended to an instrument for the channel of its ticks, it defaults to DefaultChannelPrefix
+	ChannelPrefix string
+	// TTL, if set, expires a hash not updated within it, so readers don't trade on the prices of a
+	// stopped publisher
+	TTL time.Duration
+
+	client redis.Cmdable
+}
+
+// New creates a publisher writing with the client, a *redis.Client or *redis.ClusterClient
+func New(client redis.Cmdable) *Publisher {
+	return &Publisher{client: client}
+}
+
+// Run streams prices for the instruments and publishes them until ctx is done or the stream or
+// Redis fails, returning the error
+func (p *Publisher) Run(ctx context.Context, sc *goanda.StreamingConnection, instruments []string) error {
+	for price, err := range sc.Prices(ctx, instruments) {
+		if err != nil {
+			return err
+		}
+		if err := p.Publish(ctx, price); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Publish stores a price as its instrument's latest and publishes the tick, in one round trip
+func (p *Publisher) Publish(ctx context.Context, price goanda.PricingStreamResponse) error {
+	tick, err := json.Marshal(price)
+	if err != nil {
+		return err
+	}
+
+	key := valueOr(p.KeyPrefix, DefaultKeyPrefix) + price.Instrument
+	fields := map[string]interface{}{
+		"closeoutBid": price.CloseoutBid,
+		"closeoutAsk": price.CloseoutAsk,
+		"time":        price.Time,
+		"tradeable":   strconv.FormatBool(price.Tradeable),
+	}
+	if len(price.Bids) > 0 {
+		fields["bid"] = price.Bids[0].Price
+	}
+	if len(price.Asks) > 0 {
+		fields["ask"] = price.Asks[0].Price
+	}
+
+	_, err = p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, fields)
+		if p.TTL > 0 {
+			pipe.Expire(ctx, key, p.TTL)
+		}
+		pipe.Publish(ctx, valueOr(p.ChannelPrefix, DefaultChannelPrefix)+price.Instrument, tick)
+		return nil
+	})
+	return err
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
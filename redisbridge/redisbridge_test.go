@@ -0,0 +1,70 @@
+package redisbridge
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/rollend/goanda"
+)
+
+func TestPublisher(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	ticks := client.Subscribe(ctx, "goanda:ticks:EUR_USD")
+	defer ticks.Close()
+	if _, err := ticks.Receive(ctx); err != nil {
+		t.Fatalf("Expected to subscribe, got %v", err)
+	}
+
+	dir := t.TempDir()
+	recorder, err := goanda.NewStreamRecorder(dir)
+	if err != nil {
+		t.Fatalf("Expected a recorder, got %v", err)
+	}
+	stream := "/accounts/test-account/pricing/stream?instruments=EUR_USD"
+	recorder.Record(stream, []byte(`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD","bids":[{"price":"1.1000","liquidity":1000000}],"asks":[{"price":"1.1002","liquidity":1000000}],"tradeable":true}`), time.Now())
+	recorder.Record(stream, []byte(`{"type":"PRICE","time":"2024-01-02T15:04:06Z","instrument":"EUR_USD","bids":[{"price":"1.1001","liquidity":1000000}],"asks":[{"price":"1.1003","liquidity":1000000}],"tradeable":true}`), time.Now())
+	recorder.Close()
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	replayer, err := goanda.NewStreamReplayer(paths...)
+	if err != nil {
+		t.Fatalf("Expected a replayer, got %v", err)
+	}
+
+	publisher := New(client)
+	publisher.TTL = time.Minute
+	if err := publisher.Run(ctx, replayer.StreamingConnection(), []string{"EUR_USD"}); err != nil {
+		t.Fatalf("Expected the recording to be published, got %v", err)
+	}
+
+	latest, err := client.HGetAll(ctx, "goanda:price:EUR_USD").Result()
+	if err != nil {
+		t.Fatalf("Expected the latest price, got %v", err)
+	}
+	if latest["bid"] != "1.1001" || latest["ask"] != "1.1003" || latest["time"] != "2024-01-02T15:04:06Z" || latest["tradeable"] != "true" {
+		t.Errorf("Expected the second price to be the latest, got %v", latest)
+	}
+	if ttl := server.TTL("goanda:price:EUR_USD"); ttl != time.Minute {
+		t.Errorf("Expected the key to expire in a minute, got %v", ttl)
+	}
+
+	for _, want := range []string{"1.1000", "1.1001"} {
+		select {
+		case message := <-ticks.Channel():
+			if !strings.Contains(message.Payload, `"price":"`+want+`"`) {
+				t.Errorf("Expected the tick at %s, got %s", want, message.Payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected a tick")
+		}
+	}
+}
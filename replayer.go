@@ -0,0 +1,155 @@
+package goanda
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// replayStreamURL is the streaming host of a replay connection, recorded streams are paths on it
+const replayStreamURL = "http://replay.goanda.invalid"
+
+// StreamReplayer plays recordings made by a StreamRecorder back through a StreamingConnection, so the
+// callbacks, iterators and Events of a strategy see the stream as it was recorded, without a live
+// connection. It is an http.RoundTripper serving each stream request with the recorded lines of that
+// stream. The Reconnect policy should be left unset, a replayed stream ends when its recording does.
+type StreamReplayer struct {
+	// Speed scales the original delays between lines: 1 replays in real time, 2 twice as fast.
+	// Zero, the default, replays as fast as the stream is read.
+	Speed float64
+
+	lines []RecordedLine
+}
+
+// NewStreamReplayer loads recordings, replaying them in the order given
+func NewStreamReplayer(paths ...string) (*StreamReplayer, error) {
+	r := &StreamReplayer{}
+	for _, path := range paths {
+		if err := r.load(path); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *StreamReplayer) load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, streamReadBuffer), DefaultMaxMessageSize)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var line RecordedLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("goanda: %s line %d: %w", path, n, err)
+		}
+		r.lines = append(r.lines, line)
+	}
+	return scanner.Err()
+}
+
+// StreamingConnection returns a streaming connection that replays the recordings. Its account is the
+// one of the first recorded stream. It makes no requests to OANDA, REST calls on it fail.
+func (r *StreamReplayer) StreamingConnection() *StreamingConnection {
+	accountID := ""
+	for _, line := range r.lines {
+		if parts := strings.Split(line.Stream, "/"); len(parts) > 2 && parts[1] == "accounts" {
+			accountID = parts[2]
+			break
+		}
+	}
+
+	c := &Connection{
+		hostname:  replayStreamURL,
+		accountID: accountID,
+		userAgent: apiUserAgent,
+		client:    http.Client{Transport: r},
+		started:   time.Now(),
+	}
+	sc := NewStreamingConnection(c)
+	sc.streamURL = replayStreamURL
+	return sc
+}
+
+// RoundTrip implements http.RoundTripper, serving a stream's recorded lines
+func (r *StreamReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	lines := r.match(req.URL)
+	if len(lines) == 0 {
+		body := fmt.Sprintf(`{"errorMessage":"no recording of %s"}`, req.URL.RequestURI())
+		return replayResponse(req, http.StatusNotFound, io.NopCloser(strings.NewReader(body))), nil
+	}
+
+	body, w := io.Pipe()
+	go r.play(req, lines, w)
+	return replayResponse(req, http.StatusOK, body), nil
+}
+
+// match returns the recorded lines of the requested stream. A pricing stream recorded with other
+// query parameters, such as a different set of instruments, is used if there is no exact recording.
+func (r *StreamReplayer) match(u *url.URL) []RecordedLine {
+	var exact, path []RecordedLine
+	for _, line := range r.lines {
+		if line.Stream == u.RequestURI() {
+			exact = append(exact, line)
+		}
+		if recorded, _, _ := strings.Cut(line.Stream, "?"); recorded == u.Path {
+			path = append(path, line)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return path
+}
+
+// play writes the lines to the stream's body, keeping to their original timing if Speed is set
+func (r *StreamReplayer) play(req *http.Request, lines []RecordedLine, w *io.PipeWriter) {
+	start := time.Now()
+	for _, line := range lines {
+		if r.Speed > 0 {
+			offset := time.Duration(float64(line.Received.Sub(lines[0].Received)) / r.Speed)
+			if wait := offset - time.Since(start); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-req.Context().Done():
+					timer.Stop()
+					w.CloseWithError(req.Context().Err())
+					return
+				}
+			}
+		}
+		if _, err := w.Write(append(line.Line(), '\n')); err != nil {
+			return
+		}
+	}
+	w.Close()
+}
+
+func replayResponse(req *http.Request, status int, body io.ReadCloser) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"application/octet-stream"}},
+		Body:       body,
+		Request:    req,
+	}
+}
@@ -0,0 +1,118 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeRecording(t *testing.T, lines ...RecordedLine) string {
+	path := filepath.Join(t.TempDir(), "stream.ndjson")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	for _, line := range lines {
+		encoder.Encode(line)
+	}
+	return path
+}
+
+func TestStreamReplayer(t *testing.T) {
+	defer logTestResult(t, "StreamReplayer")
+
+	start := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	pricing := "/accounts/001-001-1-001/pricing/stream?instruments=EUR_USD"
+	price := func(at time.Duration, bid string) RecordedLine {
+		message := fmt.Sprintf(`{"type":"PRICE","time":"%s","instrument":"EUR_USD","bids":[{"price":"%s","liquidity":1}]}`,
+			start.Add(at).Format(time.RFC3339Nano), bid)
+		return RecordedLine{Received: start.Add(at), Stream: pricing, Message: json.RawMessage(message)}
+	}
+	path := writeRecording(t,
+		price(0, "1.1"),
+		RecordedLine{Received: start.Add(50 * time.Millisecond), Stream: pricing, Message: json.RawMessage(`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05Z"}`)},
+		RecordedLine{Received: start.Add(60 * time.Millisecond), Stream: "/accounts/001-001-1-001/transactions/stream",
+			Message: json.RawMessage(`{"type":"ORDER_FILL","id":"6"}`)},
+		price(100*time.Millisecond, "1.2"),
+		price(200*time.Millisecond, "1.3"),
+	)
+
+	replayer, err := NewStreamReplayer(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sc := replayer.StreamingConnection()
+	if sc.account() != "001-001-1-001" {
+		t.Errorf("Expected the recorded account, got %q", sc.account())
+	}
+	heartbeats := 0
+	sc.OnHeartbeat = func(HeartbeatResponse) { heartbeats++ }
+
+	replay := func(speed float64) ([]string, time.Duration) {
+		replayer.Speed = speed
+		var bids []string
+		began := time.Now()
+		err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(price PricingStreamResponse) {
+			bids = append(bids, price.Bids[0].Price)
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return bids, time.Since(began)
+	}
+
+	bids, elapsed := replay(2)
+	if fmt.Sprint(bids) != "[1.1 1.2 1.3]" || heartbeats != 1 {
+		t.Errorf("Unexpected replay: %v, %d heartbeats", bids, heartbeats)
+	}
+	if elapsed < 90*time.Millisecond {
+		t.Errorf("Expected the replay to take half the recorded 200ms, took %v", elapsed)
+	}
+
+	if bids, elapsed := replay(0); len(bids) != 3 || elapsed > 90*time.Millisecond {
+		t.Errorf("Expected an instant replay, got %v in %v", bids, elapsed)
+	}
+
+	// Each stream replays its own recording, a stream without one fails
+	var ids []string
+	err = sc.StreamTransactions(context.Background(), func(tx TransactionStreamResponse) { ids = append(ids, tx.ID) })
+	if err != nil || fmt.Sprint(ids) != "[6]" {
+		t.Errorf("Unexpected transactions: %v (%v)", ids, err)
+	}
+	if err := sc.StreamAccountChanges(context.Background(), func(AccountChangesStreamResponse) {}); err == nil {
+		t.Error("Expected an error for a stream that was not recorded")
+	}
+}
+
+func TestStreamReplayerRecording(t *testing.T) {
+	defer logTestResult(t, "StreamReplayerRecording")
+
+	dir := t.TempDir()
+	recorder, err := NewStreamRecorder(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	recorder.Record("/accounts/a/pricing/stream?instruments=EUR_USD", []byte(`{"type":"PRICE","instrument":"EUR_USD"}`), time.Now())
+	recorder.Record("/accounts/a/pricing/stream?instruments=EUR_USD", []byte("not json"), time.Now())
+	recorder.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	replayer, err := NewStreamReplayer(files...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sc := replayer.StreamingConnection()
+	prices := 0
+	if err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) { prices++ }); err != nil || prices != 1 {
+		t.Errorf("Expected the recorded price, got %d (%v)", prices, err)
+	}
+	if sc.Metrics().StreamSkipped != 1 {
+		t.Errorf("Expected the malformed line to be replayed and skipped")
+	}
+}
@@ -0,0 +1,110 @@
+package goanda
+
+import (
+	"errors"
+	"sync"
+)
+
+// Resettable is library or application state derived from an account's transaction history,
+// such as stream checkpoints, journals and order trackers. It is cleared when the account is reset.
+// Connection, OrderTracker, OCOManager, LocalAccountState and DropCopy implement it.
+type Resettable interface {
+	ResetState() error
+}
+
+// AccountReset describes a detected reset of a practice account
+type AccountReset struct {
+	PreviousTransactionID string
+	TransactionID         string
+	PreviousBalance       float64
+	Balance               float64
+}
+
+// AccountResetDetector notices when a practice account has been reset, which restarts its transaction IDs
+// and balance, and clears the registered state so it is rebuilt from the new history rather than
+// corrupting gap recovery and P&L computations.
+type AccountResetDetector struct {
+	// OnReset, if set, is called after the registered state has been cleared
+	OnReset func(AccountReset)
+
+	mu                sync.Mutex
+	known             bool
+	lastTransactionID string
+	balance           float64
+	resettables       []Resettable
+}
+
+// NewAccountResetDetector creates a detector that clears the given state on a reset
+func NewAccountResetDetector(resettables ...Resettable) *AccountResetDetector {
+	return &AccountResetDetector{resettables: resettables}
+}
+
+// Register adds state to be cleared on a reset
+func (d *AccountResetDetector) Register(r Resettable) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.resettables = append(d.resettables, r)
+}
+
+// Seed sets the last known account position, typically restored from a persisted checkpoint,
+// so that a reset which happened while the process was not running is detected
+func (d *AccountResetDetector) Seed(lastTransactionID string, balance float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.known = true
+	d.lastTransactionID = lastTransactionID
+	d.balance = balance
+}
+
+// Observe records the account's latest transaction ID and balance, reporting whether the account was reset.
+// A reset is detected when the transaction ID goes backwards. Every registered Resettable is cleared,
+// and any errors they return are joined.
+func (d *AccountResetDetector) Observe(lastTransactionID string, balance float64) (bool, error) {
+	d.mu.Lock()
+	reset := d.known && compareTransactionIDs(lastTransactionID, d.lastTransactionID) < 0
+	event := AccountReset{
+		PreviousTransactionID: d.lastTransactionID,
+		TransactionID:         lastTransactionID,
+		PreviousBalance:       d.balance,
+		Balance:               balance,
+	}
+	d.known = true
+	d.lastTransactionID = lastTransactionID
+	d.balance = balance
+	resettables := append([]Resettable(nil), d.resettables...)
+	d.mu.Unlock()
+
+	if !reset {
+		return false, nil
+	}
+
+	var errs []error
+	for _, r := range resettables {
+		if err := r.ResetState(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if d.OnReset != nil {
+		d.OnReset(event)
+	}
+	return true, errors.Join(errs...)
+}
+
+// ResetState makes the connection's running transaction streams forget the last transaction they
+// delivered, which they would otherwise skip the reset account's transactions up to, as already seen
+func (c *Connection) ResetState() error {
+	c.resets.Add(1)
+	return nil
+}
+
+// CheckAccountReset fetches the account summary and passes it to the detector
+func (c *Connection) CheckAccountReset(d *AccountResetDetector) (bool, error) {
+	summary, err := c.GetAccountSummary()
+	if err != nil {
+		return false, err
+	}
+	return d.Observe(summary.Account.LastTransactionID, summary.Account.Balance)
+}
@@ -0,0 +1,171 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type resettableState struct {
+	resets int
+	err    error
+}
+
+func (rs *resettableState) ResetState() error {
+	rs.resets++
+	return rs.err
+}
+
+func TestAccountResetDetector(t *testing.T) {
+	defer logTestResult(t, "AccountResetDetector")
+
+	state := &resettableState{}
+	d := NewAccountResetDetector(state)
+
+	var events []AccountReset
+	d.OnReset = func(r AccountReset) {
+		events = append(events, r)
+	}
+
+	for _, id := range []string{"100", "105", "105"} {
+		if reset, err := d.Observe(id, 1000); reset || err != nil {
+			t.Fatalf("Unexpected reset at %s: %v", id, err)
+		}
+	}
+
+	reset, err := d.Observe("3", 100000)
+	if !reset || err != nil {
+		t.Fatalf("Expected a reset, got %v %v", reset, err)
+	}
+	if state.resets != 1 {
+		t.Errorf("Expected the state to be reset once, got %d", state.resets)
+	}
+	if len(events) != 1 || events[0].PreviousTransactionID != "105" || events[0].Balance != 100000 {
+		t.Errorf("Unexpected reset events: %+v", events)
+	}
+
+	// The detector rebases onto the new history
+	if reset, _ := d.Observe("4", 100000); reset {
+		t.Error("Expected no reset after rebasing")
+	}
+}
+
+func TestAccountResetDetectorJoinsErrors(t *testing.T) {
+	defer logTestResult(t, "AccountResetDetectorJoinsErrors")
+
+	failing := &resettableState{err: errors.New("journal locked")}
+	ok := &resettableState{}
+	d := NewAccountResetDetector(failing)
+	d.Register(ok)
+	d.Seed("50", 0)
+
+	reset, err := d.Observe("1", 0)
+	if !reset {
+		t.Fatal("Expected a reset")
+	}
+	if !errors.Is(err, failing.err) {
+		t.Errorf("Expected the reset error to be returned, got %v", err)
+	}
+	if ok.resets != 1 {
+		t.Error("Expected the remaining state to be reset despite the error")
+	}
+}
+
+func TestCheckAccountReset(t *testing.T) {
+	defer logTestResult(t, "CheckAccountReset")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		summary := AccountSummary{}
+		summary.Account.LastTransactionID = "2"
+		summary.Account.Balance = 100000
+		json.NewEncoder(w).Encode(summary)
+	}))
+	defer server.Close()
+
+	c := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+
+	d := NewAccountResetDetector()
+	d.Seed("9000", 42.5)
+	reset, err := c.CheckAccountReset(d)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reset {
+		t.Error("Expected the restarted transaction IDs to be detected as a reset")
+	}
+}
+
+func TestAccountResetClearsState(t *testing.T) {
+	defer logTestResult(t, "AccountResetClearsState")
+
+	c := &Connection{accountID: "test-account"}
+	tracker := NewOrderTracker(c)
+	limit := &LimitOrderTransaction{Price: "1.1000"}
+	limit.ID, limit.Type, limit.Instrument, limit.Units = "6", "LIMIT_ORDER", "EUR_USD", "100"
+	tracker.Handle(limit)
+
+	store := DirStateStore(t.TempDir())
+	store.Save("oco-test-account", []byte(`[{"id":"pair","legs":[{"clientID":"a","orderID":"6"},{"clientID":"b","orderID":"7"}]}]`))
+	oco, err := NewOCOManager(c, store)
+	if err != nil || len(oco.Pairs()) != 1 {
+		t.Fatalf("Expected the saved pair, got %v (%v)", oco.Pairs(), err)
+	}
+	state := &LocalAccountState{LastTransactionID: "7", Balance: "1000", Orders: map[string]OrderInfo{"6": {}}}
+
+	d := NewAccountResetDetector(tracker, oco, state)
+	d.Seed("7", 1000)
+	if reset, err := d.Observe("1", 100000); !reset || err != nil {
+		t.Fatalf("Expected a reset, got %v %v", reset, err)
+	}
+
+	if _, ok := tracker.Order("6"); ok {
+		t.Error("Expected the tracker to forget the old account's orders")
+	}
+	if len(oco.Pairs()) != 0 {
+		t.Errorf("Expected the oco pairs dropped, got %v", oco.Pairs())
+	}
+	if reloaded, _ := NewOCOManager(c, store); len(reloaded.Pairs()) != 0 {
+		t.Errorf("Expected the dropped pairs saved, got %v", reloaded.Pairs())
+	}
+	if state.LastTransactionID != "" || state.Balance != "" || len(state.Orders) != 0 {
+		t.Errorf("Expected the local account state emptied, got %+v", state)
+	}
+}
+
+func TestTransactionStreamAfterReset(t *testing.T) {
+	defer logTestResult(t, "TransactionStreamAfterReset")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"10","type":"ORDER_FILL","time":"2024-01-02T15:04:05Z"}` + "\n"))
+		w.Write([]byte(`{"id":"2","type":"ORDER_FILL","time":"2024-01-02T15:04:06Z"}` + "\n"))
+	}))
+	defer server.Close()
+
+	c := &Connection{accountID: "test-account", client: *server.Client()}
+	sc := &StreamingConnection{Connection: c, streamURL: server.URL}
+	d := NewAccountResetDetector(c)
+	d.Seed("10", 1000)
+
+	// the reset account's transactions, from 1 again, aren't skipped as older than the last one
+	var ids []string
+	err := sc.StreamTransactions(context.Background(), func(response TransactionStreamResponse) {
+		ids = append(ids, response.ID)
+		if response.ID == "10" {
+			d.Observe("1", 100000)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fmt.Sprint(ids) != "[10 2]" {
+		t.Errorf("Expected both transactions, got %v", ids)
+	}
+}
@@ -0,0 +1,94 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Topics an EventPublisher publishes to when none are set
+const (
+	DefaultPriceTopic       = "goanda.prices"
+	DefaultTransactionTopic = "goanda.transactions"
+)
+
+// Sink publishes messages to a message broker, the kafkasink and natssink packages adapt Kafka and NATS clients
+type Sink interface {
+	Publish(ctx context.Context, topic string, key string, value []byte) error
+}
+
+// EventPublisher publishes the prices and transactions of an Events channel to a Sink, so market data
+// can be shared beyond one process. Each is published as the JSON it was streamed as.
+type EventPublisher struct {
+	Sink Sink
+	// PriceTopic and TransactionTopic are the topics events are published to, they default to
+	// DefaultPriceTopic and DefaultTransactionTopic
+	PriceTopic       string
+	TransactionTopic string
+	// Key returns the key an event is published with, it defaults to KeyByInstrument
+	Key func(Event) string
+}
+
+// KeyByInstrument keys prices and transactions by their instrument, and transactions without one,
+// such as transfers, by their account
+func KeyByInstrument(event Event) string {
+	switch event.Kind {
+	case EventPrice:
+		return event.Price.Instrument
+	case EventTransaction:
+		var t struct {
+			Instrument string `json:"instrument"`
+		}
+		json.Unmarshal(event.Transaction.Transaction, &t)
+		if t.Instrument != "" {
+			return t.Instrument
+		}
+	}
+	return event.AccountID
+}
+
+// KeyByAccount keys events by the account they were streamed for
+func KeyByAccount(event Event) string {
+	return event.AccountID
+}
+
+// Publish publishes the events until the channel closes, returning nil, or publishing fails or the
+// channel sends an EventError, returning its error. Heartbeats are not published.
+func (p *EventPublisher) Publish(ctx context.Context, events <-chan Event) error {
+	key := p.Key
+	if key == nil {
+		key = KeyByInstrument
+	}
+
+	for event := range events {
+		var topic string
+		var value []byte
+		var err error
+		switch event.Kind {
+		case EventPrice:
+			topic = valueOr(p.PriceTopic, DefaultPriceTopic)
+			value, err = json.Marshal(event.Price)
+		case EventTransaction:
+			topic = valueOr(p.TransactionTopic, DefaultTransactionTopic)
+			value, err = json.Marshal(event.Transaction)
+		case EventError:
+			return event.Err
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.Sink.Publish(ctx, topic, key(event), value); err != nil {
+			return fmt.Errorf("goanda: publishing to %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,75 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type sinkMessage struct {
+	topic string
+	key   string
+	value string
+}
+
+type testSink struct {
+	messages []sinkMessage
+	err      error
+}
+
+func (s *testSink) Publish(ctx context.Context, topic string, key string, value []byte) error {
+	s.messages = append(s.messages, sinkMessage{topic, key, string(value)})
+	return s.err
+}
+
+func TestEventPublisher(t *testing.T) {
+	defer logTestResult(t, "EventPublisher")
+
+	events := make(chan Event, 10)
+	events <- Event{Kind: EventPrice, AccountID: "test-account", Price: PricingStreamResponse{Type: "PRICE", Instrument: "EUR_USD"}}
+	events <- Event{Kind: EventHeartbeat, AccountID: "test-account"}
+	events <- Event{Kind: EventTransaction, AccountID: "test-account", Transaction: TransactionStreamResponse{
+		Type:        "ORDER_FILL",
+		Transaction: json.RawMessage(`{"type":"ORDER_FILL","instrument":"USD_JPY"}`),
+	}}
+	events <- Event{Kind: EventTransaction, AccountID: "test-account", Transaction: TransactionStreamResponse{
+		Type:        "TRANSFER_FUNDS",
+		Transaction: json.RawMessage(`{"type":"TRANSFER_FUNDS"}`),
+	}}
+	close(events)
+
+	sink := &testSink{}
+	publisher := EventPublisher{Sink: sink, TransactionTopic: "fills"}
+	if err := publisher.Publish(context.Background(), events); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(sink.messages) != 3 {
+		t.Fatalf("Expected the heartbeat to be skipped, got %+v", sink.messages)
+	}
+	if m := sink.messages[0]; m.topic != DefaultPriceTopic || m.key != "EUR_USD" || !strings.Contains(m.value, `"instrument":"EUR_USD"`) {
+		t.Errorf("Expected the price keyed by instrument, got %+v", m)
+	}
+	if m := sink.messages[1]; m.topic != "fills" || m.key != "USD_JPY" {
+		t.Errorf("Expected the fill keyed by its instrument, got %+v", m)
+	}
+	if m := sink.messages[2]; m.key != "test-account" {
+		t.Errorf("Expected the transfer keyed by account, got %+v", m)
+	}
+
+	failure := errors.New("failed")
+	events = make(chan Event, 1)
+	events <- Event{Kind: EventError, Err: failure}
+	if err := publisher.Publish(context.Background(), events); !errors.Is(err, failure) {
+		t.Errorf("Expected the stream's error, got %v", err)
+	}
+
+	events = make(chan Event, 1)
+	events <- Event{Kind: EventPrice, AccountID: "test-account", Price: PricingStreamResponse{Instrument: "EUR_USD"}}
+	publisher = EventPublisher{Sink: &testSink{err: failure}, Key: KeyByAccount}
+	if err := publisher.Publish(context.Background(), events); !errors.Is(err, failure) {
+		t.Errorf("Expected the sink's error, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package goanda
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrNoPrice is returned when there is no price to read: a side with no buckets, as when the market is
+// closed, or an instrument a PriceCache has not been sent
+var ErrNoPrice = errors.New("goanda: no price quoted")
+
+// BestBid parses the highest bid, which OANDA sends first
+func (p PricingStreamResponse) BestBid() (float64, error) {
+	return bestPrice("bid", p.Instrument, p.Bids)
+}
+
+// BestAsk parses the lowest ask, which OANDA sends first
+func (p PricingStreamResponse) BestAsk() (float64, error) {
+	return bestPrice("ask", p.Instrument, p.Asks)
+}
+
+// Mid returns the price halfway between the best bid and ask
+func (p PricingStreamResponse) Mid() (float64, error) {
+	bid, ask, err := p.best()
+	if err != nil {
+		return 0, err
+	}
+	return (bid + ask) / 2, nil
+}
+
+// Spread returns the difference between the best ask and bid, in the instrument's price
+func (p PricingStreamResponse) Spread() (float64, error) {
+	bid, ask, err := p.best()
+	if err != nil {
+		return 0, err
+	}
+	return ask - bid, nil
+}
+
+// SpreadPips returns the difference between the best ask and bid in pips of the instrument,
+// the details of which come from Connection.GetAccountInstruments
+func (p PricingStreamResponse) SpreadPips(instrument InstrumentDetails) (float64, error) {
+	bid, ask, err := p.best()
+	if err != nil {
+		return 0, err
+	}
+	return spreadPips(bid, ask, instrument), nil
+}
+
+func (p PricingStreamResponse) best() (bid float64, ask float64, err error) {
+	if bid, err = p.BestBid(); err != nil {
+		return 0, 0, err
+	}
+	if ask, err = p.BestAsk(); err != nil {
+		return 0, 0, err
+	}
+	return bid, ask, nil
+}
+
+// Mid returns the price halfway between the best bid and ask, or zero if either side is missing
+func (p ParsedPrice) Mid() float64 {
+	if len(p.Bids) == 0 || len(p.Asks) == 0 {
+		return 0
+	}
+	return (p.Bid() + p.Ask()) / 2
+}
+
+// Spread returns the difference between the best ask and bid, or zero if either side is missing
+func (p ParsedPrice) Spread() float64 {
+	if len(p.Bids) == 0 || len(p.Asks) == 0 {
+		return 0
+	}
+	return p.Ask() - p.Bid()
+}
+
+// SpreadPips returns the difference between the best ask and bid in pips of the instrument,
+// or zero if either side is missing
+func (p ParsedPrice) SpreadPips(instrument InstrumentDetails) float64 {
+	if len(p.Bids) == 0 || len(p.Asks) == 0 {
+		return 0
+	}
+	return spreadPips(p.Bid(), p.Ask(), instrument)
+}
+
+func bestPrice(side string, instrument string, buckets []RawPriceBucket) (float64, error) {
+	if len(buckets) == 0 {
+		return 0, fmt.Errorf("%w: %s has no %s", ErrNoPrice, instrument, side)
+	}
+	return parsePrice(side, buckets[0].Price)
+}
+
+// spreadPips converts a spread to pips, rounded to the fraction of a pip the instrument is quoted in
+// so float error doesn't turn 1.2 pips into 1.1999999999. Details without a display precision are
+// rounded to a millionth of a pip.
+func spreadPips(bid float64, ask float64, instrument InstrumentDetails) float64 {
+	pips := (ask - bid) / math.Pow10(instrument.PipLocation)
+	digits := instrument.DisplayPrecision + instrument.PipLocation
+	if instrument.DisplayPrecision == 0 {
+		digits = 6
+	} else if digits < 0 {
+		digits = 0
+	}
+	scale := math.Pow10(digits)
+	return math.Round(pips*scale) / scale
+}
@@ -0,0 +1,71 @@
+package goanda
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPriceSpread(t *testing.T) {
+	defer logTestResult(t, "PriceSpread")
+
+	price := PricingStreamResponse{
+		Instrument: "EUR_USD",
+		Time:       "2024-01-02T15:04:05Z",
+		Bids:       []RawPriceBucket{{Price: "1.10000", Liquidity: 1000000}, {Price: "1.09990", Liquidity: 5000000}},
+		Asks:       []RawPriceBucket{{Price: "1.10012", Liquidity: 1000000}},
+	}
+	eurusd := InstrumentDetails{Name: "EUR_USD", PipLocation: -4, DisplayPrecision: 5}
+
+	bid, err := price.BestBid()
+	if err != nil || bid != 1.1 {
+		t.Errorf("Expected the best bid 1.1, got %v (%v)", bid, err)
+	}
+	ask, err := price.BestAsk()
+	if err != nil || ask != 1.10012 {
+		t.Errorf("Expected the best ask 1.10012, got %v (%v)", ask, err)
+	}
+	if mid, err := price.Mid(); err != nil || mid != 1.10006 {
+		t.Errorf("Expected the mid 1.10006, got %v (%v)", mid, err)
+	}
+	if pips, err := price.SpreadPips(eurusd); err != nil || pips != 1.2 {
+		t.Errorf("Expected a spread of 1.2 pips, got %v (%v)", pips, err)
+	}
+
+	usdjpy := PricingStreamResponse{
+		Instrument: "USD_JPY",
+		Bids:       []RawPriceBucket{{Price: "150.123"}},
+		Asks:       []RawPriceBucket{{Price: "150.137"}},
+	}
+	if pips, err := usdjpy.SpreadPips(InstrumentDetails{PipLocation: -2, DisplayPrecision: 3}); err != nil || pips != 1.4 {
+		t.Errorf("Expected a spread of 1.4 pips, got %v (%v)", pips, err)
+	}
+
+	parsed, err := price.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if parsed.Mid() != 1.10006 || parsed.SpreadPips(eurusd) != 1.2 {
+		t.Errorf("Expected the parsed price to agree, got %v and %v pips", parsed.Mid(), parsed.SpreadPips(eurusd))
+	}
+}
+
+func TestPriceSpreadWithoutQuotes(t *testing.T) {
+	defer logTestResult(t, "PriceSpreadWithoutQuotes")
+
+	closed := PricingStreamResponse{Instrument: "EUR_USD", Bids: []RawPriceBucket{{Price: "1.1"}}}
+	if _, err := closed.Mid(); !errors.Is(err, ErrNoPrice) {
+		t.Errorf("Expected ErrNoPrice without asks, got %v", err)
+	}
+	if _, err := closed.BestBid(); err != nil {
+		t.Errorf("Expected the bid to parse, got %v", err)
+	}
+
+	invalid := PricingStreamResponse{Instrument: "EUR_USD", Bids: []RawPriceBucket{{Price: "not a price"}}, Asks: []RawPriceBucket{{Price: "1.1"}}}
+	if _, err := invalid.Spread(); err == nil || errors.Is(err, ErrNoPrice) {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+
+	if (ParsedPrice{Asks: []PriceBucket{{Price: 1.1}}}).SpreadPips(InstrumentDetails{PipLocation: -4}) != 0 {
+		t.Error("Expected a zero spread for a parsed price without bids")
+	}
+}
@@ -0,0 +1,176 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSSEKeepAlive is how often a PriceEventSource writes a comment to idle connections when no KeepAlive is set
+const DefaultSSEKeepAlive = 15 * time.Second
+
+// PriceEventSource is an http.Handler republishing a price stream as Server-Sent Events, so a web
+// page can follow prices with an EventSource and no custom protocol. Every connection shares the one
+// stream, opened by Run. Each price is sent as a "price" event of its JSON:
+//
+//	event: price
+//	data: {"type":"PRICE","time":"...","instrument":"EUR_USD",...}
+//
+// A connection receives every instrument unless it asks for some with the instruments query
+// parameter, /prices?instruments=EUR_USD,USD_JPY. When the stream ends an "error" event is sent with
+// its error and the connections are closed.
+type PriceEventSource struct {
+	// Options are those of the price stream
+	Options PriceStreamOptions
+	// KeepAlive is how often a comment is written to idle connections, so proxies don't close them.
+	// It defaults to DefaultSSEKeepAlive.
+	KeepAlive time.Duration
+	// ClientBuffer is the number of events queued for a connection before it is closed as too slow,
+	// it defaults to DefaultEventBuffer
+	ClientBuffer int
+
+	sc          *StreamingConnection
+	instruments []string
+
+	mu      sync.Mutex
+	clients map[*sseClient]struct{}
+}
+
+type sseClient struct {
+	instruments []string
+	events      chan []byte
+}
+
+// NewPriceEventSource creates a handler republishing prices for the instruments
+func NewPriceEventSource(sc *StreamingConnection, instruments ...string) *PriceEventSource {
+	return &PriceEventSource{sc: sc, instruments: instruments, clients: map[*sseClient]struct{}{}}
+}
+
+// Run streams prices to the connections until ctx is done or the stream fails, returning its error
+func (s *PriceEventSource) Run(ctx context.Context) error {
+	err := s.sc.streamPrices(ctx, s.instruments, s.Options, func(price PricingStreamResponse) error {
+		data, err := json.Marshal(price)
+		if err != nil {
+			return err
+		}
+		s.broadcast(price.Instrument, sseEvent("price", data))
+		return nil
+	})
+
+	message := "price stream ended"
+	if err != nil {
+		message = err.Error()
+	}
+	data, _ := json.Marshal(message)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		select {
+		case c.events <- sseEvent("error", data):
+		default:
+		}
+		s.drop(c)
+	}
+	return err
+}
+
+// broadcast queues an event for the connections following the instrument, closing those that are too slow
+func (s *PriceEventSource) broadcast(instrument string, event []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		if len(c.instruments) > 0 && !slices.Contains(c.instruments, instrument) {
+			continue
+		}
+		select {
+		case c.events <- event:
+		default:
+			s.sc.logf("goanda: closing a slow event stream connection")
+			s.drop(c)
+		}
+	}
+}
+
+// drop closes a connection's queue, the connection ends once it has written what is left. It needs s.mu.
+func (s *PriceEventSource) drop(c *sseClient) {
+	if _, ok := s.clients[c]; ok {
+		delete(s.clients, c)
+		close(c.events)
+	}
+}
+
+// ServeHTTP streams events to a connection until it closes or the price stream ends
+func (s *PriceEventSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var instruments []string
+	if query := r.URL.Query().Get("instruments"); query != "" {
+		instruments = strings.Split(query, ",")
+		for _, instrument := range instruments {
+			if !slices.Contains(s.instruments, instrument) {
+				http.Error(w, fmt.Sprintf("%s is not streamed", instrument), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	buffer := s.ClientBuffer
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+	c := &sseClient{instruments: instruments, events: make(chan []byte, buffer)}
+	s.mu.Lock()
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.drop(c)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := s.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultSSEKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-c.events:
+			if !ok {
+				return
+			}
+			if _, err := w.Write(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// sseEvent formats an event, data must not contain newlines
+func sseEvent(name string, data []byte) []byte {
+	return []byte("event: " + name + "\ndata: " + string(data) + "\n\n")
+}
@@ -0,0 +1,95 @@
+package goanda
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPriceEventSource(t *testing.T) {
+	defer logTestResult(t, "PriceEventSource")
+
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"USD_JPY"}` + "\n"))
+		w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:06Z","instrument":"EUR_USD"}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  upstream.URL,
+		accountID: "test-account",
+		client:    *upstream.Client(),
+	})
+	sc.streamURL = upstream.URL
+
+	source := NewPriceEventSource(sc, "EUR_USD", "USD_JPY")
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan error, 1)
+	go func() { ran <- source.Run(ctx) }()
+
+	server := httptest.NewServer(source)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?instruments=GBP_USD")
+	if err != nil {
+		t.Fatalf("Expected a response, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an instrument not streamed, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "?instruments=EUR_USD")
+	if err != nil {
+		t.Fatalf("Expected a response, got %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %q", ct)
+	}
+	close(release)
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected a line")
+			return ""
+		}
+	}
+
+	if event, data := next(), next(); event != "event: price" || !strings.Contains(data, `"instrument":"EUR_USD"`) {
+		t.Errorf("Expected only the EUR_USD price, got %q %q", event, data)
+	}
+	next()
+
+	cancel()
+	if err := <-ran; err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Run, got %v", err)
+	}
+	if event, data := next(), next(); event != "event: error" || data != `data: "context canceled"` {
+		t.Errorf("Expected an error event, got %q %q", event, data)
+	}
+	next()
+	if _, ok := <-lines; ok {
+		t.Error("Expected the connection to close")
+	}
+}
@@ -0,0 +1,272 @@
+package goanda
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StrategyConfig declares how a strategy is wired: what it trades, the indicators it computes,
+// its risk settings and the rules it acts on. It is usually loaded from YAML with LoadStrategyConfig.
+//
+//	name: eurusd-trend
+//	instruments: [EUR_USD]
+//	granularities: [M15, H1]
+//	indicators:
+//	  - name: fast
+//	    type: ema
+//	    params: {period: 12}
+//	risk:
+//	  maxRiskPerTrade: 0.01
+//	  maxOpenTrades: 3
+//	rules:
+//	  - name: enter-long
+//	    when: fast > slow
+//	    then: buy
+type StrategyConfig struct {
+	Name          string            `yaml:"name"`
+	Instruments   []string          `yaml:"instruments"`
+	Granularities []string          `yaml:"granularities"`
+	Indicators    []IndicatorConfig `yaml:"indicators"`
+	Risk          RiskConfig        `yaml:"risk"`
+	Rules         []RuleConfig      `yaml:"rules"`
+}
+
+// IndicatorConfig names an indicator of a registered type and gives its parameters
+type IndicatorConfig struct {
+	Name   string             `yaml:"name"`
+	Type   string             `yaml:"type"`
+	Params map[string]float64 `yaml:"params"`
+}
+
+// RiskConfig limits how much a strategy can put at risk
+type RiskConfig struct {
+	// MaxRiskPerTrade is the fraction of NAV that may be lost on a single trade, such as 0.01 for 1%
+	MaxRiskPerTrade float64 `yaml:"maxRiskPerTrade"`
+	MaxOpenTrades   int     `yaml:"maxOpenTrades"`
+	StopLossPips    float64 `yaml:"stopLossPips"`
+	TakeProfitPips  float64 `yaml:"takeProfitPips"`
+}
+
+// RuleConfig is a rule for a rule engine to evaluate, When is its condition and Then its action
+type RuleConfig struct {
+	Name string `yaml:"name"`
+	When string `yaml:"when"`
+	Then string `yaml:"then"`
+}
+
+// RuleActions are the actions a rule may take
+var RuleActions = []string{"buy", "sell", "close"}
+
+// LoadStrategyConfig reads and validates a YAML strategy configuration.
+// Unknown keys are rejected so that a misspelt setting is not silently ignored.
+func LoadStrategyConfig(r io.Reader) (StrategyConfig, error) {
+	var sc StrategyConfig
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&sc); err != nil {
+		return StrategyConfig{}, fmt.Errorf("strategy config: %w", err)
+	}
+
+	return sc, sc.Validate()
+}
+
+// LoadStrategyConfigFile reads and validates a YAML strategy configuration from a file
+func LoadStrategyConfigFile(path string) (StrategyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return StrategyConfig{}, err
+	}
+	return LoadStrategyConfig(bytes.NewReader(data))
+}
+
+// Validate checks the configuration, returning every problem found joined into one error
+func (sc StrategyConfig) Validate() error {
+	var errs []error
+	invalid := func(format string, v ...interface{}) {
+		errs = append(errs, fmt.Errorf("strategy config: "+format, v...))
+	}
+
+	if sc.Name == "" {
+		invalid("name is required")
+	}
+	if len(sc.Instruments) == 0 {
+		invalid("at least one instrument is required")
+	}
+	for _, instrument := range sc.Instruments {
+		if !strings.Contains(instrument, "_") {
+			invalid("instrument %q is not of the form BASE_QUOTE", instrument)
+		}
+	}
+	if len(sc.Granularities) == 0 {
+		invalid("at least one granularity is required")
+	}
+	for _, g := range sc.Granularities {
+		if _, err := ParseGranularity(g); err != nil {
+			invalid("%v", err)
+		}
+	}
+
+	indicators := map[string]bool{}
+	for i, indicator := range sc.Indicators {
+		switch {
+		case indicator.Name == "":
+			invalid("indicator %d has no name", i)
+		case indicators[indicator.Name]:
+			invalid("indicator %q is declared twice", indicator.Name)
+		}
+		if indicator.Type == "" {
+			invalid("indicator %q has no type", indicator.Name)
+		}
+		indicators[indicator.Name] = true
+	}
+
+	if sc.Risk.MaxRiskPerTrade < 0 || sc.Risk.MaxRiskPerTrade > 1 {
+		invalid("maxRiskPerTrade must be a fraction between 0 and 1, got %v", sc.Risk.MaxRiskPerTrade)
+	}
+	if sc.Risk.MaxOpenTrades < 0 {
+		invalid("maxOpenTrades cannot be negative")
+	}
+	if sc.Risk.StopLossPips < 0 || sc.Risk.TakeProfitPips < 0 {
+		invalid("stopLossPips and takeProfitPips cannot be negative")
+	}
+
+	for i, rule := range sc.Rules {
+		if rule.When == "" {
+			invalid("rule %d has no condition", i)
+		}
+		if !isRuleAction(rule.Then) {
+			invalid("rule %d has action %q, expected one of %s", i, rule.Then, strings.Join(RuleActions, ", "))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func isRuleAction(action string) bool {
+	for _, a := range RuleActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseGranularity parses a granularity in the OANDA format, such as M15 or H1
+func ParseGranularity(s string) (Granularity, error) {
+	for g, name := range candlestickGranularity {
+		if name == s {
+			return g, nil
+		}
+	}
+	return 0, fmt.Errorf("no such granularity %q", s)
+}
+
+// Indicator computes a value from a series of candles
+type Indicator interface {
+	// Update adds the next candle, returning the indicator's value and whether it has seen enough candles to be valid
+	Update(candle Candles) (float64, bool)
+}
+
+// IndicatorFactory creates an indicator from its configured parameters
+type IndicatorFactory func(params map[string]float64) (Indicator, error)
+
+// IndicatorRegistry maps indicator types to their factories
+type IndicatorRegistry map[string]IndicatorFactory
+
+// DefaultIndicators provides the sma and ema indicators, each taking a period parameter
+var DefaultIndicators = IndicatorRegistry{
+	"sma": func(params map[string]float64) (Indicator, error) {
+		period, err := periodParam(params)
+		return &movingAverage{period: period}, err
+	},
+	"ema": func(params map[string]float64) (Indicator, error) {
+		period, err := periodParam(params)
+		return &movingAverage{period: period, exponential: true}, err
+	},
+}
+
+// StrategyPipeline is a strategy configuration with its granularities parsed and indicators constructed
+type StrategyPipeline struct {
+	Config        StrategyConfig
+	Granularities []Granularity
+	Indicators    map[string]Indicator
+}
+
+// Build constructs the strategy's pipeline, creating its indicators from the registry
+func (sc StrategyConfig) Build(registry IndicatorRegistry) (*StrategyPipeline, error) {
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+
+	sp := &StrategyPipeline{
+		Config:     sc,
+		Indicators: map[string]Indicator{},
+	}
+	for _, name := range sc.Granularities {
+		g, _ := ParseGranularity(name)
+		sp.Granularities = append(sp.Granularities, g)
+	}
+
+	for _, ic := range sc.Indicators {
+		factory, ok := registry[ic.Type]
+		if !ok {
+			return nil, fmt.Errorf("strategy config: indicator %q has unknown type %q", ic.Name, ic.Type)
+		}
+		indicator, err := factory(ic.Params)
+		if err != nil {
+			return nil, fmt.Errorf("strategy config: indicator %q: %w", ic.Name, err)
+		}
+		sp.Indicators[ic.Name] = indicator
+	}
+
+	return sp, nil
+}
+
+func periodParam(params map[string]float64) (int, error) {
+	period := int(params["period"])
+	if period < 1 || float64(period) != params["period"] {
+		return 0, fmt.Errorf("period must be a positive whole number, got %v", params["period"])
+	}
+	return period, nil
+}
+
+// movingAverage is a simple or exponential moving average of candle closes
+type movingAverage struct {
+	period      int
+	exponential bool
+	closes      []float64
+	sum         float64
+	value       float64
+	count       int
+}
+
+func (ma *movingAverage) Update(candle Candles) (float64, bool) {
+	price := candle.Mid.Close
+	ma.count++
+
+	if ma.exponential {
+		if ma.count == 1 {
+			ma.value = price
+		} else {
+			k := 2 / float64(ma.period+1)
+			ma.value = price*k + ma.value*(1-k)
+		}
+		return ma.value, ma.count >= ma.period
+	}
+
+	ma.closes = append(ma.closes, price)
+	ma.sum += price
+	if len(ma.closes) > ma.period {
+		ma.sum -= ma.closes[0]
+		ma.closes = ma.closes[1:]
+	}
+	ma.value = ma.sum / float64(len(ma.closes))
+	return ma.value, len(ma.closes) == ma.period
+}
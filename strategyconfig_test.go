@@ -0,0 +1,104 @@
+package goanda
+
+import (
+	"strings"
+	"testing"
+)
+
+const testStrategyConfig = `
+name: eurusd-trend
+instruments: [EUR_USD, GBP_USD]
+granularities: [M15, H1]
+indicators:
+  - name: fast
+    type: sma
+    params: {period: 2}
+  - name: slow
+    type: ema
+    params: {period: 3}
+risk:
+  maxRiskPerTrade: 0.01
+  maxOpenTrades: 3
+  stopLossPips: 20
+rules:
+  - name: enter-long
+    when: fast > slow
+    then: buy
+`
+
+func TestLoadStrategyConfig(t *testing.T) {
+	defer logTestResult(t, "LoadStrategyConfig")
+
+	sc, err := LoadStrategyConfig(strings.NewReader(testStrategyConfig))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sc.Name != "eurusd-trend" || len(sc.Instruments) != 2 || sc.Risk.MaxOpenTrades != 3 {
+		t.Errorf("Unexpected config: %+v", sc)
+	}
+	if sc.Indicators[1].Params["period"] != 3 {
+		t.Errorf("Expected the slow period to be 3, got %v", sc.Indicators[1].Params)
+	}
+
+	sp, err := sc.Build(DefaultIndicators)
+	if err != nil {
+		t.Fatalf("Unexpected build error: %v", err)
+	}
+	if len(sp.Granularities) != 2 || sp.Granularities[1] != GranularityHour {
+		t.Errorf("Unexpected granularities: %v", sp.Granularities)
+	}
+
+	fast := sp.Indicators["fast"]
+	fast.Update(Candles{Mid: Candle{Close: 1}})
+	value, ready := fast.Update(Candles{Mid: Candle{Close: 2}})
+	if !ready || value != 1.5 {
+		t.Errorf("Expected a ready SMA of 1.5, got %v %v", value, ready)
+	}
+	value, _ = fast.Update(Candles{Mid: Candle{Close: 4}})
+	if value != 3 {
+		t.Errorf("Expected the SMA to roll to 3, got %v", value)
+	}
+}
+
+func TestLoadStrategyConfigErrors(t *testing.T) {
+	defer logTestResult(t, "LoadStrategyConfigErrors")
+
+	tests := []struct {
+		name     string
+		config   string
+		contains string
+	}{
+		{"unknown key", testStrategyConfig + "leverage: 50\n", "leverage"},
+		{"bad granularity", strings.Replace(testStrategyConfig, "M15", "M7", 1), "M7"},
+		{"bad action", strings.Replace(testStrategyConfig, "then: buy", "then: hodl", 1), "hodl"},
+		{"bad risk", strings.Replace(testStrategyConfig, "0.01", "5", 1), "maxRiskPerTrade"},
+		{"duplicate indicator", strings.Replace(testStrategyConfig, "name: slow", "name: fast", 1), "declared twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadStrategyConfig(strings.NewReader(tt.config))
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected an error mentioning %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
+
+func TestStrategyConfigBuildErrors(t *testing.T) {
+	defer logTestResult(t, "StrategyConfigBuildErrors")
+
+	sc, err := LoadStrategyConfig(strings.NewReader(strings.Replace(testStrategyConfig, "type: sma", "type: rsi", 1)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := sc.Build(DefaultIndicators); err == nil || !strings.Contains(err.Error(), "rsi") {
+		t.Errorf("Expected an unknown type error, got %v", err)
+	}
+
+	sc.Indicators[0].Type = "sma"
+	sc.Indicators[0].Params["period"] = 2.5
+	if _, err := sc.Build(DefaultIndicators); err == nil {
+		t.Error("Expected an error for a fractional period")
+	}
+}
@@ -2,15 +2,177 @@ package goanda
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+)
+
+// errStopStream is returned by stream handlers to end a stream early without an error
+var errStopStream = errors.New("stop stream")
+
+// ErrStaleStream is returned when a stream receives nothing, not even a heartbeat, for the HeartbeatTimeout
+var ErrStaleStream = errors.New("goanda: stream stale, no heartbeat received")
+
+// DefaultMaxMessageSize is the longest stream message read when no MaxMessageSize is set.
+// The read buffer starts small and only grows to this size if a message needs it.
+const DefaultMaxMessageSize = 16 << 20
+
+// streamReadBuffer is the initial size of a stream's read buffer
+const streamReadBuffer = 64 << 10
+
+// Streams are read with their own http client, as the REST client's overall timeout would end them
+const (
+	streamDialTimeout     = 10 * time.Second
+	streamKeepAlive       = 30 * time.Second
+	streamTLSTimeout      = 10 * time.Second
+	streamResponseTimeout = 30 * time.Second
 )
 
 type StreamingConnection struct {
 	*Connection
 	streamURL string
+	// client reads the streams, it is the connection's client without an overall timeout
+	client *http.Client
+	// accountID, if set, is the account streamed in place of the connection's, see ForAccount
+	accountID string
+
+	// PanicPolicy decides whether a stream ends or continues when a callback panics,
+	// the default is PanicStop
+	PanicPolicy PanicPolicy
+	// OnPanic, if set, is called with every panic recovered from a callback, including its stack trace
+	OnPanic func(*PanicError)
+	// Quota, if set, limits the rate at which each stream delivers messages
+	Quota *StreamQuota
+	// Reconnect, if set, reconnects streams that drop instead of returning, see DefaultReconnectPolicy
+	Reconnect *ReconnectPolicy
+	// OnReconnect, if set, is called before each reconnection attempt
+	OnReconnect func(ReconnectEvent)
+	// DropCopy, if set, records every execution received on the transaction stream
+	DropCopy *DropCopy
+	// Recorder, if set, records every line received on the streams, see StreamRecorder
+	Recorder *StreamRecorder
+	// OnHeartbeat, if set, is called with each heartbeat received on a stream, OANDA sends one every five seconds
+	OnHeartbeat func(HeartbeatResponse)
+	// HeartbeatTimeout, if set, is how long a stream may go without receiving anything before it is
+	// considered dead. It is then torn down and reconnected, or ErrStaleStream returned if there is no
+	// Reconnect policy. Twice the heartbeat interval, ten seconds, or more is recommended.
+	HeartbeatTimeout time.Duration
+
+	// AccountChangesInterval is how often StreamAccountChanges polls, it defaults to DefaultAccountChangesInterval
+	AccountChangesInterval time.Duration
+
+	// MaxMessageSize is the longest message, in bytes, a stream will read, it defaults to DefaultMaxMessageSize.
+	// Transaction and account change snapshots can be far longer than a price.
+	MaxMessageSize int
+
+	// OnEvent, if set, is called with the stream's internal events, such as heartbeats, skipped messages
+	// and reconnects, for metrics. Skipped messages and reconnects are also logged, heartbeats are not.
+	OnEvent func(StreamEvent)
+
+	// OnError, if set, is called with every error a stream recovers from and keeps reading after, each a
+	// *StreamError: messages skipped because they could not be decoded or were longer than MaxMessageSize,
+	// the failures it reconnects after, and callback panics under PanicContinue, wrapping the *PanicError.
+	// Errors that end the stream are returned instead.
+	OnError func(error)
+
+	// StrictDecoding checks every message against the struct it is decoded into, ending the stream with a
+	// *SchemaError on an unknown or missing field instead of decoding it leniently. It is meant for CI
+	// against the practice API or recorded streams, to catch changes in what OANDA sends.
+	StrictDecoding bool
+
+	// Backpressure, if set, buffers messages between the stream and its callback so a slow callback does
+	// not stall the connection, see Backpressure. Without it the callback is run as each message is read.
+	Backpressure *Backpressure
+
+	// heartbeat is called with the local time each heartbeat is received, it is used by StreamManager
+	heartbeat func(time.Time)
+}
+
+// StreamEventKind identifies a StreamEvent
+type StreamEventKind string
+
+const (
+	// StreamEventHeartbeat is a heartbeat received from OANDA
+	StreamEventHeartbeat StreamEventKind = "HEARTBEAT"
+	// StreamEventSkipped is a message that could not be read or decoded, it is skipped and the stream continues
+	StreamEventSkipped StreamEventKind = "SKIPPED"
+	// StreamEventReconnect is a dropped stream about to be reconnected
+	StreamEventReconnect StreamEventKind = "RECONNECT"
+	// StreamEventDropped is a message discarded by the Backpressure policy because the callback fell behind
+	StreamEventDropped StreamEventKind = "DROPPED"
+	// StreamEventPanic is a panic recovered from a callback, see PanicPolicy
+	StreamEventPanic StreamEventKind = "PANIC"
+)
+
+// StreamEvent is something that happened inside a stream which is not delivered to its callback
+type StreamEvent struct {
+	Kind StreamEventKind
+	URL  string
+	Time time.Time
+	// Err is the decoding error of a skipped message, the cause of a reconnect, or the *PanicError of a panic
+	Err error
+	// Message is the raw line of a heartbeat, skipped or dropped message, or of the message a callback panicked on
+	Message []byte
+}
+
+// StreamError is an error a stream recovered from, see OnError
+type StreamError struct {
+	URL string
+	// Message is the raw line of a skipped message, if it was read
+	Message []byte
+	Err     error
+}
+
+func (e *StreamError) Error() string { return fmt.Sprintf("goanda: stream %s: %v", e.URL, e.Err) }
+func (e *StreamError) Unwrap() error { return e.Err }
+
+// malformedMessage is returned by stream handlers for messages that cannot be decoded
+type malformedMessage struct {
+	err error
+}
+
+func (m *malformedMessage) Error() string { return m.err.Error() }
+func (m *malformedMessage) Unwrap() error { return m.err }
+
+// decodeMessage decodes a stream message, marking decoding failures so the message is skipped
+func decodeMessage(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return &malformedMessage{err}
+	}
+	return nil
+}
+
+func (sc *StreamingConnection) event(kind StreamEventKind, url string, err error, message []byte) {
+	if sc.OnEvent != nil {
+		sc.notify("OnEvent", func() {
+			sc.OnEvent(StreamEvent{Kind: kind, URL: url, Time: time.Now(), Err: err, Message: message})
+		})
+	}
+}
+
+// recovered reports an error the stream carries on after to OnError
+func (sc *StreamingConnection) recovered(url string, err error, message []byte) {
+	if sc.OnError != nil {
+		sc.notify("OnError", func() {
+			sc.OnError(&StreamError{URL: url, Message: message, Err: err})
+		})
+	}
+}
+
+// skip records a message that could not be read or decoded, the stream continues with the next one
+func (sc *StreamingConnection) skip(url string, err error, message []byte) {
+	sc.metrics.streamSkipped.Add(1)
+	sc.logf("goanda: stream %s skipped a malformed message: %v", url, err)
+	sc.event(StreamEventSkipped, url, err, message)
+	sc.recovered(url, err, message)
 }
 
 func NewStreamingConnection(c *Connection) *StreamingConnection {
@@ -22,23 +184,126 @@ func NewStreamingConnection(c *Connection) *StreamingConnection {
 	return &StreamingConnection{
 		Connection: c,
 		streamURL:  streamURL,
+		client:     streamingClient(c.client),
+	}
+}
+
+// ForAccount returns a copy of the streaming connection, with the same settings, that streams another
+// account the connection's token has access to, such as a sub-account
+func (sc *StreamingConnection) ForAccount(accountID string) *StreamingConnection {
+	s := *sc
+	s.accountID = accountID
+	return &s
+}
+
+// account returns the ID of the account streamed
+func (sc *StreamingConnection) account() string {
+	if sc.accountID != "" {
+		return sc.accountID
+	}
+	return sc.Connection.accountID
+}
+
+// streamingClient derives the client used for streams from a connection's REST client. The overall
+// timeout is removed, as a stream lives for as long as it is read. With the default transport, streams
+// get their own with timeouts on dialling, the TLS handshake and the response headers instead, and TCP
+// keep-alives to detect connections that die silently. A configured transport is shared with REST calls.
+func streamingClient(rest http.Client) *http.Client {
+	client := rest
+	client.Timeout = 0
+
+	if client.Transport == nil {
+		dialer := &net.Dialer{Timeout: streamDialTimeout, KeepAlive: streamKeepAlive}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = streamTLSTimeout
+		transport.ResponseHeaderTimeout = streamResponseTimeout
+		client.Transport = transport
 	}
+	return &client
 }
 
 func (c *Connection) NewStreamingConnection() *StreamingConnection {
 	return NewStreamingConnection(c)
 }
 
-func (sc *StreamingConnection) StreamPrices(instruments []string, callback func(PricingStreamResponse)) error {
-	endpoint := fmt.Sprintf("/accounts/%s/pricing/stream", sc.accountID)
-	url := sc.streamURL + endpoint + "?instruments=" + strings.Join(instruments, "%2C")
+// StreamPrices streams prices for the instruments until ctx is cancelled, returning ctx.Err(),
+// or the stream fails
+func (sc *StreamingConnection) StreamPrices(ctx context.Context, instruments []string, callback func(PricingStreamResponse)) error {
+	return sc.StreamPricesWithOptions(ctx, instruments, PriceStreamOptions{}, callback)
+}
+
+// PriceStreamOptions sets the optional query parameters of the pricing stream, and filters
+// that skip prices before they reach the callback
+type PriceStreamOptions struct {
+	// DisableSnapshot stops OANDA sending the current price of each instrument when the stream opens,
+	// so the first message for an instrument is its next change
+	DisableSnapshot bool
+	// IncludeHomeConversions adds the home currency conversion factors to each price
+	IncludeHomeConversions bool
 
-	return sc.stream(url, func(data []byte) error {
+	// TradeableOnly skips prices of instruments that cannot currently be traded
+	TradeableOnly bool
+	// Only, if set, delivers the prices of these instruments alone, for a stream shared with other consumers
+	Only []string
+	// MinChangePips, if set, skips a price unless its best bid or ask has moved by at least this many
+	// pips since the last price delivered for the instrument
+	MinChangePips float64
+	// PipLocations are the instruments' pip locations for MinChangePips, see Instruments.PipLocations.
+	// Instruments without one are assumed to have a pip of 0.01 if quoted in JPY and 0.0001 otherwise.
+	PipLocations map[string]int
+
+	// MaxUpdatesPerSecond, if set, conflates each instrument's prices to at most this many a second.
+	// A price arriving sooner is held, replacing any held before it, and delivered once the instrument
+	// is due an update, so the latest price always arrives promptly. It is for consumers such as
+	// dashboards that need to be current but not to see every tick.
+	MaxUpdatesPerSecond int
+}
+
+func (o PriceStreamOptions) query() string {
+	query := ""
+	if o.DisableSnapshot {
+		query += "&snapshot=false"
+	}
+	if o.IncludeHomeConversions {
+		query += "&includeHomeConversions=true"
+	}
+	return query
+}
+
+// StreamPricesWithOptions streams prices for the instruments, see StreamPrices
+func (sc *StreamingConnection) StreamPricesWithOptions(ctx context.Context, instruments []string, opts PriceStreamOptions, callback func(PricingStreamResponse)) error {
+	return sc.streamPrices(ctx, instruments, opts, func(response PricingStreamResponse) error {
+		callback(response)
+		return nil
+	})
+}
+
+func (sc *StreamingConnection) streamPrices(ctx context.Context, instruments []string, opts PriceStreamOptions, handler func(PricingStreamResponse) error) error {
+	endpoint := fmt.Sprintf("/accounts/%s/pricing/stream", sc.account())
+	url := sc.streamURL + endpoint + "?instruments=" + strings.Join(instruments, "%2C") + opts.query()
+	filter := newPriceFilter(opts)
+
+	deliver := handler
+	var throttle *priceThrottle
+	if opts.MaxUpdatesPerSecond > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		// Held prices are delivered from a timer, so they get the panic handling the stream gives the rest
+		throttle = newPriceThrottle(opts.MaxUpdatesPerSecond, handler, func(price PricingStreamResponse) error {
+			return sc.deliver(url, func([]byte) error { return handler(price) }, nil)
+		}, cancel)
+		deliver = throttle.admit
+	}
+
+	err := sc.stream(ctx, url, func(data []byte) error {
 		var response PricingStreamResponse
-		err := json.Unmarshal(data, &response)
-		if err != nil {
+		if err := sc.decode(data, &response); err != nil {
 			return err
 		}
+		response.Latency = sc.observeLatency(response.Time, time.Now())
 		if response.Type == "" {
 			// This might be an error response
 			var errorResp struct {
@@ -48,116 +313,644 @@ func (sc *StreamingConnection) StreamPrices(instruments []string, callback func(
 				return fmt.Errorf("API error: %s", errorResp.ErrorMessage)
 			}
 		}
+		if filter != nil && !filter.allow(response) {
+			return nil
+		}
+		return deliver(response)
+	})
+	if throttle != nil {
+		if stopErr := throttle.stop(err == nil); err == nil {
+			err = stopErr
+		}
+		err = stopped(err)
+	}
+	return err
+}
+
+// StreamTransactions streams the account's transactions until ctx is cancelled, returning ctx.Err(),
+// or the stream fails. When the stream reconnects, the transactions missed while it was disconnected
+// are fetched and delivered in order before the live ones, so none are lost.
+func (sc *StreamingConnection) StreamTransactions(ctx context.Context, callback func(TransactionStreamResponse)) error {
+	return sc.StreamTransactionsSince(ctx, "", callback)
+}
+
+// StreamTransactionsSince is StreamTransactions, first delivering the transactions after lastTransactionID,
+// such as the last one processed before a restart. An empty lastTransactionID starts from live data.
+func (sc *StreamingConnection) StreamTransactionsSince(ctx context.Context, lastTransactionID string, callback func(TransactionStreamResponse)) error {
+	return sc.streamTransactionsSince(ctx, lastTransactionID, func(response TransactionStreamResponse) error {
 		callback(response)
 		return nil
 	})
 }
 
-func (sc *StreamingConnection) StreamTransactions(callback func(TransactionStreamResponse)) error {
-	endpoint := fmt.Sprintf("/accounts/%s/transactions/stream", sc.accountID)
+func (sc *StreamingConnection) streamTransactions(ctx context.Context, handler func(TransactionStreamResponse) error) error {
+	return sc.streamTransactionsSince(ctx, "", handler)
+}
+
+func (sc *StreamingConnection) streamTransactionsSince(ctx context.Context, last string, handler func(TransactionStreamResponse) error) error {
+	endpoint := fmt.Sprintf("/accounts/%s/transactions/stream", sc.account())
 	url := sc.streamURL + endpoint
 
-	return sc.stream(url, func(data []byte) error {
-		var response TransactionStreamResponse
-		err := json.Unmarshal(data, &response)
+	// Heartbeats carry the account's last transaction, so a stream dropping before any transaction
+	// arrives still backfills what it missed. Transactions may be delivered from another goroutine
+	// with a Backpressure policy. backfilled is the last transaction fetched by a backfill. Both are
+	// forgotten once the account is reset, see Connection.ResetState, its IDs starting again.
+	var mu sync.Mutex
+	var backfilled string
+	resets := sc.resets.Load()
+	rebase := func() {
+		if current := sc.resets.Load(); current != resets {
+			resets, last, backfilled = current, "", ""
+		}
+	}
+	heartbeat := func(heartbeat HeartbeatResponse) {
+		mu.Lock()
+		defer mu.Unlock()
+		rebase()
+		if last == "" {
+			last = heartbeat.LastTransactionID
+		}
+	}
+
+	// Backfill once connected, so nothing falls between the fetch and the live stream
+	backfill := func(deliver func([][]byte) error) error {
+		mu.Lock()
+		rebase()
+		since := last
+		mu.Unlock()
+		if since == "" {
+			return nil
+		}
+		missed, err := sc.transactionsSince(sc.account(), since)
 		if err != nil {
+			return &dropError{err}
+		}
+		if len(missed) > 0 {
+			sc.logf("goanda: stream %s backfilling %d transactions after %s", url, len(missed), since)
+			var newest TransactionStreamResponse
+			if err := json.Unmarshal(missed[len(missed)-1], &newest); err == nil {
+				mu.Lock()
+				backfilled = newest.id()
+				mu.Unlock()
+			}
+		}
+		return deliver(missed)
+	}
+
+	return sc.streamWith(ctx, url, func(data []byte) error {
+		var response TransactionStreamResponse
+		if err := sc.decode(data, &response); err != nil {
 			return err
 		}
+		received := time.Now()
+		// Live transactions already delivered by a backfill are skipped, and the backfilled ones are
+		// left out of the latency metrics, as they are late by however long the stream was down
+		live := true
+		if id := response.id(); id != "" {
+			mu.Lock()
+			rebase()
+			seen := last != "" && compareTransactionIDs(id, last) <= 0
+			if !seen {
+				last = id
+			}
+			live = backfilled == "" || compareTransactionIDs(id, backfilled) > 0
+			mu.Unlock()
+			if seen {
+				return nil
+			}
+		}
+		if live {
+			response.Latency = sc.observeLatency(response.Time, received)
+		} else {
+			response.Latency, _ = messageLatency(response.Time, received)
+		}
+		if sc.DropCopy != nil {
+			if err := sc.DropCopy.RecordStreamed(response); err != nil {
+				sc.logf("goanda: drop copy: %v", err)
+				sc.recovered(url, fmt.Errorf("goanda: drop copy: %w", err), data)
+			}
+		}
+		return handler(response)
+	}, streamHooks{connected: backfill, heartbeat: heartbeat})
+}
+
+// StreamCandles streams candles for the instrument until ctx is cancelled, returning ctx.Err(),
+// or the stream fails. OANDA has no candle stream, so the candles are built from the instrument's
+// price stream by a CandleAggregator. Each tick delivers the candle in progress, with Complete false,
+// and a candle is delivered again with Complete true once a tick or heartbeat passes the end of its bucket.
+func (sc *StreamingConnection) StreamCandles(ctx context.Context, instrument string, granularity string, callback func(CandlestickStreamResponse)) error {
+	return sc.streamCandles(ctx, instrument, granularity, func(response CandlestickStreamResponse) error {
 		callback(response)
 		return nil
 	})
 }
 
-func (sc *StreamingConnection) StreamAccountChanges(callback func(AccountChangesStreamResponse)) error {
-	endpoint := fmt.Sprintf("/accounts/%s/changes/stream", sc.accountID)
-	url := sc.streamURL + endpoint
+func (sc *StreamingConnection) streamCandles(ctx context.Context, instrument string, granularity string, handler func(CandlestickStreamResponse) error) error {
+	g, err := ParseGranularity(granularity)
+	if err != nil {
+		return err
+	}
+	aggregator, err := NewCandleAggregator(instrument, g)
+	if err != nil {
+		return err
+	}
+
+	// Heartbeats and prices may be delivered from different goroutines with a Backpressure policy
+	var mu sync.Mutex
+	var stop error
+	emit := func(candle CandlestickStreamResponse) {
+		if stop == nil {
+			stop = handler(candle)
+		}
+	}
+
+	prices := *sc
+	onHeartbeat := sc.OnHeartbeat
+	prices.OnHeartbeat = func(heartbeat HeartbeatResponse) {
+		if onHeartbeat != nil {
+			onHeartbeat(heartbeat)
+		}
+		if t, err := time.Parse(time.RFC3339Nano, heartbeat.Time); err == nil {
+			mu.Lock()
+			defer mu.Unlock()
+			if candle, ok := aggregator.Close(t); ok {
+				emit(candle)
+			}
+		}
+	}
 
-	return sc.stream(url, func(data []byte) error {
-		var response AccountChangesStreamResponse
-		err := json.Unmarshal(data, &response)
+	return prices.streamPrices(ctx, []string{instrument}, PriceStreamOptions{}, func(price PricingStreamResponse) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		candles, err := aggregator.Add(price)
 		if err != nil {
-			return err
+			return &malformedMessage{err}
 		}
-		callback(response)
-		return nil
+		for _, candle := range candles {
+			emit(candle)
+		}
+		return stop
 	})
 }
 
-func (sc *StreamingConnection) StreamCandles(instrument string, granularity string, callback func(CandlestickStreamResponse)) error {
-	endpoint := fmt.Sprintf("/accounts/%s/instruments/%s/candles/stream", sc.accountID, instrument)
-	url := sc.streamURL + endpoint + "?granularity=" + granularity
+// StreamRaw streams the lines of a streaming endpoint, undecoded, until ctx is cancelled, returning ctx.Err(),
+// or the stream fails. It is for decoding messages yourself. url is either a full URL or a path on
+// the streaming host, such as "/accounts/<id>/pricing/stream?instruments=EUR_USD". Heartbeats are
+// not passed to callback, they go to OnHeartbeat as on the other streams, and the Reconnect, Quota and
+// Backpressure policies apply. Each line is the callback's to keep. An error from callback ends the
+// stream and is returned.
+func (sc *StreamingConnection) StreamRaw(ctx context.Context, url string, callback func(line []byte) error) error {
+	if strings.HasPrefix(url, "/") {
+		url = sc.streamURL + url
+	}
+	return sc.stream(ctx, url, callback)
+}
 
-	return sc.stream(url, func(data []byte) error {
-		var response CandlestickStreamResponse
-		err := json.Unmarshal(data, &response)
-		if err != nil {
+// stream reads the newline delimited messages of a streaming endpoint, passing each one that
+// is not a heartbeat to handler. It runs until the stream ends, handler returns an error or ctx is done,
+// in which case ctx.Err() is returned, or ErrConnectionClosed if the connection was closed.
+// A handler returning errStopStream ends the stream without error.
+// Streams that drop are reconnected according to the Reconnect policy, if one is set.
+func (sc *StreamingConnection) stream(ctx context.Context, url string, handler func([]byte) error) error {
+	return sc.streamWith(ctx, url, handler, streamHooks{})
+}
+
+// streamHooks are called by streamWith as the stream runs, besides its handler
+type streamHooks struct {
+	// connected is called each time the stream connects and before it is read. It can deliver
+	// messages of its own, such as ones missed while disconnected, through deliver.
+	connected func(deliver func([][]byte) error) error
+	// heartbeat is called with each heartbeat, from the goroutine reading the stream
+	heartbeat func(HeartbeatResponse)
+}
+
+// streamWith is stream, calling hooks as the stream runs
+func (sc *StreamingConnection) streamWith(ctx context.Context, url string, handler func([]byte) error, hooks streamHooks) error {
+	ctx, done, err := sc.life.worker(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	stat := sc.metrics.openStream(url, time.Now())
+	defer sc.metrics.closeStream(stat)
+
+	next := handler
+	handler = func(data []byte) error {
+		err := next(data)
+		var malformed *malformedMessage
+		if errors.As(err, &malformed) {
+			stat.skipped.Add(1)
+			sc.skip(url, malformed.err, data)
+			return nil
+		}
+		return err
+	}
+
+	attempt := 0
+	for {
+		healthy := false
+		err := sc.streamOnce(ctx, url, handler, hooks, stat, &healthy)
+
+		if errors.Is(err, ErrCredentialsInvalid) && sc.Reconnect != nil {
+			// Wait for the credentials to be replaced rather than reconnecting into more 401s
+			if err := sc.awaitCredentials(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var drop *dropError
+		if !errors.As(err, &drop) {
 			return err
 		}
-		callback(response)
-		return nil
-	})
+		if sc.Reconnect == nil {
+			if drop.err == io.EOF {
+				return nil
+			}
+			return drop.err
+		}
+
+		if healthy {
+			attempt = 0
+		}
+		attempt++
+		if sc.Reconnect.MaxRetries > 0 && attempt > sc.Reconnect.MaxRetries {
+			return fmt.Errorf("goanda: stream reconnect attempts exhausted: %w", drop.err)
+		}
+
+		delay := sc.Reconnect.backoff(attempt)
+		sc.logf("goanda: stream %s dropped (%v), reconnecting in %v", url, drop.err, delay)
+		sc.metrics.streamReconnects.Add(1)
+		stat.reconnects.Add(1)
+		sc.event(StreamEventReconnect, url, drop.err, nil)
+		sc.recovered(url, drop.err, nil)
+		if sc.OnReconnect != nil {
+			sc.notify("OnReconnect", func() {
+				sc.OnReconnect(ReconnectEvent{URL: url, Attempt: attempt, Err: drop.err, Delay: delay})
+			})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return context.Cause(ctx)
+		}
+	}
 }
 
-func (sc *StreamingConnection) stream(url string, handler func([]byte) error) error {
-	req, err := http.NewRequest("GET", url, nil)
+// streamOnce connects to a streaming endpoint and reads it until it ends.
+// Failures that a reconnect could recover from are returned as a *dropError,
+// io.EOF in one meaning the server ended the stream. healthy is set once a message has been received.
+func (sc *StreamingConnection) streamOnce(ctx context.Context, url string, handler func([]byte) error, hooks streamHooks, stat *streamStat, healthy *bool) error {
+	attempt, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// The watchdog tears the stream down if nothing, not even a heartbeat, arrives in time
+	alive := func() {}
+	if sc.HeartbeatTimeout > 0 {
+		watchdog := time.AfterFunc(sc.HeartbeatTimeout, func() {
+			cancel(ErrStaleStream)
+		})
+		defer watchdog.Stop()
+		alive = func() {
+			watchdog.Reset(sc.HeartbeatTimeout)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(attempt, "GET", url, nil)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Authorization", sc.authHeader)
+	authorization, err := sc.authorization()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
 	req.Header.Set("Accept-Datetime-Format", "RFC3339")
 
-	resp, err := sc.client.Do(req)
+	client := sc.client
+	if client == nil {
+		// Built without NewStreamingConnection
+		rest := sc.Connection.client
+		rest.Timeout = 0
+		client = &rest
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if stale(attempt) {
+			return &dropError{ErrStaleStream}
+		}
+		return &dropError{err}
 	}
 	defer resp.Body.Close()
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
+	if resp.StatusCode >= 400 {
+		sc.metrics.errors.Add(1)
+		apiErr := newAPIError(req, resp)
+		if errors.Is(apiErr, ErrCredentialsInvalid) {
+			sc.rejectCredentials(authorization, apiErr)
+		}
+		if apiErr.RateLimited() || resp.StatusCode >= 500 {
+			return &dropError{apiErr}
+		}
+		return apiErr
+	}
+
+	sc.metrics.streamsOpened.Add(1)
+	sc.metrics.streamsActive.Add(1)
+	defer sc.metrics.streamsActive.Add(-1)
+
+	meter := newStreamMeter(sc.Quota, url, time.Now(), sc.logf)
+
+	deliverAll := sc.deliverAll
+	var queue *deliveryQueue
+	if sc.Backpressure != nil {
+		queue = newDeliveryQueue(*sc.Backpressure, func(message []byte) {
+			sc.metrics.streamDropped.Add(1)
+			sc.event(StreamEventDropped, url, nil, message)
+		})
+		go queue.run(func(message []byte) error {
+			return sc.deliver(url, handler, message)
+		}, func() {
+			cancel(errDeliveryFailed)
+		})
+		defer queue.abort()
+
+		deliverAll = func(_ string, _ func([]byte) error, messages [][]byte) error {
+			for _, message := range messages {
+				if err := queue.push(attempt, message); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	if hooks.connected != nil {
+		err := hooks.connected(func(messages [][]byte) error {
+			return deliverAll(url, handler, messages)
+		})
+		if err != nil {
+			return stopped(err)
+		}
+	}
+
+	maxMessageSize := sc.MaxMessageSize
+	if maxMessageSize <= 0 {
+		maxMessageSize = DefaultMaxMessageSize
+	}
+	reader := bufio.NewReaderSize(resp.Body, min(streamReadBuffer, maxMessageSize))
+	var readErr error
+	for {
+		data, err := readLine(reader, maxMessageSize)
+		if err != nil {
+			var malformed *malformedMessage
+			if !errors.As(err, &malformed) {
+				if err != io.EOF {
+					readErr = err
+				}
+				break
+			}
+			alive()
+			*healthy = true
+			stat.skipped.Add(1)
+			sc.skip(url, malformed.err, nil)
+			continue
+		}
+		alive()
+		line := string(data)
 		if line == "" {
 			continue
 		}
+		*healthy = true
+		if sc.Recorder != nil {
+			if err := sc.Recorder.Record(strings.TrimPrefix(url, sc.streamURL), data, time.Now()); err != nil {
+				sc.logf("goanda: stream recorder: %v", err)
+				sc.recovered(url, fmt.Errorf("goanda: stream recorder: %w", err), data)
+			}
+		}
 
 		// Handle heartbeats
 		if strings.HasPrefix(line, "{\"type\":\"HEARTBEAT\"") {
+			sc.metrics.streamHeartbeats.Add(1)
+			sc.metrics.lastHeartbeat.Store(time.Now().UnixNano())
+			stat.heartbeat(time.Now())
+			meter.observe(len(line), time.Now())
 			var heartbeat HeartbeatResponse
 			err := json.Unmarshal([]byte(line), &heartbeat)
 			if err == nil {
-				fmt.Printf("Received heartbeat at %s\n", heartbeat.Time)
+				if t, err := time.Parse(time.RFC3339Nano, heartbeat.Time); err == nil {
+					sc.observeServerTime(t, time.Now(), 0)
+				}
+				if hooks.heartbeat != nil {
+					hooks.heartbeat(heartbeat)
+				}
+				if sc.OnHeartbeat != nil {
+					err := sc.deliver(url, func([]byte) error {
+						sc.OnHeartbeat(heartbeat)
+						return nil
+					}, nil)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			sc.event(StreamEventHeartbeat, url, nil, []byte(line))
+			if sc.heartbeat != nil {
+				sc.heartbeat(time.Now())
+			}
+			if err := deliverAll(url, handler, meter.flush()); err != nil {
+				return stopped(err)
 			}
 			continue
 		}
 
-		err := handler([]byte(line))
-		if err != nil {
+		sc.metrics.streamMessages.Add(1)
+		stat.message(time.Now())
+		if err := deliverAll(url, handler, meter.admit([]byte(line), time.Now())); err != nil {
+			return stopped(err)
+		}
+	}
+
+	if queue != nil {
+		if err := queue.failure(); err != nil {
+			return stopped(err)
+		}
+	}
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	if stale(attempt) {
+		return &dropError{ErrStaleStream}
+	}
+	if readErr != nil {
+		return &dropError{readErr}
+	}
+	if err := deliverAll(url, handler, meter.drain()); err != nil {
+		return stopped(err)
+	}
+	if queue != nil {
+		if err := queue.finish(); err != nil {
+			return stopped(err)
+		}
+	}
+	return &dropError{io.EOF}
+}
+
+// readLine reads the next line of a stream without its line ending. A line longer than max is discarded
+// and returned as a *malformedMessage wrapping bufio.ErrTooLong, so the stream can carry on with the next.
+func readLine(r *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !tooLong && len(line)+len(chunk) > max {
+			tooLong = true
+			line = nil
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if tooLong {
+			if err != nil {
+				return nil, err
+			}
+			return nil, &malformedMessage{fmt.Errorf("goanda: stream message longer than MaxMessageSize of %d bytes: %w", max, bufio.ErrTooLong)}
+		}
+		// A last line without a line ending is still a message
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		return bytes.TrimSuffix(line, []byte("\r")), nil
+	}
+}
+
+// stale reports whether a stream attempt was torn down by its heartbeat watchdog
+func stale(attempt context.Context) bool {
+	return errors.Is(context.Cause(attempt), ErrStaleStream)
+}
+
+// stopped maps errStopStream, a request to end the stream early, to a clean exit
+func stopped(err error) error {
+	if errors.Is(err, errStopStream) {
+		return nil
+	}
+	return err
+}
+
+// deliverAll passes each message to handler in order, stopping at the first error
+func (sc *StreamingConnection) deliverAll(url string, handler func([]byte) error, messages [][]byte) error {
+	for _, message := range messages {
+		if err := sc.deliver(url, handler, message); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	return scanner.Err()
+// deliver passes a message to handler, applying the panic policy should it panic. A panic the stream
+// continues after is reported to OnError like any other error it recovers from.
+func (sc *StreamingConnection) deliver(url string, handler func([]byte) error, message []byte) error {
+	err := callSafely(func() error {
+		return handler(message)
+	})
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		sc.logf("goanda: stream callback panicked: %v\n%s", panicErr.Value, panicErr.Stack)
+		sc.event(StreamEventPanic, url, panicErr, message)
+		if sc.OnPanic != nil {
+			sc.notify("OnPanic", func() { sc.OnPanic(panicErr) })
+		}
+		if sc.PanicPolicy == PanicContinue {
+			sc.recovered(url, panicErr, message)
+			return nil
+		}
+	}
+	return err
+}
+
+// notify runs one of the connection's observer hooks, logging a panic in it rather than letting it
+// tear down the stream that called it
+func (sc *StreamingConnection) notify(hook string, fn func()) {
+	err := callSafely(func() error {
+		fn()
+		return nil
+	})
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		sc.logf("goanda: %s panicked: %v\n%s", hook, panicErr.Value, panicErr.Stack)
+	}
+}
+
+// observeLatency returns how long after its server time a message was received, recording it in the
+// connection's metrics. The clocks are not corrected for skew, so the latency includes the difference
+// between the server's clock and ours. A message without a valid time has a latency of zero and is not
+// recorded.
+func (sc *StreamingConnection) observeLatency(serverTime string, received time.Time) time.Duration {
+	latency, ok := messageLatency(serverTime, received)
+	if ok {
+		sc.metrics.observeLatency(latency)
+	}
+	return latency
+}
+
+// messageLatency returns how long after its server time a message was received, and false if the
+// time isn't valid
+func messageLatency(serverTime string, received time.Time) (time.Duration, bool) {
+	t, err := time.Parse(time.RFC3339Nano, serverTime)
+	if err != nil {
+		return 0, false
+	}
+	return received.Sub(t), true
+}
+
+// RawPriceBucket is a price and the liquidity available at it as OANDA sends them, with the price as
+// a string. PriceBucket is its parsed form, see PricingStreamResponse.Parse.
+type RawPriceBucket struct {
+	Price     string `json:"price"`
+	Liquidity int    `json:"liquidity"`
 }
 
 type PricingStreamResponse struct {
-	Type       string `json:"type"`
-	Time       string `json:"time"`
-	Instrument string `json:"instrument,omitempty"`
-	Bids       []struct {
-		Price     string `json:"price"`
-		Liquidity int    `json:"liquidity"`
-	} `json:"bids,omitempty"`
-	Asks []struct {
-		Price     string `json:"price"`
-		Liquidity int    `json:"liquidity"`
-	} `json:"asks,omitempty"`
-	CloseoutBid string `json:"closeoutBid,omitempty"`
-	CloseoutAsk string `json:"closeoutAsk,omitempty"`
-	Status      string `json:"status,omitempty"`
-	Tradeable   bool   `json:"tradeable,omitempty"`
+	Type            string           `json:"type"`
+	Time            string           `json:"time"`
+	Instrument      string           `json:"instrument,omitempty"`
+	Bids            []RawPriceBucket `json:"bids,omitempty"`
+	Asks            []RawPriceBucket `json:"asks,omitempty"`
+	CloseoutBid     string           `json:"closeoutBid,omitempty"`
+	CloseoutAsk     string           `json:"closeoutAsk,omitempty"`
+	Status          string           `json:"status,omitempty"`
+	Tradeable       bool             `json:"tradeable,omitempty"`
+	HomeConversions []HomeConversion `json:"homeConversions,omitempty"`
+	// Latency is how long after its server time the message was received. It includes the difference
+	// between the clocks, see Connection.ClockSkew, and any time spent buffered under a Backpressure policy.
+	Latency time.Duration `json:"-"`
+}
+
+// HomeConversion holds the factors converting amounts in a currency to the account's home currency
+type HomeConversion struct {
+	Currency      string `json:"currency"`
+	AccountGain   string `json:"accountGain"`
+	AccountLoss   string `json:"accountLoss"`
+	PositionValue string `json:"positionValue"`
 }
 
 type TransactionStreamResponse struct {
+	// ID is the transaction's ID, TransactionID is set instead by older messages
+	ID            string          `json:"id,omitempty"`
 	Type          string          `json:"type"`
 	Time          string          `json:"time"`
 	TransactionID string          `json:"transactionID,omitempty"`
@@ -165,31 +958,60 @@ type TransactionStreamResponse struct {
 	BatchID       string          `json:"batchID,omitempty"`
 	RequestID     string          `json:"requestID,omitempty"`
 	Transaction   json.RawMessage `json:"transaction,omitempty"`
+	// Latency is how long after its server time the message was received. For transactions backfilled
+	// after a reconnect it is how late they were delivered, and it is left out of the latency metrics.
+	Latency time.Duration `json:"-"`
 }
 
+// id returns the ID of the streamed transaction
+func (r TransactionStreamResponse) id() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	if r.TransactionID != "" || len(r.Transaction) == 0 {
+		return r.TransactionID
+	}
+	var t struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(r.Transaction, &t)
+	return t.ID
+}
+
+// AccountChangesStreamResponse is a poll of the account's changes, see StreamAccountChanges
 type AccountChangesStreamResponse struct {
-	Type              string          `json:"type"`
-	Time              string          `json:"time"`
+	// Type is always ACCOUNT_CHANGES, and Time the local time of the poll
+	Type string `json:"type"`
+	Time string `json:"time"`
+	// Changes and State are the changes and state of the poll's response, see Decode.
+	// LastTransactionID is the last transaction they include.
 	Changes           json.RawMessage `json:"changes"`
 	State             json.RawMessage `json:"state"`
 	LastTransactionID string          `json:"lastTransactionID"`
+	// Latency is always zero, polled changes have no server time
+	Latency time.Duration `json:"-"`
 }
 type CandlestickStreamResponse struct {
-	Type        string `json:"type"`
-	Time        string `json:"time"`
-	Instrument  string `json:"instrument"`
-	Granularity string `json:"granularity"`
-	Candles     []struct {
-		Time     string `json:"time"`
-		Bid      Candle `json:"bid,omitempty"`
-		Ask      Candle `json:"ask,omitempty"`
-		Mid      Candle `json:"mid,omitempty"`
-		Volume   int    `json:"volume"`
-		Complete bool   `json:"complete"`
-	} `json:"candles"`
+	Type        string         `json:"type"`
+	Time        string         `json:"time"`
+	Instrument  string         `json:"instrument"`
+	Granularity string         `json:"granularity"`
+	Candles     []StreamCandle `json:"candles"`
+}
+
+// StreamCandle is a candle of a CandlestickStreamResponse
+type StreamCandle struct {
+	Time     string `json:"time"`
+	Bid      Candle `json:"bid,omitempty"`
+	Ask      Candle `json:"ask,omitempty"`
+	Mid      Candle `json:"mid,omitempty"`
+	Volume   int    `json:"volume"`
+	Complete bool   `json:"complete"`
 }
 
 type HeartbeatResponse struct {
 	Type string `json:"type"`
 	Time string `json:"time"`
+	// LastTransactionID is the account's last transaction, sent only on the transaction stream
+	LastTransactionID string `json:"lastTransactionID,omitempty"`
 }
@@ -1,9 +1,16 @@
 package goanda
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -48,14 +55,8 @@ func TestStreamPrices(t *testing.T) {
 			Type:       "PRICE",
 			Time:       time.Now().Format(time.RFC3339),
 			Instrument: "EUR_USD",
-			Bids: []struct {
-				Price     string `json:"price"`
-				Liquidity int    `json:"liquidity"`
-			}{{Price: "1.1000", Liquidity: 1000000}},
-			Asks: []struct {
-				Price     string `json:"price"`
-				Liquidity int    `json:"liquidity"`
-			}{{Price: "1.1001", Liquidity: 1000000}},
+			Bids:       []RawPriceBucket{{Price: "1.1000", Liquidity: 1000000}},
+			Asks:       []RawPriceBucket{{Price: "1.1001", Liquidity: 1000000}},
 		}
 		err := json.NewEncoder(w).Encode(response)
 		if err != nil {
@@ -76,7 +77,7 @@ func TestStreamPrices(t *testing.T) {
 	sc.streamURL = server.URL
 
 	instruments := []string{"EUR_USD"}
-	err := sc.StreamPrices(instruments, func(response PricingStreamResponse) {
+	err := sc.StreamPrices(context.Background(), instruments, func(response PricingStreamResponse) {
 		if response.Type != "PRICE" {
 			t.Errorf("Expected response type to be PRICE, got %s", response.Type)
 		}
@@ -110,10 +111,10 @@ func TestStreamTransactions(t *testing.T) {
 		}
 
 		response := TransactionStreamResponse{
-			Type:          "TRANSACTION",
-			Time:          time.Now().Format(time.RFC3339),
-			TransactionID: "1234",
-			AccountID:     "test-account",
+			Type:      "TRANSACTION",
+			Time:      time.Now().Format(time.RFC3339),
+			ID:        "1234",
+			AccountID: "test-account",
 		}
 		err := json.NewEncoder(w).Encode(response)
 		if err != nil {
@@ -133,15 +134,15 @@ func TestStreamTransactions(t *testing.T) {
 	// Override the streamURL to use the test server
 	sc.streamURL = server.URL
 
-	err := sc.StreamTransactions(func(response TransactionStreamResponse) {
+	err := sc.StreamTransactions(context.Background(), func(response TransactionStreamResponse) {
 		if response.Type != "TRANSACTION" {
 			t.Errorf("Expected response type to be TRANSACTION, got %s", response.Type)
 		}
 		if response.AccountID != "test-account" {
 			t.Errorf("Expected account ID to be test-account, got %s", response.AccountID)
 		}
-		if response.TransactionID != "1234" {
-			t.Errorf("Expected transaction ID to be 1234, got %s", response.TransactionID)
+		if response.ID != "1234" {
+			t.Errorf("Expected transaction ID to be 1234, got %s", response.ID)
 		}
 	})
 
@@ -150,25 +151,29 @@ func TestStreamTransactions(t *testing.T) {
 	}
 }
 
-func TestStreamAccountChanges(t *testing.T) {
-	defer logTestResult(t, "TestStreamAccountChanges")
+func TestStreamCandles(t *testing.T) {
+	defer logTestResult(t, "TestStreamCandles")
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/accounts/test-account/changes/stream" {
+		// Candles are built from the price stream
+		if r.URL.Path != "/accounts/test-account/pricing/stream" {
 			t.Errorf("Unexpected path: %s", r.URL.Path)
 			http.Error(w, "Invalid path", http.StatusBadRequest)
 			return
 		}
-
-		response := AccountChangesStreamResponse{
-			Type:              "ACCOUNT_CHANGES",
-			Time:              time.Now().Format(time.RFC3339),
-			LastTransactionID: "5678",
-			Changes:           json.RawMessage(`{"orders":[],"trades":[]}`),
+		if r.URL.Query().Get("instruments") != "EUR_USD" {
+			t.Errorf("Unexpected instruments: %s", r.URL.Query().Get("instruments"))
 		}
-		err := json.NewEncoder(w).Encode(response)
-		if err != nil {
-			t.Errorf("Failed to encode response: %v", err)
+
+		for _, tick := range []struct{ time, bid, ask string }{
+			{"2024-01-02T15:04:10Z", "1.1000", "1.1002"},
+			{"2024-01-02T15:04:40Z", "1.1010", "1.1012"},
+			{"2024-01-02T15:04:50Z", "1.0990", "1.0992"},
+			{"2024-01-02T15:05:05Z", "1.1004", "1.1006"},
+		} {
+			fmt.Fprintf(w, `{"type":"PRICE","time":"%s","instrument":"EUR_USD","bids":[{"price":"%s","liquidity":1}],"asks":[{"price":"%s","liquidity":1}]}`+"\n",
+				tick.time, tick.bid, tick.ask)
 		}
+		w.Write([]byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:06:00Z"}` + "\n"))
 	}))
 	defer server.Close()
 
@@ -183,60 +188,51 @@ func TestStreamAccountChanges(t *testing.T) {
 	// Override the streamURL to use the test server
 	sc.streamURL = server.URL
 
-	err := sc.StreamAccountChanges(func(response AccountChangesStreamResponse) {
-		if response.Type != "ACCOUNT_CHANGES" {
-			t.Errorf("Expected response type to be ACCOUNT_CHANGES, got %s", response.Type)
+	var complete []CandlestickStreamResponse
+	updates := 0
+	err := sc.StreamCandles(context.Background(), "EUR_USD", "M1", func(response CandlestickStreamResponse) {
+		if response.Type != "CANDLESTICK" || response.Instrument != "EUR_USD" || response.Granularity != "M1" {
+			t.Errorf("Unexpected response: %+v", response)
 		}
-		if response.LastTransactionID != "5678" {
-			t.Errorf("Expected last transaction ID to be 5678, got %s", response.LastTransactionID)
+		if response.Candles[0].Complete {
+			complete = append(complete, response)
+		} else {
+			updates++
 		}
 	})
-
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
-}
 
-func TestStreamCandles(t *testing.T) {
-	defer logTestResult(t, "TestStreamCandles")
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/accounts/test-account/instruments/EUR_USD/candles/stream" {
-			t.Errorf("Unexpected path: %s", r.URL.Path)
-			http.Error(w, "Invalid path", http.StatusBadRequest)
-			return
-		}
+	if updates != 4 || len(complete) != 2 {
+		t.Fatalf("Expected 4 updates and 2 complete candles, got %d and %d", updates, len(complete))
+	}
+	candle := complete[0].Candles[0]
+	if candle.Time != "2024-01-02T15:04:00Z" || candle.Volume != 3 {
+		t.Errorf("Unexpected candle: %+v", candle)
+	}
+	if candle.Bid != (Candle{Open: 1.1, High: 1.101, Low: 1.099, Close: 1.099}) {
+		t.Errorf("Unexpected bid candle: %+v", candle.Bid)
+	}
+	if candle.Mid.Open != 1.1001 || candle.Mid.Close != 1.0991 {
+		t.Errorf("Unexpected mid candle: %+v", candle.Mid)
+	}
+	// The last candle is completed by the heartbeat
+	if last := complete[1].Candles[0]; last.Time != "2024-01-02T15:05:00Z" || last.Volume != 1 {
+		t.Errorf("Unexpected candle: %+v", last)
+	}
 
-		if r.URL.Query().Get("granularity") != "M1" {
-			t.Errorf("Unexpected granularity: %s", r.URL.Query().Get("granularity"))
-			http.Error(w, "Invalid granularity", http.StatusBadRequest)
-			return
-		}
+	if err := sc.StreamCandles(context.Background(), "EUR_USD", "W", func(CandlestickStreamResponse) {}); err == nil {
+		t.Error("Expected an error for weekly candles")
+	}
+}
 
-		response := CandlestickStreamResponse{
-			Type:        "CANDLESTICK",
-			Time:        time.Now().Format(time.RFC3339),
-			Instrument:  "EUR_USD",
-			Granularity: "M1",
-			Candles: []struct {
-				Time     string `json:"time"`
-				Bid      Candle `json:"bid,omitempty"`
-				Ask      Candle `json:"ask,omitempty"`
-				Mid      Candle `json:"mid,omitempty"`
-				Volume   int    `json:"volume"`
-				Complete bool   `json:"complete"`
-			}{
-				{
-					Time: time.Now().Format(time.RFC3339),
-					Mid: Candle{
-						Open: 1.1000,
-						High: 1.1001,
-						Low:  1.0999,
-						Close: 1.1000,
-					},
-					Volume:   100,
-					Complete: true,
-				},
-			},
+func TestStreamHeartbeat(t *testing.T) {
+	defer logTestResult(t, "TestStreamHeartbeat")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := HeartbeatResponse{
+			Type: "HEARTBEAT",
+			Time: time.Now().Format(time.RFC3339),
 		}
 		err := json.NewEncoder(w).Encode(response)
 		if err != nil {
@@ -256,64 +252,374 @@ func TestStreamCandles(t *testing.T) {
 	// Override the streamURL to use the test server
 	sc.streamURL = server.URL
 
-	err := sc.StreamCandles("EUR_USD", "M1", func(response CandlestickStreamResponse) {
-		if response.Type != "CANDLESTICK" {
-			t.Errorf("Expected response type to be CANDLESTICK, got %s", response.Type)
-		}
-		if response.Instrument != "EUR_USD" {
-			t.Errorf("Expected instrument to be EUR_USD, got %s", response.Instrument)
-		}
-		if response.Granularity != "M1" {
-			t.Errorf("Expected granularity to be M1, got %s", response.Granularity)
-		}
-		if len(response.Candles) == 0 {
-			t.Errorf("Expected at least one candle, got none")
-		} else {
-			candle := response.Candles[0]
-			if candle.Mid.Open != 1.1000 || candle.Mid.High != 1.1001 || candle.Mid.Low != 1.0999 || candle.Mid.Close != 1.1000 {
-				t.Errorf("Unexpected candle data: %+v", candle.Mid)
-			}
-		}
+	var heartbeats []HeartbeatResponse
+	sc.OnHeartbeat = func(heartbeat HeartbeatResponse) {
+		heartbeats = append(heartbeats, heartbeat)
+	}
+
+	// Heartbeats are not passed to the price callback
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(response PricingStreamResponse) {
+		t.Errorf("Unexpected pricing response: %+v", response)
 	})
 
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
+	if len(heartbeats) != 1 || heartbeats[0].Type != "HEARTBEAT" {
+		t.Errorf("Expected the heartbeat to be passed to OnHeartbeat, got %+v", heartbeats)
+	}
 }
 
-func TestStreamHeartbeat(t *testing.T) {
-	defer logTestResult(t, "TestStreamHeartbeat")
+func TestStreamSkipsMalformedMessages(t *testing.T) {
+	defer logTestResult(t, "TestStreamSkipsMalformedMessages")
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		response := HeartbeatResponse{
-			Type: "HEARTBEAT",
-			Time: time.Now().Format(time.RFC3339),
-		}
-		err := json.NewEncoder(w).Encode(response)
-		if err != nil {
-			t.Errorf("Failed to encode response: %v", err)
-		}
+		w.Write([]byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05.000000000Z"}` + "\n"))
+		w.Write([]byte(`{"type":"PRICE","instrument":"EUR_USD","bids":"not a list"}` + "\n"))
+		w.Write([]byte(`{"type":"PRICE","instrument":"GBP_USD"}` + "\n"))
 	}))
 	defer server.Close()
 
+	logs := &bytes.Buffer{}
 	conn := &Connection{
 		hostname:   server.URL,
 		accountID:  "test-account",
 		authHeader: "Bearer test-token",
 		client:     *server.Client(),
+		logger:     log.New(logs, "", 0),
 	}
 	sc := NewStreamingConnection(conn)
+	sc.streamURL = server.URL
 
-	// Override the streamURL to use the test server
+	var events []StreamEventKind
+	sc.OnEvent = func(event StreamEvent) {
+		events = append(events, event.Kind)
+	}
+	var streamErrs []error
+	sc.OnError = func(err error) {
+		streamErrs = append(streamErrs, err)
+	}
+
+	var prices []string
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD", "GBP_USD"}, func(response PricingStreamResponse) {
+		prices = append(prices, response.Instrument)
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(prices) != 1 || prices[0] != "GBP_USD" {
+		t.Errorf("Expected the stream to continue past the malformed message, got %v", prices)
+	}
+	if fmt.Sprint(events) != "[HEARTBEAT SKIPPED]" {
+		t.Errorf("Unexpected events: %v", events)
+	}
+	var streamErr *StreamError
+	if len(streamErrs) != 1 || !errors.As(streamErrs[0], &streamErr) || !strings.Contains(string(streamErr.Message), "not a list") {
+		t.Errorf("Expected the skipped message on OnError, got %v", streamErrs)
+	}
+	if !strings.Contains(logs.String(), "skipped a malformed message") {
+		t.Errorf("Expected the skipped message to be logged, got %q", logs.String())
+	}
+}
+
+func TestStreamLongMessages(t *testing.T) {
+	defer logTestResult(t, "TestStreamLongMessages")
+
+	// A transaction far beyond bufio.Scanner's default 64KB token limit
+	comment := strings.Repeat("x", 1<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":"1","type":"ORDER_FILL","comment":"%s"}`+"\n", comment)
+		w.Write([]byte(`{"type":"ORDER_FILL","id":"2"}` + "\n"))
+	}))
+	defer server.Close()
+
+	conn := &Connection{
+		hostname:   server.URL,
+		accountID:  "test-account",
+		authHeader: "Bearer test-token",
+		client:     *server.Client(),
+	}
+	sc := NewStreamingConnection(conn)
 	sc.streamURL = server.URL
 
-	// This test is a bit tricky because heartbeats are handled internally.
-	// We'll use the StreamPrices function, but send a heartbeat instead.
-	err := sc.StreamPrices([]string{"EUR_USD"}, func(response PricingStreamResponse) {
-		t.Errorf("Unexpected pricing response: %+v", response)
+	var ids []string
+	err := sc.StreamTransactions(context.Background(), func(response TransactionStreamResponse) {
+		ids = append(ids, response.ID)
+		if response.ID == "1" && len(response.Transaction) < len(comment) {
+			t.Errorf("Expected the whole transaction, got %d bytes", len(response.Transaction))
+		}
 	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if fmt.Sprint(ids) != "[1 2]" {
+		t.Errorf("Expected both transactions, got %v", ids)
+	}
 
+	// Messages beyond the limit are skipped and reported, the stream carries on
+	sc.MaxMessageSize = 64 << 10
+	var streamErrs []error
+	sc.OnError = func(err error) { streamErrs = append(streamErrs, err) }
+	ids = nil
+	err = sc.StreamTransactions(context.Background(), func(response TransactionStreamResponse) {
+		ids = append(ids, response.ID)
+	})
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
-	// If we reach this point without errors, it means the heartbeat was properly handled
+	if fmt.Sprint(ids) != "[2]" {
+		t.Errorf("Expected the long transaction to be skipped, got %v", ids)
+	}
+	var streamErr *StreamError
+	if len(streamErrs) != 1 || !errors.As(streamErrs[0], &streamErr) || !errors.Is(streamErr, bufio.ErrTooLong) {
+		t.Errorf("Expected a StreamError wrapping bufio.ErrTooLong, got %v", streamErrs)
+	}
+}
+
+func TestStreamRaw(t *testing.T) {
+	defer logTestResult(t, "StreamRaw")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/pricing/stream" || r.URL.Query().Get("instruments") != "EUR_USD" {
+			t.Errorf("Unexpected request: %s", r.URL)
+		}
+		w.Write([]byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05Z"}` + "\n"))
+		w.Write([]byte(`{"type":"PRICE","instrument":"EUR_USD"}` + "\n"))
+		w.Write([]byte("not json\n"))
+		w.Write([]byte(`{"type":"PRICE","instrument":"EUR_USD","bids":"stop"}` + "\n"))
+		w.Write([]byte(`{"type":"PRICE","instrument":"EUR_USD"}` + "\n"))
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	})
+	sc.streamURL = server.URL
+
+	stop := errors.New("stop")
+	var lines []string
+	err := sc.StreamRaw(context.Background(), "/accounts/test-account/pricing/stream?instruments=EUR_USD", func(line []byte) error {
+		lines = append(lines, string(line))
+		if bytes.Contains(line, []byte("stop")) {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected the callback's error, got %v", err)
+	}
+	if len(lines) != 3 || lines[1] != "not json" {
+		t.Errorf("Expected every line but the heartbeat, got %q", lines)
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	defer logTestResult(t, "ReadLine")
+
+	r := bufio.NewReaderSize(strings.NewReader("a\r\n\n"+strings.Repeat("x", 40)+"\nlast"), 16)
+	var lines []string
+	for {
+		line, err := readLine(r, 32)
+		var malformed *malformedMessage
+		if errors.As(err, &malformed) && errors.Is(err, bufio.ErrTooLong) {
+			lines = append(lines, "<too long>")
+			continue
+		}
+		if err != nil {
+			break
+		}
+		lines = append(lines, string(line))
+	}
+	if fmt.Sprintf("%q", lines) != `["a" "" "<too long>" "last"]` {
+		t.Errorf("Unexpected lines: %q", lines)
+	}
+}
+
+func TestStreamCancellation(t *testing.T) {
+	defer logTestResult(t, "TestStreamCancellation")
+	closed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"PRICE","instrument":"EUR_USD"}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(closed)
+	}))
+	defer server.Close()
+
+	conn := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+	sc := NewStreamingConnection(conn)
+	sc.streamURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := sc.StreamPrices(ctx, []string{"EUR_USD"}, func(response PricingStreamResponse) {
+		cancel()
+	})
+
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("Expected the response body to be closed when the context was cancelled")
+	}
+}
+
+func TestStreamPricesWithOptions(t *testing.T) {
+	defer logTestResult(t, "TestStreamPricesWithOptions")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("snapshot") != "false" || query.Get("includeHomeConversions") != "true" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"type":"PRICE","instrument":"EUR_JPY","homeConversions":[{"currency":"JPY","accountGain":"0.0067","accountLoss":"0.0068","positionValue":"0.00675"}]}` + "\n"))
+	}))
+	defer server.Close()
+
+	conn := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+	sc := NewStreamingConnection(conn)
+	sc.streamURL = server.URL
+
+	var received []PricingStreamResponse
+	opts := PriceStreamOptions{DisableSnapshot: true, IncludeHomeConversions: true}
+	err := sc.StreamPricesWithOptions(context.Background(), []string{"EUR_JPY"}, opts, func(response PricingStreamResponse) {
+		received = append(received, response)
+	})
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(received) != 1 || len(received[0].HomeConversions) != 1 || received[0].HomeConversions[0].AccountGain != "0.0067" {
+		t.Errorf("Expected home conversions to be decoded, got %+v", received)
+	}
+}
+
+func TestStreamTransactionsBackfill(t *testing.T) {
+	defer logTestResult(t, "TestStreamTransactionsBackfill")
+	transaction := func(id string) string {
+		return `{"id":"` + id + `","accountID":"test-account","userID":1,"batchID":"` + id + `",` +
+			`"time":"2024-01-02T15:04:05Z","type":"ORDER_FILL","orderID":"4","instrument":"EUR_USD","units":"100","price":"1.10000"}` + "\n"
+	}
+	heartbeat := `{"type":"HEARTBEAT","lastTransactionID":"6","time":"2024-01-02T15:04:05Z"}` + "\n"
+
+	connections := 0
+	first := transaction("5") + transaction("6")
+	var backfills []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/transactions/sinceid":
+			backfills = append(backfills, r.URL.Query().Get("id"))
+			w.Write([]byte(`{"lastTransactionID":"8","transactions":[` +
+				`{"id":"7","type":"ORDER_FILL","time":"2020-01-02T15:04:05Z","accountID":"test-account"},` +
+				`{"id":"8","type":"DAILY_FINANCING","time":"2020-01-02T15:04:06Z","accountID":"test-account"}]}`))
+		case "/accounts/test-account/transactions/stream":
+			connections++
+			if connections == 1 {
+				w.Write([]byte(first))
+				return
+			}
+			w.Write([]byte(heartbeat + transaction("8") + transaction("9")))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	conn := &Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+	}
+	sc := NewStreamingConnection(conn)
+	sc.streamURL = server.URL
+	sc.Reconnect = &ReconnectPolicy{InitialBackoff: time.Millisecond}
+
+	stream := func(since string) []string {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var ids []string
+		err := sc.StreamTransactionsSince(ctx, since, func(response TransactionStreamResponse) {
+			ids = append(ids, response.ID)
+			if response.ID == "8" && len(response.Transaction) == 0 {
+				t.Error("Expected a backfilled transaction to carry its body")
+			}
+			// the backfill's transactions are years older than the live ones
+			if response.ID == "8" && response.Latency < 365*24*time.Hour {
+				t.Errorf("Expected a backfilled transaction's latency, got %v", response.Latency)
+			}
+			if response.ID == "9" {
+				cancel()
+			}
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		return ids
+	}
+
+	if ids := stream(""); fmt.Sprint(ids) != "[5 6 7 8 9]" {
+		t.Errorf("Expected every transaction once and in order, got %v", ids)
+	}
+	if fmt.Sprint(backfills) != "[6]" {
+		t.Errorf("Expected one backfill after 6, got %v", backfills)
+	}
+
+	// A stream dropping before any transaction backfills from the last one its heartbeats reported
+	connections, backfills, first = 0, nil, heartbeat
+	if ids := stream(""); fmt.Sprint(ids) != "[7 8 9]" || fmt.Sprint(backfills) != "[6]" {
+		t.Errorf("Expected a backfill after the heartbeat's 6, got %v from %v", ids, backfills)
+	}
+
+	// Resuming from a known ID backfills before the first connection
+	connections, backfills, first = 1, nil, ""
+	if ids := stream("6"); fmt.Sprint(ids) != "[7 8 9]" || fmt.Sprint(backfills) != "[6]" {
+		t.Errorf("Expected [7 8 9], got %v", ids)
+	}
+	if latest := time.Since(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)); sc.Metrics().StreamLatencyMax > latest+time.Minute {
+		t.Errorf("Expected the backfilled transactions left out of the latency metrics, got %v", sc.Metrics().StreamLatencyMax)
+	}
+}
+
+func TestStreamOutlivesRESTTimeout(t *testing.T) {
+	defer logTestResult(t, "TestStreamOutlivesRESTTimeout")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"PRICE","instrument":"EUR_USD"}` + "\n"))
+		w.(http.Flusher).Flush()
+		time.Sleep(150 * time.Millisecond)
+		w.Write([]byte(`{"type":"PRICE","instrument":"EUR_USD"}` + "\n"))
+	}))
+	defer server.Close()
+
+	client := *server.Client()
+	client.Timeout = 50 * time.Millisecond
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    client,
+	})
+	sc.streamURL = server.URL
+
+	prices := 0
+	err := sc.StreamPrices(context.Background(), []string{"EUR_USD"}, func(PricingStreamResponse) { prices++ })
+	if err != nil || prices != 2 {
+		t.Errorf("Expected the stream to outlive the REST timeout, got %d prices (%v)", prices, err)
+	}
+	if sc.Connection.client.Timeout != 50*time.Millisecond {
+		t.Error("Expected the REST client to keep its timeout")
+	}
+
+	// Without a configured transport, streams get their own
+	stream := streamingClient(http.Client{Timeout: httpTimeout})
+	transport, ok := stream.Transport.(*http.Transport)
+	if stream.Timeout != 0 || !ok || transport.ResponseHeaderTimeout != streamResponseTimeout || transport.TLSHandshakeTimeout != streamTLSTimeout {
+		t.Errorf("Unexpected streaming client: %+v", stream)
+	}
 }
@@ -0,0 +1,108 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Defaults of SubmitOrdersOptions
+const (
+	DefaultSubmitConcurrency = 4
+	DefaultSubmitsPerSecond  = 20
+)
+
+// OrderRequest is an order SubmitOrders can submit, an OrderPayload or an *OrderBuilder
+type OrderRequest interface {
+	Build() (OrderPayload, error)
+}
+
+// Build returns the payload, so that an OrderPayload is an OrderRequest
+func (p OrderPayload) Build() (OrderPayload, error) {
+	return p, nil
+}
+
+// SubmitOrdersOptions tunes SubmitOrders
+type SubmitOrdersOptions struct {
+	// Concurrency is how many orders may be in flight at once, it defaults to DefaultSubmitConcurrency
+	Concurrency int
+	// PerSecond is the most orders submitted each second, it defaults to DefaultSubmitsPerSecond
+	PerSecond int
+}
+
+// OrderSubmitResult is the outcome of one of the orders given to SubmitOrders
+type OrderSubmitResult struct {
+	// Created is OANDA's response to the order, set if it was created
+	Created OrderCreateResponse
+	// Rejected reports whether OANDA refused the order, for RejectReason: the order's RejectReason when
+	// Err is an OrderRejectedError, and otherwise OANDA's message
+	Rejected     bool
+	RejectReason string
+	// Err is why the order wasn't created: its rejection, the order failing to build, in which case it
+	// wasn't sent, or a failed request. After a failed request OANDA may or may not have the order,
+	// GetOrderByClientID finds it if it was given a client ID.
+	Err error
+}
+
+// Sent reports whether the order reached OANDA, created or rejected
+func (r OrderSubmitResult) Sent() bool {
+	return r.Err == nil || r.Rejected
+}
+
+// SubmitOrders creates the orders concurrently within the rate in opts, returning a result for each
+// in the same order whether or not it was created, so that one failure doesn't lose the rest of the
+// batch. The error joins every failure. Orders are not submitted once ctx is done.
+func (c *Connection) SubmitOrders(ctx context.Context, orders []OrderRequest, opts SubmitOrdersOptions) ([]OrderSubmitResult, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSubmitConcurrency
+	}
+	perSecond := opts.PerSecond
+	if perSecond <= 0 {
+		perSecond = DefaultSubmitsPerSecond
+	}
+
+	results := make([]OrderSubmitResult, len(orders))
+	payloads := make([]OrderPayload, len(orders))
+	var valid []int
+	for i, order := range orders {
+		var err error
+		if payloads[i], err = order.Build(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	started := paced(ctx, len(valid), concurrency, perSecond, func(i int) {
+		results[valid[i]] = c.submitOrderRequest(payloads[valid[i]])
+	})
+	for _, i := range valid[started:] {
+		results[i].Err = ctx.Err()
+	}
+
+	var errs []error
+	for i, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("goanda: submitting order %d: %w", i, result.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (c *Connection) submitOrderRequest(payload OrderPayload) OrderSubmitResult {
+	var result OrderSubmitResult
+	result.Err = c.createOrder(payload, &result.Created)
+
+	var rejected OrderRejectedError
+	var apiErr APIError
+	switch {
+	case errors.As(result.Err, &rejected):
+		result.Rejected = true
+		result.RejectReason = string(rejected.RejectReason)
+	case errors.As(result.Err, &apiErr) && !apiErr.RateLimited() && apiErr.Response.StatusCode < 500:
+		result.Rejected = true
+		result.RejectReason = apiErr.Message
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package goanda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmitOrders(t *testing.T) {
+	defer logTestResult(t, "SubmitOrders")
+
+	var inFlight, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		var payload OrderPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		switch payload.Order.Instrument {
+		case "GBP_USD":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"orderRejectTransaction":{"type":"MARKET_ORDER_REJECT","rejectReason":"INSUFFICIENT_MARGIN"},
+				"errorCode":"INSUFFICIENT_MARGIN","errorMessage":"Insufficient margin"}`))
+		case "USD_JPY":
+			// the connection drops before OANDA answers
+			panic(http.ErrAbortHandler)
+		default:
+			fmt.Fprintf(w, `{"orderCreateTransaction":{"id":"7","type":"MARKET_ORDER","instrument":%q},
+				"orderFillTransaction":{"id":"8","type":"ORDER_FILL","orderID":"7"},"lastTransactionID":"8"}`, payload.Order.Instrument)
+		}
+	}))
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	orders := []OrderRequest{
+		OrderPayload{Order: OrderBody{Type: "MARKET", Instrument: "EUR_USD", Units: 1000}},
+		OrderPayload{Order: OrderBody{Type: "MARKET", Instrument: "GBP_USD", Units: 1000}},
+		NewMarketOrder("AUD_USD").Units(1000),
+		NewLimitOrder("EUR_USD").Units(1000),
+		OrderPayload{Order: OrderBody{Type: "MARKET", Instrument: "USD_JPY", Units: 1000}},
+	}
+	results, err := c.SubmitOrders(context.Background(), orders, SubmitOrdersOptions{Concurrency: 2, PerSecond: 1000})
+	if err == nil {
+		t.Fatal("Expected the failures to be reported")
+	}
+	if len(results) != len(orders) {
+		t.Fatalf("Expected a result per order, got %d", len(results))
+	}
+
+	for _, i := range []int{0, 2} {
+		if results[i].Err != nil || results[i].Created.OrderFillTransaction == nil || !results[i].Sent() {
+			t.Errorf("Expected order %d to be created, got %+v", i, results[i])
+		}
+	}
+	if create, ok := results[2].Created.OrderCreateTransaction.(*MarketOrderTransaction); !ok || create.Instrument != "AUD_USD" {
+		t.Errorf("Expected the built order to be created, got %#v", results[2].Created.OrderCreateTransaction)
+	}
+	if !results[1].Rejected || results[1].RejectReason != string(RejectInsufficientMargin) || !results[1].Sent() {
+		t.Errorf("Expected order 1 to be rejected, got %+v", results[1])
+	}
+	if results[3].Err == nil || results[3].Sent() {
+		t.Errorf("Expected the limit order without a price to fail to build, got %+v", results[3])
+	}
+	if results[4].Err == nil || results[4].Rejected || results[4].Sent() {
+		t.Errorf("Expected order 4's request to fail, got %+v", results[4])
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 orders in flight, got %d", peak)
+	}
+}
+
+func TestSubmitOrdersStopsWithContext(t *testing.T) {
+	defer logTestResult(t, "SubmitOrdersStopsWithContext")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var submitted int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&submitted, 1)
+		cancel()
+		w.Write([]byte(`{"orderCreateTransaction":{"id":"7","type":"MARKET_ORDER"},"lastTransactionID":"7"}`))
+	}))
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	order := OrderPayload{Order: OrderBody{Type: "MARKET", Instrument: "EUR_USD", Units: 1000}}
+	results, err := c.SubmitOrders(ctx, []OrderRequest{order, order, order}, SubmitOrdersOptions{Concurrency: 1, PerSecond: 10})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the cancellation to be reported, got %v", err)
+	}
+	if submitted != 1 || results[0].Err != nil {
+		t.Errorf("Expected only the first order to be submitted, got %d", submitted)
+	}
+	if !errors.Is(results[2].Err, context.Canceled) || results[2].Sent() {
+		t.Errorf("Expected the last order not to be submitted, got %+v", results[2])
+	}
+}
@@ -0,0 +1,217 @@
+package goanda
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PriceSubscription is a single price stream whose instruments can be changed while it runs.
+// A change opens a stream for the new set of instruments while the current one keeps delivering,
+// and switches over once the new stream produces its first message, so subscribers see no gap.
+// Prices repeated by the new stream's snapshot are suppressed, so each instrument's prices are
+// delivered in time order without duplicates.
+type PriceSubscription struct {
+	// Options are used for every stream the subscription opens
+	Options PriceStreamOptions
+
+	sc *StreamingConnection
+
+	mu          sync.Mutex
+	instruments []string
+	running     bool
+	ctx         context.Context
+	cancel      context.CancelCauseFunc
+	callback    func(PricingStreamResponse)
+	active      *subscriptionStream
+	pending     *subscriptionStream
+	last        map[string]time.Time
+	deliverMu   sync.Mutex
+}
+
+type subscriptionStream struct {
+	instruments []string
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// NewPriceSubscription creates a subscription using the streaming connection's settings
+func NewPriceSubscription(sc *StreamingConnection, instruments ...string) *PriceSubscription {
+	s := &PriceSubscription{sc: sc}
+	s.Subscribe(instruments...)
+	return s
+}
+
+// Subscribe adds instruments, reconnecting the stream if it is running
+func (s *PriceSubscription) Subscribe(instruments ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, instrument := range instruments {
+		if !slices.Contains(s.instruments, instrument) {
+			s.instruments = append(s.instruments, instrument)
+		}
+	}
+	s.renegotiate()
+}
+
+// Unsubscribe removes instruments, reconnecting the stream if it is running.
+// Prices for removed instruments stop being delivered immediately.
+func (s *PriceSubscription) Unsubscribe(instruments ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.instruments = slices.DeleteFunc(s.instruments, func(instrument string) bool {
+		return slices.Contains(instruments, instrument)
+	})
+	s.renegotiate()
+}
+
+// Instruments returns the subscribed instruments
+func (s *PriceSubscription) Instruments() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.instruments...)
+}
+
+// Run streams prices for the subscribed instruments to callback until ctx is done, returning ctx.Err(),
+// or the stream ends, returning its error. Set a Reconnect policy on the streaming connection to have
+// the stream recover from drops.
+func (s *PriceSubscription) Run(ctx context.Context, callback func(PricingStreamResponse)) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errors.New("goanda: price subscription is already running")
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	s.ctx = ctx
+	s.cancel = cancel
+	s.callback = callback
+	s.last = map[string]time.Time{}
+	s.running = true
+	s.renegotiate()
+	s.mu.Unlock()
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	streams := []*subscriptionStream{s.active, s.pending}
+	s.active, s.pending = nil, nil
+	s.running = false
+	s.mu.Unlock()
+
+	for _, stream := range streams {
+		if stream != nil {
+			stream.cancel()
+			<-stream.done
+		}
+	}
+
+	err := context.Cause(ctx)
+	if errors.Is(err, errStopStream) {
+		return nil
+	}
+	return err
+}
+
+// renegotiate opens a stream for the current instruments, replacing any stream still being opened.
+// The active stream is left running until the new one is live. It must be called with s.mu held.
+func (s *PriceSubscription) renegotiate() {
+	if !s.running {
+		return
+	}
+	if s.pending != nil {
+		s.pending.cancel()
+		s.pending = nil
+	}
+	if s.active != nil && slices.Equal(s.active.instruments, s.instruments) {
+		return
+	}
+	if len(s.instruments) == 0 {
+		if s.active != nil {
+			s.active.cancel()
+			s.active = nil
+		}
+		return
+	}
+	s.pending = s.open(append([]string(nil), s.instruments...))
+}
+
+func (s *PriceSubscription) open(instruments []string) *subscriptionStream {
+	ctx, cancel := context.WithCancel(s.ctx)
+	stream := &subscriptionStream{
+		instruments: instruments,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	sc := *s.sc
+	onHeartbeat := sc.OnHeartbeat
+	sc.OnHeartbeat = func(heartbeat HeartbeatResponse) {
+		if s.promote(stream) && onHeartbeat != nil {
+			onHeartbeat(heartbeat)
+		}
+	}
+	opts := s.Options
+
+	go func() {
+		defer close(stream.done)
+		err := sc.streamPrices(ctx, instruments, opts, func(response PricingStreamResponse) error {
+			s.deliver(ctx, stream, response)
+			return nil
+		})
+
+		if ctx.Err() == nil {
+			if err == nil {
+				err = errStopStream
+			}
+			s.cancel(err)
+		}
+	}()
+	return stream
+}
+
+// promote makes a pending stream active once it is live, closing the stream it replaces.
+// It reports whether the stream is the active one.
+func (s *PriceSubscription) promote(stream *subscriptionStream) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stream == s.pending {
+		if s.active != nil {
+			s.active.cancel()
+		}
+		s.active = stream
+		s.pending = nil
+	}
+	return stream == s.active
+}
+
+func (s *PriceSubscription) deliver(ctx context.Context, stream *subscriptionStream, response PricingStreamResponse) {
+	s.deliverMu.Lock()
+	defer s.deliverMu.Unlock()
+
+	// A replaced stream may still be winding down
+	if !s.promote(stream) || ctx.Err() != nil {
+		return
+	}
+
+	s.mu.Lock()
+	subscribed := slices.Contains(s.instruments, response.Instrument)
+	fresh := true
+	if t, err := time.Parse(time.RFC3339Nano, response.Time); err == nil {
+		fresh = t.After(s.last[response.Instrument])
+		if fresh {
+			s.last[response.Instrument] = t
+		}
+	}
+	callback := s.callback
+	s.mu.Unlock()
+
+	if subscribed && fresh {
+		callback(response)
+	}
+}
@@ -0,0 +1,124 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tickServer streams a price for each requested instrument every few milliseconds, stamped from a shared clock,
+// and starts each stream with a snapshot of the latest price
+type tickServer struct {
+	clock   atomic.Int64
+	mu      sync.Mutex
+	streams []string
+}
+
+func (s *tickServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	instruments := strings.Split(r.URL.Query().Get("instruments"), ",")
+	s.mu.Lock()
+	s.streams = append(s.streams, strings.Join(instruments, ","))
+	s.mu.Unlock()
+
+	base := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	write := func(tick int64) {
+		for _, instrument := range instruments {
+			fmt.Fprintf(w, `{"type":"PRICE","instrument":"%s","time":"%s"}`+"\n",
+				instrument, base.Add(time.Duration(tick)*time.Second).Format(time.RFC3339Nano))
+		}
+		w.(http.Flusher).Flush()
+	}
+
+	write(s.clock.Load())
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(2 * time.Millisecond):
+			write(s.clock.Add(1))
+		}
+	}
+}
+
+func TestPriceSubscriptionChangesWithoutGaps(t *testing.T) {
+	defer logTestResult(t, "PriceSubscriptionChangesWithoutGaps")
+
+	backend := &tickServer{}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	sc := &StreamingConnection{
+		Connection: &Connection{accountID: "test-account", client: *server.Client()},
+		streamURL:  server.URL,
+	}
+	s := NewPriceSubscription(sc, "EUR_USD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	received := map[string][]time.Time{}
+	count := func(instrument string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received[instrument])
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- s.Run(ctx, func(price PricingStreamResponse) {
+			tm, _ := time.Parse(time.RFC3339Nano, price.Time)
+			mu.Lock()
+			received[price.Instrument] = append(received[price.Instrument], tm)
+			mu.Unlock()
+		})
+	}()
+
+	waitFor := func(what string, cond func() bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitFor("EUR_USD prices", func() bool { return count("EUR_USD") >= 5 })
+	s.Subscribe("AUD_USD")
+	waitFor("AUD_USD prices", func() bool { return count("AUD_USD") >= 5 })
+	s.Unsubscribe("EUR_USD")
+	stopped := count("EUR_USD")
+	mu.Lock()
+	audBefore := len(received["AUD_USD"])
+	mu.Unlock()
+	waitFor("AUD_USD prices after unsubscribing", func() bool { return count("AUD_USD") >= audBefore+5 })
+
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// A price already being delivered when Unsubscribe returned may still arrive
+	if count("EUR_USD") > stopped+1 {
+		t.Errorf("Expected no EUR_USD prices after unsubscribing, got %d more", count("EUR_USD")-stopped)
+	}
+	for instrument, times := range received {
+		for i := 1; i < len(times); i++ {
+			if !times[i].After(times[i-1]) {
+				t.Fatalf("Expected %s prices in order without duplicates, got %v after %v", instrument, times[i], times[i-1])
+			}
+		}
+	}
+
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if strings.Join(backend.streams, " ") != "EUR_USD EUR_USD,AUD_USD AUD_USD" {
+		t.Errorf("Unexpected streams: %v", backend.streams)
+	}
+}
@@ -0,0 +1,237 @@
+package goanda
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// supervisorStableRun is how long a stream has to run for its restarts to stop counting as consecutive
+const supervisorStableRun = time.Minute
+
+// Supervisor owns a set of named streams, restarting each one that ends until it is removed or the
+// supervisor is closed. Streams usually end on errors a Reconnect policy does not retry, such as a
+// rejected request or a callback's error, or when OANDA closes them and there is no Reconnect policy.
+type Supervisor struct {
+	// Restart sets the backoff between restarts of a stream, and with MaxRetries how many consecutive
+	// restarts are made before it is given up on. It defaults to DefaultReconnectPolicy.
+	// Restarts stop counting as consecutive once a stream runs for a minute.
+	Restart *ReconnectPolicy
+	// OnExit, if set, is called each time a stream ends, with the error it ended with
+	OnExit func(name string, err error)
+
+	sc *StreamingConnection
+
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	streams map[string]*supervisedStream
+	order   []string
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+type supervisedStream struct {
+	cancel   context.CancelFunc
+	done     chan struct{}
+	running  bool
+	gaveUp   bool
+	started  time.Time
+	restarts int
+	lastErr  error
+}
+
+// SupervisedStreamHealth describes one of a supervisor's streams
+type SupervisedStreamHealth struct {
+	Name    string
+	Running bool
+	// GaveUp is set once the stream has failed more consecutive times than the policy's MaxRetries
+	GaveUp   bool
+	Started  time.Time
+	Restarts int
+	LastErr  error
+}
+
+// SupervisorHealth describes every stream of a supervisor, in the order they were added
+type SupervisorHealth struct {
+	Streams []SupervisedStreamHealth
+	// Healthy is true if every stream is running
+	Healthy bool
+}
+
+// NewSupervisor creates a supervisor for streams of the streaming connection
+func NewSupervisor(sc *StreamingConnection) *Supervisor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Supervisor{
+		sc:      sc,
+		ctx:     ctx,
+		cancel:  cancel,
+		streams: map[string]*supervisedStream{},
+	}
+}
+
+// Add starts a stream under the supervisor. run should block for as long as the stream lasts, and
+// return when ctx is done.
+func (s *Supervisor) Add(name string, run func(ctx context.Context) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("goanda: supervisor is closed")
+	}
+	if _, ok := s.streams[name]; ok {
+		return fmt.Errorf("goanda: supervisor already has a stream named %q", name)
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	stream := &supervisedStream{cancel: cancel, done: make(chan struct{})}
+	s.streams[name] = stream
+	s.order = append(s.order, name)
+
+	s.wg.Add(1)
+	go s.supervise(ctx, name, stream, run)
+	return nil
+}
+
+// AddPrices adds a price stream for the instruments, see StreamPrices
+func (s *Supervisor) AddPrices(name string, instruments []string, callback func(PricingStreamResponse)) error {
+	return s.Add(name, func(ctx context.Context) error {
+		return s.sc.StreamPrices(ctx, instruments, callback)
+	})
+}
+
+// AddTransactions adds the transaction stream, see StreamTransactions. As each restart backfills
+// from the last transaction delivered, none are missed while it is down.
+func (s *Supervisor) AddTransactions(name string, callback func(TransactionStreamResponse)) error {
+	last := ""
+	return s.Add(name, func(ctx context.Context) error {
+		return s.sc.streamTransactionsSince(ctx, last, func(response TransactionStreamResponse) error {
+			if id := response.id(); id != "" {
+				last = id
+			}
+			callback(response)
+			return nil
+		})
+	})
+}
+
+// AddAccountChanges adds polling of the account's changes, see StreamAccountChanges
+func (s *Supervisor) AddAccountChanges(name string, callback func(AccountChangesStreamResponse)) error {
+	return s.Add(name, func(ctx context.Context) error {
+		return s.sc.StreamAccountChanges(ctx, callback)
+	})
+}
+
+// Remove stops a stream and removes it from the supervisor, waiting for it to end
+func (s *Supervisor) Remove(name string) {
+	s.mu.Lock()
+	stream, ok := s.streams[name]
+	if ok {
+		delete(s.streams, name)
+		for i, n := range s.order {
+			if n == name {
+				s.order = append(s.order[:i], s.order[i+1:]...)
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if ok {
+		stream.cancel()
+		<-stream.done
+	}
+}
+
+// Health returns the state of every stream
+func (s *Supervisor) Health() SupervisorHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := SupervisorHealth{Healthy: true}
+	for _, name := range s.order {
+		stream := s.streams[name]
+		health.Streams = append(health.Streams, SupervisedStreamHealth{
+			Name:     name,
+			Running:  stream.running,
+			GaveUp:   stream.gaveUp,
+			Started:  stream.started,
+			Restarts: stream.restarts,
+			LastErr:  stream.lastErr,
+		})
+		if !stream.running {
+			health.Healthy = false
+		}
+	}
+	return health
+}
+
+// Close stops every stream and waits for them to end
+func (s *Supervisor) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// supervise runs a stream, restarting it each time it ends until ctx is done
+func (s *Supervisor) supervise(ctx context.Context, name string, stream *supervisedStream, run func(ctx context.Context) error) {
+	defer s.wg.Done()
+	defer close(stream.done)
+
+	policy := s.Restart
+	if policy == nil {
+		policy = &DefaultReconnectPolicy
+	}
+
+	attempt := 0
+	for {
+		started := time.Now()
+		s.mu.Lock()
+		stream.running = true
+		stream.started = started
+		s.mu.Unlock()
+
+		err := run(ctx)
+
+		s.mu.Lock()
+		stream.running = false
+		stream.lastErr = err
+		s.mu.Unlock()
+		if s.OnExit != nil {
+			s.OnExit(name, err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(started) >= supervisorStableRun {
+			attempt = 0
+		}
+		attempt++
+		if policy.MaxRetries > 0 && attempt > policy.MaxRetries {
+			s.sc.logf("goanda: supervised stream %s ended (%v), giving up after %d restarts", name, err, policy.MaxRetries)
+			s.mu.Lock()
+			stream.gaveUp = true
+			s.mu.Unlock()
+			return
+		}
+
+		delay := policy.backoff(attempt)
+		s.sc.logf("goanda: supervised stream %s ended (%v), restarting in %v", name, err, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		s.mu.Lock()
+		stream.restarts++
+		s.mu.Unlock()
+	}
+}
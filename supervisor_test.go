@@ -0,0 +1,125 @@
+package goanda
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSupervisor(t *testing.T) {
+	defer logTestResult(t, "Supervisor")
+
+	var connects int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first connection is closed at once, the restart stays open
+		n := atomic.AddInt32(&connects, 1)
+		w.Write([]byte(`{"type":"PRICE","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD"}` + "\n"))
+		w.(http.Flusher).Flush()
+		if n > 1 {
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	sc := NewStreamingConnection(&Connection{
+		hostname:  server.URL,
+		accountID: "test-account",
+		client:    *server.Client(),
+		logger:    log.New(&bytes.Buffer{}, "", 0),
+	})
+	sc.streamURL = server.URL
+
+	s := NewSupervisor(sc)
+	s.Restart = &ReconnectPolicy{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	exits := make(chan error, 10)
+	s.OnExit = func(name string, err error) { exits <- err }
+
+	prices := make(chan struct{}, 10)
+	if err := s.AddPrices("prices", []string{"EUR_USD"}, func(PricingStreamResponse) { prices <- struct{}{} }); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := s.AddPrices("prices", []string{"EUR_USD"}, func(PricingStreamResponse) {}); err == nil {
+		t.Error("Expected an error adding a second stream with the same name")
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-prices:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected a price from each connection")
+		}
+	}
+	select {
+	case <-exits:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected OnExit for the closed stream")
+	}
+
+	health := s.Health()
+	if len(health.Streams) != 1 || health.Streams[0].Name != "prices" {
+		t.Fatalf("Expected the prices stream, got %+v", health.Streams)
+	}
+	if !health.Healthy || !health.Streams[0].Running || health.Streams[0].Restarts != 1 {
+		t.Errorf("Expected a running stream restarted once, got %+v", health)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Errorf("Expected no error closing, got %v", err)
+	}
+	if health := s.Health(); health.Healthy || health.Streams[0].Running {
+		t.Errorf("Expected no running streams after Close, got %+v", health)
+	}
+	if err := s.Add("late", func(context.Context) error { return nil }); err == nil {
+		t.Error("Expected an error adding a stream after Close")
+	}
+}
+
+func TestSupervisorGivesUp(t *testing.T) {
+	defer logTestResult(t, "SupervisorGivesUp")
+
+	sc := NewStreamingConnection(&Connection{logger: log.New(&bytes.Buffer{}, "", 0)})
+	s := NewSupervisor(sc)
+	s.Restart = &ReconnectPolicy{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRetries: 2}
+	defer s.Close()
+
+	failure := errors.New("failed")
+	var runs int32
+	s.Add("failing", func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return failure
+	})
+	s.Add("blocking", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !s.Health().Streams[0].GaveUp {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the failing stream to be given up on")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := atomic.LoadInt32(&runs); n != 3 {
+		t.Errorf("Expected the first run and 2 restarts, got %d runs", n)
+	}
+	health := s.Health()
+	if health.Healthy || !errors.Is(health.Streams[0].LastErr, failure) {
+		t.Errorf("Expected an unhealthy supervisor with the stream's error, got %+v", health)
+	}
+	if !health.Streams[1].Running {
+		t.Errorf("Expected the other stream to keep running, got %+v", health.Streams[1])
+	}
+
+	s.Remove("blocking")
+	if health := s.Health(); len(health.Streams) != 1 {
+		t.Errorf("Expected the removed stream to be gone, got %+v", health.Streams)
+	}
+}
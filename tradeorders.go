@@ -0,0 +1,216 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// TradeOrders are the changes SetTradeOrders makes to an open trade's dependent orders. An order that is
+// set replaces the trade's current one, if it has one, and an order left nil and not cancelled is kept.
+// A take profit is set by its Price, a stop loss and a guaranteed stop loss by their Price or their
+// Distance from the trade's price, and a trailing stop loss by its Distance.
+type TradeOrders struct {
+	TakeProfit         *OnFill
+	StopLoss           *OnFill
+	TrailingStopLoss   *OnFill
+	GuaranteedStopLoss *OnFill
+
+	// CancelTakeProfit and the rest cancel the trade's order of their kind
+	CancelTakeProfit         bool
+	CancelStopLoss           bool
+	CancelTrailingStopLoss   bool
+	CancelGuaranteedStopLoss bool
+}
+
+// MarshalJSON sends the orders set and a null for each order cancelled, which is how OANDA is asked to
+// cancel it, leaving out the orders kept
+func (o TradeOrders) MarshalJSON() ([]byte, error) {
+	body := map[string]*OnFill{}
+	for _, order := range o.orders() {
+		if order.details != nil || order.cancel {
+			body[order.field] = order.details
+		}
+	}
+	return json.Marshal(body)
+}
+
+type tradeOrder struct {
+	name     string
+	field    string
+	details  *OnFill
+	cancel   bool
+	price    bool
+	distance bool
+}
+
+func (o TradeOrders) orders() []tradeOrder {
+	return []tradeOrder{
+		{"take profit", "takeProfit", o.TakeProfit, o.CancelTakeProfit, true, false},
+		{"stop loss", "stopLoss", o.StopLoss, o.CancelStopLoss, true, true},
+		{"trailing stop loss", "trailingStopLoss", o.TrailingStopLoss, o.CancelTrailingStopLoss, false, true},
+		{"guaranteed stop loss", "guaranteedStopLoss", o.GuaranteedStopLoss, o.CancelGuaranteedStopLoss, true, true},
+	}
+}
+
+// validate checks each order is set by what it may be set by and that there is something to change
+func (o TradeOrders) validate() error {
+	changed := false
+	for _, order := range o.orders() {
+		if order.details != nil && order.cancel {
+			return fmt.Errorf("goanda: a %s is set or cancelled, not both", order.name)
+		}
+		if err := validateOnFill(order.name, order.details, order.price, order.distance); err != nil {
+			return err
+		}
+		changed = changed || order.details != nil || order.cancel
+	}
+	switch {
+	case !changed:
+		return errors.New("goanda: no trade orders to set or cancel")
+	case o.StopLoss != nil && o.GuaranteedStopLoss != nil:
+		return errors.New("goanda: a trade takes a stop loss or a guaranteed stop loss, not both")
+	}
+	return nil
+}
+
+// TradeOrdersResponse is OANDA's response to setting a trade's dependent orders, with the creation and
+// cancellation of each of them that changed. A new order may fill at once, when the market is already
+// past its price, in which case its fill and the cancellation of the order created in its place are
+// given too.
+type TradeOrdersResponse struct {
+	// TakeProfitOrderCancelTransaction cancels the trade's previous take profit, if it had one
+	TakeProfitOrderCancelTransaction *OrderCancelTransaction
+	// TakeProfitOrderTransaction creates the trade's new take profit, nil if it was cancelled or not changed
+	TakeProfitOrderTransaction              *TakeProfitOrderTransaction
+	TakeProfitOrderFillTransaction          *OrderFillTransaction
+	TakeProfitOrderCreatedCancelTransaction *OrderCancelTransaction
+
+	StopLossOrderCancelTransaction        *OrderCancelTransaction
+	StopLossOrderTransaction              *StopLossOrderTransaction
+	StopLossOrderFillTransaction          *OrderFillTransaction
+	StopLossOrderCreatedCancelTransaction *OrderCancelTransaction
+
+	TrailingStopLossOrderCancelTransaction *OrderCancelTransaction
+	TrailingStopLossOrderTransaction       *TrailingStopLossOrderTransaction
+
+	GuaranteedStopLossOrderCancelTransaction        *OrderCancelTransaction
+	GuaranteedStopLossOrderTransaction              *GuaranteedStopLossOrderTransaction
+	GuaranteedStopLossOrderFillTransaction          *OrderFillTransaction
+	GuaranteedStopLossOrderCreatedCancelTransaction *OrderCancelTransaction
+
+	RelatedTransactionIDs []string
+	LastTransactionID     string
+}
+
+// UnmarshalJSON decodes the response's transactions into their concrete types
+func (r *TradeOrdersResponse) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		TakeProfitOrderCancelTransaction        json.RawMessage             `json:"takeProfitOrderCancelTransaction"`
+		TakeProfitOrderTransaction              *TakeProfitOrderTransaction `json:"takeProfitOrderTransaction"`
+		TakeProfitOrderFillTransaction          json.RawMessage             `json:"takeProfitOrderFillTransaction"`
+		TakeProfitOrderCreatedCancelTransaction json.RawMessage             `json:"takeProfitOrderCreatedCancelTransaction"`
+
+		StopLossOrderCancelTransaction        json.RawMessage           `json:"stopLossOrderCancelTransaction"`
+		StopLossOrderTransaction              *StopLossOrderTransaction `json:"stopLossOrderTransaction"`
+		StopLossOrderFillTransaction          json.RawMessage           `json:"stopLossOrderFillTransaction"`
+		StopLossOrderCreatedCancelTransaction json.RawMessage           `json:"stopLossOrderCreatedCancelTransaction"`
+
+		TrailingStopLossOrderCancelTransaction json.RawMessage                   `json:"trailingStopLossOrderCancelTransaction"`
+		TrailingStopLossOrderTransaction       *TrailingStopLossOrderTransaction `json:"trailingStopLossOrderTransaction"`
+
+		GuaranteedStopLossOrderCancelTransaction        json.RawMessage                     `json:"guaranteedStopLossOrderCancelTransaction"`
+		GuaranteedStopLossOrderTransaction              *GuaranteedStopLossOrderTransaction `json:"guaranteedStopLossOrderTransaction"`
+		GuaranteedStopLossOrderFillTransaction          json.RawMessage                     `json:"guaranteedStopLossOrderFillTransaction"`
+		GuaranteedStopLossOrderCreatedCancelTransaction json.RawMessage                     `json:"guaranteedStopLossOrderCreatedCancelTransaction"`
+
+		RelatedTransactionIDs []string `json:"relatedTransactionIDs"`
+		LastTransactionID     string   `json:"lastTransactionID"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*r = TradeOrdersResponse{
+		TakeProfitOrderTransaction:         raw.TakeProfitOrderTransaction,
+		StopLossOrderTransaction:           raw.StopLossOrderTransaction,
+		TrailingStopLossOrderTransaction:   raw.TrailingStopLossOrderTransaction,
+		GuaranteedStopLossOrderTransaction: raw.GuaranteedStopLossOrderTransaction,
+		RelatedTransactionIDs:              raw.RelatedTransactionIDs,
+		LastTransactionID:                  raw.LastTransactionID,
+	}
+	for _, cancel := range []struct {
+		data        json.RawMessage
+		transaction **OrderCancelTransaction
+	}{
+		{raw.TakeProfitOrderCancelTransaction, &r.TakeProfitOrderCancelTransaction},
+		{raw.TakeProfitOrderCreatedCancelTransaction, &r.TakeProfitOrderCreatedCancelTransaction},
+		{raw.StopLossOrderCancelTransaction, &r.StopLossOrderCancelTransaction},
+		{raw.StopLossOrderCreatedCancelTransaction, &r.StopLossOrderCreatedCancelTransaction},
+		{raw.TrailingStopLossOrderCancelTransaction, &r.TrailingStopLossOrderCancelTransaction},
+		{raw.GuaranteedStopLossOrderCancelTransaction, &r.GuaranteedStopLossOrderCancelTransaction},
+		{raw.GuaranteedStopLossOrderCreatedCancelTransaction, &r.GuaranteedStopLossOrderCreatedCancelTransaction},
+	} {
+		var err error
+		if *cancel.transaction, err = decodeCancel(cancel.data); err != nil {
+			return err
+		}
+	}
+	for _, fill := range []struct {
+		data        json.RawMessage
+		transaction **OrderFillTransaction
+	}{
+		{raw.TakeProfitOrderFillTransaction, &r.TakeProfitOrderFillTransaction},
+		{raw.StopLossOrderFillTransaction, &r.StopLossOrderFillTransaction},
+		{raw.GuaranteedStopLossOrderFillTransaction, &r.GuaranteedStopLossOrderFillTransaction},
+	} {
+		var err error
+		if *fill.transaction, err = decodeFill(fill.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTradeOrders creates, replaces and cancels an open trade's take profit, stop loss, trailing stop loss
+// and guaranteed stop loss in one request. The trade is given by its ID, or by its client ID prefixed
+// with @. An order OANDA refuses returns an OrderRejectedError, and none of the changes are made.
+// The orders set are adjusted and checked as new orders are, see ConnectionConfig.AdjustGTDForClockSkew
+// and ConnectionConfig.OrderPrecision, which fits them to the trade's instrument.
+func (c *Connection) SetTradeOrders(tradeSpecifier string, orders TradeOrders) (TradeOrdersResponse, error) {
+	if err := orders.validate(); err != nil {
+		return TradeOrdersResponse{}, err
+	}
+	if err := c.prepareTradeOrders(tradeSpecifier, &orders); err != nil {
+		return TradeOrdersResponse{}, err
+	}
+	var response TradeOrdersResponse
+	err := c.putAndUnmarshal("/accounts/"+c.accountID+"/trades/"+tradeSpecifier+"/orders", orders, &response)
+	return response, orderRejection(err)
+}
+
+// prepareTradeOrders prepares the orders set as prepareOrder does the dependent orders of a new order,
+// replacing rather than changing the caller's details
+func (c *Connection) prepareTradeOrders(tradeSpecifier string, orders *TradeOrders) error {
+	order := OrderBody{
+		TradeID:                  tradeSpecifier,
+		TakeProfitOnFill:         orders.TakeProfit,
+		StopLossOnFill:           orders.StopLoss,
+		TrailingStopLossOnFill:   orders.TrailingStopLoss,
+		GuaranteedStopLossOnFill: orders.GuaranteedStopLoss,
+	}
+	if err := c.prepareOrder(&order); err != nil {
+		return err
+	}
+	orders.TakeProfit = order.TakeProfitOnFill
+	orders.StopLoss = order.StopLossOnFill
+	orders.TrailingStopLoss = order.TrailingStopLossOnFill
+	orders.GuaranteedStopLoss = order.GuaranteedStopLossOnFill
+	return nil
+}
+
+// SetTradeTrailingStopLoss creates, replaces or, if trailing is nil, cancels an open trade's trailing
+// stop loss, set by its Distance, see SetTradeOrders
+func (c *Connection) SetTradeTrailingStopLoss(tradeSpecifier string, trailing *OnFill) (TradeOrdersResponse, error) {
+	return c.SetTradeOrders(tradeSpecifier, TradeOrders{TrailingStopLoss: trailing, CancelTrailingStopLoss: trailing == nil})
+}
@@ -0,0 +1,221 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetTradeTrailingStopLoss(t *testing.T) {
+	defer logTestResult(t, "SetTradeTrailingStopLoss")
+
+	var bodies []map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/accounts/test-account/trades/@my-trade/orders" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		bodies = append(bodies, body)
+
+		if string(body["trailingStopLoss"]) == "null" {
+			w.Write([]byte(`{"trailingStopLossOrderCancelTransaction":{"id":"9","type":"ORDER_CANCEL","orderID":"8",
+				"reason":"CLIENT_REQUEST"},"relatedTransactionIDs":["9"],"lastTransactionID":"9"}`))
+			return
+		}
+		w.Write([]byte(`{"trailingStopLossOrderCancelTransaction":{"id":"7","type":"ORDER_CANCEL","orderID":"5",
+				"reason":"CLIENT_REQUEST_REPLACED","replacedByOrderID":"8"},
+			"trailingStopLossOrderTransaction":{"id":"8","type":"TRAILING_STOP_LOSS_ORDER","tradeID":"4",
+				"distance":"0.00200","timeInForce":"GTC","replacesOrderID":"5"},
+			"relatedTransactionIDs":["7","8"],"lastTransactionID":"8"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	set, err := c.SetTradeTrailingStopLoss("@my-trade", &OnFill{Distance: "0.00200"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(bodies[0]["trailingStopLoss"]) != `{"distance":"0.00200"}` {
+		t.Errorf("Expected the trailing stop's distance to be sent, got %s", bodies[0]["trailingStopLoss"])
+	}
+	if set.TrailingStopLossOrderTransaction == nil || set.TrailingStopLossOrderTransaction.Distance != "0.00200" {
+		t.Errorf("Expected the new trailing stop, got %+v", set.TrailingStopLossOrderTransaction)
+	}
+	if set.TrailingStopLossOrderCancelTransaction == nil || set.TrailingStopLossOrderCancelTransaction.ReplacedByOrderID != "8" {
+		t.Errorf("Expected the previous trailing stop's cancellation, got %+v", set.TrailingStopLossOrderCancelTransaction)
+	}
+
+	cancelled, err := c.SetTradeTrailingStopLoss("@my-trade", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancelled.TrailingStopLossOrderTransaction != nil || cancelled.TrailingStopLossOrderCancelTransaction == nil || cancelled.LastTransactionID != "9" {
+		t.Errorf("Expected the trailing stop's cancellation, got %+v", cancelled)
+	}
+
+	if _, err := c.SetTradeTrailingStopLoss("@my-trade", &OnFill{Price: "1.1000"}); err == nil {
+		t.Error("Expected a trailing stop set by price to be refused")
+	}
+	if len(bodies) != 2 {
+		t.Errorf("Expected the refused trailing stop not to be sent, got %d requests", len(bodies))
+	}
+}
+
+func TestSetTradeOrders(t *testing.T) {
+	defer logTestResult(t, "SetTradeOrders")
+
+	var body map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/accounts/test-account/trades/4/orders" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		w.Write([]byte(`{
+			"takeProfitOrderCancelTransaction":{"id":"10","type":"ORDER_CANCEL","orderID":"5","reason":"CLIENT_REQUEST_REPLACED"},
+			"takeProfitOrderTransaction":{"id":"11","type":"TAKE_PROFIT_ORDER","tradeID":"4","price":"1.1050","timeInForce":"GTC"},
+			"takeProfitOrderFillTransaction":{"id":"12","type":"ORDER_FILL","orderID":"11","price":"1.1052","reason":"TAKE_PROFIT_ORDER"},
+			"stopLossOrderCancelTransaction":{"id":"13","type":"ORDER_CANCEL","orderID":"6","reason":"CLIENT_REQUEST"},
+			"relatedTransactionIDs":["10","11","12","13"],"lastTransactionID":"13"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	response, err := c.SetTradeOrders("4", TradeOrders{
+		TakeProfit:     &OnFill{Price: "1.1050"},
+		CancelStopLoss: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(body) != 2 || string(body["takeProfit"]) != `{"price":"1.1050"}` || string(body["stopLoss"]) != "null" {
+		t.Errorf("Expected the take profit to be set and the stop loss cancelled, got %v", body)
+	}
+
+	if response.TakeProfitOrderTransaction == nil || response.TakeProfitOrderTransaction.Price != "1.1050" ||
+		response.TakeProfitOrderCancelTransaction == nil || response.TakeProfitOrderCancelTransaction.OrderID != "5" {
+		t.Errorf("Expected the take profit to be replaced, got %+v", response)
+	}
+	if response.TakeProfitOrderFillTransaction == nil || response.TakeProfitOrderFillTransaction.Price != "1.1052" {
+		t.Errorf("Expected the take profit's fill, got %+v", response.TakeProfitOrderFillTransaction)
+	}
+	if response.StopLossOrderCancelTransaction == nil || response.StopLossOrderTransaction != nil || response.TrailingStopLossOrderTransaction != nil {
+		t.Errorf("Expected only the stop loss's cancellation, got %+v", response)
+	}
+	if len(response.RelatedTransactionIDs) != 4 || response.LastTransactionID != "13" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
+func TestSetTradeOrdersPrepared(t *testing.T) {
+	defer logTestResult(t, "SetTradeOrdersPrepared")
+
+	var body map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accounts/test-account/instruments":
+			json.NewEncoder(w).Encode(map[string]Instruments{"instruments": {eurusdDetails}})
+		case "/accounts/test-account/trades/4":
+			w.Write([]byte(`{"trade":{"id":"4","instrument":"EUR_USD"},"lastTransactionID":"6"}`))
+		case "/accounts/test-account/trades/4/orders":
+			body = nil
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Write([]byte(`{"relatedTransactionIDs":["7"],"lastTransactionID":"7"}`))
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client(), precision: PrecisionRound}
+
+	// the prices are fitted to the trade's instrument, leaving the caller's alone
+	stopLoss := &OnFill{Price: "1.079996"}
+	if _, err := c.SetTradeOrders("4", TradeOrders{StopLoss: stopLoss}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(body["stopLoss"]) != `{"price":"1.08000"}` || stopLoss.Price != "1.079996" {
+		t.Errorf("Expected the stop loss rounded to the instrument's precision, got %s", body["stopLoss"])
+	}
+
+	c.precision = PrecisionStrict
+	body = nil
+	if _, err := c.SetTradeOrders("4", TradeOrders{TakeProfit: &OnFill{Price: "1.100004"}}); !errors.Is(err, ErrPrecision) || body != nil {
+		t.Errorf("Expected the unrounded take profit to be refused without being sent, got %v", err)
+	}
+
+	// an expiry already past by OANDA's clock is refused
+	c.precision = PrecisionAsGiven
+	stale := &OnFill{Price: "1.1050", TimeInForce: "GTD", GtdTime: time.Now().Add(-time.Minute)}
+	if _, err := c.SetTradeOrders("4", TradeOrders{TakeProfit: stale}); !errors.Is(err, ErrGTDPassed) || body != nil {
+		t.Errorf("Expected the stale take profit to be refused without being sent, got %v", err)
+	}
+}
+
+func TestSetTradeOrdersValidation(t *testing.T) {
+	defer logTestResult(t, "SetTradeOrdersValidation")
+
+	c := &Connection{hostname: "http://127.0.0.1:0", accountID: "test-account"}
+	tests := map[string]TradeOrders{
+		"nothing to change":          {},
+		"take profit by distance":    {TakeProfit: &OnFill{Distance: "0.0050"}},
+		"set and cancelled":          {StopLoss: &OnFill{Price: "1.0950"}, CancelStopLoss: true},
+		"both stop losses":           {StopLoss: &OnFill{Price: "1.0950"}, GuaranteedStopLoss: &OnFill{Price: "1.0900"}},
+		"trailing stop without size": {TrailingStopLoss: &OnFill{}},
+	}
+	for name, orders := range tests {
+		if _, err := c.SetTradeOrders("4", orders); err == nil || errors.As(err, new(APIError)) {
+			t.Errorf("Expected %s to be refused without being sent, got %v", name, err)
+		}
+	}
+}
+
+func TestSetTradeOrdersRejected(t *testing.T) {
+	defer logTestResult(t, "SetTradeOrdersRejected")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"stopLossOrderRejectTransaction":{"id":"10","type":"STOP_LOSS_ORDER_REJECT","tradeID":"4",
+			"rejectReason":"STOP_LOSS_ON_FILL_LOSS"},"relatedTransactionIDs":["10"],"lastTransactionID":"10",
+			"errorCode":"STOP_LOSS_ON_FILL_LOSS","errorMessage":"The stop loss would close the trade at once"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	_, err := c.SetTradeOrders("4", TradeOrders{StopLoss: &OnFill{Price: "1.2000"}})
+	var rejected OrderRejectedError
+	if !errors.As(err, &rejected) || rejected.RejectReason != "STOP_LOSS_ON_FILL_LOSS" {
+		t.Fatalf("Expected the stop loss's rejection, got %v", err)
+	}
+	if rejected.Transaction == nil || rejected.Transaction.TradeID != "4" || rejected.LastTransactionID != "10" {
+		t.Errorf("Expected the reject transaction, got %+v", rejected.Transaction)
+	}
+}
+
+func TestTradeTrailingStopValue(t *testing.T) {
+	defer logTestResult(t, "TradeTrailingStopValue")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"trade":{"id":"4","instrument":"EUR_USD","currentUnits":"100",
+			"trailingStopLossOrder":{"id":"8","type":"TRAILING_STOP_LOSS","tradeID":"4","distance":"0.00200",
+				"timeInForce":"GTD","gtdTime":"2030-01-02T15:04:05Z","state":"PENDING","trailingStopValue":"1.09850"}},
+			"lastTransactionID":"8"}`))
+	}))
+	defer server.Close()
+
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+	trade, err := c.GetTrade("4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	trailing := trade.Trade.TrailingStopLossOrder
+	if trailing == nil || trailing.TrailingStopValue != "1.09850" || trailing.Distance != "0.00200" || trailing.GtdTime.Year() != 2030 {
+		t.Errorf("Expected the trade's trailing stop, got %+v", trailing)
+	}
+}
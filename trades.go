@@ -3,6 +3,7 @@ package goanda
 // Supporting OANDA docs - http://developer.oanda.com/rest-live-v20/trade-ep/
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -31,11 +32,132 @@ type Trade struct {
 	AverageClosePrice     string                 `json:"averageClosePrice,omitempty"`
 	ClosingTransactionIDs []string               `json:"closingTransactionIDs,omitempty"`
 	Financing             string                 `json:"financing"`
+	DividendAdjustment    string                 `json:"dividendAdjustment,omitempty"`
 	CloseTime             time.Time              `json:"closeTime,omitempty"`
 	ClientExtensions      *OrderExtensions       `json:"clientExtensions,omitempty"`
 	TakeProfitOrder       *TakeProfitOrder       `json:"takeProfitOrder,omitempty"`
 	StopLossOrder         *StopLossOrder         `json:"stopLossOrder,omitempty"`
 	TrailingStopLossOrder *TrailingStopLossOrder `json:"trailingStopLossOrder,omitempty"`
+	// GuaranteedStopLossOrder takes the place of StopLossOrder for a trade with a guaranteed stop loss
+	GuaranteedStopLossOrder *StopLossOrder `json:"guaranteedStopLossOrder,omitempty"`
+}
+
+// ParsedTrade is a Trade with its units, prices and profit and loss parsed, and the IDs of the orders
+// linked to it in place of the orders. Amounts OANDA omits, such as a closed trade's unrealized profit
+// and loss, are left at zero.
+type ParsedTrade struct {
+	ID         string
+	Instrument string
+	// Price is the price the trade was opened at
+	Price    float64
+	OpenTime time.Time
+	// State is OPEN, CLOSED or CLOSE_WHEN_TRADEABLE
+	State string
+	// InitialUnits and CurrentUnits are positive for a long trade and negative for a short one
+	InitialUnits          float64
+	CurrentUnits          float64
+	InitialMarginRequired float64
+	MarginUsed            float64
+	RealizedPL            float64
+	UnrealizedPL          float64
+	Financing             float64
+	DividendAdjustment    float64
+	AverageClosePrice     float64
+	CloseTime             time.Time
+	ClosingTransactionIDs []string
+	ClientExtensions      *OrderExtensions
+
+	// TakeProfitOrderID and the rest are the IDs of the trade's dependent orders, empty for those it
+	// doesn't have
+	TakeProfitOrderID         string
+	StopLossOrderID           string
+	TrailingStopLossOrderID   string
+	GuaranteedStopLossOrderID string
+}
+
+// Open reports whether the trade is still open
+func (t ParsedTrade) Open() bool {
+	return t.State == "OPEN"
+}
+
+// Long reports whether the trade bought its units
+func (t ParsedTrade) Long() bool {
+	return t.InitialUnits > 0
+}
+
+// LinkedOrderIDs returns the IDs of the trade's dependent orders
+func (t ParsedTrade) LinkedOrderIDs() []string {
+	var ids []string
+	for _, id := range []string{t.TakeProfitOrderID, t.StopLossOrderID, t.TrailingStopLossOrderID, t.GuaranteedStopLossOrderID} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Parse converts the trade's strings into numbers
+func (t Trade) Parse() (ParsedTrade, error) {
+	parsed := ParsedTrade{
+		ID:                    t.ID,
+		Instrument:            t.Instrument,
+		OpenTime:              t.OpenTime,
+		State:                 t.State,
+		CloseTime:             t.CloseTime,
+		ClosingTransactionIDs: t.ClosingTransactionIDs,
+		ClientExtensions:      t.ClientExtensions,
+	}
+
+	var err error
+	if parsed.Price, err = parsePrice("trade "+t.ID+" price", t.Price); err != nil {
+		return ParsedTrade{}, err
+	}
+	for _, f := range []struct {
+		name  string
+		value string
+		dest  *float64
+	}{
+		{"initial units", t.InitialUnits, &parsed.InitialUnits},
+		{"current units", t.CurrentUnits, &parsed.CurrentUnits},
+		{"initial margin required", t.InitialMarginRequired, &parsed.InitialMarginRequired},
+		{"margin used", t.MarginUsed, &parsed.MarginUsed},
+		{"realized PL", t.RealizedPL, &parsed.RealizedPL},
+		{"unrealized PL", t.UnrealizedPL, &parsed.UnrealizedPL},
+		{"financing", t.Financing, &parsed.Financing},
+		{"dividend adjustment", t.DividendAdjustment, &parsed.DividendAdjustment},
+		{"average close price", t.AverageClosePrice, &parsed.AverageClosePrice},
+	} {
+		if *f.dest, err = parseOptionalPrice("trade "+t.ID+" "+f.name, f.value); err != nil {
+			return ParsedTrade{}, err
+		}
+	}
+
+	if t.TakeProfitOrder != nil {
+		parsed.TakeProfitOrderID = t.TakeProfitOrder.ID
+	}
+	if t.StopLossOrder != nil {
+		parsed.StopLossOrderID = t.StopLossOrder.ID
+	}
+	if t.TrailingStopLossOrder != nil {
+		parsed.TrailingStopLossOrderID = t.TrailingStopLossOrder.ID
+	}
+	if t.GuaranteedStopLossOrder != nil {
+		parsed.GuaranteedStopLossOrderID = t.GuaranteedStopLossOrder.ID
+	}
+	return parsed, nil
+}
+
+// Parse parses each of the trades, see Trade.Parse
+func (rt ReceivedTrades) Parse() ([]ParsedTrade, error) {
+	parsed := make([]ParsedTrade, 0, len(rt.Trades))
+	for _, trade := range rt.Trades {
+		p, err := trade.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("goanda: parsing trade %s: %w", trade.ID, err)
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
 }
 
 type TakeProfitOrder struct {
@@ -73,9 +195,13 @@ type TrailingStopLossOrder struct {
 	ClientTradeID    string           `json:"clientTradeID,omitempty"`
 	Distance         string           `json:"distance"`
 	TimeInForce      string           `json:"timeInForce"`
+	GtdTime          time.Time        `json:"gtdTime,omitempty"`
 	TriggerCondition string           `json:"triggerCondition"`
 	State            string           `json:"state"`
 	ClientExtensions *OrderExtensions `json:"clientExtensions,omitempty"`
+	// TrailingStopValue is the price the order now closes the trade at, moved as the market moves in
+	// the trade's favour
+	TrailingStopValue string `json:"trailingStopValue,omitempty"`
 }
 
 type CloseTradePayload struct {
@@ -164,37 +290,79 @@ type PriceLevel struct {
 	Liquidity string `json:"liquidity"`
 }
 
-func (c *Connection) GetTradesForInstrument(instrument string) (ReceivedTrades, error) {
+// TradesOptions filters the trades returned by ListTrades, fields left empty are not filtered on
+type TradesOptions struct {
+	// IDs lists the trades to return
+	IDs []string
+	// State is OPEN, the default, CLOSED, CLOSE_WHEN_TRADEABLE or ALL
+	State      string
+	Instrument string
+	// Count is the most trades returned, OANDA returns 50 by default and at most 500
+	Count int
+	// BeforeID returns only the trades older than it, to page back through the account's trades
+	BeforeID string
+}
+
+func (o TradesOptions) query() string {
+	return listQuery(o.IDs, o.State, o.Instrument, o.Count, o.BeforeID)
+}
+
+// ListTrades returns the account's trades selected by opts, newest first, see ReceivedTrades.Parse
+func (c *Connection) ListTrades(opts TradesOptions) (ReceivedTrades, error) {
 	rt := ReceivedTrades{}
-	err := c.getAndUnmarshal(
-		"/accounts/"+
-			c.accountID+
-			"/trades"+
-			"?instrument="+
-			instrument,
-		&rt,
-	)
+	err := c.getAndUnmarshal("/accounts/"+c.accountID+"/trades"+opts.query(), &rt)
 	return rt, err
 }
 
-func (c *Connection) GetOpenTrades() (ReceivedTrades, error) {
+// ListOpenTrades returns every open trade on the instrument, or on the account if instrument is empty.
+// Unlike ListTrades it is not limited to a page of trades.
+func (c *Connection) ListOpenTrades(instrument string) (ReceivedTrades, error) {
 	rt := ReceivedTrades{}
-	err := c.getAndUnmarshal("/accounts/"+c.accountID+"/openTrades", &rt)
-	return rt, err
+	if err := c.getAndUnmarshal("/accounts/"+c.accountID+"/openTrades", &rt); err != nil || instrument == "" {
+		return rt, err
+	}
+
+	var trades []Trade
+	for _, trade := range rt.Trades {
+		if trade.Instrument == instrument {
+			trades = append(trades, trade)
+		}
+	}
+	rt.Trades = trades
+	return rt, nil
 }
 
-func (c *Connection) GetTrade(ticket string) (ReceivedTrade, error) {
+// GetTradesForInstrument returns the instrument's open trades, a page of them, see ListTrades
+func (c *Connection) GetTradesForInstrument(instrument string) (ReceivedTrades, error) {
+	return c.ListTrades(TradesOptions{Instrument: instrument})
+}
+
+// GetOpenTrades returns the account's open trades, see ListOpenTrades
+func (c *Connection) GetOpenTrades() (ReceivedTrades, error) {
+	return c.ListOpenTrades("")
+}
+
+// GetTrade returns a trade, open or closed, by its ID, or by its client ID prefixed with @, see
+// GetTradeByClientID and Trade.Parse
+func (c *Connection) GetTrade(tradeSpecifier string) (ReceivedTrade, error) {
 	rt := ReceivedTrade{}
 	err := c.getAndUnmarshal(
 		"/accounts/"+
 			c.accountID+
 			"/trades/"+
-			ticket,
+			tradeSpecifier,
 		&rt,
 	)
 	return rt, err
 }
 
+// GetTradeByClientID returns the trade tagged with the client ID in its client extensions, as given by
+// the TradeClientExtensions of the order that opened it. A trade that does not exist is an APIError
+// with a 404 status.
+func (c *Connection) GetTradeByClientID(clientID string) (ReceivedTrade, error) {
+	return c.GetTrade(clientSpecifier(clientID))
+}
+
 // Default is close the whole position using the string "ALL" in body.units
 func (c *Connection) ReduceTradeSize(ticket string, body CloseTradePayload) (ModifiedTrade, error) {
 	mt := ModifiedTrade{}
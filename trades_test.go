@@ -405,3 +405,122 @@ func TestReduceTradeSize(t *testing.T) {
 		t.Errorf("Expected OrderFillTransaction.TradeReduced.Units to be 50, got %s", modifiedTrade.OrderFillTransaction.TradeReduced.Units)
 	}
 }
+
+func TestListTrades(t *testing.T) {
+	defer logTestResult(t, "ListTrades")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/trades" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if query.Get("ids") != "3,5" || query.Get("state") != "CLOSED" || query.Get("instrument") != "EUR_USD" ||
+			query.Get("count") != "10" || query.Get("beforeID") != "7" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+
+		w.Write([]byte(`{"trades":[
+			{"id":"5","instrument":"EUR_USD","price":"1.1000","openTime":"2024-01-02T15:04:05Z","state":"CLOSED",
+				"initialUnits":"-100","currentUnits":"0","realizedPL":"1.50","financing":"-0.02",
+				"averageClosePrice":"1.0985","closingTransactionIDs":["9"],"closeTime":"2024-01-02T16:04:05Z"},
+			{"id":"3","instrument":"EUR_USD","price":"1.0950","openTime":"2024-01-02T14:04:05Z","state":"CLOSED",
+				"initialUnits":"100","currentUnits":"0","realizedPL":"x"}],
+			"lastTransactionID":"10"}`))
+	}))
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	trades, err := c.ListTrades(TradesOptions{IDs: []string{"3", "5"}, State: "CLOSED", Instrument: "EUR_USD", Count: 10, BeforeID: "7"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if trades.LastTransactionID != "10" || len(trades.Trades) != 2 {
+		t.Fatalf("Unexpected trades: %+v", trades)
+	}
+
+	closed, err := trades.Trades[0].Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if closed.Price != 1.1 || closed.InitialUnits != -100 || closed.Long() || closed.Open() || closed.RealizedPL != 1.5 ||
+		closed.AverageClosePrice != 1.0985 || closed.UnrealizedPL != 0 || closed.CloseTime.IsZero() {
+		t.Errorf("Unexpected parsed trade: %+v", closed)
+	}
+	if _, err := trades.Parse(); err == nil {
+		t.Error("Expected an error for the trade with an invalid realized PL")
+	}
+}
+
+func TestListOpenTrades(t *testing.T) {
+	defer logTestResult(t, "ListOpenTrades")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/openTrades" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"trades":[
+			{"id":"6","instrument":"EUR_USD","price":"1.1000","state":"OPEN","initialUnits":"100","currentUnits":"60",
+				"unrealizedPL":"-2.40","marginUsed":"2.20","takeProfitOrder":{"id":"7"},"trailingStopLossOrder":{"id":"8"}},
+			{"id":"4","instrument":"USD_JPY","price":"150.00","state":"OPEN","initialUnits":"-100","currentUnits":"-100",
+				"guaranteedStopLossOrder":{"id":"5","guaranteed":true}}],
+			"lastTransactionID":"9"}`))
+	}))
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	trades, err := c.ListOpenTrades("EUR_USD")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	parsed, err := trades.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(parsed) != 1 || parsed[0].ID != "6" {
+		t.Fatalf("Expected only the EUR_USD trade, got %+v", parsed)
+	}
+	trade := parsed[0]
+	if !trade.Open() || !trade.Long() || trade.CurrentUnits != 60 || trade.UnrealizedPL != -2.4 || trade.MarginUsed != 2.2 {
+		t.Errorf("Unexpected parsed trade: %+v", trade)
+	}
+	if ids := trade.LinkedOrderIDs(); len(ids) != 2 || ids[0] != "7" || ids[1] != "8" {
+		t.Errorf("Expected the take profit and trailing stop loss, got %v", ids)
+	}
+
+	all, err := c.ListOpenTrades("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if guaranteed, _ := all.Trades[1].Parse(); guaranteed.GuaranteedStopLossOrderID != "5" || guaranteed.Long() {
+		t.Errorf("Expected the short trade's guaranteed stop loss, got %+v", guaranteed)
+	}
+}
+
+func TestGetTradeByClientID(t *testing.T) {
+	defer logTestResult(t, "GetTradeByClientID")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/test-account/trades/@my-trade" {
+			http.Error(w, `{"errorMessage":"The Trade specified does not exist"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"trade":{"id":"6","instrument":"SPX500_USD","price":"4700.0","state":"CLOSED","initialUnits":"2",
+			"currentUnits":"0","realizedPL":"12.00","dividendAdjustment":"-0.35","clientExtensions":{"id":"my-trade"}},
+			"lastTransactionID":"9"}`))
+	}))
+	defer server.Close()
+	c := &Connection{hostname: server.URL, accountID: "test-account", client: *server.Client()}
+
+	rt, err := c.GetTradeByClientID("my-trade")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	trade, err := rt.Trade.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if trade.ID != "6" || trade.ClientExtensions.ID != "my-trade" || trade.DividendAdjustment != -0.35 || trade.RealizedPL != 12 {
+		t.Errorf("Unexpected trade: %+v", trade)
+	}
+
+	if _, err := c.GetTrade("7"); !notFound(err) {
+		t.Errorf("Expected a trade that doesn't exist to be a 404, got %v", err)
+	}
+}
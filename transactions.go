@@ -1,7 +1,10 @@
 package goanda
 
 import (
+	"encoding/json"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,52 +19,37 @@ type TransactionPages struct {
 	To                time.Time `json:"to"`
 }
 
+type TransactionDetails struct {
+	AccountBalance string    `json:"accountBalance"`
+	AccountID      string    `json:"accountID"`
+	BatchID        string    `json:"batchID"`
+	Financing      string    `json:"financing"`
+	ID             string    `json:"id"`
+	Instrument     string    `json:"instrument"`
+	OrderID        string    `json:"orderID"`
+	Pl             string    `json:"pl"`
+	Price          string    `json:"price"`
+	Reason         string    `json:"reason"`
+	Time           time.Time `json:"time"`
+	TradeOpened    struct {
+		TradeID string `json:"tradeID"`
+		Units   string `json:"units"`
+	} `json:"tradeOpened"`
+	Type         string           `json:"type"`
+	Units        string           `json:"units"`
+	UserID       int              `json:"userID"`
+	TradesClosed []TradeReduction `json:"tradesClosed,omitempty"`
+	TradeReduced *TradeReduction  `json:"tradeReduced,omitempty"`
+}
+
 type Transaction struct {
-	LastTransactionID string `json:"lastTransactionID"`
-	Transaction       struct {
-		AccountBalance string    `json:"accountBalance"`
-		AccountID      string    `json:"accountID"`
-		BatchID        string    `json:"batchID"`
-		Financing      string    `json:"financing"`
-		ID             string    `json:"id"`
-		Instrument     string    `json:"instrument"`
-		OrderID        string    `json:"orderID"`
-		Pl             string    `json:"pl"`
-		Price          string    `json:"price"`
-		Reason         string    `json:"reason"`
-		Time           time.Time `json:"time"`
-		TradeOpened    struct {
-			TradeID string `json:"tradeID"`
-			Units   string `json:"units"`
-		} `json:"tradeOpened"`
-		Type   string `json:"type"`
-		Units  string `json:"units"`
-		UserID int    `json:"userID"`
-	} `json:"transaction"`
+	LastTransactionID string             `json:"lastTransactionID"`
+	Transaction       TransactionDetails `json:"transaction"`
 }
 
 type Transactions struct {
-	LastTransactionID string `json:"lastTransactionID"`
-	Transactions      []struct {
-		AccountBalance string    `json:"accountBalance"`
-		AccountID      string    `json:"accountID"`
-		BatchID        string    `json:"batchID"`
-		Financing      string    `json:"financing"`
-		ID             string    `json:"id"`
-		Instrument     string    `json:"instrument"`
-		OrderID        string    `json:"orderID"`
-		Pl             string    `json:"pl"`
-		Price          string    `json:"price"`
-		Reason         string    `json:"reason"`
-		Time           time.Time `json:"time"`
-		TradeOpened    struct {
-			TradeID string `json:"tradeID"`
-			Units   string `json:"units"`
-		} `json:"tradeOpened"`
-		Type   string `json:"type"`
-		Units  string `json:"units"`
-		UserID int    `json:"userID"`
-	} `json:"transactions"`
+	LastTransactionID string               `json:"lastTransactionID"`
+	Transactions      []TransactionDetails `json:"transactions"`
 }
 
 // https://golang.org/pkg/time/#Time.AddDate
@@ -103,3 +91,60 @@ func (c *Connection) GetTransactionsSinceId(id string) (Transactions, error) {
 	)
 	return tr, err
 }
+
+// transactionsSince fetches the account's transactions after id as stream messages
+func (c *Connection) transactionsSince(accountID string, id string) ([][]byte, error) {
+	var response struct {
+		Transactions []json.RawMessage `json:"transactions"`
+	}
+	if err := c.getAndUnmarshal("/accounts/"+accountID+"/transactions/sinceid?id="+id, &response); err != nil {
+		return nil, err
+	}
+
+	messages := make([][]byte, 0, len(response.Transactions))
+	for _, raw := range response.Transactions {
+		var t struct {
+			ID        string `json:"id"`
+			Type      string `json:"type"`
+			Time      string `json:"time"`
+			AccountID string `json:"accountID"`
+			BatchID   string `json:"batchID"`
+			RequestID string `json:"requestID"`
+		}
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, err
+		}
+		message, err := json.Marshal(TransactionStreamResponse{
+			ID:            t.ID,
+			Type:          t.Type,
+			Time:          t.Time,
+			TransactionID: t.ID,
+			AccountID:     t.AccountID,
+			BatchID:       t.BatchID,
+			RequestID:     t.RequestID,
+			Transaction:   raw,
+		})
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// compareTransactionIDs compares two transaction IDs numerically, returning -1, 0 or 1.
+// IDs that are not numbers are compared as strings.
+func compareTransactionIDs(a string, b string) int {
+	ai, aErr := strconv.ParseInt(a, 10, 64)
+	bi, bErr := strconv.ParseInt(b, 10, 64)
+	if aErr != nil || bErr != nil {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case ai < bi:
+		return -1
+	case ai > bi:
+		return 1
+	}
+	return 0
+}
@@ -74,26 +74,7 @@ func TestGetTransaction(t *testing.T) {
 
 		response := Transaction{
 			LastTransactionID: "1000",
-			Transaction: struct {
-				AccountBalance string    `json:"accountBalance"`
-				AccountID      string    `json:"accountID"`
-				BatchID        string    `json:"batchID"`
-				Financing      string    `json:"financing"`
-				ID             string    `json:"id"`
-				Instrument     string    `json:"instrument"`
-				OrderID        string    `json:"orderID"`
-				Pl             string    `json:"pl"`
-				Price          string    `json:"price"`
-				Reason         string    `json:"reason"`
-				Time           time.Time `json:"time"`
-				TradeOpened    struct {
-					TradeID string `json:"tradeID"`
-					Units   string `json:"units"`
-				} `json:"tradeOpened"`
-				Type   string `json:"type"`
-				Units  string `json:"units"`
-				UserID int    `json:"userID"`
-			}{
+			Transaction: TransactionDetails{
 				ID:         "1000",
 				Type:       "MARKET_ORDER",
 				Instrument: "EUR_USD",
@@ -148,26 +129,7 @@ func TestGetTransactionsSinceId(t *testing.T) {
 
 		response := Transactions{
 			LastTransactionID: "1001",
-			Transactions: []struct {
-				AccountBalance string    `json:"accountBalance"`
-				AccountID      string    `json:"accountID"`
-				BatchID        string    `json:"batchID"`
-				Financing      string    `json:"financing"`
-				ID             string    `json:"id"`
-				Instrument     string    `json:"instrument"`
-				OrderID        string    `json:"orderID"`
-				Pl             string    `json:"pl"`
-				Price          string    `json:"price"`
-				Reason         string    `json:"reason"`
-				Time           time.Time `json:"time"`
-				TradeOpened    struct {
-					TradeID string `json:"tradeID"`
-					Units   string `json:"units"`
-				} `json:"tradeOpened"`
-				Type   string `json:"type"`
-				Units  string `json:"units"`
-				UserID int    `json:"userID"`
-			}{
+			Transactions: []TransactionDetails{
 				{
 					ID:         "1000",
 					Type:       "MARKET_ORDER",
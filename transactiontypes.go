@@ -0,0 +1,464 @@
+package goanda
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// TypedTransaction is a transaction decoded into its concrete type, use a type switch to handle the
+// types of interest:
+//
+//	switch t := tx.(type) {
+//	case *OrderFillTransaction:
+//	case *StopLossOrderTransaction:
+//	}
+//
+// It cannot be called Transaction, which is GetTransaction's response.
+type TypedTransaction interface {
+	Header() TransactionHeader
+}
+
+// TransactionHeader holds the fields common to every transaction
+type TransactionHeader struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	UserID    int       `json:"userID"`
+	AccountID string    `json:"accountID"`
+	BatchID   string    `json:"batchID"`
+	RequestID string    `json:"requestID,omitempty"`
+	Type      string    `json:"type"`
+}
+
+// Header returns the transaction's common fields
+func (h TransactionHeader) Header() TransactionHeader {
+	return h
+}
+
+// OrderFillTransaction is an ORDER_FILL, an order being filled and the trades it opened or closed
+type OrderFillTransaction struct {
+	TransactionHeader
+	OrderID                       string           `json:"orderID"`
+	ClientOrderID                 string           `json:"clientOrderID,omitempty"`
+	Instrument                    string           `json:"instrument"`
+	Units                         string           `json:"units"`
+	Price                         string           `json:"price"`
+	FullVWAP                      string           `json:"fullVWAP,omitempty"`
+	Reason                        string           `json:"reason"`
+	PL                            string           `json:"pl"`
+	QuotePL                       string           `json:"quotePL,omitempty"`
+	Financing                     string           `json:"financing"`
+	Commission                    string           `json:"commission"`
+	GuaranteedExecutionFee        string           `json:"guaranteedExecutionFee,omitempty"`
+	QuoteGuaranteedExecutionFee   string           `json:"quoteGuaranteedExecutionFee,omitempty"`
+	HalfSpreadCost                string           `json:"halfSpreadCost,omitempty"`
+	AccountBalance                string           `json:"accountBalance"`
+	GainQuoteHomeConversionFactor string           `json:"gainQuoteHomeConversionFactor,omitempty"`
+	LossQuoteHomeConversionFactor string           `json:"lossQuoteHomeConversionFactor,omitempty"`
+	TradeOpened                   *TradeReduction  `json:"tradeOpened,omitempty"`
+	TradesClosed                  []TradeReduction `json:"tradesClosed,omitempty"`
+	TradeReduced                  *TradeReduction  `json:"tradeReduced,omitempty"`
+}
+
+// CloseReason is why the fill closed or reduced trades
+func (t *OrderFillTransaction) CloseReason() CloseReason {
+	return CloseReasonFromFill(t.Reason)
+}
+
+// GuaranteedExecutionFee is the fee charged for the guaranteed stop losses of the trades a fill opened
+// or closed, zero if they had none
+type GuaranteedExecutionFee struct {
+	// Fee is in the account's home currency
+	Fee float64
+	// QuoteFee is in the instrument's quote currency
+	QuoteFee float64
+}
+
+// GuaranteedFee parses the fill's guaranteed execution fee
+func (t *OrderFillTransaction) GuaranteedFee() (GuaranteedExecutionFee, error) {
+	var fee GuaranteedExecutionFee
+	var err error
+	if fee.Fee, err = parseOptionalPrice("guaranteed execution fee", t.GuaranteedExecutionFee); err != nil {
+		return GuaranteedExecutionFee{}, err
+	}
+	if fee.QuoteFee, err = parseOptionalPrice("quote guaranteed execution fee", t.QuoteGuaranteedExecutionFee); err != nil {
+		return GuaranteedExecutionFee{}, err
+	}
+	return fee, nil
+}
+
+// OrderTransaction holds the fields shared by the transactions creating an order
+type OrderTransaction struct {
+	TransactionHeader
+	Instrument               string           `json:"instrument,omitempty"`
+	Units                    string           `json:"units,omitempty"`
+	TimeInForce              string           `json:"timeInForce"`
+	PositionFill             string           `json:"positionFill,omitempty"`
+	TriggerCondition         string           `json:"triggerCondition,omitempty"`
+	Reason                   string           `json:"reason"`
+	ClientExtensions         *OrderExtensions `json:"clientExtensions,omitempty"`
+	TakeProfitOnFill         *OnFill          `json:"takeProfitOnFill,omitempty"`
+	StopLossOnFill           *OnFill          `json:"stopLossOnFill,omitempty"`
+	GuaranteedStopLossOnFill *OnFill          `json:"guaranteedStopLossOnFill,omitempty"`
+	TrailingStopLossOnFill   *OnFill          `json:"trailingStopLossOnFill,omitempty"`
+	TradeClientExtensions    *OrderExtensions `json:"tradeClientExtensions,omitempty"`
+	ReplacesOrderID          string           `json:"replacesOrderID,omitempty"`
+	CancellingTransactionID  string           `json:"cancellingTransactionID,omitempty"`
+}
+
+// MarketOrderTransaction is a MARKET_ORDER, the creation of a market order
+type MarketOrderTransaction struct {
+	OrderTransaction
+	PriceBound string `json:"priceBound,omitempty"`
+}
+
+// LimitOrderTransaction is a LIMIT_ORDER, the creation of a limit order
+type LimitOrderTransaction struct {
+	OrderTransaction
+	Price   string    `json:"price"`
+	GTDTime time.Time `json:"gtdTime,omitempty"`
+}
+
+// StopOrderTransaction is a STOP_ORDER, the creation of a stop order
+type StopOrderTransaction struct {
+	OrderTransaction
+	Price      string    `json:"price"`
+	PriceBound string    `json:"priceBound,omitempty"`
+	GTDTime    time.Time `json:"gtdTime,omitempty"`
+}
+
+// MarketIfTouchedOrderTransaction is a MARKET_IF_TOUCHED_ORDER, the creation of a market-if-touched order
+type MarketIfTouchedOrderTransaction struct {
+	OrderTransaction
+	Price      string    `json:"price"`
+	PriceBound string    `json:"priceBound,omitempty"`
+	GTDTime    time.Time `json:"gtdTime,omitempty"`
+}
+
+// FixedPriceOrderTransaction is a FIXED_PRICE_ORDER, an order OANDA creates already filled at a set
+// price, such as when a trade is moved between accounts
+type FixedPriceOrderTransaction struct {
+	OrderTransaction
+	Price string `json:"price"`
+	// TradeState is the state the trades the order opens are given
+	TradeState string `json:"tradeState,omitempty"`
+}
+
+// DependentOrderTransaction holds the fields shared by the transactions creating an order on a trade
+type DependentOrderTransaction struct {
+	OrderTransaction
+	TradeID       string    `json:"tradeID"`
+	ClientTradeID string    `json:"clientTradeID,omitempty"`
+	GTDTime       time.Time `json:"gtdTime,omitempty"`
+	// OrderFillTransactionID is the fill that opened the trade, for orders created on fill
+	OrderFillTransactionID string `json:"orderFillTransactionID,omitempty"`
+}
+
+// TakeProfitOrderTransaction is a TAKE_PROFIT_ORDER, the creation of a take profit order
+type TakeProfitOrderTransaction struct {
+	DependentOrderTransaction
+	Price string `json:"price"`
+}
+
+// StopLossOrderTransaction is a STOP_LOSS_ORDER, the creation of a stop loss order
+type StopLossOrderTransaction struct {
+	DependentOrderTransaction
+	Price    string `json:"price,omitempty"`
+	Distance string `json:"distance,omitempty"`
+}
+
+// GuaranteedStopLossOrderTransaction is a GUARANTEED_STOP_LOSS_ORDER, the creation of a guaranteed stop loss order
+type GuaranteedStopLossOrderTransaction struct {
+	DependentOrderTransaction
+	Price                      string `json:"price,omitempty"`
+	Distance                   string `json:"distance,omitempty"`
+	GuaranteedExecutionPremium string `json:"guaranteedExecutionPremium,omitempty"`
+}
+
+// TrailingStopLossOrderTransaction is a TRAILING_STOP_LOSS_ORDER, the creation of a trailing stop loss order
+type TrailingStopLossOrderTransaction struct {
+	DependentOrderTransaction
+	Distance string `json:"distance"`
+}
+
+// OrderCancelTransaction is an ORDER_CANCEL, an order being cancelled
+type OrderCancelTransaction struct {
+	TransactionHeader
+	OrderID           string `json:"orderID"`
+	ClientOrderID     string `json:"clientOrderID,omitempty"`
+	Reason            string `json:"reason"`
+	ReplacedByOrderID string `json:"replacedByOrderID,omitempty"`
+}
+
+// OrderClientExtensionsModifyTransaction is an ORDER_CLIENT_EXTENSIONS_MODIFY, an order's client extensions
+// or those of the trade it opens being changed
+type OrderClientExtensionsModifyTransaction struct {
+	TransactionHeader
+	OrderID                     string           `json:"orderID"`
+	ClientOrderID               string           `json:"clientOrderID,omitempty"`
+	ClientExtensionsModify      *OrderExtensions `json:"clientExtensionsModify,omitempty"`
+	TradeClientExtensionsModify *OrderExtensions `json:"tradeClientExtensionsModify,omitempty"`
+}
+
+// TradeClientExtensionsModifyTransaction is a TRADE_CLIENT_EXTENSIONS_MODIFY, an open trade's client
+// extensions being changed
+type TradeClientExtensionsModifyTransaction struct {
+	TransactionHeader
+	TradeID                     string           `json:"tradeID"`
+	ClientTradeID               string           `json:"clientTradeID,omitempty"`
+	TradeClientExtensionsModify *OrderExtensions `json:"tradeClientExtensionsModify,omitempty"`
+}
+
+// OrderRejectTransaction is any of the *_REJECT transactions, an order or request OANDA refused
+type OrderRejectTransaction struct {
+	TransactionHeader
+	Instrument   string `json:"instrument,omitempty"`
+	Units        string `json:"units,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	RejectReason string `json:"rejectReason"`
+	OrderID      string `json:"orderID,omitempty"`
+	TradeID      string `json:"tradeID,omitempty"`
+}
+
+// DailyFinancingTransaction is a DAILY_FINANCING, the financing charged or paid on open positions
+type DailyFinancingTransaction struct {
+	TransactionHeader
+	Financing      string `json:"financing"`
+	AccountBalance string `json:"accountBalance"`
+}
+
+// TransferFundsTransaction is a TRANSFER_FUNDS, a deposit into or withdrawal from the account
+type TransferFundsTransaction struct {
+	TransactionHeader
+	Amount         string `json:"amount"`
+	FundingReason  string `json:"fundingReason"`
+	Comment        string `json:"comment,omitempty"`
+	AccountBalance string `json:"accountBalance"`
+}
+
+// CreateTransaction is a CREATE, the account being created
+type CreateTransaction struct {
+	TransactionHeader
+	DivisionID    int    `json:"divisionID"`
+	SiteID        int    `json:"siteID"`
+	AccountUserID int    `json:"accountUserID"`
+	AccountNumber int    `json:"accountNumber"`
+	HomeCurrency  string `json:"homeCurrency"`
+}
+
+// CloseTransaction is a CLOSE, the account being closed
+type CloseTransaction struct {
+	TransactionHeader
+}
+
+// ReopenTransaction is a REOPEN, a closed account being reopened
+type ReopenTransaction struct {
+	TransactionHeader
+}
+
+// ClientConfigureTransaction is a CLIENT_CONFIGURE, the account's alias or margin rate being changed
+type ClientConfigureTransaction struct {
+	TransactionHeader
+	Alias      string `json:"alias,omitempty"`
+	MarginRate string `json:"marginRate,omitempty"`
+}
+
+// MarginCallTransaction is a MARGIN_CALL_ENTER, MARGIN_CALL_EXTEND or MARGIN_CALL_EXIT, the account
+// entering, having extended or leaving a margin call, see Type
+type MarginCallTransaction struct {
+	TransactionHeader
+	// ExtensionNumber is how many times the margin call has been extended, for a MARGIN_CALL_EXTEND
+	ExtensionNumber int `json:"extensionNumber,omitempty"`
+}
+
+// DelayedTradeClosureTransaction is a DELAYED_TRADE_CLOSURE, trades whose closing was delayed, as when
+// the market was closed, being closed
+type DelayedTradeClosureTransaction struct {
+	TransactionHeader
+	Reason string `json:"reason"`
+	// TradeIDs is the closed trades' IDs, separated by commas, see Trades
+	TradeIDs string `json:"tradeIDs"`
+}
+
+// Trades returns the IDs of the closed trades
+func (t *DelayedTradeClosureTransaction) Trades() []string {
+	if t.TradeIDs == "" {
+		return nil
+	}
+	return strings.Split(t.TradeIDs, ",")
+}
+
+// DividendAdjustmentTransaction is a DIVIDEND_ADJUSTMENT, a CFD's dividend paid or charged on its open
+// trades
+type DividendAdjustmentTransaction struct {
+	TransactionHeader
+	Instrument                   string                    `json:"instrument"`
+	DividendAdjustment           string                    `json:"dividendAdjustment"`
+	QuoteDividendAdjustment      string                    `json:"quoteDividendAdjustment,omitempty"`
+	AccountBalance               string                    `json:"accountBalance"`
+	OpenTradeDividendAdjustments []TradeDividendAdjustment `json:"openTradeDividendAdjustments,omitempty"`
+}
+
+// TradeDividendAdjustment is the part of a dividend adjustment paid or charged on one trade
+type TradeDividendAdjustment struct {
+	TradeID                 string `json:"tradeID"`
+	DividendAdjustment      string `json:"dividendAdjustment"`
+	QuoteDividendAdjustment string `json:"quoteDividendAdjustment,omitempty"`
+}
+
+// ResetResettablePLTransaction is a RESET_RESETTABLE_PL, the account's resettable profit and loss being
+// reset to zero
+type ResetResettablePLTransaction struct {
+	TransactionHeader
+}
+
+// UnknownTransaction is a transaction of a type without a concrete type, its fields are in Raw
+type UnknownTransaction struct {
+	TransactionHeader
+	Raw json.RawMessage `json:"-"`
+}
+
+// DecodeTransaction decodes a transaction into the concrete type for its type field
+func DecodeTransaction(data []byte) (TypedTransaction, error) {
+	var header TransactionHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("goanda: decoding transaction: %w", err)
+	}
+
+	t := newTransaction(header.Type)
+	if t == nil {
+		return &UnknownTransaction{TransactionHeader: header, Raw: data}, nil
+	}
+
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("goanda: decoding %s transaction: %w", header.Type, err)
+	}
+	return t, nil
+}
+
+// newTransaction returns the concrete type a transaction of the type is decoded into, or nil if the
+// type isn't one the library knows
+func newTransaction(transactionType string) TypedTransaction {
+	switch transactionType {
+	case "ORDER_FILL":
+		return &OrderFillTransaction{}
+	case "MARKET_ORDER":
+		return &MarketOrderTransaction{}
+	case "LIMIT_ORDER":
+		return &LimitOrderTransaction{}
+	case "STOP_ORDER":
+		return &StopOrderTransaction{}
+	case "MARKET_IF_TOUCHED_ORDER":
+		return &MarketIfTouchedOrderTransaction{}
+	case "FIXED_PRICE_ORDER":
+		return &FixedPriceOrderTransaction{}
+	case "TAKE_PROFIT_ORDER":
+		return &TakeProfitOrderTransaction{}
+	case "STOP_LOSS_ORDER":
+		return &StopLossOrderTransaction{}
+	case "GUARANTEED_STOP_LOSS_ORDER":
+		return &GuaranteedStopLossOrderTransaction{}
+	case "TRAILING_STOP_LOSS_ORDER":
+		return &TrailingStopLossOrderTransaction{}
+	case "ORDER_CANCEL":
+		return &OrderCancelTransaction{}
+	case "ORDER_CLIENT_EXTENSIONS_MODIFY":
+		return &OrderClientExtensionsModifyTransaction{}
+	case "TRADE_CLIENT_EXTENSIONS_MODIFY":
+		return &TradeClientExtensionsModifyTransaction{}
+	case "DAILY_FINANCING":
+		return &DailyFinancingTransaction{}
+	case "TRANSFER_FUNDS":
+		return &TransferFundsTransaction{}
+	case "CREATE":
+		return &CreateTransaction{}
+	case "CLOSE":
+		return &CloseTransaction{}
+	case "REOPEN":
+		return &ReopenTransaction{}
+	case "CLIENT_CONFIGURE":
+		return &ClientConfigureTransaction{}
+	case "MARGIN_CALL_ENTER", "MARGIN_CALL_EXTEND", "MARGIN_CALL_EXIT":
+		return &MarginCallTransaction{}
+	case "DELAYED_TRADE_CLOSURE":
+		return &DelayedTradeClosureTransaction{}
+	case "DIVIDEND_ADJUSTMENT":
+		return &DividendAdjustmentTransaction{}
+	case "RESET_RESETTABLE_PL":
+		return &ResetResettablePLTransaction{}
+	default:
+		if strings.HasSuffix(transactionType, "_REJECT") {
+			return &OrderRejectTransaction{}
+		}
+		return nil
+	}
+}
+
+// UnmarshalJSON decodes a message of the transaction stream. OANDA streams each transaction as it is,
+// with its own id and type at the top level, so for any message but a heartbeat the whole message is
+// kept as its Transaction, unless it nests one, as backfilled transactions do.
+func (r *TransactionStreamResponse) UnmarshalJSON(data []byte) error {
+	type message TransactionStreamResponse
+	var m message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	if len(m.Transaction) == 0 && m.Type != "HEARTBEAT" {
+		m.Transaction = append(json.RawMessage(nil), data...)
+	}
+	*r = TransactionStreamResponse(m)
+	return nil
+}
+
+// conformSchema checks a message of the transaction stream against the type DecodeTransaction decodes
+// its transaction into, or a heartbeat against HeartbeatResponse. Transactions of types the library
+// doesn't know are not checked.
+func (r *TransactionStreamResponse) conformSchema(value interface{}, path string) *SchemaError {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if object["type"] == "HEARTBEAT" {
+		err := conform(value, reflect.TypeOf(HeartbeatResponse{}), path)
+		if err != nil {
+			err.Type = "HeartbeatResponse"
+		}
+		return err
+	}
+	if nested, ok := object["transaction"]; ok {
+		// a backfilled transaction, nested in a message of its own
+		type message TransactionStreamResponse
+		if err := conform(value, reflect.TypeOf(message{}), path); err != nil {
+			err.Type = "TransactionStreamResponse"
+			return err
+		}
+		return conformTransaction(nested, join(path, "transaction"))
+	}
+	return conformTransaction(value, path)
+}
+
+// conformTransaction checks a transaction against its concrete type
+func conformTransaction(value interface{}, path string) *SchemaError {
+	object, _ := value.(map[string]interface{})
+	transactionType, _ := object["type"].(string)
+	t := newTransaction(transactionType)
+	if t == nil {
+		return nil
+	}
+	err := conform(value, reflect.TypeOf(t), path)
+	if err != nil {
+		err.Type = reflect.TypeOf(t).Elem().Name()
+	}
+	return err
+}
+
+// Decode decodes the streamed transaction into its concrete type
+func (r TransactionStreamResponse) Decode() (TypedTransaction, error) {
+	if len(r.Transaction) == 0 {
+		return nil, errors.New("goanda: stream message has no transaction")
+	}
+	return DecodeTransaction(r.Transaction)
+}
@@ -0,0 +1,158 @@
+package goanda
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeTransaction(t *testing.T) {
+	defer logTestResult(t, "DecodeTransaction")
+
+	tests := []struct {
+		message string
+		check   func(TypedTransaction) bool
+	}{
+		{
+			`{"id":"6","type":"ORDER_FILL","time":"2024-01-02T15:04:05Z","orderID":"5","instrument":"EUR_USD","units":"-100",
+				"price":"1.10000","reason":"STOP_LOSS_ORDER","pl":"-1.5","tradesClosed":[{"tradeID":"3","units":"-100","realizedPL":"-1.5"}]}`,
+			func(tx TypedTransaction) bool {
+				fill, ok := tx.(*OrderFillTransaction)
+				return ok && fill.ID == "6" && fill.CloseReason().StoppedOut() && fill.TradesClosed[0].TradeID == "3"
+			},
+		},
+		{
+			`{"id":"7","type":"STOP_LOSS_ORDER","time":"2024-01-02T15:04:05Z","tradeID":"3","price":"1.09","timeInForce":"GTC","reason":"ON_FILL"}`,
+			func(tx TypedTransaction) bool {
+				stop, ok := tx.(*StopLossOrderTransaction)
+				return ok && stop.TradeID == "3" && stop.Price == "1.09" && stop.TimeInForce == "GTC"
+			},
+		},
+		{
+			`{"id":"8","type":"MARKET_ORDER","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD","units":"100","timeInForce":"FOK",
+				"clientExtensions":{"id":"my-order"}}`,
+			func(tx TypedTransaction) bool {
+				market, ok := tx.(*MarketOrderTransaction)
+				return ok && market.ClientExtensions.ID == "my-order"
+			},
+		},
+		{
+			`{"id":"9","type":"LIMIT_ORDER_REJECT","time":"2024-01-02T15:04:05Z","rejectReason":"INSUFFICIENT_MARGIN"}`,
+			func(tx TypedTransaction) bool {
+				reject, ok := tx.(*OrderRejectTransaction)
+				return ok && reject.RejectReason == "INSUFFICIENT_MARGIN" && reject.Header().Type == "LIMIT_ORDER_REJECT"
+			},
+		},
+		{
+			`{"id":"11","type":"FIXED_PRICE_ORDER","time":"2024-01-02T15:04:05Z","instrument":"EUR_USD","units":"100",
+				"price":"1.1000","tradeState":"OPEN","reason":"PLATFORM_ACCOUNT_MIGRATION"}`,
+			func(tx TypedTransaction) bool {
+				fixed, ok := tx.(*FixedPriceOrderTransaction)
+				return ok && fixed.Price == "1.1000" && fixed.TradeState == "OPEN" && fixed.Reason == "PLATFORM_ACCOUNT_MIGRATION"
+			},
+		},
+		{
+			`{"id":"12","type":"TRADE_CLIENT_EXTENSIONS_MODIFY","time":"2024-01-02T15:04:05Z","tradeID":"3",
+				"tradeClientExtensionsModify":{"id":"my-trade","tag":"breakout"}}`,
+			func(tx TypedTransaction) bool {
+				modify, ok := tx.(*TradeClientExtensionsModifyTransaction)
+				return ok && modify.TradeID == "3" && modify.TradeClientExtensionsModify.Tag == "breakout"
+			},
+		},
+		{
+			`{"id":"13","type":"MARGIN_CALL_EXTEND","time":"2024-01-02T15:04:05Z","extensionNumber":2}`,
+			func(tx TypedTransaction) bool {
+				call, ok := tx.(*MarginCallTransaction)
+				return ok && call.ExtensionNumber == 2 && call.Type == "MARGIN_CALL_EXTEND"
+			},
+		},
+		{
+			`{"id":"14","type":"DELAYED_TRADE_CLOSURE","time":"2024-01-02T15:04:05Z","reason":"MARKET_HALTED","tradeIDs":"3,5"}`,
+			func(tx TypedTransaction) bool {
+				closure, ok := tx.(*DelayedTradeClosureTransaction)
+				return ok && len(closure.Trades()) == 2 && closure.Trades()[1] == "5"
+			},
+		},
+		{
+			`{"id":"15","type":"DIVIDEND_ADJUSTMENT","time":"2024-01-02T15:04:05Z","instrument":"SPX500_USD",
+				"dividendAdjustment":"-1.20","accountBalance":"998.80","openTradeDividendAdjustments":[{"tradeID":"3","dividendAdjustment":"-1.20"}]}`,
+			func(tx TypedTransaction) bool {
+				dividend, ok := tx.(*DividendAdjustmentTransaction)
+				return ok && dividend.DividendAdjustment == "-1.20" && dividend.OpenTradeDividendAdjustments[0].TradeID == "3"
+			},
+		},
+		{
+			`{"id":"1","type":"CREATE","time":"2024-01-02T15:04:05Z","divisionID":4,"accountNumber":1,"homeCurrency":"USD"}`,
+			func(tx TypedTransaction) bool {
+				create, ok := tx.(*CreateTransaction)
+				return ok && create.HomeCurrency == "USD" && create.DivisionID == 4
+			},
+		},
+		{
+			`{"id":"10","type":"A_NEW_TRANSACTION_TYPE","time":"2024-01-02T15:04:05Z"}`,
+			func(tx TypedTransaction) bool {
+				unknown, ok := tx.(*UnknownTransaction)
+				return ok && unknown.ID == "10" && len(unknown.Raw) > 0
+			},
+		},
+	}
+	for _, test := range tests {
+		tx, err := DecodeTransaction([]byte(test.message))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			continue
+		}
+		if !test.check(tx) {
+			t.Errorf("Unexpected transaction: %#v", tx)
+		}
+	}
+
+	if _, err := DecodeTransaction([]byte(`{"type":"ORDER_FILL","units":100}`)); err == nil {
+		t.Error("Expected an error for a malformed transaction")
+	}
+}
+
+func TestTransactionStreamResponseDecode(t *testing.T) {
+	defer logTestResult(t, "TransactionStreamResponseDecode")
+
+	response := TransactionStreamResponse{
+		Type:        "ORDER_FILL",
+		Transaction: json.RawMessage(`{"id":"6","type":"DAILY_FINANCING","financing":"-0.25"}`),
+	}
+	tx, err := response.Decode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if financing, ok := tx.(*DailyFinancingTransaction); !ok || financing.Financing != "-0.25" {
+		t.Errorf("Unexpected transaction: %#v", tx)
+	}
+
+	if _, err := (TransactionStreamResponse{Type: "HEARTBEAT"}).Decode(); err == nil {
+		t.Error("Expected an error without a transaction")
+	}
+}
+
+func TestTransactionStreamResponseUnmarshal(t *testing.T) {
+	defer logTestResult(t, "TransactionStreamResponseUnmarshal")
+
+	// OANDA streams the transaction itself, not nested in the message
+	var response TransactionStreamResponse
+	line := `{"id":"6","type":"ORDER_FILL","time":"2024-01-02T15:04:05Z","orderID":"5","units":"100","price":"1.1"}`
+	if err := json.Unmarshal([]byte(line), &response); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tx, err := response.Decode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fill, ok := tx.(*OrderFillTransaction); !ok || fill.ID != "6" || fill.OrderID != "5" || response.id() != "6" {
+		t.Errorf("Unexpected transaction: %#v", tx)
+	}
+
+	var heartbeat TransactionStreamResponse
+	if err := json.Unmarshal([]byte(`{"type":"HEARTBEAT","lastTransactionID":"6","time":"2024-01-02T15:04:05Z"}`), &heartbeat); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(heartbeat.Transaction) != 0 {
+		t.Errorf("Expected a heartbeat without a transaction, got %s", heartbeat.Transaction)
+	}
+}
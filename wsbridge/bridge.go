@@ -0,0 +1,273 @@
+// Package wsbridge serves goanda's price and transaction streams over WebSocket, so browser dashboards
+// and services in other languages can share one upstream connection to OANDA.
+//
+// A Server streams prices for a fixed set of instruments and fans them out to its clients, each
+// receiving the instruments it subscribed to. Clients choose them with the instruments query
+// parameter, ws://host/prices?instruments=EUR_USD,USD_JPY, and change them by sending:
+//
+//	{"subscribe":["EUR_USD","USD_JPY"]}
+//	{"unsubscribe":["USD_JPY"]}
+//
+// Every message sent to clients is a JSON Message.
+package wsbridge
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rollend/goanda"
+)
+
+// DefaultClientBuffer is the number of messages queued for a client when no ClientBuffer is set
+const DefaultClientBuffer = 256
+
+// Message types sent to clients
+const (
+	MessagePrice       = "price"
+	MessageTransaction = "transaction"
+	// MessageSubscribed answers a subscription change with the client's instruments
+	MessageSubscribed = "subscribed"
+	MessageError      = "error"
+)
+
+// Message is a message sent to clients, Type says which of its fields is set
+type Message struct {
+	Type        string                            `json:"type"`
+	Price       *goanda.PricingStreamResponse     `json:"price,omitempty"`
+	Transaction *goanda.TransactionStreamResponse `json:"transaction,omitempty"`
+	Instruments []string                          `json:"instruments,omitempty"`
+	Error       string                            `json:"error,omitempty"`
+}
+
+// SubscriptionRequest is a message clients send to change their instruments
+type SubscriptionRequest struct {
+	Subscribe   []string `json:"subscribe,omitempty"`
+	Unsubscribe []string `json:"unsubscribe,omitempty"`
+}
+
+// Server is an http.Handler accepting WebSocket clients, and streams to them while Run is running
+type Server struct {
+	// Authorize, if set, checks each client's upgrade request. Clients it returns an error for are
+	// refused with 401 Unauthorized. See TokenAuth.
+	Authorize func(r *http.Request) error
+	// Transactions sends the account's transactions to every client
+	Transactions bool
+	// ClientBuffer is the number of messages queued for a client before it is disconnected as too
+	// slow, it defaults to DefaultClientBuffer
+	ClientBuffer int
+
+	sc          *goanda.StreamingConnection
+	instruments []string
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+}
+
+type client struct {
+	conn *conn
+	send chan []byte
+
+	// instruments is only used under the server's lock
+	instruments map[string]bool
+	closeCode   int
+	closeReason string
+}
+
+// NewServer creates a server streaming prices for the instruments, which are all clients may subscribe to
+func NewServer(sc *goanda.StreamingConnection, instruments ...string) *Server {
+	return &Server{sc: sc, instruments: instruments, clients: map[*client]struct{}{}}
+}
+
+// TokenAuth returns an Authorize function accepting clients that present the token, either as a
+// bearer token in the Authorization header or, as browsers can't set headers on WebSockets, in the
+// token query parameter.
+func TokenAuth(token string) func(r *http.Request) error {
+	return func(r *http.Request) error {
+		presented := r.URL.Query().Get("token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			presented = bearer
+		}
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			return errors.New("wsbridge: invalid token")
+		}
+		return nil
+	}
+}
+
+// Run streams from OANDA and sends to the clients until ctx is done or a stream fails, then
+// disconnects them and returns the error.
+func (s *Server) Run(ctx context.Context) error {
+	events := s.sc.Events(ctx, goanda.EventsOptions{Instruments: s.instruments, Transactions: s.Transactions})
+
+	var err error
+	for event := range events {
+		switch event.Kind {
+		case goanda.EventPrice:
+			price := event.Price
+			s.broadcast(price.Instrument, Message{Type: MessagePrice, Price: &price})
+		case goanda.EventTransaction:
+			transaction := event.Transaction
+			s.broadcast("", Message{Type: MessageTransaction, Transaction: &transaction})
+		case goanda.EventError:
+			err = event.Err
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	for c := range s.clients {
+		c.closeCode, c.closeReason = closeGoingAway, "upstream stream ended"
+		s.drop(c)
+	}
+	s.mu.Unlock()
+	return err
+}
+
+// broadcast sends a message to the clients subscribed to the instrument, or to every client if there is none
+func (s *Server) broadcast(instrument string, message Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		if instrument != "" && !c.instruments[instrument] {
+			continue
+		}
+		s.enqueue(c, data)
+	}
+}
+
+// enqueue queues a message for a client, disconnecting it if its queue is full. It needs s.mu.
+func (s *Server) enqueue(c *client, data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		// The client's writes are blocked, closing the connection unblocks them
+		c.closeCode, c.closeReason = closePolicy, "too slow"
+		s.drop(c)
+		c.conn.nc.Close()
+	}
+}
+
+// drop removes a client, its writer closes the connection once its queue is sent. It needs s.mu.
+func (s *Server) drop(c *client) {
+	if _, ok := s.clients[c]; ok {
+		delete(s.clients, c)
+		close(c.send)
+	}
+}
+
+// ServeHTTP accepts a WebSocket client
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Authorize != nil {
+		if err := s.Authorize(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+	conn, err := accept(w, r)
+	if err != nil {
+		return
+	}
+
+	buffer := s.ClientBuffer
+	if buffer <= 0 {
+		buffer = DefaultClientBuffer
+	}
+	c := &client{conn: conn, send: make(chan []byte, buffer), instruments: map[string]bool{}, closeCode: closeNormal}
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for data := range c.send {
+			if err := conn.writeText(data); err != nil {
+				conn.nc.Close()
+			}
+		}
+		s.mu.Lock()
+		code, reason := c.closeCode, c.closeReason
+		s.mu.Unlock()
+		conn.close(code, reason)
+	}()
+
+	s.mu.Lock()
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	var initial []string
+	if instruments := r.URL.Query().Get("instruments"); instruments != "" {
+		initial = strings.Split(instruments, ",")
+	}
+	s.subscribe(c, SubscriptionRequest{Subscribe: initial})
+
+	for {
+		data, err := conn.readMessage()
+		if err != nil {
+			break
+		}
+		var request SubscriptionRequest
+		if err := json.Unmarshal(data, &request); err != nil {
+			s.reply(c, Message{Type: MessageError, Error: fmt.Sprintf("invalid subscription request: %v", err)})
+			continue
+		}
+		s.subscribe(c, request)
+	}
+
+	s.mu.Lock()
+	s.drop(c)
+	s.mu.Unlock()
+	<-written
+}
+
+// subscribe changes a client's instruments and tells it the result
+func (s *Server) subscribe(c *client, request SubscriptionRequest) {
+	var unknown []string
+	s.mu.Lock()
+	for _, instrument := range request.Subscribe {
+		if !slices.Contains(s.instruments, instrument) {
+			unknown = append(unknown, instrument)
+			continue
+		}
+		c.instruments[instrument] = true
+	}
+	for _, instrument := range request.Unsubscribe {
+		delete(c.instruments, instrument)
+	}
+	subscribed := make([]string, 0, len(c.instruments))
+	for instrument := range c.instruments {
+		subscribed = append(subscribed, instrument)
+	}
+	s.mu.Unlock()
+
+	if len(unknown) > 0 {
+		s.reply(c, Message{Type: MessageError, Error: fmt.Sprintf("instruments not streamed: %s", strings.Join(unknown, ","))})
+	}
+	slices.Sort(subscribed)
+	s.reply(c, Message{Type: MessageSubscribed, Instruments: subscribed})
+}
+
+// reply queues a message for one client
+func (s *Server) reply(c *client, message Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[c]; ok {
+		s.enqueue(c, data)
+	}
+}
@@ -0,0 +1,131 @@
+package wsbridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rollend/goanda"
+)
+
+// dial opens a WebSocket connection to the bridge, returning the handshake's status if it was refused
+func dial(t *testing.T, server *httptest.Server, path string) (*conn, int) {
+	t.Helper()
+	nc, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Expected to connect, got %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if err := req.Write(nc); err != nil {
+		t.Fatalf("Expected to send the handshake, got %v", err)
+	}
+
+	br := bufio.NewReader(nc)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("Expected a handshake response, got %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		nc.Close()
+		return nil, resp.StatusCode
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Expected the RFC 6455 accept key, got %q", accept)
+	}
+	return &conn{nc: nc, br: br, mask: true}, resp.StatusCode
+}
+
+func readMessage(t *testing.T, c *conn) Message {
+	t.Helper()
+	c.nc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	data, err := c.readMessage()
+	if err != nil {
+		t.Fatalf("Expected a message, got %v", err)
+	}
+	var message Message
+	if err := json.Unmarshal(data, &message); err != nil {
+		t.Fatalf("Expected a JSON message, got %q", data)
+	}
+	return message
+}
+
+func TestServer(t *testing.T) {
+	// The replayed prices come a second after the stream opens, once the client has subscribed
+	dir := t.TempDir()
+	recorder, err := goanda.NewStreamRecorder(dir)
+	if err != nil {
+		t.Fatalf("Expected a recorder, got %v", err)
+	}
+	stream := "/accounts/test-account/pricing/stream?instruments=EUR_USD%2CUSD_JPY"
+	start := time.Now()
+	recorder.Record(stream, []byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:04:05Z"}`), start)
+	recorder.Record(stream, []byte(`{"type":"PRICE","time":"2024-01-02T15:04:06Z","instrument":"USD_JPY"}`), start.Add(time.Second))
+	recorder.Record(stream, []byte(`{"type":"PRICE","time":"2024-01-02T15:04:06Z","instrument":"EUR_USD"}`), start.Add(time.Second))
+	recorder.Record(stream, []byte(`{"type":"HEARTBEAT","time":"2024-01-02T15:05:05Z"}`), start.Add(time.Minute))
+	recorder.Close()
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	replayer, err := goanda.NewStreamReplayer(paths...)
+	if err != nil {
+		t.Fatalf("Expected a replayer, got %v", err)
+	}
+	replayer.Speed = 1
+	sc := replayer.StreamingConnection()
+
+	bridge := NewServer(sc, "EUR_USD", "USD_JPY")
+	bridge.Authorize = TokenAuth("secret")
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan error, 1)
+	go func() { ran <- bridge.Run(ctx) }()
+
+	server := httptest.NewServer(bridge)
+	defer server.Close()
+
+	if _, status := dial(t, server, "/?instruments=EUR_USD"); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", status)
+	}
+
+	client, _ := dial(t, server, "/?instruments=EUR_USD,GBP_USD&token=secret")
+	if client == nil {
+		t.Fatal("Expected the client to be accepted")
+	}
+	if m := readMessage(t, client); m.Type != MessageError || !strings.Contains(m.Error, "GBP_USD") {
+		t.Errorf("Expected an error for the instrument not streamed, got %+v", m)
+	}
+	if m := readMessage(t, client); m.Type != MessageSubscribed || len(m.Instruments) != 1 || m.Instruments[0] != "EUR_USD" {
+		t.Errorf("Expected a subscription to EUR_USD, got %+v", m)
+	}
+
+	if m := readMessage(t, client); m.Type != MessagePrice || m.Price.Instrument != "EUR_USD" {
+		t.Errorf("Expected only the subscribed instrument's price, got %+v", m)
+	}
+
+	if err := client.writeText([]byte(`{"subscribe":["USD_JPY"],"unsubscribe":["EUR_USD"]}`)); err != nil {
+		t.Fatalf("Expected to send a subscription, got %v", err)
+	}
+	if m := readMessage(t, client); m.Type != MessageSubscribed || len(m.Instruments) != 1 || m.Instruments[0] != "USD_JPY" {
+		t.Errorf("Expected a subscription to USD_JPY, got %+v", m)
+	}
+
+	cancel()
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Run to return")
+	}
+	client.nc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, op, _, err := client.readFrame(); err != nil || op != opClose {
+		t.Errorf("Expected a close frame, got %v %v", op, err)
+	}
+}